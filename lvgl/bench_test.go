@@ -0,0 +1,82 @@
+package lvgl
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// goRegularRunes returns every rune of the BMP that Go Regular maps to a glyph.
+func goRegularRunes(t testing.TB, pf *sfnt.Font) []rune {
+	t.Helper()
+	var runes []rune
+	buf := &sfnt.Buffer{}
+	for r := rune(0x20); r < 0x10000; r++ {
+		if gi, err := pf.GlyphIndex(buf, r); err == nil && gi != 0 {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// BenchmarkNewFonts converts about 2,000 glyphs: the Go Regular repertoire at three sizes.
+func BenchmarkNewFonts(b *testing.B) {
+	pf := loadGoRegular(b)
+	runes := goRegularRunes(b, pf)
+	sizes := []uint16{12, 16, 24}
+	glyphs := float64(len(runes) * len(sizes))
+	b.ReportAllocs()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for b.Loop() {
+		if _, err := NewFonts(pf, sizes, runes, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(glyphs, "glyphs/op")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N)/glyphs, "allocs/glyph")
+}
+
+// TestNewFonts_Concurrent converts with several goroutines sharing one sfnt.Font and the
+// pools; run it with -race.
+func TestNewFonts_Concurrent(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := goRegularRunes(t, pf)[:300]
+	sizes := []uint16{12, 16, 24}
+	want, err := NewFonts(pf, sizes, runes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			got, err := NewFonts(pf, sizes, runes, Options{Parallel: true, Embolden: 0.5})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for _, size := range sizes {
+				if len(got[size]) <= len(want[size]) {
+					t.Errorf("size %d: emboldened font not larger", size)
+				}
+			}
+		})
+		wg.Go(func() {
+			got, err := NewFonts(pf, sizes, runes, Options{Parallel: true})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for _, size := range sizes {
+				if !bytes.Equal(got[size], want[size]) {
+					t.Errorf("size %d: output differs under concurrency", size)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -1,6 +1,30 @@
 package lvgl
 
-import "encoding/binary"
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+)
+
+// Cmap subtable format types (CmapSubTableHeader.FormatType).
+const (
+	CmapFormat0Full      byte = 0 // continuous range, 1 byte glyph ID offset per codepoint
+	CmapFormatSparse     byte = 1 // codepoint deltas plus 2 byte glyph ID offsets
+	CmapFormat0Tiny      byte = 2 // continuous range, consecutive glyph IDs, no data
+	CmapFormatSparseTiny byte = 3 // codepoint deltas only, consecutive glyph IDs
+)
+
+// cmapDenseMinRun is the shortest contiguous run that is worth moving out of a sparse
+// subtable into its own format 0 subtable: cutting a run out of the middle of a sparse
+// range costs two extra subtable headers (2*16 bytes) and saves at least 1 byte per entry
+// (2 for format 0 tiny).
+const cmapDenseMinRun = 16
+
+// cmapMaxGap is the largest codepoint gap kept inside one subtable. A sparse subtable only
+// pays for its entries, so gaps cost nothing by themselves, but a wider gap would make the
+// range (and every lookup scanning it) cover unrelated blocks for the price of one 16 byte
+// header.
+const cmapMaxGap = 1024
 
 type CmapTable struct {
 	Size   uint32  //4	Record size (for quick skip)
@@ -28,56 +52,171 @@ type CmapFormat0Data []uint8
 
 type CmapSparseData struct {
 	CodeDeltas  []uint16 // codePoint - range_start
-	GlyphDeltas []uint16 // delta-encoded glyph IDs（下一条我补充解释）
+	GlyphDeltas []uint16 // glyph ID - GlyphIdOffset
 }
 
 type CmapSparseTinyData []uint16 // 只存 codePoint - range_start
 
-func NewCmapTable(runes []rune) (*CmapTable, []CmapSubTableHeader, []uint16) {
-	tableRunes := CmapSplitSubTable(runes)
+// cmapRange is a run of sorted runes and their glyph IDs that ends up in one subtable.
+type cmapRange struct {
+	runes  []rune
+	glyphs []uint16
+	format byte
+}
+
+// NewCmapTable builds the cmap table for sorted, deduplicated `runes`, where runes[i] is
+// stored as glyph ID i+1 (glyph 0 is reserved for notdef). The returned data is the
+// little-endian subtable data that follows the subtable headers.
+func NewCmapTable(runes []rune) (*CmapTable, []CmapSubTableHeader, []byte) {
+	glyphIDs := make([]uint16, len(runes))
+	for i := range runes {
+		glyphIDs[i] = uint16(i + 1)
+	}
+	return NewCmapTableWithIDs(runes, glyphIDs)
+}
+
+// NewCmapTableWithIDs builds the cmap table mapping sorted, deduplicated `runes` to
+// `glyphIDs` (same length, glyph 0 is reserved for notdef). The glyph IDs do not need to
+// follow codepoint order; each subtable gets the cheapest format able to express its part
+// of the mapping.
+func NewCmapTableWithIDs(runes []rune, glyphIDs []uint16) (*CmapTable, []CmapSubTableHeader, []byte) {
+	ranges := cmapSplitRanges(runes, glyphIDs)
 	t := &CmapTable{
 		Size:   0,
 		Label:  [4]byte{'c', 'm', 'a', 'p'},
-		Tables: uint32(len(tableRunes)),
+		Tables: uint32(len(ranges)),
 	}
 	cmapDataOffset := binary.Size(t)
 	subHeaders := make([]CmapSubTableHeader, t.Tables)
-	for i, subRunes := range tableRunes {
+	for i, cr := range ranges {
 		subHeaders[i] = CmapSubTableHeader{
-			RangeStart:       uint32(subRunes[0]),
-			RangeLength:      uint16(subRunes[len(subRunes)-1] - subRunes[0] + 1),
-			GlyphIdOffset:    1,
-			DataEntriesCount: uint16(len(subRunes)),
-			FormatType:       3,
+			RangeStart:       uint32(cr.runes[0]),
+			RangeLength:      uint16(cr.runes[len(cr.runes)-1] - cr.runes[0] + 1),
+			GlyphIdOffset:    slices.Min(cr.glyphs),
+			DataEntriesCount: uint16(len(cr.runes)),
+			FormatType:       cr.format,
 		}
-		if i > 0 {
-			subHeaders[i].GlyphIdOffset = subHeaders[i-1].GlyphIdOffset + subHeaders[i-1].DataEntriesCount
+		if cr.format == CmapFormat0Tiny {
+			subHeaders[i].DataEntriesCount = 0
 		}
 	}
 	cmapDataOffset += binary.Size(subHeaders)
-	subDatas := make(CmapSparseTinyData, 0)
-	for i, subRunes := range tableRunes {
-		subHeaders[i].DataOffset = uint32(cmapDataOffset)
-		subData := make([]uint16, 0)
-		for i2 := range subRunes {
-			subData = append(subData, uint16(subRunes[i2]-subRunes[0]))
+	// Every subtable's data starts on a 4 byte boundary relative to the cmap table start:
+	// the table and subtable headers are multiples of 4 and each data block is padded.
+	subDatas := new(bytes.Buffer)
+	for i, cr := range ranges {
+		if cr.format == CmapFormat0Tiny {
+			continue
 		}
-		if blank := ((len(subData) * 2) % 4) / 2; blank != 0 {
-			subData = append(subData, make(CmapSparseTinyData, blank)...)
+		subHeaders[i].DataOffset = uint32(cmapDataOffset + subDatas.Len())
+		glyphStart := subHeaders[i].GlyphIdOffset
+		switch cr.format {
+		case CmapFormat0Full:
+			subData := make(CmapFormat0Data, len(cr.glyphs))
+			for i2, gid := range cr.glyphs {
+				subData[i2] = uint8(gid - glyphStart)
+			}
+			_ = binary.Write(subDatas, binary.LittleEndian, subData)
+		case CmapFormatSparse:
+			subData := CmapSparseData{
+				CodeDeltas:  make([]uint16, len(cr.runes)),
+				GlyphDeltas: make([]uint16, len(cr.runes)),
+			}
+			for i2 := range cr.runes {
+				subData.CodeDeltas[i2] = uint16(cr.runes[i2] - cr.runes[0])
+				subData.GlyphDeltas[i2] = cr.glyphs[i2] - glyphStart
+			}
+			_ = binary.Write(subDatas, binary.LittleEndian, subData.CodeDeltas)
+			_ = binary.Write(subDatas, binary.LittleEndian, subData.GlyphDeltas)
+		case CmapFormatSparseTiny:
+			subData := make(CmapSparseTinyData, len(cr.runes))
+			for i2 := range cr.runes {
+				subData[i2] = uint16(cr.runes[i2] - cr.runes[0])
+			}
+			_ = binary.Write(subDatas, binary.LittleEndian, subData)
 		}
-		cmapDataOffset += len(subData) * 2
-		subDatas = append(subDatas, subData...)
+		subDatas.Write(make([]byte, align4(subDatas.Len())-subDatas.Len()))
 	}
-	t.Size = uint32(cmapDataOffset)
-	return t, subHeaders, subDatas
+	t.Size = uint32(cmapDataOffset + subDatas.Len())
+	return t, subHeaders, subDatas.Bytes()
 }
 
+// cmapSplitRanges partitions the rune/glyph pairs into subtable ranges. Contiguous runs of
+// codepoints that are long enough and fit a format 0 subtable (no data when the glyph IDs are
+// consecutive, 1 byte per codepoint otherwise) get their own subtable; everything else gets
+// the cheapest format that can represent it.
+func cmapSplitRanges(runes []rune, glyphs []uint16) []cmapRange {
+	var ranges []cmapRange
+	start := 0
+	for _, chunk := range CmapSplitSubTable(runes) {
+		end := start + len(chunk)
+		sparseStart := start
+		for i := start; i < end; {
+			j := i + 1
+			for j < end && runes[j] == runes[j-1]+1 {
+				j++
+			}
+			run := cmapRange{runes: runes[i:j], glyphs: glyphs[i:j]}
+			if j-i >= cmapDenseMinRun && run.cheapestFormat() != CmapFormatSparse {
+				if sparseStart < i {
+					ranges = append(ranges, cmapRange{runes: runes[sparseStart:i], glyphs: glyphs[sparseStart:i]})
+				}
+				ranges = append(ranges, run)
+				sparseStart = j
+			}
+			i = j
+		}
+		if sparseStart < end {
+			ranges = append(ranges, cmapRange{runes: runes[sparseStart:end], glyphs: glyphs[sparseStart:end]})
+		}
+		start = end
+	}
+	for i := range ranges {
+		ranges[i].format = ranges[i].cheapestFormat()
+	}
+	return ranges
+}
+
+// cheapestFormat returns the smallest subtable format that can represent `cr`.
+func (cr *cmapRange) cheapestFormat() byte {
+	n := len(cr.runes)
+	contiguous := cr.runes[n-1]-cr.runes[0] == rune(n-1)
+	sequential := true
+	for i, gid := range cr.glyphs {
+		if int(gid) != int(cr.glyphs[0])+i {
+			sequential = false
+			break
+		}
+	}
+	switch {
+	case contiguous && sequential:
+		return CmapFormat0Tiny // no data
+	case sequential:
+		return CmapFormatSparseTiny // 2 bytes per entry
+	case contiguous && slices.Max(cr.glyphs)-slices.Min(cr.glyphs) <= 0xFF:
+		return CmapFormat0Full // 1 byte per entry
+	}
+	return CmapFormatSparse // 4 bytes per entry
+}
+
+// align4 rounds `n` up to a multiple of 4.
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// CmapSplitSubTable splits sorted `runes` into chunks that each become one or more cmap
+// subtables. A new chunk starts when the codepoint delta from the chunk start would not fit
+// the uint16 RangeLength/code deltas, or when the gap to the previous rune exceeds
+// cmapMaxGap, so distant blocks (e.g. ASCII and emoji) don't share one huge range.
 func CmapSplitSubTable(runes []rune) [][]rune {
+	if len(runes) == 0 {
+		return nil
+	}
 	startRune := runes[0]
 	item := make([]rune, 0)
 	resp := make([][]rune, 0)
 	for i := range runes {
-		if runes[i]-startRune >= 65535 {
+		if runes[i]-startRune >= 65535 || (i > 0 && runes[i]-runes[i-1] > cmapMaxGap) {
 			resp = append(resp, item)
 			item = make([]rune, 0)
 			startRune = runes[i]
@@ -0,0 +1,272 @@
+package lvgl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+func loadGoRegular(t testing.TB) *sfnt.Font {
+	t.Helper()
+	pf, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pf
+}
+
+func runeRange(from, to rune) []rune {
+	runes := make([]rune, 0, to-from+1)
+	for r := from; r <= to; r++ {
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// checkCmapRoundTrip asserts that every rune in `runes` resolves to glyph index+1 in `bf`.
+func checkCmapRoundTrip(t *testing.T, bf *BinFont, runes []rune) {
+	t.Helper()
+	for i, r := range runes {
+		id, ok := bf.GlyphID(r)
+		if !ok || id != uint32(i+1) {
+			t.Fatalf("rune %U: got glyph %d (%v), want %d", r, id, ok, i+1)
+		}
+	}
+}
+
+func TestNewCmapTable_Format0Tiny(t *testing.T) {
+	ascii := runeRange(0x20, 0x7E)
+	cmap, headers, data := NewCmapTable(ascii)
+	if len(headers) != 1 || headers[0].FormatType != CmapFormat0Tiny {
+		t.Fatalf("headers = %+v, want one format 0 tiny subtable", headers)
+	}
+	if len(data) != 0 || headers[0].DataOffset != 0 {
+		t.Fatalf("format 0 tiny must not carry data, got %d bytes at %d", len(data), headers[0].DataOffset)
+	}
+	// Sparse tiny would store 2 bytes per codepoint (95 entries, padded to 96).
+	sparseSize := 12 + 16 + 96*2
+	if int(cmap.Size) >= sparseSize {
+		t.Fatalf("cmap size %d, want less than sparse size %d", cmap.Size, sparseSize)
+	}
+	t.Logf("ASCII cmap: %d bytes (sparse tiny: %d bytes)", cmap.Size, sparseSize)
+
+	bin, err := NewFont(loadGoRegular(t), 16, ascii)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCmapRoundTrip(t, bf, ascii)
+}
+
+func TestNewCmapTable_MixedRuns(t *testing.T) {
+	// A long contiguous run between gappy codepoints.
+	runes := []rune{0x21, 0x25, 0x29}
+	runes = append(runes, runeRange('A', 'Z')...)
+	runes = append(runes, 0xA9, 0xB0, 0xE9)
+	_, headers, _ := NewCmapTable(runes)
+	var formats []byte
+	for _, h := range headers {
+		formats = append(formats, h.FormatType)
+	}
+	want := []byte{CmapFormatSparseTiny, CmapFormat0Tiny, CmapFormatSparseTiny}
+	if string(formats) != string(want) {
+		t.Fatalf("formats = %v, want %v", formats, want)
+	}
+
+	bin, err := NewFont(loadGoRegular(t), 16, runes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCmapRoundTrip(t, bf, runes)
+	if _, ok := bf.GlyphID('@'); ok {
+		t.Fatalf("unexpected glyph for %U", '@')
+	}
+}
+
+func TestNewCmapTableWithIDs_NonSequential(t *testing.T) {
+	pf := loadGoRegular(t)
+	// Contiguous codepoints with reversed glyph order (format 0 full), followed by gappy
+	// codepoints whose glyph IDs are spread too far for one byte (format sparse).
+	runes := append(runeRange('a', 'p'), 0x100, 0x120, 0x140)
+	glyphIDs := make([]uint16, 0, len(runes))
+	for i := range 16 {
+		glyphIDs = append(glyphIDs, uint16(16-i))
+	}
+	glyphIDs = append(glyphIDs, 300, 17, 18)
+	_, headers, _ := NewCmapTableWithIDs(runes, glyphIDs)
+	var formats []byte
+	for _, h := range headers {
+		formats = append(formats, h.FormatType)
+	}
+	want := []byte{CmapFormat0Full, CmapFormatSparse}
+	if string(formats) != string(want) {
+		t.Fatalf("formats = %v, want %v", formats, want)
+	}
+
+	f := &Font{HeadTable: NewHeadTable(pf, 16)}
+	glyphs := make([][]byte, 300)
+	buf := &sfnt.Buffer{}
+	for i, r := range runes {
+		g, err := AddGlyfData(buf, pf, 16, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		glyphs[glyphIDs[i]-1] = g.Bytes()
+	}
+	for i := range glyphs {
+		if glyphs[i] == nil {
+			glyphs[i] = make([]byte, 6) // empty glyph record
+		}
+	}
+	bin, _, err := f.encode(runes, glyphIDs, glyphs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range runes {
+		id, ok := bf.GlyphID(r)
+		if !ok || id != uint32(glyphIDs[i]) {
+			t.Fatalf("rune %U: got glyph %d (%v), want %d", r, id, ok, glyphIDs[i])
+		}
+		g, err := AddGlyfData(buf, pf, 16, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bg, _ := bf.Glyph(r); bg.AdvanceWidth != uint32(g.AdvanceWidth) || bg.BBoxWidth != uint32(g.BBoxWidth) {
+			t.Fatalf("rune %U: decoded glyph %+v does not match %+v", r, bg, g.GlyfDataInfo)
+		}
+	}
+	if _, ok := bf.GlyphID(0x101); ok {
+		t.Fatalf("unexpected glyph for %U", 0x101)
+	}
+}
+
+func TestCmapSplitSubTable_Gaps(t *testing.T) {
+	ascii := runeRange(0x20, 0x7E)
+	tests := []struct {
+		name   string
+		runes  []rune
+		chunks int
+	}{
+		{"ascii+emoji", append(runeRange(0x20, 0x7E), 0x1F600, 0x1F602, 0x1F60D), 2},
+		{"ascii+cjk", append(append(ascii[:len(ascii):len(ascii)], 0x4E00, 0x4E2D, 0x56FD), runeRange(0x6587, 0x65A0)...), 4},
+		{"ascii+latin1+cjk", append(append(ascii[:len(ascii):len(ascii)], 0xA9, 0xE9, 0x3001), 0x4E00), 3},
+		{"wide span", []rune{0x20, 0x420, 0x820, 0xC20, 0x1020, 0x1420, 0x1820, 0x1C20, 0x2020, 0x2420,
+			0x2820, 0x2C20, 0x3020, 0x3420, 0x3820, 0x3C20, 0x4020, 0x4420, 0x4820, 0x4C20, 0x5020,
+			0x5420, 0x5820, 0x5C20, 0x6020, 0x6420, 0x6820, 0x6C20, 0x7020, 0x7420, 0x7820, 0x7C20,
+			0x8020, 0x8420, 0x8820, 0x8C20, 0x9020, 0x9420, 0x9820, 0x9C20, 0xA020, 0xA420, 0xA820,
+			0xAC20, 0xB020, 0xB420, 0xB820, 0xBC20, 0xC020, 0xC420, 0xC820, 0xCC20, 0xD020, 0xD420,
+			0xD820, 0xDC20, 0xE020, 0xE420, 0xE820, 0xEC20, 0xF020, 0xF420, 0xF820, 0xFC20, 0x10020}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if chunks := CmapSplitSubTable(tt.runes); len(chunks) != tt.chunks {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.chunks)
+			}
+			_, headers, _ := NewCmapTable(tt.runes)
+			entries := 0
+			for i, h := range headers {
+				end := rune(h.RangeStart) + rune(h.RangeLength) - 1
+				if i > 0 && rune(h.RangeStart) <= rune(headers[i-1].RangeStart)+rune(headers[i-1].RangeLength)-1 {
+					t.Fatalf("subtable %d overlaps the previous one: %+v", i, h)
+				}
+				var n int
+				for _, r := range tt.runes {
+					if r >= rune(h.RangeStart) && r <= end {
+						n++
+					}
+				}
+				if h.FormatType == CmapFormat0Tiny {
+					if n != int(h.RangeLength) {
+						t.Fatalf("format 0 tiny subtable %d covers %d runes, range length %d", i, n, h.RangeLength)
+					}
+				} else if n != int(h.DataEntriesCount) {
+					t.Fatalf("subtable %d covers %d runes, DataEntriesCount %d", i, n, h.DataEntriesCount)
+				}
+				entries += n
+			}
+			if entries != len(tt.runes) {
+				t.Fatalf("subtables cover %d runes, want %d", entries, len(tt.runes))
+			}
+		})
+	}
+}
+
+func TestNewCmapTable_DataOffsets(t *testing.T) {
+	// Three subtables with data of odd lengths: sparse tiny (3 entries, 6 bytes),
+	// format 0 full (17 entries, 17 bytes) and full sparse (2 entries, 8 bytes).
+	runes := []rune{0x21, 0x25, 0x29}
+	runes = append(runes, runeRange(0x400, 0x410)...)
+	runes = append(runes, 0x1000, 0x1010)
+	glyphIDs := []uint16{1, 2, 3}
+	for i := range 17 {
+		glyphIDs = append(glyphIDs, uint16(20-i))
+	}
+	glyphIDs = append(glyphIDs, 30, 21)
+	cmap, headers, data := NewCmapTableWithIDs(runes, glyphIDs)
+	want := []byte{CmapFormatSparseTiny, CmapFormat0Full, CmapFormatSparse}
+	if len(headers) != len(want) {
+		t.Fatalf("got %d subtables, want %d", len(headers), len(want))
+	}
+
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, cmap)
+	_ = binary.Write(buf, binary.LittleEndian, headers)
+	buf.Write(data)
+	b := buf.Bytes()
+	if int(cmap.Size) != len(b) || len(b)%4 != 0 {
+		t.Fatalf("cmap size %d, serialized %d bytes", cmap.Size, len(b))
+	}
+
+	decoded := make([]CmapSubTableHeader, binary.LittleEndian.Uint32(b[8:]))
+	if err := binary.Read(bytes.NewReader(b[12:]), binary.LittleEndian, decoded); err != nil {
+		t.Fatal(err)
+	}
+	k := 0
+	for i, h := range decoded {
+		if h.FormatType != want[i] {
+			t.Fatalf("subtable %d: format %d, want %d", i, h.FormatType, want[i])
+		}
+		if h.DataOffset%4 != 0 || int(h.DataOffset)+cmapSubDataSize(h) > len(b) {
+			t.Fatalf("subtable %d: bad data offset %d", i, h.DataOffset)
+		}
+		sub := b[h.DataOffset:]
+		for e := range int(h.DataEntriesCount) {
+			r, gid := runes[k], glyphIDs[k]
+			k++
+			var gotCode rune
+			var gotGID uint16
+			switch h.FormatType {
+			case CmapFormat0Full:
+				gotCode, gotGID = rune(e), h.GlyphIdOffset+uint16(sub[e])
+			case CmapFormatSparse:
+				gotCode = rune(binary.LittleEndian.Uint16(sub[2*e:]))
+				gotGID = h.GlyphIdOffset + binary.LittleEndian.Uint16(sub[2*int(h.DataEntriesCount)+2*e:])
+			case CmapFormatSparseTiny:
+				gotCode, gotGID = rune(binary.LittleEndian.Uint16(sub[2*e:])), h.GlyphIdOffset+uint16(e)
+			}
+			if gotCode+rune(h.RangeStart) != r || gotGID != gid {
+				t.Fatalf("subtable %d entry %d: got %U -> %d, want %U -> %d", i, e, gotCode+rune(h.RangeStart), gotGID, r, gid)
+			}
+		}
+	}
+	if k != len(runes) {
+		t.Fatalf("decoded %d entries, want %d", k, len(runes))
+	}
+}
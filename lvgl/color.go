@@ -0,0 +1,346 @@
+package lvgl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font/sfnt"
+)
+
+// errNoUsableGlyph reports a colored glyph that has no outline and no color bitmap that can
+// be decoded. Such runes are left out of the cmap (notdef) instead of being written blank.
+var errNoUsableGlyph = errors.New("lvgl: colored glyph without usable bitmap")
+
+// colorBitmaps reads the embedded color bitmaps of a font (CBLC/CBDT or sbix), which sfnt
+// does not rasterize.
+type colorBitmaps struct {
+	cblc, cbdt []byte
+	sbix       []byte
+	numGlyphs  int
+}
+
+// loadColorBitmaps returns the color bitmap tables of `pf`, or nil when it has none.
+func loadColorBitmaps(pf *sfnt.Font) *colorBitmaps {
+	tables := sourceTables(pf)
+	cb := &colorBitmaps{cblc: tables["CBLC"], cbdt: tables["CBDT"], sbix: tables["sbix"], numGlyphs: pf.NumGlyphs()}
+	if (cb.cblc == nil || cb.cbdt == nil) && cb.sbix == nil {
+		return nil
+	}
+	return cb
+}
+
+// sourceTables returns the tables of the source of `pf` by tag, for those sfnt does not read,
+// or nil when the source cannot be had.
+func sourceTables(pf *sfnt.Font) map[string][]byte {
+	src := &bytes.Buffer{}
+	if _, err := pf.WriteSourceTo(&sfnt.Buffer{}, src); err != nil {
+		return nil
+	}
+	b := src.Bytes()
+	if len(b) < 12 {
+		return nil
+	}
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	for i := range numTables {
+		rec := 12 + 16*i
+		if rec+16 > len(b) {
+			break
+		}
+		offset, length := int(binary.BigEndian.Uint32(b[rec+8:])), int(binary.BigEndian.Uint32(b[rec+12:]))
+		if offset+length > len(b) || offset+length < offset {
+			continue
+		}
+		tables[string(b[rec:rec+4])] = b[offset : offset+length]
+	}
+	return tables
+}
+
+// glyph returns the color bitmap of `gi` scaled to `size` px and converted to coverage, or
+// false when the font has no bitmap for it.
+func (cb *colorBitmaps) glyph(gi sfnt.GlyphIndex, size uint16) (*glyph, bool) {
+	if cb.cblc != nil && cb.cbdt != nil {
+		if g, ok := cb.cbdtGlyph(gi, size); ok {
+			return g, true
+		}
+	}
+	if cb.sbix != nil {
+		return cb.sbixGlyph(gi, size, 0)
+	}
+	return nil, false
+}
+
+// bestStrike returns the index of the strike to scale from: the smallest ppem not below
+// `size`, or the largest one.
+func bestStrike(ppems []int, size uint16) int {
+	best := -1
+	for i, ppem := range ppems {
+		switch {
+		case best < 0:
+			best = i
+		case ppem >= int(size) && (ppems[best] < int(size) || ppem < ppems[best]):
+			best = i
+		case ppems[best] < int(size) && ppem > ppems[best]:
+			best = i
+		}
+	}
+	return best
+}
+
+// cbdtGlyph looks `gi` up in the CBLC strike closest to `size`.
+func (cb *colorBitmaps) cbdtGlyph(gi sfnt.GlyphIndex, size uint16) (*glyph, bool) {
+	const bitmapSizeLen = 48
+	t := cb.cblc
+	if len(t) < 8 {
+		return nil, false
+	}
+	numSizes := int(binary.BigEndian.Uint32(t[4:]))
+	if 8+numSizes*bitmapSizeLen > len(t) {
+		return nil, false
+	}
+	ppems := make([]int, numSizes)
+	for i := range ppems {
+		ppems[i] = int(t[8+i*bitmapSizeLen+45]) // ppemY
+	}
+	strike := bestStrike(ppems, size)
+	if strike < 0 {
+		return nil, false
+	}
+	bs := t[8+strike*bitmapSizeLen:]
+	arrayOffset := int(binary.BigEndian.Uint32(bs[0:]))
+	numSubTables := int(binary.BigEndian.Uint32(bs[8:]))
+	for i := range numSubTables {
+		e := arrayOffset + 8*i
+		if e+8 > len(t) {
+			return nil, false
+		}
+		first, last := sfnt.GlyphIndex(binary.BigEndian.Uint16(t[e:])), sfnt.GlyphIndex(binary.BigEndian.Uint16(t[e+2:]))
+		if gi < first || gi > last {
+			continue
+		}
+		sub := arrayOffset + int(binary.BigEndian.Uint32(t[e+4:]))
+		data, metrics, ok := cb.cbdtData(sub, int(gi-first), gi)
+		if !ok {
+			return nil, false
+		}
+		return decodeColorGlyph(data, metrics, ppems[strike], size)
+	}
+	return nil, false
+}
+
+// cbdtData returns the CBDT record of the glyph at `index` in the index subtable at `sub`,
+// with the metrics stored in the index subtable (formats 2 and 5) if any.
+func (cb *colorBitmaps) cbdtData(sub, index int, gi sfnt.GlyphIndex) ([]byte, []byte, bool) {
+	t := cb.cblc
+	if sub+8 > len(t) {
+		return nil, nil, false
+	}
+	indexFormat := binary.BigEndian.Uint16(t[sub:])
+	imageFormat := binary.BigEndian.Uint16(t[sub+2:])
+	imageDataOffset := int(binary.BigEndian.Uint32(t[sub+4:]))
+	body := t[sub+8:]
+	var start, end int
+	var metrics []byte
+	switch indexFormat {
+	case 1, 3:
+		w := 4
+		if indexFormat == 3 {
+			w = 2
+		}
+		if (index+2)*w > len(body) {
+			return nil, nil, false
+		}
+		get := func(i int) int {
+			if w == 2 {
+				return int(binary.BigEndian.Uint16(body[i*2:]))
+			}
+			return int(binary.BigEndian.Uint32(body[i*4:]))
+		}
+		start, end = get(index), get(index+1)
+	case 2:
+		if len(body) < 12 {
+			return nil, nil, false
+		}
+		imageSize := int(binary.BigEndian.Uint32(body))
+		metrics = body[4:12]
+		start, end = index*imageSize, (index+1)*imageSize
+	case 4:
+		if len(body) < 4 {
+			return nil, nil, false
+		}
+		n := int(binary.BigEndian.Uint32(body))
+		if 4+(n+1)*4 > len(body) {
+			return nil, nil, false
+		}
+		found := false
+		for k := range n {
+			if sfnt.GlyphIndex(binary.BigEndian.Uint16(body[4+k*4:])) == gi {
+				start, end = int(binary.BigEndian.Uint16(body[6+k*4:])), int(binary.BigEndian.Uint16(body[10+k*4:]))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, false
+		}
+	case 5:
+		if len(body) < 16 {
+			return nil, nil, false
+		}
+		imageSize := int(binary.BigEndian.Uint32(body))
+		metrics = body[4:12]
+		n := int(binary.BigEndian.Uint32(body[12:]))
+		if 16+n*2 > len(body) {
+			return nil, nil, false
+		}
+		k := -1
+		for j := range n {
+			if sfnt.GlyphIndex(binary.BigEndian.Uint16(body[16+j*2:])) == gi {
+				k = j
+				break
+			}
+		}
+		if k < 0 {
+			return nil, nil, false
+		}
+		start, end = k*imageSize, (k+1)*imageSize
+	default:
+		return nil, nil, false
+	}
+	start, end = imageDataOffset+start, imageDataOffset+end
+	if start >= end || end > len(cb.cbdt) {
+		return nil, nil, false
+	}
+	rec := cb.cbdt[start:end]
+	switch imageFormat {
+	case 17: // smallGlyphMetrics, data length, PNG
+		if len(rec) < 9 {
+			return nil, nil, false
+		}
+		// Widen to the bigGlyphMetrics layout, only the horizontal fields are used.
+		return pngData(rec[9:], rec[5:9]), append(rec[0:5:5], 0, 0, 0), true
+	case 18: // bigGlyphMetrics, data length, PNG
+		if len(rec) < 12 {
+			return nil, nil, false
+		}
+		return pngData(rec[12:], rec[8:12]), rec[0:8], true
+	case 19: // data length, PNG, metrics in the index subtable
+		if len(rec) < 4 || metrics == nil {
+			return nil, nil, false
+		}
+		return pngData(rec[4:], rec[0:4]), metrics, true
+	}
+	return nil, nil, false
+}
+
+func pngData(rec, length []byte) []byte {
+	n := int(binary.BigEndian.Uint32(length))
+	if n > len(rec) {
+		return nil
+	}
+	return rec[:n]
+}
+
+// decodeColorGlyph decodes the bitmap `data` with the bigGlyphMetrics `metrics` (strike
+// pixels) and scales it from `ppem` to `size`.
+func decodeColorGlyph(data, metrics []byte, ppem int, size uint16) (*glyph, bool) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil || ppem == 0 {
+		return nil, false
+	}
+	scale := float64(size) / float64(ppem)
+	bearingX, bearingY := float64(int8(metrics[2])), float64(int8(metrics[3]))
+	g := scaleColorImage(img, scale)
+	g.x = roundInt(bearingX * scale)
+	g.y = roundInt(bearingY*scale) - g.height()
+	g.advance = roundInt(float64(metrics[4]) * scale * 16)
+	return g, true
+}
+
+// sbixGlyph looks `gi` up in the sbix strike closest to `size`, following 'dupe' records
+// up to a small depth.
+func (cb *colorBitmaps) sbixGlyph(gi sfnt.GlyphIndex, size uint16, depth int) (*glyph, bool) {
+	t := cb.sbix
+	if len(t) < 8 || int(gi) >= cb.numGlyphs || depth > 2 {
+		return nil, false
+	}
+	numStrikes := int(binary.BigEndian.Uint32(t[4:]))
+	if 8+numStrikes*4 > len(t) {
+		return nil, false
+	}
+	ppems := make([]int, numStrikes)
+	offsets := make([]int, numStrikes)
+	for i := range numStrikes {
+		offsets[i] = int(binary.BigEndian.Uint32(t[8+i*4:]))
+		if offsets[i]+4 > len(t) {
+			return nil, false
+		}
+		ppems[i] = int(binary.BigEndian.Uint16(t[offsets[i]:]))
+	}
+	strike := bestStrike(ppems, size)
+	if strike < 0 {
+		return nil, false
+	}
+	s := offsets[strike]
+	o := s + 4 + int(gi)*4
+	if o+8 > len(t) {
+		return nil, false
+	}
+	start, end := s+int(binary.BigEndian.Uint32(t[o:])), s+int(binary.BigEndian.Uint32(t[o+4:]))
+	if end-start < 8 || end > len(t) {
+		return nil, false
+	}
+	rec := t[start:end]
+	originX, originY := float64(int16(binary.BigEndian.Uint16(rec))), float64(int16(binary.BigEndian.Uint16(rec[2:])))
+	switch string(rec[4:8]) {
+	case "png ", "jpg ":
+		img, _, err := image.Decode(bytes.NewReader(rec[8:]))
+		if err != nil || ppems[strike] == 0 {
+			return nil, false
+		}
+		scale := float64(size) / float64(ppems[strike])
+		g := scaleColorImage(img, scale)
+		g.x = roundInt(originX * scale)
+		g.y = roundInt(originY * scale)
+		return g, true
+	case "dupe":
+		if len(rec) < 10 {
+			return nil, false
+		}
+		return cb.sbixGlyph(sfnt.GlyphIndex(binary.BigEndian.Uint16(rec[8:])), size, depth+1)
+	}
+	return nil, false
+}
+
+// scaleColorImage scales `img` and converts it to coverage: opaque pixels keep at least half
+// coverage so light colors stay visible, darker colors get more.
+func scaleColorImage(img image.Image, scale float64) *glyph {
+	b := img.Bounds()
+	w, h := max(1, roundInt(float64(b.Dx())*scale)), max(1, roundInt(float64(b.Dy())*scale))
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(rgba, rgba.Bounds(), img, b, draw.Src, nil)
+	alpha := image.NewAlpha(rgba.Bounds())
+	for y := range h {
+		for x := range w {
+			c := rgba.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			// Un-premultiplied luma in [0, 255].
+			luma := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000 * 255 / int(c.A)
+			alpha.SetAlpha(x, y, color.Alpha{A: uint8(int(c.A) * (510 - min(255, luma)) / 510)})
+		}
+	}
+	return &glyph{alpha: alpha}
+}
+
+func roundInt(v float64) int {
+	return int(math.Round(v))
+}
@@ -0,0 +1,136 @@
+package lvgl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// buildSfnt assembles a font file from `tables` (checksums are left zero).
+func buildSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.BigEndian, []uint16{0x0001, 0x0000, uint16(len(tags)), 0, 0, 0})
+	offset := 12 + 16*len(tags)
+	for _, tag := range tags {
+		buf.WriteString(tag)
+		_ = binary.Write(buf, binary.BigEndian, []uint32{0, uint32(offset), uint32(len(tables[tag]))})
+		offset += align4(len(tables[tag]))
+	}
+	for _, tag := range tags {
+		buf.Write(tables[tag])
+		buf.Write(make([]byte, align4(len(tables[tag]))-len(tables[tag])))
+	}
+	return buf.Bytes()
+}
+
+// sfntTables splits the font file `b` into its tables.
+func sfntTables(b []byte) map[string][]byte {
+	tables := make(map[string][]byte)
+	for i := range int(binary.BigEndian.Uint16(b[4:])) {
+		rec := b[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		tables[string(rec[:4])] = b[offset : offset+length]
+	}
+	return tables
+}
+
+// colorEmojiFont turns Go Regular into a CBDT color bitmap font whose only bitmap is a
+// 64 ppem red disc for the glyph of 'A'; all other glyphs are colored without bitmap.
+func colorEmojiFont(t *testing.T) *sfnt.Font {
+	t.Helper()
+	pf := loadGoRegular(t)
+	gi, err := pf.GlyphIndex(nil, 'A')
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			if (x-32)*(x-32)+(y-32)*(y-32) < 30*30 {
+				img.SetRGBA(x, y, color.RGBA{R: 0xE0, G: 0x20, B: 0x20, A: 0xFF})
+			}
+		}
+	}
+	pngBuf := &bytes.Buffer{}
+	if err := png.Encode(pngBuf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	// CBDT: header, then one format 17 record.
+	cbdt := &bytes.Buffer{}
+	_ = binary.Write(cbdt, binary.BigEndian, []uint16{3, 0})
+	cbdt.Write([]byte{64, 64, 0, 56, 64}) // height, width, bearingX, bearingY, advance
+	_ = binary.Write(cbdt, binary.BigEndian, uint32(pngBuf.Len()))
+	cbdt.Write(pngBuf.Bytes())
+	recLen := cbdt.Len() - 4
+
+	// CBLC: header, one BitmapSize, one IndexSubTableArray entry, one format 1 subtable.
+	cblc := &bytes.Buffer{}
+	_ = binary.Write(cblc, binary.BigEndian, []uint16{3, 0})
+	_ = binary.Write(cblc, binary.BigEndian, uint32(1))
+	_ = binary.Write(cblc, binary.BigEndian, []uint32{56, 24, 1, 0})
+	cblc.Write(make([]byte, 24)) // hori, vert line metrics
+	_ = binary.Write(cblc, binary.BigEndian, []uint16{uint16(gi), uint16(gi)})
+	cblc.Write([]byte{64, 64, 32, 1})
+	_ = binary.Write(cblc, binary.BigEndian, []uint16{uint16(gi), uint16(gi)})
+	_ = binary.Write(cblc, binary.BigEndian, uint32(8))
+	_ = binary.Write(cblc, binary.BigEndian, []uint16{1, 17})
+	_ = binary.Write(cblc, binary.BigEndian, []uint32{4, 0, uint32(recLen)})
+
+	tables := sfntTables(goregular.TTF)
+	delete(tables, "glyf")
+	delete(tables, "loca")
+	tables["CBDT"] = cbdt.Bytes()
+	tables["CBLC"] = cblc.Bytes()
+	cf, err := sfnt.Parse(buildSfnt(tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cf
+}
+
+func TestNewFont_ColorBitmap(t *testing.T) {
+	pf := colorEmojiFont(t)
+	bin, err := NewFont(pf, 32, []rune("AB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := bf.Glyph('A')
+	if !ok {
+		t.Fatal("no glyph for 'A'")
+	}
+	// 64 ppem strike scaled to 32 px.
+	if g.BBoxWidth != 32 || g.BBoxHeight != 32 || g.BBoxX != 0 || g.BBoxY != 28-32 {
+		t.Fatalf("bbox = %d,%d %dx%d, want 0,-4 32x32", g.BBoxX, g.BBoxY, g.BBoxWidth, g.BBoxHeight)
+	}
+	ink := 0
+	for _, b := range g.Bitmap {
+		if b != 0 {
+			ink++
+		}
+	}
+	if ink < len(g.Bitmap)/2 {
+		t.Fatalf("bitmap mostly empty: %d of %d bytes set", ink, len(g.Bitmap))
+	}
+	// 'B' has neither an outline nor a bitmap, it must not be written as a blank glyph.
+	if _, ok := bf.GlyphID('B'); ok {
+		t.Fatal("'B' should be left to notdef")
+	}
+}
@@ -0,0 +1,117 @@
+package lvgl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zhimiaox/subfont/ttf"
+	"golang.org/x/image/font/sfnt"
+)
+
+// ConvertFile converts the runes in `ranges` (see ttf.ParseRuneRanges) of the TrueType font
+// at `ttfPath` at each of `sizes` and writes one binary per size to `outPath` with "{size}"
+// replaced by the size, e.g. "build/font_{size}.bin". The placeholder is required for more
+// than one size. No sizes, an empty range or a range the font maps none of is an error.
+// Parent directories are created and every file is written atomically, so
+// an interrupted build never leaves a truncated font behind. It returns the Stats of each
+// size.
+func ConvertFile(ttfPath, outPath string, sizes []uint16, ranges string, opts Options) (map[uint16]*Stats, error) {
+	return ConvertFileContext(context.Background(), ttfPath, outPath, sizes, ranges, opts)
+}
+
+// ConvertFileContext is ConvertFile that stops with the error of `ctx` once `ctx` is done,
+// checked between sizes and glyphs. No file is written when the conversion is cancelled.
+func ConvertFileContext(ctx context.Context, ttfPath, outPath string, sizes []uint16, ranges string, opts Options) (map[uint16]*Stats, error) {
+	b, err := os.ReadFile(ttfPath)
+	if err != nil {
+		return nil, err
+	}
+	return convert(ctx, b, ttfPath, outPath, sizes, ranges, opts)
+}
+
+// ConvertFS is ConvertFile for the TrueType font `ttfPath` of `fsys`, such as an embed.FS.
+// The output is still written to the local `outPath`.
+func ConvertFS(fsys fs.FS, ttfPath, outPath string, sizes []uint16, ranges string, opts Options) (map[uint16]*Stats, error) {
+	b, err := fs.ReadFile(fsys, ttfPath)
+	if err != nil {
+		return nil, err
+	}
+	return convert(context.Background(), b, ttfPath, outPath, sizes, ranges, opts)
+}
+
+// convert converts the source font `b` read from `ttfPath`, see ConvertFileContext.
+func convert(ctx context.Context, b []byte, ttfPath, outPath string, sizes []uint16, ranges string, opts Options) (map[uint16]*Stats, error) {
+	if len(sizes) > 1 && !strings.Contains(outPath, "{size}") {
+		return nil, fmt.Errorf("lvgl: output path %q needs a {size} placeholder for %d sizes", outPath, len(sizes))
+	}
+	if len(sizes) == 0 {
+		return nil, errors.New("lvgl: no sizes to convert")
+	}
+	runes, err := ttf.ParseRuneRanges(ranges)
+	if err != nil {
+		return nil, fmt.Errorf("lvgl: %w", err)
+	}
+	if len(runes) == 0 {
+		return nil, fmt.Errorf("lvgl: range %q has no runes", ranges)
+	}
+	pf, err := sfnt.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("lvgl: %s: %w", ttfPath, err)
+	}
+	if !mapsAny(pf, runes) {
+		return nil, fmt.Errorf("lvgl: %s maps none of the runes of %q", ttfPath, ranges)
+	}
+	bins, stats, err := NewFontsContext(ctx, pf, sizes, runes, opts)
+	if err != nil {
+		return nil, err
+	}
+	for size, bin := range bins {
+		if err := writeFileAtomic(strings.ReplaceAll(outPath, "{size}", strconv.Itoa(int(size))), bin); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// mapsAny reports whether `pf` maps any of `runes` to a glyph.
+func mapsAny(pf *sfnt.Font, runes []rune) bool {
+	buf := &sfnt.Buffer{}
+	for _, r := range runes {
+		if gi, err := pf.GlyphIndex(buf, r); err == nil && gi != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileAtomic writes `b` to a temporary file next to `name` and renames it into place.
+func writeFileAtomic(name string, b []byte) (err error) {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = tmp.Write(b)
+	err = errors.Join(err, tmp.Sync(), tmp.Close())
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,107 @@
+package lvgl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestConvertFile(t *testing.T) {
+	dir := t.TempDir()
+	ttfPath := filepath.Join(dir, "Go-Regular.ttf")
+	if err := os.WriteFile(ttfPath, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "build", "fonts", "go_{size}.bin")
+	stats, err := ConvertFile(ttfPath, out, []uint16{12, 16}, "U+20-U+7E,é,0x2265", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []uint16{12, 16} {
+		name := filepath.Join(dir, "build", "fonts", fmt.Sprintf("go_%d.bin", size))
+		bin, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(bin); err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if st := stats[size]; st == nil || st.Glyphs != 97 || st.TotalBytes != len(bin) {
+			t.Fatalf("size %d: stats %+v for a %d byte file", size, st, len(bin))
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "build", "fonts"))
+	if len(entries) != 2 {
+		t.Fatalf("got %d files, want 2 (no temporary files left behind)", len(entries))
+	}
+
+	if _, err := ConvertFile(ttfPath, filepath.Join(dir, "go.bin"), []uint16{12, 16}, "A-Z", Options{}); err == nil {
+		t.Fatal("expected an error for several sizes without a {size} placeholder")
+	}
+	if _, err := ConvertFile(ttfPath, out, []uint16{12}, "Z-A", Options{}); err == nil {
+		t.Fatal("expected an error for an invalid range")
+	}
+	for _, tt := range []struct {
+		sizes  []uint16
+		ranges string
+	}{
+		{nil, "A-Z"},
+		{[]uint16{12}, ""},
+		{[]uint16{12}, "U+E000-U+E0FF"}, // private use, not in Go Regular
+	} {
+		if _, err := ConvertFile(ttfPath, out, tt.sizes, tt.ranges, Options{}); err == nil {
+			t.Fatalf("sizes %v, range %q: expected an error", tt.sizes, tt.ranges)
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "build", "fonts")); len(entries) != 2 {
+		t.Fatalf("got %d files after failed conversions, want 2", len(entries))
+	}
+}
+
+func TestConvertFileContext(t *testing.T) {
+	dir := t.TempDir()
+	ttfPath := filepath.Join(dir, "Go-Regular.ttf")
+	if err := os.WriteFile(ttfPath, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out := filepath.Join(dir, "go_{size}.bin")
+	if _, err := ConvertFileContext(ctx, ttfPath, out, []uint16{12, 16}, "U+20-U+7E", Options{Parallel: true}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("got %d files, want only the source font", len(entries))
+	}
+}
+
+func TestConvertFS(t *testing.T) {
+	fsys := fstest.MapFS{"fonts/Go-Regular.ttf": {Data: goregular.TTF}}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "go_{size}.bin")
+	if _, err := ConvertFS(fsys, "fonts/Go-Regular.ttf", out, []uint16{16}, "A-Z", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	bin, err := ParseFS(os.DirFS(dir), "go_16.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bin.Runes(); len(got) != 26 {
+		t.Fatalf("got %d runes, want 26", len(got))
+	}
+
+	fsys["fonts/bad.ttf"] = &fstest.MapFile{Data: []byte("not a font")}
+	if _, err := ConvertFS(fsys, "fonts/bad.ttf", out, []uint16{16}, "A-Z", Options{}); err == nil || !strings.Contains(err.Error(), "fonts/bad.ttf") {
+		t.Fatalf("got %v, want an error naming the font", err)
+	}
+	if _, err := ParseFS(fsys, "fonts/bad.ttf"); err == nil || !strings.Contains(err.Error(), "fonts/bad.ttf") {
+		t.Fatalf("got %v, want an error naming the binary", err)
+	}
+}
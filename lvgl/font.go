@@ -2,11 +2,18 @@ package lvgl
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"slices"
+	"sync"
 
+	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 type Font struct {
@@ -14,50 +21,316 @@ type Font struct {
 	*CmapTable
 	*LocaTable
 	*GlyfTable
+	*KernTable
 }
 
 func NewFont(pf *sfnt.Font, size uint16, runes []rune) ([]byte, error) {
 	if len(runes) == 0 {
 		return nil, nil
 	}
-	slices.Sort(runes)
-	runes = slices.Compact(runes)
+	c, err := newConverter(pf, runes, Options{})
+	if err != nil {
+		return nil, err
+	}
+	bin, _, err := c.font(context.Background(), &sfnt.Buffer{}, size)
+	return bin, err
+}
+
+// NewFonts converts `runes` of `pf` at each of `sizes`, returning one binary per size. The
+// glyph lookups are done once and shared by all sizes.
+func NewFonts(pf *sfnt.Font, sizes []uint16, runes []rune, opts Options) (map[uint16][]byte, error) {
+	bins, _, err := NewFontsWithStats(pf, sizes, runes, opts)
+	return bins, err
+}
+
+// NewFontsWithStats is NewFonts that also returns the Stats of each size.
+func NewFontsWithStats(pf *sfnt.Font, sizes []uint16, runes []rune, opts Options) (map[uint16][]byte, map[uint16]*Stats, error) {
+	return NewFontsContext(context.Background(), pf, sizes, runes, opts)
+}
+
+// NewFontsContext is NewFontsWithStats that stops with the error of `ctx` once `ctx` is done,
+// checked between sizes and glyphs.
+func NewFontsContext(ctx context.Context, pf *sfnt.Font, sizes []uint16, runes []rune, opts Options) (map[uint16][]byte, map[uint16]*Stats, error) {
+	if len(runes) == 0 {
+		return nil, nil, nil
+	}
+	c, err := newConverter(pf, runes, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	sizes = slices.Compact(slices.Sorted(slices.Values(sizes)))
+	bins := make([][]byte, len(sizes))
+	stats := make([]*Stats, len(sizes))
+	errs := make([]error, len(sizes))
+	if opts.Parallel {
+		var wg sync.WaitGroup
+		for i, size := range sizes {
+			wg.Go(func() {
+				bins[i], stats[i], errs[i] = c.font(ctx, &sfnt.Buffer{}, size)
+			})
+		}
+		wg.Wait()
+	} else {
+		buf := &sfnt.Buffer{}
+		for i, size := range sizes {
+			bins[i], stats[i], errs[i] = c.font(ctx, buf, size)
+		}
+	}
+	resp := make(map[uint16][]byte, len(sizes))
+	respStats := make(map[uint16]*Stats, len(sizes))
+	for i, size := range sizes {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		resp[size] = bins[i]
+		respStats[size] = stats[i]
+	}
+	return resp, respStats, nil
+}
+
+// converter holds the size independent state of a conversion: the sorted runes and their
+// glyph indices in the source font.
+type converter struct {
+	pf     *sfnt.Font
+	opts   Options
+	runes  []rune
+	glyphs []sfnt.GlyphIndex
+
+	colorOnce sync.Once
+	color     *colorBitmaps // embedded color bitmaps, loaded on first use.
+
+	kernOnce sync.Once
+	kern     []sourceKernPair // pairs of the kern table, loaded on first use.
+}
+
+func newConverter(pf *sfnt.Font, runes []rune, opts Options) (*converter, error) {
+	if _, err := opts.bitsPerPixel(); err != nil {
+		return nil, err
+	}
+	c := &converter{
+		pf:    pf,
+		opts:  opts,
+		runes: slices.Compact(slices.Sorted(slices.Values(runes))),
+	}
+	buf := &sfnt.Buffer{}
+	c.glyphs = make([]sfnt.GlyphIndex, len(c.runes))
+	for i, r := range c.runes {
+		gi, err := pf.GlyphIndex(buf, r)
+		if err != nil {
+			return nil, fmt.Errorf("lvgl: rune %U: %w", r, err)
+		}
+		c.glyphs[i] = gi
+	}
+	return c, nil
+}
+
+// monospaceAdvance returns the monospace cell width in FP4.
+func (c *converter) monospaceAdvance(glyphs []*glyph) int {
+	if c.opts.MonospaceAdvance > 0 {
+		return int(math.Round(c.opts.MonospaceAdvance * 16))
+	}
+	cell := 0
+	for _, g := range glyphs {
+		cell = max(cell, g.advance)
+	}
+	return (cell + 15) &^ 15
+}
+
+// cancelCheckInterval is the number of glyphs rasterized between checks whether the context
+// of NewFontsContext is done.
+const cancelCheckInterval = 64
+
+// font rasterizes all glyphs at `size` scaled by Options.Scale and writes the binary font of
+// the logical `size`. `buf` must not be shared with concurrent calls.
+func (c *converter) font(ctx context.Context, buf *sfnt.Buffer, size uint16) ([]byte, *Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("lvgl: size %d: conversion cancelled: %w", size, err)
+	}
 	f := new(Font)
-	f.HeadTable = NewHeadTable(pf, size)
-	cmapTable, cmapSubHeaders, cmapSubData := NewCmapTable(runes)
+	f.HeadTable = NewHeadTable(c.pf, size)
+	f.HeadTable.BitsPerPixel, _ = c.opts.bitsPerPixel()
+	px, err := c.opts.pixelSize(size)
+	if err != nil {
+		return nil, nil, err
+	}
+	glyphs := make([]*glyph, 0, len(c.runes))
+	runes := make([]rune, 0, len(c.runes))
+	ids := make([]sfnt.GlyphIndex, 0, len(c.runes))
+	var dropped []rune
+	for i, r := range c.runes {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, fmt.Errorf("lvgl: size %d: conversion cancelled after %d of %d glyphs: %w", size, i, len(c.runes), err)
+			}
+		}
+		g, err := c.rasterize(buf, px, c.glyphs[i])
+		if errors.Is(err, errNoUsableGlyph) {
+			dropped = append(dropped, r)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("lvgl: size %d: rune %U: %w", size, r, err)
+		}
+		g.embolden(c.opts.Embolden)
+		glyphs = append(glyphs, g)
+		runes = append(runes, r)
+		ids = append(ids, c.glyphs[i])
+	}
+	if len(dropped) > 0 {
+		slog.Warn("lvgl: colored glyphs without usable bitmap mapped to notdef", "size", size, "runes", string(dropped), "runes_raw", dropped)
+	}
+	if c.opts.Monospace {
+		cell := c.monospaceAdvance(glyphs)
+		for i, g := range glyphs {
+			if err := g.fitCell(cell, c.opts.MonospaceClip); err != nil {
+				return nil, nil, fmt.Errorf("lvgl: size %d: rune %U: %w", size, runes[i], err)
+			}
+		}
+		f.HeadTable.AdvanceWidthBits = 0
+		f.HeadTable.DefAdvanceWidth = uint16(cell)
+	}
+	var clipped []rune
+	if c.opts.MaxGlyphSize > 0 {
+		for i, g := range glyphs {
+			if g.clip(c.opts.MaxGlyphSize) {
+				clipped = append(clipped, runes[i])
+			}
+		}
+		if len(clipped) > 0 {
+			slog.Warn("lvgl: glyphs larger than the maximum glyph size clipped", "size", size, "max", c.opts.MaxGlyphSize, "runes", string(clipped), "runes_raw", clipped)
+		}
+	}
+	f.HeadTable.fitBitWidths(glyphs)
+	// All records go to one buffer, bitmap[i] is sliced from it once it stops growing.
+	bw := &bitWriter{}
+	ends := make([]int, len(glyphs))
+	ascent, descent := 0, 0
+	for i, g := range glyphs {
+		f.HeadTable.encodeGlyph(bw, g)
+		ends[i] = len(bw.bytes())
+		if i == 0 {
+			ascent, descent = g.y+g.height(), g.y
+		} else {
+			ascent, descent = max(ascent, g.y+g.height()), min(descent, g.y)
+		}
+		putAlpha(g.alpha)
+		g.alpha = nil
+	}
+	bitmap := make([][]byte, len(glyphs))
+	for i, start := 0, 0; i < len(ends); i++ {
+		bitmap[i] = bw.bytes()[start:ends[i]:ends[i]]
+		start = ends[i]
+	}
+	f.HeadTable.Ascent, f.HeadTable.Descent = uint16(ascent), int16(descent)
+	f.HeadTable.MaxY, f.HeadTable.MinY = int16(ascent), int16(descent)
+	glyphIDs := make([]uint16, len(runes))
+	for i := range runes {
+		glyphIDs[i] = uint16(i + 1)
+	}
+	var pairs []KernPair
+	if c.opts.Kerning {
+		pairs = c.kernPairs(px, ids)
+	}
+	bin, stats, err := f.encode(runes, glyphIDs, bitmap, pairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	stats.Dropped = dropped
+	stats.Clipped = clipped
+	return bin, stats, nil
+}
+
+// kernPairs returns the non-zero kerning of the kern table of the source font at `size`
+// between the source glyphs `ids`, which get glyph IDs 1..len(ids). The pairs of the table are
+// looked up by glyph, so the cost grows with the pairs rather than the square of the glyphs.
+func (c *converter) kernPairs(size uint16, ids []sfnt.GlyphIndex) []KernPair {
+	c.kernOnce.Do(func() {
+		c.kern = parseSourceKern(sourceTables(c.pf)["kern"])
+	})
+	if len(c.kern) == 0 {
+		return nil
+	}
+	// Runes that share a source glyph get glyph IDs of their own.
+	glyphIDs := make(map[sfnt.GlyphIndex][]uint16, len(ids))
+	for i, id := range ids {
+		glyphIDs[id] = append(glyphIDs[id], uint16(i+1))
+	}
+	upem := c.pf.UnitsPerEm()
+	var pairs []KernPair
+	for _, p := range c.kern {
+		v := kernFP4(p.value, size, upem)
+		if v == 0 {
+			continue
+		}
+		for _, left := range glyphIDs[p.left] {
+			for _, right := range glyphIDs[p.right] {
+				pairs = append(pairs, KernPair{Left: left, Right: right, Value: v})
+			}
+		}
+	}
+	return pairs
+}
+
+// rasterize renders the source glyph `gi`. Glyphs without outlines are taken from the
+// embedded color bitmaps when the font has them; a colored glyph without a usable bitmap
+// returns errNoUsableGlyph.
+func (c *converter) rasterize(buf *sfnt.Buffer, size uint16, gi sfnt.GlyphIndex) (*glyph, error) {
+	g, err := rasterizeGlyph(buf, c.pf, size, gi, &c.opts)
+	if err != nil && !errors.Is(err, sfnt.ErrColoredGlyph) {
+		return nil, err
+	}
+	if err == nil && g.width() > 0 && g.height() > 0 {
+		return g, nil
+	}
+	c.colorOnce.Do(func() {
+		c.color = loadColorBitmaps(c.pf)
+	})
+	if c.color != nil {
+		if cg, ok := c.color.glyph(gi, size); ok {
+			if advance, err := c.pf.GlyphAdvance(buf, gi, fixed.I(int(size)), font.HintingNone); err == nil {
+				cg.advance = int(advanceFP4(advance))
+			}
+			return cg, nil
+		}
+	}
+	if err != nil {
+		return nil, errNoUsableGlyph
+	}
+	return g, nil
+}
+
+// encode writes the binary font. runes[i] maps to glyphIDs[i], and glyphs[id-1] is the
+// glyph record of glyph ID id (glyph 0, notdef, is left empty). The kern table is only
+// written when there are kerning `pairs`.
+func (f *Font) encode(runes []rune, glyphIDs []uint16, glyphs [][]byte, pairs []KernPair) ([]byte, *Stats, error) {
+	cmapTable, cmapSubHeaders, cmapSubData := NewCmapTableWithIDs(runes, glyphIDs)
 	f.CmapTable = cmapTable
 	f.LocaTable = NewLocaTable()
-	f.LocaTable.EntryCount = uint32(len(runes) + 1)
+	f.LocaTable.EntryCount = uint32(len(glyphs) + 1)
 	f.GlyfTable = NewGlyfTable()
-	sfntBuf := &sfnt.Buffer{}
-	bitmap := make([][]byte, len(runes))
 	bitmapSize := int(f.GlyfTable.Size)
 	locaOffset := []uint32{
 		uint32(bitmapSize), uint32(bitmapSize),
 	}
-	ascent, descent := 0, 0
-	for i, r := range runes {
-		if glyfData, err := AddGlyfData(sfntBuf, pf, size, r); err == nil {
-			bitmap[i] = glyfData.Bytes()
-			if i == 0 {
-				ascent, descent = int(glyfData.BBoxY)+int(glyfData.BBoxHeight), int(glyfData.BBoxY)
-			} else {
-				ascent, descent = max(ascent, int(glyfData.BBoxY)+int(glyfData.BBoxHeight)), min(descent, int(glyfData.BBoxY))
-			}
-		} else {
-			slog.Error("字体数据生成失败", "r", string(r), "glyfData", glyfData, "err", err)
-		}
-		bitmapSize += len(bitmap[i])
+	for i := range glyphs {
+		bitmapSize += len(glyphs[i])
 		locaOffset = append(locaOffset, uint32(bitmapSize))
 	}
-	f.HeadTable.Ascent, f.HeadTable.Descent = uint16(ascent), int16(descent)
-	f.HeadTable.MaxY, f.HeadTable.MinY = int16(ascent), int16(descent)
-	f.LocaTable.Size += uint32(len(locaOffset) * 4)
-	f.GlyfTable.Size += uint32(bitmapSize)
-	binBuf := &bytes.Buffer{}
-	if err := binary.Write(binBuf, binary.LittleEndian, f.HeadTable); err != nil {
-		slog.Error("Error encoding HeadTable", "err", err)
+	f.LocaTable.Size = uint32(align4(int(f.LocaTable.Size) + len(locaOffset)*4))
+	// The last record is padded so the following table starts aligned.
+	f.GlyfTable.Size = uint32(align4(bitmapSize))
+	var kernData []byte
+	f.KernTable = nil
+	if len(pairs) > 0 {
+		f.KernTable, kernData, f.HeadTable.KerningScale = NewKernTable(pairs)
 	}
+	// Additional tables after the head: cmap, loca, glyf and the optional kern.
+	f.HeadTable.Tables = 3
+	if f.KernTable != nil {
+		f.HeadTable.Tables++
+	}
+	binBuf := &bytes.Buffer{}
+	f.HeadTable.Size = uint32(writeHead(binBuf, f.HeadTable))
 	if err := binary.Write(binBuf, binary.LittleEndian, f.CmapTable); err != nil {
 		slog.Error("Error encoding CmapTable", "err", err)
 	}
@@ -73,11 +346,54 @@ func NewFont(pf *sfnt.Font, size uint16, runes []rune) ([]byte, error) {
 	if err := binary.Write(binBuf, binary.LittleEndian, locaOffset); err != nil {
 		slog.Error("Error encoding LocaTable data", "err", err)
 	}
+	padTo4(binBuf)
 	if err := binary.Write(binBuf, binary.LittleEndian, f.GlyfTable); err != nil {
 		slog.Error("Error encoding GlyfTable", "err", err)
 	}
-	for i := range bitmap {
-		binBuf.Write(bitmap[i])
+	for i := range glyphs {
+		binBuf.Write(glyphs[i])
+	}
+	padTo4(binBuf)
+	if f.KernTable != nil {
+		if err := binary.Write(binBuf, binary.LittleEndian, f.KernTable); err != nil {
+			slog.Error("Error encoding KernTable", "err", err)
+		}
+		binBuf.Write(kernData)
+	}
+	if n := countTables(binBuf.Bytes()); n != int(f.HeadTable.Tables) {
+		return nil, nil, fmt.Errorf("lvgl: wrote %d tables after the head, head says %d", n, f.HeadTable.Tables)
+	}
+	return binBuf.Bytes(), newStats(f, runes, glyphIDs, glyphs), nil
+}
+
+// writeHead writes `head` padded to a multiple of 4 bytes, as the format requires, and
+// returns the padded size. The head's own Size field must already hold that size.
+func writeHead(w *bytes.Buffer, head any) int {
+	start := w.Len()
+	if err := binary.Write(w, binary.LittleEndian, head); err != nil {
+		slog.Error("Error encoding HeadTable", "err", err)
+	}
+	n := w.Len() - start
+	w.Write(make([]byte, align4(n)-n))
+	return align4(n)
+}
+
+// padTo4 appends zero bytes until the length of `w` is a multiple of 4.
+func padTo4(w *bytes.Buffer) {
+	w.Write(make([]byte, align4(w.Len())-w.Len()))
+}
+
+// countTables walks the table records following the head of `b` by their Size fields.
+func countTables(b []byte) int {
+	n := 0
+	offset := int(binary.LittleEndian.Uint32(b))
+	for offset+8 <= len(b) {
+		size := int(binary.LittleEndian.Uint32(b[offset:]))
+		if size < 8 {
+			break
+		}
+		offset += size
+		n++
 	}
-	return binBuf.Bytes(), nil
+	return n
 }
@@ -1,7 +1,11 @@
 package lvgl
 
 import (
+	"bytes"
+	"errors"
+	"math"
 	"os"
+	"reflect"
 	"testing"
 
 	"golang.org/x/image/font/sfnt"
@@ -21,3 +25,140 @@ func TestNewFont(t *testing.T) {
 	bin, _ := NewFont(pf, 32, append([]rune("abgpqttx"), 0x71CA, 0x01F16C, 0x2265))
 	_ = os.WriteFile("out.bin", bin, 655)
 }
+
+func TestNewFonts(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := append(runeRange(0x20, 0x7E), 0xA9, 0xE9, 0x2265)
+	sizes := []uint16{12, 16, 24, 32}
+	for _, parallel := range []bool{false, true} {
+		bins, err := NewFonts(pf, sizes, runes, Options{Parallel: parallel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bins) != len(sizes) {
+			t.Fatalf("got %d fonts, want %d", len(bins), len(sizes))
+		}
+		for _, size := range sizes {
+			want, err := NewFont(pf, size, runes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mustValidate(t, bins[size])
+			if !bytes.Equal(bins[size], want) {
+				t.Fatalf("parallel=%v size %d: output differs from NewFont", parallel, size)
+			}
+		}
+	}
+}
+
+func TestNewFonts_Monospace(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := runeRange(0x21, 0x7E)
+	bins, err := NewFonts(pf, []uint16{16}, runes, Options{Monospace: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bins[16])
+	bf, err := Parse(bins[16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.Head.AdvanceWidthBits != 0 || bf.Head.DefAdvanceWidth == 0 || bf.Head.DefAdvanceWidth%16 != 0 {
+		t.Fatalf("head advance bits %d, default advance %d", bf.Head.AdvanceWidthBits, bf.Head.DefAdvanceWidth)
+	}
+	cell := int32(bf.Head.DefAdvanceWidth / 16)
+	for _, r := range runes {
+		g, _ := bf.Glyph(r)
+		if g.AdvanceWidth != uint32(bf.Head.DefAdvanceWidth) {
+			t.Fatalf("rune %q: advance %d, want %d", r, g.AdvanceWidth, bf.Head.DefAdvanceWidth)
+		}
+		// Left and right margins inside the cell differ by at most one pixel.
+		left, right := g.BBoxX, cell-g.BBoxX-int32(g.BBoxWidth)
+		if left < 0 || right < 0 || left-right > 1 || right-left > 1 {
+			t.Fatalf("rune %q: bbox x %d width %d not centered in %d px", r, g.BBoxX, g.BBoxWidth, cell)
+		}
+	}
+
+	if _, err := NewFonts(pf, []uint16{16}, runes, Options{Monospace: true, MonospaceAdvance: 6}); !errors.Is(err, ErrGlyphWiderThanCell) {
+		t.Fatalf("err = %v, want ErrGlyphWiderThanCell", err)
+	}
+	bins, err = NewFonts(pf, []uint16{16}, runes, Options{Monospace: true, MonospaceAdvance: 6, MonospaceClip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bins[16])
+	if bf, err = Parse(bins[16]); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range runes {
+		if g, _ := bf.Glyph(r); g.BBoxX < 0 || g.BBoxX+int32(g.BBoxWidth) > 6 {
+			t.Fatalf("rune %q: bbox x %d width %d exceeds the 6 px cell", r, g.BBoxX, g.BBoxWidth)
+		}
+	}
+}
+
+func TestNewFonts_Scale(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := []rune("Hgj")
+	bins, err := NewFonts(pf, []uint16{16}, runes, Options{Scale: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bins[16])
+	scaled, err := Parse(bins[16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(size uint16) *BinFont {
+		bin, err := NewFont(pf, size, runes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bf, err := Parse(bin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bf
+	}
+	logical, pixel := parse(16), parse(32)
+	if scaled.Head.FontSize != 16 || scaled.Head.TypoAscent != logical.Head.TypoAscent {
+		t.Fatalf("head size %d typo ascent %d, want the logical 16 and %d", scaled.Head.FontSize,
+			scaled.Head.TypoAscent, logical.Head.TypoAscent)
+	}
+	if scaled.Head.Ascent != pixel.Head.Ascent || scaled.Head.Descent != pixel.Head.Descent {
+		t.Fatalf("ascent/descent %d/%d, want the 32 px %d/%d", scaled.Head.Ascent, scaled.Head.Descent,
+			pixel.Head.Ascent, pixel.Head.Descent)
+	}
+	for _, r := range runes {
+		s, _ := scaled.Glyph(r)
+		l, _ := logical.Glyph(r)
+		p, _ := pixel.Glyph(r)
+		if !reflect.DeepEqual(s, p) {
+			t.Fatalf("rune %q: scaled glyph %+v, want the 32 px glyph %+v", r, s, p)
+		}
+		if d := int(s.BBoxHeight) - 2*int(l.BBoxHeight); d < -1 || d > 1 {
+			t.Fatalf("rune %q: height %d, want about twice %d", r, s.BBoxHeight, l.BBoxHeight)
+		}
+	}
+
+	if _, err := NewFonts(pf, []uint16{16}, runes, Options{Scale: -1}); err == nil {
+		t.Fatal("expected an error for a negative scale")
+	}
+}
+
+func TestNewHeadTable_Underline(t *testing.T) {
+	pf := loadGoRegular(t)
+	post := pf.PostTable()
+	if post == nil || post.UnderlinePosition >= 0 || post.UnderlineThickness <= 0 {
+		t.Fatalf("unexpected post table %+v", post)
+	}
+	upem := float64(pf.UnitsPerEm())
+	for _, size := range []uint16{12, 16, 48} {
+		h := NewHeadTable(pf, size)
+		pos := math.Round(float64(post.UnderlinePosition) * float64(size) / upem)
+		thickness := math.Round(float64(post.UnderlineThickness) * float64(size) / upem)
+		if float64(h.UnderlinePosition) != pos || float64(h.UnderlineThickness) != thickness {
+			t.Fatalf("size %d: underline %d/%d, want %v/%v", size, h.UnderlinePosition, h.UnderlineThickness, pos, thickness)
+		}
+	}
+}
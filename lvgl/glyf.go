@@ -2,15 +2,16 @@ package lvgl
 
 import (
 	"bytes"
-	"encoding/binary"
+	"fmt"
 	"image"
+	"math"
+	"math/bits"
 
 	"golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 
 	"golang.org/x/image/font/sfnt"
-	"golang.org/x/image/vector"
 )
 
 type GlyfTable struct {
@@ -25,18 +26,63 @@ type GlyfData struct {
 }
 
 type GlyfDataInfo struct {
-	AdvanceWidth int16 //advanceWidth (length/format in font header, may have 4 fractional bits)
-	BBoxX        int8  //NN	BBox X (length in font header)
-	BBoxY        int8  //NN	BBox Y (length in font header)
-	BBoxWidth    uint8 //NN	BBox Width (length in font header)
-	BBoxHeight   uint8 //NN	BBox Height (length in font header)
+	AdvanceWidth int16  //advanceWidth (length/format in font header, may have 4 fractional bits)
+	BBoxX        int16  //NN	BBox X (signed, length in font header)
+	BBoxY        int16  //NN	BBox Y (signed, length in font header)
+	BBoxWidth    uint16 //NN	BBox Width (length in font header)
+	BBoxHeight   uint16 //NN	BBox Height (length in font header)
 }
 
+// Bytes returns the glyph record for the bit widths of NewHeadTable: a 16 bit advance and
+// 8 bit bbox fields, or nil if the glyph does not fit them, see Encode.
 func (d *GlyfData) Bytes() []byte {
-	buf := &bytes.Buffer{}
-	_ = binary.Write(buf, binary.BigEndian, d.GlyfDataInfo)
-	_, _ = d.Bitmap.WriteTo(buf)
-	return buf.Bytes()
+	b, err := d.Encode(&HeadTable{BitsPerPixel: 4, XyBits: 8, WhBits: 8, AdvanceWidthBits: 16})
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// Encode returns the glyph record packed with the bit widths of `h` as the font writer packs
+// it. A value that does not fit its width gives an error, as does a BitsPerPixel other than
+// the 4 of Bitmap.
+func (d *GlyfData) Encode(h *HeadTable) ([]byte, error) {
+	if h.BitsPerPixel != 4 {
+		return nil, fmt.Errorf("lvgl: glyph bitmap of 4 bpp, head has %d", h.BitsPerPixel)
+	}
+	for _, f := range []struct {
+		name        string
+		bits, width int
+	}{
+		{"bbox x", signedBits(int(d.BBoxX)), int(h.XyBits)},
+		{"bbox y", signedBits(int(d.BBoxY)), int(h.XyBits)},
+		{"bbox width", bits.Len16(d.BBoxWidth), int(h.WhBits)},
+		{"bbox height", bits.Len16(d.BBoxHeight), int(h.WhBits)},
+	} {
+		if f.bits > f.width {
+			return nil, fmt.Errorf("lvgl: glyph %s needs %d bits, head has %d", f.name, f.bits, f.width)
+		}
+	}
+	if h.AdvanceWidthBits > 0 && (d.AdvanceWidth < 0 || bits.Len16(uint16(d.AdvanceWidth)) > int(h.AdvanceWidthBits)) {
+		return nil, fmt.Errorf("lvgl: glyph advance %d does not fit %d bits", d.AdvanceWidth, h.AdvanceWidthBits)
+	}
+	pixels := int(d.BBoxWidth) * int(d.BBoxHeight)
+	if d.Bitmap.Len() < (pixels+1)/2 {
+		return nil, fmt.Errorf("lvgl: glyph bitmap of %d bytes for %d pixels", d.Bitmap.Len(), pixels)
+	}
+	bw := &bitWriter{}
+	if h.AdvanceWidthBits > 0 {
+		bw.write(uint32(d.AdvanceWidth), int(h.AdvanceWidthBits))
+	}
+	bw.write(uint32(d.BBoxX), int(h.XyBits))
+	bw.write(uint32(d.BBoxY), int(h.XyBits))
+	bw.write(uint32(d.BBoxWidth), int(h.WhBits))
+	bw.write(uint32(d.BBoxHeight), int(h.WhBits))
+	bitmap := d.Bitmap.Bytes()
+	for i := range pixels {
+		bw.write(uint32(bitmap[i/2]>>(4*(1-i%2))), 4)
+	}
+	return bw.bytes(), nil
 }
 
 func NewGlyfTable() *GlyfTable {
@@ -47,34 +93,233 @@ func NewGlyfTable() *GlyfTable {
 	}
 }
 
+// advanceFP4 converts a 26.6 fixed point advance to the FP4 (4 fractional bits) value
+// stored when HeadTable.AdvanceWidthFormat is 1, rounding to the nearest 1/16 px.
+func advanceFP4(advance fixed.Int26_6) int16 {
+	return int16((advance + 2) >> 2)
+}
+
 func AddGlyfData(buf *sfnt.Buffer, pf *sfnt.Font, fontSize uint16, r rune) (*GlyfData, error) {
 	glyphIndex, err := pf.GlyphIndex(buf, r)
 	if err != nil {
 		return nil, err
 	}
+	g, err := rasterizeGlyph(buf, pf, fontSize, glyphIndex, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &GlyfData{
+		GlyfDataInfo: g.info(),
+		Bitmap:       bytes.NewBuffer(g.pack(4)),
+	}, nil
+}
+
+// glyph is a rasterized glyph before it is packed into a glyf record.
+type glyph struct {
+	advance int          // FP4
+	x, y    int          // bbox left and bottom relative to the origin, y up
+	alpha   *image.Alpha // coverage of the bbox, top row first
+}
+
+func (g *glyph) width() int {
+	return g.alpha.Rect.Dx()
+}
+
+func (g *glyph) height() int {
+	return g.alpha.Rect.Dy()
+}
+
+func (g *glyph) info() GlyfDataInfo {
+	return GlyfDataInfo{
+		AdvanceWidth: int16(g.advance),
+		BBoxX:        int16(g.x),
+		BBoxY:        int16(g.y),
+		BBoxWidth:    uint16(g.width()),
+		BBoxHeight:   uint16(g.height()),
+	}
+}
+
+// embolden dilates the bitmap by `radius` px with a separable max filter, growing the bbox
+// by ceil(radius) on every side and the advance by 2*radius. The bbox is moved right by
+// ceil(radius) so that the left bearing is kept and the glyph grows into the wider advance.
+func (g *glyph) embolden(radius float64) {
+	if radius <= 0 {
+		return
+	}
+	g.advance += int(math.Round(radius * 32))
+	if g.width() == 0 || g.height() == 0 {
+		return
+	}
+	full := int(radius)
+	frac := radius - float64(full)
+	grow := int(math.Ceil(radius))
+	w, h := g.width(), g.height()
+	src := getAlpha(w+2*grow, h+2*grow)
+	draw.Copy(src, image.Point{X: grow, Y: grow}, g.alpha, g.alpha.Rect, draw.Src, nil)
+	putAlpha(g.alpha)
+	// dilate returns max(a[i-full..i+full], frac*a[i-full-1], frac*a[i+full+1]) of `get`.
+	dilate := func(n int, get func(i int) uint8, set func(i int, v uint8)) {
+		out := make([]uint8, n)
+		for i := range n {
+			var v uint8
+			for d := -full; d <= full; d++ {
+				if j := i + d; j >= 0 && j < n {
+					v = max(v, get(j))
+				}
+			}
+			if frac > 0 {
+				for _, j := range []int{i - full - 1, i + full + 1} {
+					if j >= 0 && j < n {
+						v = max(v, uint8(float64(get(j))*frac+0.5))
+					}
+				}
+			}
+			out[i] = v
+		}
+		for i, v := range out {
+			set(i, v)
+		}
+	}
+	r := src.Rect
+	for y := range r.Dy() {
+		row := src.Pix[y*src.Stride:]
+		dilate(r.Dx(), func(i int) uint8 { return row[i] }, func(i int, v uint8) { row[i] = v })
+	}
+	for x := range r.Dx() {
+		dilate(r.Dy(), func(i int) uint8 { return src.Pix[i*src.Stride+x] }, func(i int, v uint8) { src.Pix[i*src.Stride+x] = v })
+	}
+	g.alpha = src
+	g.y -= grow
+}
+
+// fitCell sets the advance to `cell` (FP4) and centers the bitmap in it. A bitmap wider
+// than the cell is cropped around its center when `clip` is set.
+func (g *glyph) fitCell(cell int, clip bool) error {
+	g.advance = cell
+	if w := g.width(); w*16 > cell {
+		if !clip {
+			return ErrGlyphWiderThanCell
+		}
+		cw := cell / 16
+		off := (w - cw) / 2
+		r := g.alpha.Rect
+		g.alpha = g.alpha.SubImage(image.Rect(r.Min.X+off, r.Min.Y, r.Min.X+off+cw, r.Max.Y)).(*image.Alpha)
+	}
+	g.x = (cell/16 - g.width()) / 2
+	return nil
+}
+
+// clip crops the bitmap to at most `size` px in each direction, keeping the middle of the
+// glyph, and reports whether anything was cut off.
+func (g *glyph) clip(size int) bool {
+	w, h := g.width(), g.height()
+	if w <= size && h <= size {
+		return false
+	}
+	cw, ch := min(w, size), min(h, size)
+	offX, offY := (w-cw)/2, (h-ch)/2
+	r := g.alpha.Rect
+	g.alpha = g.alpha.SubImage(image.Rect(r.Min.X+offX, r.Min.Y+offY, r.Min.X+offX+cw, r.Min.Y+offY+ch)).(*image.Alpha)
+	// y is the bottom edge, offY rows were cut from the top, h-ch-offY from the bottom.
+	g.x += offX
+	g.y += h - ch - offY
+	return true
+}
+
+// pack returns the bitmap with `bpp` bits per pixel, MSB first, rows not padded.
+func (g *glyph) pack(bpp int) []byte {
+	bw := &bitWriter{}
+	g.packTo(bw, bpp)
+	return bw.bytes()
+}
+
+func (g *glyph) packTo(bw *bitWriter, bpp int) {
+	r := g.alpha.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			bw.write(uint32(g.alpha.AlphaAt(x, y).A>>(8-bpp)), bpp)
+		}
+	}
+}
+
+// encodeGlyph appends the glyf record of `g` with the bit lengths of `h` to `bw`, starting
+// on a byte boundary.
+func (h *HeadTable) encodeGlyph(bw *bitWriter, g *glyph) {
+	bw.align()
+	if h.AdvanceWidthBits > 0 {
+		bw.write(uint32(g.advance), int(h.AdvanceWidthBits))
+	}
+	bw.write(uint32(g.x), int(h.XyBits))
+	bw.write(uint32(g.y), int(h.XyBits))
+	bw.write(uint32(g.width()), int(h.WhBits))
+	bw.write(uint32(g.height()), int(h.WhBits))
+	g.packTo(bw, int(h.BitsPerPixel))
+}
+
+// bitWriter writes MSB first bit fields, the inverse of bitReader.
+type bitWriter struct {
+	data []byte
+	pos  int
+}
+
+// write appends the low `n` bits of `v`.
+func (bw *bitWriter) write(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if bw.pos%8 == 0 {
+			bw.data = append(bw.data, 0)
+		}
+		bw.data[bw.pos/8] |= byte(v>>i&1) << (7 - bw.pos%8)
+		bw.pos++
+	}
+}
+
+// align skips to the next byte boundary.
+func (bw *bitWriter) align() {
+	bw.pos = len(bw.data) * 8
+}
+
+func (bw *bitWriter) bytes() []byte {
+	return bw.data
+}
+
+// shearSegments slants `segments` in place by x' = x + y*tan (y up, so positive angles
+// lean to the right) and returns the new bounds of their points.
+func shearSegments(segments sfnt.Segments, tan float64) fixed.Rectangle26_6 {
+	for i := range segments {
+		seg := &segments[i]
+		for k := range segmentPoints(*seg) {
+			// Segment coordinates grow downwards.
+			seg.Args[k].X -= fixed.Int26_6(math.Round(float64(seg.Args[k].Y) * tan))
+		}
+	}
+	return segmentBounds(segments)
+}
+
+// rasterizeGlyph renders the source glyph `glyphIndex` at `fontSize` px. `opts` may be nil.
+func rasterizeGlyph(buf *sfnt.Buffer, pf *sfnt.Font, fontSize uint16, glyphIndex sfnt.GlyphIndex, opts *Options) (*glyph, error) {
 	fontI := fixed.I(int(fontSize))
 	bounds, advance, err := pf.GlyphBounds(buf, glyphIndex, fontI, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
 	segments, err := pf.LoadGlyph(buf, glyphIndex, fontI, nil)
 	if err != nil {
 		return nil, err
 	}
-	info := &GlyfData{
-		GlyfDataInfo: GlyfDataInfo{
-			AdvanceWidth: int16(advance.Round() * 16), // LVGL FP4,
-			BBoxX:        int8(bounds.Min.X.Round()),
-			BBoxY:        -int8(bounds.Max.Y.Round()),
-			BBoxWidth:    uint8(bounds.Max.X.Round() - bounds.Min.X.Round()),
-			BBoxHeight:   uint8(bounds.Max.Y.Round() - bounds.Min.Y.Round()),
-		},
-		Bitmap: new(bytes.Buffer),
+	if opts != nil && opts.SlantDegrees != 0 && len(segments) > 0 {
+		bounds = shearSegments(segments, math.Tan(opts.SlantDegrees*math.Pi/180))
+	}
+	if opts != nil && opts.GridFit && len(segments) > 0 {
+		bounds = gridFitSegments(segments, fontSize)
 	}
 	var (
-		width   = int(info.BBoxWidth)
-		height  = int(info.BBoxHeight)
+		width   = bounds.Max.X.Round() - bounds.Min.X.Round()
+		height  = bounds.Max.Y.Round() - bounds.Min.Y.Round()
 		originX = float32(-bounds.Min.X.Round())
 		originY = float32(-bounds.Min.Y.Round())
 	)
-	rasterizer := vector.NewRasterizer(width, height)
+	rasterizer := getRasterizer(width, height)
+	defer putRasterizer(rasterizer)
 	rasterizer.DrawOp = draw.Src
 	for _, seg := range segments {
 		switch seg.Op {
@@ -106,25 +351,10 @@ func AddGlyfData(buf *sfnt.Buffer, pf *sfnt.Font, fontSize uint16, r rune) (*Gly
 			)
 		}
 	}
-	dst := image.NewAlpha(image.Rect(0, 0, width, height))
+	dst := getAlpha(width, height)
 	rasterizer.Draw(dst, dst.Bounds(), image.Opaque, image.Point{})
-	// 4bit一个像素点
-	bSplit, bByte := 0, byte(0)
-	for y := range height {
-		for x := range width {
-			a := dst.AlphaAt(x, y).A >> 4
-			if bSplit == 0 {
-				bByte = a << 4
-				bSplit = 1
-			} else {
-				bByte |= a
-				info.Bitmap.WriteByte(bByte)
-				bSplit = 0
-			}
-		}
-	}
-	if bSplit != 0 {
-		info.Bitmap.WriteByte(bByte)
+	if opts != nil && opts.Darken {
+		darken(dst, fontSize)
 	}
 
 	/*
@@ -141,5 +371,10 @@ func AddGlyfData(buf *sfnt.Buffer, pf *sfnt.Font, fontSize uint16, r rune) (*Gly
 		os.Stdout.Write(buf23)
 	*/
 
-	return info, nil
+	return &glyph{
+		advance: int(advanceFP4(advance)),
+		x:       bounds.Min.X.Round(),
+		y:       -bounds.Max.Y.Round(),
+		alpha:   dst,
+	}, nil
 }
@@ -0,0 +1,417 @@
+package lvgl
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestAdvanceFP4(t *testing.T) {
+	tests := []struct {
+		advance fixed.Int26_6
+		want    int16
+	}{
+		{fixed.I(10), 160},
+		{fixed.I(10) + 16, 164}, // 10.25 px
+		{fixed.I(10) + 32, 168}, // 10.5 px
+		{fixed.I(10) + 1, 160},  // 10.015625 px
+		{fixed.I(10) + 2, 161},  // rounds half up
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := advanceFP4(tt.advance); got != tt.want {
+			t.Errorf("advanceFP4(%v) = %d, want %d", tt.advance, got, tt.want)
+		}
+	}
+}
+
+func TestGlyfData_Encode(t *testing.T) {
+	d := &GlyfData{
+		GlyfDataInfo: GlyfDataInfo{AdvanceWidth: 300, BBoxX: -200, BBoxY: 300, BBoxWidth: 3, BBoxHeight: 1},
+		Bitmap:       bytes.NewBuffer([]byte{0x12, 0x30}),
+	}
+	if got := d.Bytes(); got != nil {
+		t.Errorf("Bytes() = % x, want nil for a bbox over 8 bits", got)
+	}
+	if _, err := d.Encode(NewHeadTable(loadGoRegular(t), 16)); err == nil {
+		t.Error("Encode with 8 bit bbox fields: want error")
+	}
+	h := &HeadTable{BitsPerPixel: 4, XyBits: 10, WhBits: 2, AdvanceWidthBits: 9}
+	got, err := d.Encode(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bw := &bitWriter{}
+	bw.write(300, 9)
+	bw.write(uint32(0x400-200), 10)
+	bw.write(300, 10)
+	bw.write(3, 2)
+	bw.write(1, 2)
+	bw.write(0x123, 12)
+	if want := bw.bytes(); !bytes.Equal(got, want) {
+		t.Errorf("Encode = % x, want % x", got, want)
+	}
+
+	d.AdvanceWidth = -1
+	if _, err := d.Encode(h); err == nil {
+		t.Error("Encode of a negative advance: want error")
+	}
+}
+
+func TestNewFont_FractionalAdvance(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := runeRange('a', 'z')
+	bin, err := NewFont(pf, 13, runes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf.Head.AdvanceWidthFormat != 1 || bf.Head.AdvanceWidthBits != 16 {
+		t.Fatalf("head advance format %d / %d bits, want FP4 in 16 bits", bf.Head.AdvanceWidthFormat, bf.Head.AdvanceWidthBits)
+	}
+	buf := &sfnt.Buffer{}
+	fractional := false
+	for _, r := range runes {
+		gi, _ := pf.GlyphIndex(buf, r)
+		advance, err := pf.GlyphAdvance(buf, gi, fixed.I(13), font.HintingNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, _ := bf.Glyph(r)
+		if want := uint32((advance + 2) >> 2); g.AdvanceWidth != want {
+			t.Fatalf("rune %q: advance %d, want %d (%v)", r, g.AdvanceWidth, want, advance)
+		}
+		fractional = fractional || g.AdvanceWidth%16 != 0
+	}
+	if !fractional {
+		t.Fatal("expected some fractional advances at 13 px")
+	}
+}
+
+// ink sums the 4 bpp pixel values of a decoded bitmap.
+func ink(bitmap []byte) int {
+	sum := 0
+	for _, b := range bitmap {
+		sum += int(b>>4) + int(b&0x0F)
+	}
+	return sum
+}
+
+func TestNewFonts_Embolden(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := []rune("lo")
+	plain, err := NewFonts(pf, []uint16{24}, runes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bold, err := NewFonts(pf, []uint16{24}, runes, Options{Embolden: 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, plain[24])
+	pf1, err := Parse(plain[24])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bold[24])
+	bf1, err := Parse(bold[24])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range runes {
+		p, _ := pf1.Glyph(r)
+		b, _ := bf1.Glyph(r)
+		// The left bearing is kept: the ink grows to the right into the wider advance.
+		if b.BBoxWidth != p.BBoxWidth+2 || b.BBoxHeight != p.BBoxHeight+2 || b.BBoxX != p.BBoxX || b.BBoxY != p.BBoxY-1 {
+			t.Fatalf("rune %q: bbox %d,%d %dx%d, want %d,%d %dx%d", r, b.BBoxX, b.BBoxY, b.BBoxWidth, b.BBoxHeight,
+				p.BBoxX, p.BBoxY-1, p.BBoxWidth+2, p.BBoxHeight+2)
+		}
+		if b.AdvanceWidth != p.AdvanceWidth+32 {
+			t.Fatalf("rune %q: advance %d, want %d", r, b.AdvanceWidth, p.AdvanceWidth+32)
+		}
+		if pi, bi := ink(p.Bitmap), ink(b.Bitmap); float64(bi) < 1.3*float64(pi) {
+			t.Fatalf("rune %q: ink %d -> %d, want a clearly heavier glyph", r, pi, bi)
+		}
+	}
+}
+
+// rowCenter returns the ink weighted x center of row `y` of a decoded 4 bpp glyph.
+func rowCenter(g *BinGlyph, y int) float64 {
+	sum, weighted := 0, 0
+	for x := range int(g.BBoxWidth) {
+		i := y*int(g.BBoxWidth) + x
+		v := int(g.Bitmap[i/2] >> (4 * (1 - i%2)) & 0x0F)
+		sum += v
+		weighted += v * x
+	}
+	return float64(weighted) / float64(sum)
+}
+
+func TestNewFonts_Slant(t *testing.T) {
+	pf := loadGoRegular(t)
+	for _, deg := range []float64{20, -20} {
+		bins, err := NewFonts(pf, []uint16{48}, []rune("l"), Options{SlantDegrees: deg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bins[48])
+		bf, err := Parse(bins[48])
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, _ := bf.Glyph('l')
+		// Compare two rows of the straight stem, Go Regular's 'l' has a tail at the bottom.
+		top, bottom := 1, int(g.BBoxHeight)*2/3
+		got := rowCenter(g, top) - rowCenter(g, bottom)
+		want := float64(bottom-top) * math.Tan(deg*math.Pi/180)
+		if math.Abs(got-want) > 1 {
+			t.Fatalf("%v degrees: top-bottom offset %.2f px, want %.2f", deg, got, want)
+		}
+	}
+	plain, _ := NewFont(pf, 48, []rune("l"))
+	bins, _ := NewFonts(pf, []uint16{48}, []rune("l"), Options{SlantDegrees: 20})
+	p, _ := Parse(plain)
+	s, _ := Parse(bins[48])
+	pg, _ := p.Glyph('l')
+	sg, _ := s.Glyph('l')
+	if pg.AdvanceWidth != sg.AdvanceWidth {
+		t.Fatalf("advance changed from %d to %d", pg.AdvanceWidth, sg.AdvanceWidth)
+	}
+}
+
+// rowPixels returns the x positions of the set pixels of row `y` of a decoded 1 bpp glyph.
+func rowPixels(g *BinGlyph, y int) []int {
+	var xs []int
+	for x := range int(g.BBoxWidth) {
+		i := y*int(g.BBoxWidth) + x
+		if g.Bitmap[i/8]>>(7-i%8)&1 != 0 {
+			xs = append(xs, x)
+		}
+	}
+	return xs
+}
+
+func TestNewFonts_GridFit(t *testing.T) {
+	pf := loadGoRegular(t)
+	stem := func(size uint16, opts Options) *BinGlyph {
+		t.Helper()
+		bins, err := NewFonts(pf, []uint16{size}, []rune("l"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bins[size])
+		bf, err := Parse(bins[size])
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, _ := bf.Glyph('l')
+		return g
+	}
+	// Rows of the straight stem, Go Regular's 'l' has a tail at the bottom.
+	g := stem(12, Options{BitsPerPixel: 1, GridFit: true})
+	first := rowPixels(g, 0)
+	for y := range int(g.BBoxHeight) * 2 / 3 {
+		xs := rowPixels(g, y)
+		if len(xs) < 1 || len(xs) > 2 || xs[len(xs)-1]-xs[0] != len(xs)-1 {
+			t.Fatalf("row %d: pixels %v, want a solid 1-2 px run", y, xs)
+		}
+		if !slices.Equal(xs, first) {
+			t.Fatalf("row %d: pixels %v, want the column %v of row 0", y, xs, first)
+		}
+	}
+
+	// At 8 bpp the fitted stem has no partially covered pixels, the unhinted one does.
+	partial := func(g *BinGlyph) int {
+		n := 0
+		for _, v := range g.Bitmap[:int(g.BBoxWidth)*(int(g.BBoxHeight)*2/3)] {
+			if v != 0 && v != 0xFF {
+				n++
+			}
+		}
+		return n
+	}
+	for size := uint16(10); size <= 14; size++ {
+		if n := partial(stem(size, Options{BitsPerPixel: 8})); n == 0 {
+			t.Logf("%d px: unhinted stem is already on the pixel grid", size)
+		}
+		if n := partial(stem(size, Options{BitsPerPixel: 8, GridFit: true})); n != 0 {
+			t.Fatalf("%d px: %d partially covered stem pixels after grid fitting", size, n)
+		}
+	}
+}
+
+func TestNewFonts_Darken(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := []rune("il")
+	plain, err := NewFonts(pf, []uint16{12}, runes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark, err := NewFonts(pf, []uint16{12}, runes, Options{Darken: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, dark[12])
+	p, _ := Parse(plain[12])
+	d, _ := Parse(dark[12])
+	for _, r := range runes {
+		pg, _ := p.Glyph(r)
+		dg, _ := d.Glyph(r)
+		if pg.BBoxWidth != dg.BBoxWidth || pg.BBoxHeight != dg.BBoxHeight {
+			t.Fatalf("rune %q: darkening changed the bbox", r)
+		}
+		if pi, di := ink(pg.Bitmap), ink(dg.Bitmap); di <= pi {
+			t.Fatalf("rune %q: ink %d -> %d, want a darker glyph", r, pi, di)
+		}
+	}
+}
+
+func TestNewFonts_BitsPerPixel(t *testing.T) {
+	pf := loadGoRegular(t)
+	for _, bpp := range []int{1, 2, 3, 4, 8} {
+		bins, err := NewFonts(pf, []uint16{12}, runeRange('a', 'z'), Options{BitsPerPixel: bpp})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bins[12])
+		bf, _ := Parse(bins[12])
+		if int(bf.Head.BitsPerPixel) != bpp {
+			t.Fatalf("head bits per pixel %d, want %d", bf.Head.BitsPerPixel, bpp)
+		}
+	}
+	if _, err := NewFonts(pf, []uint16{12}, []rune("a"), Options{BitsPerPixel: 5}); err == nil {
+		t.Fatal("expected an error for 5 bpp")
+	}
+}
+
+func TestNewFonts_NegativeBearing(t *testing.T) {
+	pf := loadGoRegular(t)
+	for _, tt := range []struct {
+		size  uint16
+		runes string
+	}{
+		{96, "j°"},
+		// The degree sign sits more than 127 px above the baseline.
+		{320, "°"},
+	} {
+		bins, err := NewFonts(pf, []uint16{tt.size}, []rune(tt.runes), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bins[tt.size])
+		bf, err := Parse(bins[tt.size])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range tt.runes {
+			g, _ := bf.Glyph(r)
+			gi, _ := pf.GlyphIndex(nil, r)
+			bounds, _, _ := pf.GlyphBounds(nil, gi, fixed.I(int(tt.size)), font.HintingNone)
+			if g.BBoxX != int32(bounds.Min.X.Round()) || g.BBoxY != int32(-bounds.Max.Y.Round()) {
+				t.Fatalf("%d px %q: bbox origin %d,%d, want %d,%d", tt.size, r, g.BBoxX, g.BBoxY,
+					bounds.Min.X.Round(), -bounds.Max.Y.Round())
+			}
+			checkPlacement(t, pf, tt.size, r, g)
+		}
+		if j, ok := bf.Glyph('j'); ok && j.BBoxX >= 0 {
+			t.Fatalf("%d px: 'j' bbox x %d, want a negative bearing", tt.size, j.BBoxX)
+		}
+		if deg, _ := bf.Glyph('°'); tt.size == 320 && (deg.BBoxY <= math.MaxInt8 || bf.Head.XyBits <= 8) {
+			t.Fatalf("%d px: '°' bbox y %d with %d xy bits", tt.size, deg.BBoxY, bf.Head.XyBits)
+		}
+	}
+}
+
+// checkPlacement draws `r` with an opentype.Face and compares the top left ink pixel with
+// the one of the decoded 4 bpp glyph `g` placed at the same pen position.
+func checkPlacement(t *testing.T, pf *sfnt.Font, size uint16, r rune, g *BinGlyph) {
+	t.Helper()
+	face, err := opentype.NewFace(pf, &opentype.FaceOptions{Size: float64(size), DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+	dot := image.Pt(int(size), int(size)*3/2)
+	dst := image.NewAlpha(image.Rect(0, 0, int(size)*3, int(size)*3))
+	d := &font.Drawer{Dst: dst, Src: image.Opaque, Face: face, Dot: fixed.P(dot.X, dot.Y)}
+	d.DrawString(string(r))
+	want := image.Pt(-1, -1)
+	for y := 0; y < dst.Rect.Dy() && want.Y < 0; y++ {
+		for x := range dst.Rect.Dx() {
+			if dst.AlphaAt(x, y).A >= 0x80 {
+				want = image.Pt(x, y)
+				break
+			}
+		}
+	}
+	got := image.Pt(-1, -1)
+	top := dot.Y - int(g.BBoxY) - int(g.BBoxHeight)
+	for y := 0; y < int(g.BBoxHeight) && got.Y < 0; y++ {
+		for x := range int(g.BBoxWidth) {
+			i := y*int(g.BBoxWidth) + x
+			if g.Bitmap[i/2]>>(4*(1-i%2))&0x0F >= 0x08 {
+				got = image.Pt(dot.X+int(g.BBoxX)+x, top+y)
+				break
+			}
+		}
+	}
+	if d := got.Sub(want); d.X < -1 || d.X > 1 || d.Y < -1 || d.Y > 1 {
+		t.Fatalf("%d px %q: first ink pixel at %v, drawn at %v", size, r, got, want)
+	}
+}
+
+func TestNewFonts_HugeGlyph(t *testing.T) {
+	pf := loadGoRegular(t)
+	// At 320 px 'j' is taller than the 255 px that 8 bit w/h fields hold.
+	runes := []rune("aj")
+	bins, stats, err := NewFontsWithStats(pf, []uint16{320}, runes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bins[320])
+	bf, err := Parse(bins[320])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi, _ := pf.GlyphIndex(nil, 'j')
+	bounds, _, _ := pf.GlyphBounds(nil, gi, fixed.I(320), font.HintingNone)
+	j, _ := bf.Glyph('j')
+	if want := bounds.Max.Y.Round() - bounds.Min.Y.Round(); int(j.BBoxHeight) != want || want <= math.MaxUint8 {
+		t.Fatalf("'j' height %d, want %d (more than 255)", j.BBoxHeight, want)
+	}
+	if bf.Head.WhBits <= 8 || len(stats[320].Clipped) != 0 {
+		t.Fatalf("wh bits %d, clipped %q", bf.Head.WhBits, stats[320].Clipped)
+	}
+	checkPlacement(t, pf, 320, 'j', j)
+
+	bins, stats, err = NewFontsWithStats(pf, []uint16{320}, runes, Options{MaxGlyphSize: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bins[320])
+	if bf, err = Parse(bins[320]); err != nil {
+		t.Fatal(err)
+	}
+	if bf.Head.WhBits != 8 || string(stats[320].Clipped) != "j" {
+		t.Fatalf("wh bits %d, clipped %q, want 8 and \"j\"", bf.Head.WhBits, stats[320].Clipped)
+	}
+	c, _ := bf.Glyph('j')
+	if c.BBoxHeight != 255 || c.BBoxWidth != j.BBoxWidth {
+		t.Fatalf("clipped 'j' is %dx%d, want %dx255", c.BBoxWidth, c.BBoxHeight, j.BBoxWidth)
+	}
+	// The crop keeps the middle rows: the top edge moves down by half the excess.
+	if top, want := c.BBoxY+int32(c.BBoxHeight), j.BBoxY+int32(j.BBoxHeight)-int32(j.BBoxHeight-255)/2; top != want {
+		t.Fatalf("clipped 'j' top %d, want %d", top, want)
+	}
+}
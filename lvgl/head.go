@@ -2,7 +2,9 @@ package lvgl
 
 import (
 	"encoding/binary"
+	"math/bits"
 
+	"github.com/zhimiaox/subfont/ttf"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
@@ -34,18 +36,18 @@ type HeadTable struct {
 	AdvanceWidthFormat byte //1	advanceWidthFormat (0 - Uint, 1 - unsigned with 4 bits)
 	//位图与 BBox 配置
 	BitsPerPixel     byte //1	Bits per pixel (1, 2, 3 or 4)
-	XyBits           byte //1	Glyph BBox x/y bits length (unsigned)
+	XyBits           byte //1	Glyph BBox x/y bits length (signed)
 	WhBits           byte //1	Glyph BBox w/h bits length (unsigned)
 	AdvanceWidthBits byte //1	Glyph advanceWidth bits length (unsigned, may be FP4)
 	// 压缩信息
 	CompressionId byte //1	Compression alg ID (0 - raw bits, 1 - RLE-like with XOR prefilter, 2 - RLE-like only without prefilter)
 	SubpixelsMode byte //1	Subpixel rendering. 0 - none, 1 - horisontal resolution of bitmaps is 3x, 2 - vertical resolution of bitmaps is 3x.
-	tmpReserved1  byte //1	Reserved (align to 2x)
+	_             byte //1	Reserved (align to 2x)
 	// 下划线喜喜
 	UnderlinePosition  int16 //2	Underline position (int16), scaled post.underlinePosition
 	UnderlineThickness int16 //2	Underline thickness (uint16), scaled post.underlineThickness
 	//尾部对其
-	//Blank []uint8 //x	Unused (Align header length to 4x)
+	//Blank []uint8 //x	Unused (Align header length to 4x), written by writeHead
 }
 
 func NewHeadTable(pf *sfnt.Font, fontSize uint16) *HeadTable {
@@ -54,7 +56,7 @@ func NewHeadTable(pf *sfnt.Font, fontSize uint16) *HeadTable {
 		Size:               48,
 		Label:              [4]byte{'h', 'e', 'a', 'd'},
 		Version:            1,
-		Tables:             3,
+		Tables:             3, // cmap, loca, glyf; Font.encode counts the tables it writes
 		FontSize:           fontSize,
 		Ascent:             0, //Math.max(...glyphs.map(g => g.bbox.y + g.bbox.height)),
 		Descent:            0, //Math.min(...glyphs.map(g => g.bbox.y)),
@@ -74,9 +76,34 @@ func NewHeadTable(pf *sfnt.Font, fontSize uint16) *HeadTable {
 		AdvanceWidthBits:   16,
 	}
 	if postTable := pf.PostTable(); postTable != nil {
-		t.UnderlinePosition = postTable.UnderlinePosition
-		t.UnderlineThickness = postTable.UnderlineThickness
+		s := ttf.NewScaler(int(pf.UnitsPerEm()), float64(fontSize))
+		t.UnderlinePosition = int16(s.ScaleRound(int(postTable.UnderlinePosition)))
+		t.UnderlineThickness = int16(s.ScaleRound(int(postTable.UnderlineThickness)))
 	}
-	t.Size = uint32(binary.Size(t))
+	t.Size = uint32(align4(binary.Size(t)))
 	return t
 }
+
+// fitBitWidths widens XyBits, WhBits and AdvanceWidthBits where the values of a glyph do not
+// fit them, so no bbox or advance is truncated in the glyf records. Widths that suffice are
+// kept, as is an AdvanceWidthBits of 0 (monospace, advances in DefAdvanceWidth).
+func (t *HeadTable) fitBitWidths(glyphs []*glyph) {
+	xy, wh, adv := int(t.XyBits), int(t.WhBits), int(t.AdvanceWidthBits)
+	for _, g := range glyphs {
+		xy = max(xy, signedBits(g.x), signedBits(g.y))
+		wh = max(wh, bits.Len(uint(g.width())), bits.Len(uint(g.height())))
+		adv = max(adv, bits.Len(uint(g.advance)))
+	}
+	t.XyBits, t.WhBits = byte(xy), byte(wh)
+	if t.AdvanceWidthBits > 0 {
+		t.AdvanceWidthBits = byte(adv)
+	}
+}
+
+// signedBits returns the number of bits of `v` in two's complement, sign bit included.
+func signedBits(v int) int {
+	if v < 0 {
+		v = ^v
+	}
+	return bits.Len(uint(v)) + 1
+}
@@ -0,0 +1,178 @@
+package lvgl
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// gridEdge is a straight, axis-aligned piece of outline: its coordinate across the axis and
+// the direction it is drawn in along the axis.
+type gridEdge struct {
+	pos fixed.Int26_6
+	dir int
+}
+
+// gridAnchor moves outline coordinate `from` to `to` on one axis.
+type gridAnchor struct {
+	from, to fixed.Int26_6
+}
+
+// gridFitSegments is a minimal autohinter. Vertical and horizontal line segments are paired
+// into stems (an edge followed by the nearest edge of the opposite direction no farther than
+// `maxStem`), every stem is moved to a whole pixel and given a whole pixel width of at least
+// 1 px; lone edges, the outline bounds and the baseline are rounded to whole pixels. All
+// other points are interpolated between those anchors, so curves follow the stems they join.
+// It returns the new bounds of the points.
+func gridFitSegments(segments sfnt.Segments, fontSize uint16) fixed.Rectangle26_6 {
+	maxStem := fixed.I(int(fontSize)) / 4
+	var xEdges, yEdges []gridEdge
+	var cur fixed.Point26_6
+	for _, seg := range segments {
+		n := segmentPoints(seg)
+		end := seg.Args[n-1]
+		if seg.Op == sfnt.SegmentOpLineTo {
+			dx, dy := end.X-cur.X, end.Y-cur.Y
+			// Accept edges within ~7° of an axis and at least a quarter pixel long.
+			switch {
+			case abs(dy) >= 16 && abs(dx)*8 <= abs(dy):
+				xEdges = append(xEdges, gridEdge{pos: (cur.X + end.X) / 2, dir: sign(dy)})
+			case abs(dx) >= 16 && abs(dy)*8 <= abs(dx):
+				yEdges = append(yEdges, gridEdge{pos: (cur.Y + end.Y) / 2, dir: sign(dx)})
+			}
+		}
+		cur = end
+	}
+	bounds := segmentBounds(segments)
+	xAnchors := gridAnchors(xEdges, maxStem, bounds.Min.X, bounds.Max.X)
+	yAnchors := gridAnchors(yEdges, maxStem, bounds.Min.Y, bounds.Max.Y, 0)
+	for i := range segments {
+		seg := &segments[i]
+		for k := range segmentPoints(*seg) {
+			p := &seg.Args[k]
+			p.X, p.Y = gridMap(xAnchors, p.X), gridMap(yAnchors, p.Y)
+		}
+	}
+	return segmentBounds(segments)
+}
+
+// gridAnchors pairs `edges` into stems and returns the anchors of one axis sorted by their
+// original coordinate, with monotonic targets. `extra` coordinates are rounded to whole
+// pixels unless a stem already claims them.
+func gridAnchors(edges []gridEdge, maxStem fixed.Int26_6, extra ...fixed.Int26_6) []gridAnchor {
+	slices.SortStableFunc(edges, func(a, b gridEdge) int { return int(a.pos - b.pos) })
+	edges = slices.CompactFunc(edges, func(a, b gridEdge) bool { return a.dir == b.dir && b.pos-a.pos <= 1 })
+	anchors := make([]gridAnchor, 0, len(edges)+len(extra))
+	paired := make([]bool, len(edges))
+	for i, e := range edges {
+		if paired[i] {
+			continue
+		}
+		for j := i + 1; j < len(edges) && edges[j].pos-e.pos <= maxStem; j++ {
+			if !paired[j] && edges[j].dir != e.dir {
+				paired[i], paired[j] = true, true
+				from := roundPixel(e.pos)
+				width := max(fixed.I(1), roundPixel(edges[j].pos-e.pos))
+				anchors = append(anchors, gridAnchor{e.pos, from}, gridAnchor{edges[j].pos, from + width})
+				break
+			}
+		}
+		if !paired[i] {
+			anchors = append(anchors, gridAnchor{e.pos, roundPixel(e.pos)})
+		}
+	}
+	for _, v := range extra {
+		anchors = append(anchors, gridAnchor{v, roundPixel(v)})
+	}
+	// Stems come first, so a stable sort keeps their targets for shared coordinates.
+	slices.SortStableFunc(anchors, func(a, b gridAnchor) int { return int(a.from - b.from) })
+	anchors = slices.CompactFunc(anchors, func(a, b gridAnchor) bool { return a.from == b.from })
+	for i := 1; i < len(anchors); i++ {
+		anchors[i].to = max(anchors[i].to, anchors[i-1].to)
+	}
+	return anchors
+}
+
+// gridMap moves `v` with the anchors around it: points between two anchors are interpolated
+// linearly, points outside all anchors are shifted with the nearest one.
+func gridMap(anchors []gridAnchor, v fixed.Int26_6) fixed.Int26_6 {
+	if len(anchors) == 0 {
+		return v
+	}
+	i, found := slices.BinarySearchFunc(anchors, v, func(a gridAnchor, v fixed.Int26_6) int { return int(a.from - v) })
+	switch {
+	case found:
+		return anchors[i].to
+	case i == 0:
+		return v + anchors[0].to - anchors[0].from
+	case i == len(anchors):
+		return v + anchors[i-1].to - anchors[i-1].from
+	}
+	a, b := anchors[i-1], anchors[i]
+	t := float64(v-a.from) / float64(b.from-a.from)
+	return a.to + fixed.Int26_6(math.Round(t*float64(b.to-a.to)))
+}
+
+// darken boosts partial coverage of `alpha` at small sizes, which reads like a slightly
+// wider outline: a' = 1-(1-a)^gamma with gamma from 1.5 at 10 px and below to 1 at 20 px.
+func darken(alpha *image.Alpha, fontSize uint16) {
+	gamma := 1 + 0.5*min(1, max(0, (20-float64(fontSize))/10))
+	if gamma == 1 {
+		return
+	}
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(math.Round(255 * (1 - math.Pow(1-float64(i)/255, gamma))))
+	}
+	for i, a := range alpha.Pix {
+		alpha.Pix[i] = lut[a]
+	}
+}
+
+// roundPixel rounds `v` to a whole pixel.
+func roundPixel(v fixed.Int26_6) fixed.Int26_6 {
+	return (v + 32) &^ 63
+}
+
+// segmentPoints returns the number of points in seg.Args used by its op.
+func segmentPoints(seg sfnt.Segment) int {
+	switch seg.Op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	}
+	return 1
+}
+
+// segmentBounds returns the bounds of all points of `segments`, control points included.
+func segmentBounds(segments sfnt.Segments) fixed.Rectangle26_6 {
+	bounds := fixed.Rectangle26_6{
+		Min: fixed.Point26_6{X: math.MaxInt32, Y: math.MaxInt32},
+		Max: fixed.Point26_6{X: math.MinInt32, Y: math.MinInt32},
+	}
+	for _, seg := range segments {
+		for _, p := range seg.Args[:segmentPoints(seg)] {
+			bounds.Min.X, bounds.Min.Y = min(bounds.Min.X, p.X), min(bounds.Min.Y, p.Y)
+			bounds.Max.X, bounds.Max.Y = max(bounds.Max.X, p.X), max(bounds.Max.Y, p.Y)
+		}
+	}
+	return bounds
+}
+
+func abs(v fixed.Int26_6) fixed.Int26_6 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v fixed.Int26_6) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,103 @@
+package lvgl
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// KernFormatPairs is the sorted pairs kern format (KernTable.Format).
+const KernFormatPairs byte = 0
+
+type KernTable struct {
+	Size   uint32  //4	Record size (for quick skip)
+	Label  [4]byte //4	kern (table marker)
+	Format byte    //1	Format type (0 => sorted pairs, 3 => array M*N of classes)
+	_      [3]byte //3	Unused (align to 4)
+	// format 0: Entries count uint32, glyph ID pairs, int8 values
+}
+
+// KernPair is the kerning of glyph Left followed by glyph Right, in FP4 px.
+type KernPair struct {
+	Left, Right uint16
+	Value       int
+}
+
+// NewKernTable builds a sorted pairs kern table with 2 byte glyph IDs for `pairs` and
+// returns it with its data. The values are stored in one byte, `scale` is the
+// head.KerningScale (FP12.4) that brings them back to FP4.
+func NewKernTable(pairs []KernPair) (*KernTable, []byte, uint16) {
+	pairs = slices.Clone(pairs)
+	slices.SortFunc(pairs, func(a, b KernPair) int {
+		return cmp.Or(cmp.Compare(a.Left, b.Left), cmp.Compare(a.Right, b.Right))
+	})
+	maxValue := 0
+	for _, p := range pairs {
+		maxValue = max(maxValue, p.Value, -p.Value)
+	}
+	// value * scale / 16 = FP4 kerning, with value in int8.
+	scale := max(16, (maxValue*16+126)/127)
+	data := new(bytes.Buffer)
+	_ = binary.Write(data, binary.LittleEndian, uint32(len(pairs)))
+	for _, p := range pairs {
+		_ = binary.Write(data, binary.LittleEndian, []uint16{p.Left, p.Right})
+	}
+	for _, p := range pairs {
+		v := (p.Value*16 + cmp.Compare(p.Value, 0)*scale/2) / scale
+		data.WriteByte(byte(int8(v)))
+	}
+	data.Write(make([]byte, align4(data.Len())-data.Len()))
+	t := &KernTable{
+		Label:  [4]byte{'k', 'e', 'r', 'n'},
+		Format: KernFormatPairs,
+	}
+	t.Size = uint32(binary.Size(t) + data.Len())
+	return t, data.Bytes(), uint16(scale)
+}
+
+// sourceKernPair is a pair of the kern table of a source font, in font units.
+type sourceKernPair struct {
+	left, right sfnt.GlyphIndex
+	value       int16
+}
+
+// parseSourceKern returns the pairs of the kern table `data` of a source font: those of its
+// first subtable if it is of version 0 and format 0 with horizontal kerning, like sfnt reads it,
+// otherwise none.
+func parseSourceKern(data []byte) []sourceKernPair {
+	be := binary.BigEndian
+	// Header: version, nTables. Subtable: version, length, format, coverage, nPairs and the
+	// search fields, then the pairs.
+	const headerSize, subtableSize, pairSize = 4, 14, 6
+	if len(data) < headerSize+subtableSize || be.Uint16(data) != 0 || be.Uint16(data[2:]) == 0 {
+		return nil
+	}
+	sub := data[headerSize:]
+	if be.Uint16(sub) != 0 || sub[4] != 0 || sub[5] != 0x01 {
+		return nil
+	}
+	n := int(be.Uint16(sub[6:]))
+	// The subtable length is a uint16 that fonts with many pairs overflow, so it is not used.
+	if len(sub) < subtableSize+pairSize*n {
+		return nil
+	}
+	pairs := make([]sourceKernPair, n)
+	for i := range pairs {
+		p := sub[subtableSize+pairSize*i:]
+		pairs[i] = sourceKernPair{sfnt.GlyphIndex(be.Uint16(p)), sfnt.GlyphIndex(be.Uint16(p[2:])), int16(be.Uint16(p[4:]))}
+	}
+	return pairs
+}
+
+// kernFP4 returns the kerning `units` of a font of `unitsPerEm` at `size` px in FP4, rounded half
+// away from zero.
+func kernFP4(units int16, size uint16, unitsPerEm sfnt.Units) int {
+	v, upem := int(units)*int(size)*16, int(unitsPerEm)
+	if v >= 0 {
+		return (v + upem/2) / upem
+	}
+	return (v - upem/2) / upem
+}
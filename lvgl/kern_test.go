@@ -0,0 +1,166 @@
+package lvgl
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// kernFont adds a kern table with `pairs` (font units) to Go Regular.
+func kernFont(t *testing.T, pairs map[[2]rune]int16) *sfnt.Font {
+	t.Helper()
+	pf := loadGoRegular(t)
+	type pair struct {
+		left, right uint16
+		value       int16
+	}
+	var list []pair
+	for k, v := range pairs {
+		l, _ := pf.GlyphIndex(nil, k[0])
+		r, _ := pf.GlyphIndex(nil, k[1])
+		list = append(list, pair{uint16(l), uint16(r), v})
+	}
+	slices.SortFunc(list, func(a, b pair) int {
+		return cmp.Or(cmp.Compare(a.left, b.left), cmp.Compare(a.right, b.right))
+	})
+	kern := &bytes.Buffer{}
+	_ = binary.Write(kern, binary.BigEndian, []uint16{0, 1})                                // version, nTables
+	_ = binary.Write(kern, binary.BigEndian, []uint16{0, uint16(14 + 6*len(list)), 0x0001}) // version, length, coverage
+	_ = binary.Write(kern, binary.BigEndian, []uint16{uint16(len(list)), 0, 0, 0})          // nPairs, search fields
+	for _, p := range list {
+		_ = binary.Write(kern, binary.BigEndian, []uint16{p.left, p.right, uint16(p.value)})
+	}
+	tables := sfntTables(goregular.TTF)
+	tables["kern"] = kern.Bytes()
+	kf, err := sfnt.Parse(buildSfnt(tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kf
+}
+
+func TestNewFonts_KernTableCount(t *testing.T) {
+	// Go Regular has 2048 units per em: -200 units at 32 px is -3.125 px, FP4 -50.
+	pf := kernFont(t, map[[2]rune]int16{{'A', 'V'}: -200, {'T', 'o'}: -150})
+	runes := []rune("AVTo")
+	for _, kerning := range []bool{false, true} {
+		bins, err := NewFonts(pf, []uint16{32}, runes, Options{Kerning: kerning})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bins[32])
+		bf, err := Parse(bins[32])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !kerning {
+			if bf.Head.Tables != 3 || bf.Kern != nil {
+				t.Fatalf("without kerning: head tables %d, kern %v", bf.Head.Tables, bf.Kern)
+			}
+			continue
+		}
+		if bf.Head.Tables != 4 || bf.Kern == nil || len(bf.KernPairs) != 2 {
+			t.Fatalf("with kerning: head tables %d, pairs %+v", bf.Head.Tables, bf.KernPairs)
+		}
+		a, _ := bf.GlyphID('A')
+		v, _ := bf.GlyphID('V')
+		if p := bf.KernPairs[0]; uint32(p.Left) != a || uint32(p.Right) != v || p.Value < -51 || p.Value > -49 {
+			t.Fatalf("A-V pair = %+v, want %d-%d -50", p, a, v)
+		}
+	}
+}
+
+func TestNewKernTable(t *testing.T) {
+	// Unsorted, with a value past int8 in FP4 so that the scale grows.
+	pairs := []KernPair{{Left: 3, Right: 1, Value: -4000}, {Left: 1, Right: 2, Value: 5}, {Left: 1, Right: 1, Value: -16}}
+	table, data, scale := NewKernTable(pairs)
+	if want := uint16((4000*16 + 126) / 127); scale != want {
+		t.Fatalf("scale %d, want %d", scale, want)
+	}
+	if int(table.Size) != binary.Size(table)+len(data) || len(data)%4 != 0 {
+		t.Fatalf("size %d with %d bytes of data", table.Size, len(data))
+	}
+	if n := binary.LittleEndian.Uint32(data); n != 3 {
+		t.Fatalf("%d pairs", n)
+	}
+	for i, want := range [][2]uint16{{1, 1}, {1, 2}, {3, 1}} {
+		got := [2]uint16{binary.LittleEndian.Uint16(data[4+4*i:]), binary.LittleEndian.Uint16(data[6+4*i:])}
+		if got != want {
+			t.Fatalf("pair %d is %v, want %v", i, got, want)
+		}
+	}
+	// Values come back within half a step of the scale.
+	for i, want := range []int{-16, 5, -4000} {
+		got := int(int8(data[4+4*3+i])) * int(scale) / 16
+		if diff := got - want; diff < -int(scale)/32-1 || diff > int(scale)/32+1 {
+			t.Fatalf("value %d is %d, want %d", i, got, want)
+		}
+	}
+	// Small values keep the FP4 scale of 1.
+	if _, _, scale := NewKernTable(pairs[1:]); scale != 16 {
+		t.Fatalf("scale %d, want 16", scale)
+	}
+}
+
+func TestParseSourceKern(t *testing.T) {
+	be := binary.BigEndian
+	kern := func(version, coverage uint16, pairs ...uint16) []byte {
+		b := be.AppendUint16(be.AppendUint16(nil, version), 1)
+		b = be.AppendUint16(be.AppendUint16(be.AppendUint16(b, 0), uint16(14+2*len(pairs))), coverage)
+		b = be.AppendUint16(b, uint16(len(pairs)/3))
+		b = append(b, make([]byte, 6)...)
+		for _, v := range pairs {
+			b = be.AppendUint16(b, v)
+		}
+		return b
+	}
+	got := parseSourceKern(kern(0, 0x0001, 4, 7, 0xFF38, 4, 9, 12))
+	want := []sourceKernPair{{4, 7, -200}, {4, 9, 12}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("pairs %v, want %v", got, want)
+	}
+	for name, data := range map[string][]byte{
+		"Apple version 1": kern(1, 0x0001, 4, 7, 1),
+		"vertical":        kern(0, 0x0000, 4, 7, 1),
+		"format 2":        kern(0, 0x0201, 4, 7, 1),
+		"truncated":       kern(0, 0x0001, 4, 7, 1)[:20],
+		"none":            nil,
+	} {
+		if got := parseSourceKern(data); got != nil {
+			t.Fatalf("%s: pairs %v", name, got)
+		}
+	}
+
+	for _, tt := range []struct {
+		units int16
+		size  uint16
+		want  int
+	}{{-200, 32, -50}, {200, 32, 50}, {1, 16, 0}, {-4, 16, -1}, {-3, 16, 0}, {4, 16, 1}} {
+		if got := kernFP4(tt.units, tt.size, 2048); got != tt.want {
+			t.Fatalf("%d units at %d px: %d, want %d", tt.units, tt.size, got, tt.want)
+		}
+	}
+}
+
+// TestConverter_KernPairs checks that source glyphs that several glyph IDs are drawn from, as
+// for runes sharing a glyph, give each of them the kerning of the glyph.
+func TestConverter_KernPairs(t *testing.T) {
+	pf := kernFont(t, map[[2]rune]int16{{'A', 'V'}: -200, {'V', 'A'}: 1})
+	a, _ := pf.GlyphIndex(nil, 'A')
+	v, _ := pf.GlyphIndex(nil, 'V')
+	c := &converter{pf: pf}
+	got := c.kernPairs(32, []sfnt.GlyphIndex{a, v, a})
+	// V-A rounds to 0 at 32 px and is left out.
+	want := []KernPair{{Left: 1, Right: 2, Value: -50}, {Left: 3, Right: 2, Value: -50}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("pairs %+v, want %+v", got, want)
+	}
+	if got := (&converter{pf: loadGoRegular(t)}).kernPairs(32, []sfnt.GlyphIndex{a, v}); got != nil {
+		t.Fatalf("without kern table: pairs %+v", got)
+	}
+}
@@ -0,0 +1,88 @@
+package lvgl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Options configures the conversion of NewFonts. The zero value matches NewFont.
+type Options struct {
+	// Parallel rasterizes the requested sizes concurrently, one goroutine per size.
+	Parallel bool
+
+	// Kerning writes a kern table with the pair kerning of the source font's kern table, of
+	// the pairs of its first subtable of format 0 between the converted runes. Kerning of GPOS
+	// is not read.
+	Kerning bool
+
+	// Embolden dilates every glyph bitmap by this radius in px (synthetic bold). The bbox
+	// grows by twice the radius rounded up, to the right, up and down, and the advance by
+	// twice the radius, so the left bearing is kept; a fractional radius adds a partially
+	// covered outer ring.
+	Embolden float64
+
+	// SlantDegrees shears the outlines around the baseline before rasterization (synthetic
+	// oblique); positive angles lean to the right. Advances are unchanged.
+	SlantDegrees float64
+
+	// Monospace gives every glyph the same advance and centers its bitmap in that cell. The
+	// advance is stored once in the head (DefAdvanceWidth) instead of per glyph.
+	Monospace bool
+	// MonospaceAdvance is the cell width in px; 0 uses the widest advance of the glyph set
+	// rounded up to a whole pixel.
+	MonospaceAdvance float64
+	// MonospaceClip crops glyphs wider than the cell to the cell width instead of failing
+	// with ErrGlyphWiderThanCell.
+	MonospaceClip bool
+
+	// BitsPerPixel is the bitmap depth: 1, 2, 3, 4 or 8; 0 means 4. Coverage is truncated,
+	// so at 1 bpp a pixel is set when it is at least half covered.
+	BitsPerPixel int
+
+	// GridFit snaps vertical and horizontal stems, the glyph bounds and the baseline to
+	// whole pixels before rasterization, which keeps small sizes crisp at low bit depths.
+	GridFit bool
+	// Darken boosts partially covered pixels below 20 px so thin stems do not wash out.
+	Darken bool
+
+	// Scale rasterizes every size at size*Scale px, rounded to a whole pixel; 0 means 1.
+	// LVGL has no notion of DPI and draws everything in pixels, so bitmaps, bboxes, advances,
+	// kerning and the bitmap derived ascent/descent are stored at the scaled size. The head
+	// keeps FontSize and the typographic line metrics of the logical size the caller asked
+	// for, and NewFonts keys its results by the logical size.
+	Scale float64
+
+	// MaxGlyphSize crops bitmaps wider or taller than this many px around their middle and
+	// lists the glyphs in Stats.Clipped; 0 means no limit. The bbox bit widths in the head
+	// grow with the largest glyph, but LVGL's runtime glyph descriptors hold 8 bit box sizes,
+	// so 255 keeps huge glyphs (stacked marks, box drawing at large sizes) loadable.
+	MaxGlyphSize int
+}
+
+// pixelSize returns the size in px glyphs of the logical `size` are rasterized at.
+func (o *Options) pixelSize(size uint16) (uint16, error) {
+	if o.Scale == 0 || o.Scale == 1 {
+		return size, nil
+	}
+	px := math.Round(float64(size) * o.Scale)
+	if !(px >= 1 && px <= math.MaxUint16) {
+		return 0, fmt.Errorf("lvgl: size %d at scale %v is out of range", size, o.Scale)
+	}
+	return uint16(px), nil
+}
+
+// bitsPerPixel returns the bitmap depth selected by `o`.
+func (o *Options) bitsPerPixel() (byte, error) {
+	switch o.BitsPerPixel {
+	case 0:
+		return 4, nil
+	case 1, 2, 3, 4, 8:
+		return byte(o.BitsPerPixel), nil
+	}
+	return 0, fmt.Errorf("lvgl: unsupported bits per pixel %d", o.BitsPerPixel)
+}
+
+// ErrGlyphWiderThanCell is returned in monospace mode for a glyph whose bitmap does not fit
+// the cell and Options.MonospaceClip is not set.
+var ErrGlyphWiderThanCell = errors.New("lvgl: glyph wider than monospace cell")
@@ -0,0 +1,348 @@
+package lvgl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// BinFont is a decoded LVGL binary font, the inverse of NewFont. It is mainly used to
+// check generated binaries the way the LVGL loader (lv_binfont_loader.c) reads them.
+type BinFont struct {
+	Head          HeadTable
+	Cmap          CmapTable
+	CmapSubTables []CmapSubTableHeader
+	Loca          LocaTable
+	Offsets       []uint32 // glyph offsets relative to the glyf table start, len = Loca.EntryCount.
+	Glyf          GlyfTable
+	Glyphs        []BinGlyph // indexed by glyph ID, glyph 0 is notdef.
+	Kern          *KernTable // nil without kern table.
+	KernPairs     []KernPair // values in FP4 px, scaled back with Head.KerningScale.
+
+	cmapData [][]byte // raw data of each cmap subtable, nil when the subtable has none.
+	cmapEnd  int      // offset of the cmap table end in the binary.
+	locaEnd  int
+	glyfEnd  int
+	end      int // offset of the last table end.
+}
+
+// BinGlyph is a decoded glyph record of the glyf table.
+type BinGlyph struct {
+	AdvanceWidth uint32 // as stored, FP4 when Head.AdvanceWidthFormat is 1.
+	BBoxX        int32
+	BBoxY        int32
+	BBoxWidth    uint32
+	BBoxHeight   uint32
+	Bitmap       []byte // BBoxWidth*BBoxHeight pixels of Head.BitsPerPixel, MSB first.
+}
+
+var errBinTruncated = errors.New("lvgl: truncated binary")
+
+// ParseFS decodes the LVGL binary font `name` of `fsys`, such as an embed.FS, see Parse.
+// Errors include `name`.
+func ParseFS(fsys fs.FS, name string) (*BinFont, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// Parse decodes the LVGL binary font `b`.
+func Parse(b []byte) (*BinFont, error) {
+	f := &BinFont{}
+	r := bytes.NewReader(b)
+
+	// head.
+	if err := binary.Read(r, binary.LittleEndian, &f.Head); err != nil {
+		return nil, fmt.Errorf("lvgl: head: %w", errBinTruncated)
+	}
+	if f.Head.Label != [4]byte{'h', 'e', 'a', 'd'} {
+		return nil, fmt.Errorf("lvgl: head: bad label %q", f.Head.Label[:])
+	}
+	offset := int(f.Head.Size)
+
+	// cmap.
+	cmapStart := offset
+	if err := readTableAt(b, cmapStart, "cmap", &f.Cmap); err != nil {
+		return nil, err
+	}
+	f.cmapEnd = cmapStart + int(f.Cmap.Size)
+	if f.cmapEnd > len(b) {
+		return nil, fmt.Errorf("lvgl: cmap: %w", errBinTruncated)
+	}
+	f.CmapSubTables = make([]CmapSubTableHeader, f.Cmap.Tables)
+	r = bytes.NewReader(b[cmapStart+binary.Size(f.Cmap) : f.cmapEnd])
+	if err := binary.Read(r, binary.LittleEndian, f.CmapSubTables); err != nil {
+		return nil, fmt.Errorf("lvgl: cmap subtables: %w", errBinTruncated)
+	}
+	f.cmapData = make([][]byte, len(f.CmapSubTables))
+	for i, sub := range f.CmapSubTables {
+		size := cmapSubDataSize(sub)
+		if size == 0 {
+			continue
+		}
+		start := cmapStart + int(sub.DataOffset)
+		if sub.DataOffset == 0 || start+size > f.cmapEnd {
+			return nil, fmt.Errorf("lvgl: cmap subtable %d: data outside table", i)
+		}
+		f.cmapData[i] = b[start : start+size]
+	}
+
+	// loca.
+	locaStart := f.cmapEnd
+	if err := readTableAt(b, locaStart, "loca", &f.Loca); err != nil {
+		return nil, err
+	}
+	f.locaEnd = locaStart + int(f.Loca.Size)
+	if f.locaEnd > len(b) {
+		return nil, fmt.Errorf("lvgl: loca: %w", errBinTruncated)
+	}
+	entrySize := 4
+	if f.Head.IndexToLocFormat == 0 {
+		entrySize = 2
+	}
+	entries := b[locaStart+binary.Size(f.Loca) : f.locaEnd]
+	if len(entries) < int(f.Loca.EntryCount)*entrySize {
+		return nil, fmt.Errorf("lvgl: loca: %w", errBinTruncated)
+	}
+	f.Offsets = make([]uint32, f.Loca.EntryCount)
+	for i := range f.Offsets {
+		if entrySize == 2 {
+			f.Offsets[i] = uint32(binary.LittleEndian.Uint16(entries[i*2:]))
+		} else {
+			f.Offsets[i] = binary.LittleEndian.Uint32(entries[i*4:])
+		}
+	}
+
+	// glyf.
+	glyfStart := f.locaEnd
+	if err := readTableAt(b, glyfStart, "glyf", &f.Glyf); err != nil {
+		return nil, err
+	}
+	f.glyfEnd = glyfStart + int(f.Glyf.Size)
+	if f.glyfEnd > len(b) {
+		return nil, fmt.Errorf("lvgl: glyf: %w", errBinTruncated)
+	}
+	glyf := b[glyfStart:f.glyfEnd]
+	f.Glyphs = make([]BinGlyph, len(f.Offsets))
+	for i, start := range f.Offsets {
+		end := uint32(len(glyf))
+		if i+1 < len(f.Offsets) {
+			end = f.Offsets[i+1]
+		}
+		if start < uint32(binary.Size(f.Glyf)) || start > end || end > uint32(len(glyf)) {
+			return nil, fmt.Errorf("lvgl: glyph %d: offsets %d..%d outside glyf", i, start, end)
+		}
+		if i == 0 {
+			// notdef, ignored by the loader.
+			continue
+		}
+		g, err := f.decodeGlyph(glyf[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("lvgl: glyph %d: %w", i, err)
+		}
+		f.Glyphs[i] = g
+	}
+	f.end = f.glyfEnd
+
+	// kern.
+	if f.Head.Tables > 3 {
+		if err := f.parseKern(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// parseKern reads the sorted pairs kern table following glyf.
+func (f *BinFont) parseKern(b []byte) error {
+	f.Kern = new(KernTable)
+	if err := readTableAt(b, f.glyfEnd, "kern", f.Kern); err != nil {
+		return err
+	}
+	f.end = f.glyfEnd + int(f.Kern.Size)
+	if f.end > len(b) {
+		return fmt.Errorf("lvgl: kern: %w", errBinTruncated)
+	}
+	if f.Kern.Format != KernFormatPairs {
+		return fmt.Errorf("lvgl: kern: unsupported format %d", f.Kern.Format)
+	}
+	data := b[f.glyfEnd+binary.Size(f.Kern) : f.end]
+	if len(data) < 4 {
+		return fmt.Errorf("lvgl: kern: %w", errBinTruncated)
+	}
+	n := int(binary.LittleEndian.Uint32(data))
+	idSize := 1
+	if f.Head.GlyphIdFormat == 1 {
+		idSize = 2
+	}
+	if 4+n*(2*idSize+1) > len(data) {
+		return fmt.Errorf("lvgl: kern: %w", errBinTruncated)
+	}
+	f.KernPairs = make([]KernPair, n)
+	values := data[4+n*2*idSize:]
+	for i := range f.KernPairs {
+		p := &f.KernPairs[i]
+		if idSize == 2 {
+			p.Left = binary.LittleEndian.Uint16(data[4+i*4:])
+			p.Right = binary.LittleEndian.Uint16(data[6+i*4:])
+		} else {
+			p.Left, p.Right = uint16(data[4+i*2]), uint16(data[5+i*2])
+		}
+		p.Value = int(int8(values[i])) * int(f.Head.KerningScale) >> 4
+	}
+	return nil
+}
+
+// readTableAt reads the table record header `v` at `offset` in `b` and checks its label.
+func readTableAt(b []byte, offset int, label string, v any) error {
+	if offset < 0 || offset > len(b) {
+		return fmt.Errorf("lvgl: %s: %w", label, errBinTruncated)
+	}
+	if err := binary.Read(bytes.NewReader(b[offset:]), binary.LittleEndian, v); err != nil {
+		return fmt.Errorf("lvgl: %s: %w", label, errBinTruncated)
+	}
+	if offset+8 > len(b) || string(b[offset+4:offset+8]) != label {
+		return fmt.Errorf("lvgl: expected %s table at offset %d", label, offset)
+	}
+	return nil
+}
+
+// cmapSubDataSize returns the size in bytes of the data of cmap subtable `sub` (without padding).
+func cmapSubDataSize(sub CmapSubTableHeader) int {
+	switch sub.FormatType {
+	case CmapFormat0Full:
+		return int(sub.DataEntriesCount)
+	case CmapFormatSparse:
+		return 4 * int(sub.DataEntriesCount)
+	case CmapFormatSparseTiny:
+		return 2 * int(sub.DataEntriesCount)
+	}
+	return 0
+}
+
+// GlyphID resolves `r` through the cmap subtables the way LVGL does.
+func (f *BinFont) GlyphID(r rune) (uint32, bool) {
+	for i, sub := range f.CmapSubTables {
+		if r < rune(sub.RangeStart) || r >= rune(sub.RangeStart)+rune(sub.RangeLength) {
+			continue
+		}
+		rcp := int(r - rune(sub.RangeStart))
+		data := f.cmapData[i]
+		switch sub.FormatType {
+		case CmapFormat0Tiny:
+			return uint32(sub.GlyphIdOffset) + uint32(rcp), true
+		case CmapFormat0Full:
+			if rcp >= len(data) {
+				return 0, false
+			}
+			return uint32(sub.GlyphIdOffset) + uint32(data[rcp]), true
+		case CmapFormatSparse, CmapFormatSparseTiny:
+			n := int(sub.DataEntriesCount)
+			for k := 0; k < n; k++ {
+				if int(binary.LittleEndian.Uint16(data[2*k:])) != rcp {
+					continue
+				}
+				if sub.FormatType == CmapFormatSparseTiny {
+					return uint32(sub.GlyphIdOffset) + uint32(k), true
+				}
+				return uint32(sub.GlyphIdOffset) + uint32(binary.LittleEndian.Uint16(data[2*n+2*k:])), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Runes returns all codepoints mapped by the cmap, in subtable order.
+func (f *BinFont) Runes() []rune {
+	var runes []rune
+	for i, sub := range f.CmapSubTables {
+		start := rune(sub.RangeStart)
+		switch sub.FormatType {
+		case CmapFormat0Tiny, CmapFormat0Full:
+			for k := range rune(sub.RangeLength) {
+				runes = append(runes, start+k)
+			}
+		case CmapFormatSparse, CmapFormatSparseTiny:
+			for k := range int(sub.DataEntriesCount) {
+				runes = append(runes, start+rune(binary.LittleEndian.Uint16(f.cmapData[i][2*k:])))
+			}
+		}
+	}
+	return runes
+}
+
+// Glyph returns the decoded glyph for `r`.
+func (f *BinFont) Glyph(r rune) (*BinGlyph, bool) {
+	id, ok := f.GlyphID(r)
+	if !ok || id == 0 || int(id) >= len(f.Glyphs) {
+		return nil, false
+	}
+	return &f.Glyphs[id], true
+}
+
+// decodeGlyph decodes one bit-packed glyph record.
+func (f *BinFont) decodeGlyph(data []byte) (BinGlyph, error) {
+	h := f.Head
+	br := &bitReader{data: data}
+	var g BinGlyph
+	if h.AdvanceWidthBits == 0 {
+		g.AdvanceWidth = uint32(h.DefAdvanceWidth)
+	} else {
+		g.AdvanceWidth = br.read(int(h.AdvanceWidthBits))
+	}
+	g.BBoxX = br.readSigned(int(h.XyBits))
+	g.BBoxY = br.readSigned(int(h.XyBits))
+	g.BBoxWidth = br.read(int(h.WhBits))
+	g.BBoxHeight = br.read(int(h.WhBits))
+	if br.overrun {
+		return g, errors.New("header exceeds record")
+	}
+	bits := int(g.BBoxWidth) * int(g.BBoxHeight) * int(h.BitsPerPixel)
+	if br.pos+bits > len(data)*8 {
+		return g, fmt.Errorf("bitmap needs %d bits, record has %d", bits, len(data)*8-br.pos)
+	}
+	g.Bitmap = make([]byte, (bits+7)/8)
+	for i := range g.Bitmap {
+		n := min(8, bits-i*8)
+		g.Bitmap[i] = byte(br.read(n) << (8 - n))
+	}
+	return g, nil
+}
+
+// bitReader reads MSB first bit fields.
+type bitReader struct {
+	data    []byte
+	pos     int
+	overrun bool
+}
+
+func (br *bitReader) read(n int) uint32 {
+	var v uint32
+	for range n {
+		if br.pos >= len(br.data)*8 {
+			br.overrun = true
+			return 0
+		}
+		bit := br.data[br.pos/8] >> (7 - br.pos%8) & 1
+		v = v<<1 | uint32(bit)
+		br.pos++
+	}
+	return v
+}
+
+func (br *bitReader) readSigned(n int) int32 {
+	v := br.read(n)
+	if n > 0 && v&(1<<(n-1)) != 0 {
+		return int32(int64(v) - int64(1)<<n)
+	}
+	return int32(v)
+}
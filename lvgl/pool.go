@@ -0,0 +1,49 @@
+package lvgl
+
+import (
+	"image"
+	"sync"
+
+	"golang.org/x/image/vector"
+)
+
+// Rasterizers and glyph coverage images are pooled: both are needed once per glyph and
+// sized by the glyph bbox, so reusing their buffers avoids most per glyph allocations.
+// sfnt.Buffer is not pooled, each conversion goroutine owns one.
+var (
+	rasterizerPool = sync.Pool{New: func() any { return vector.NewRasterizer(0, 0) }}
+	alphaPool      = sync.Pool{New: func() any { return new(image.Alpha) }}
+)
+
+// getRasterizer returns a pooled rasterizer reset to `w`x`h`.
+func getRasterizer(w, h int) *vector.Rasterizer {
+	z := rasterizerPool.Get().(*vector.Rasterizer)
+	z.Reset(w, h)
+	return z
+}
+
+func putRasterizer(z *vector.Rasterizer) {
+	rasterizerPool.Put(z)
+}
+
+// getAlpha returns a cleared `w`x`h` image from the pool.
+func getAlpha(w, h int) *image.Alpha {
+	a := alphaPool.Get().(*image.Alpha)
+	n := w * h
+	if cap(a.Pix) < n {
+		a.Pix = make([]uint8, n)
+	} else {
+		a.Pix = a.Pix[:n]
+		clear(a.Pix)
+	}
+	a.Stride = w
+	a.Rect = image.Rect(0, 0, w, h)
+	return a
+}
+
+// putAlpha returns `a` to the pool, it must not be used afterwards.
+func putAlpha(a *image.Alpha) {
+	if a != nil {
+		alphaPool.Put(a)
+	}
+}
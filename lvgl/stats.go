@@ -0,0 +1,108 @@
+package lvgl
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// statsLargestGlyphs is the number of glyphs listed in Stats.Largest.
+const statsLargestGlyphs = 10
+
+// Stats describes where the bytes of a generated binary font went.
+type Stats struct {
+	Size   uint16 // font size in px
+	Glyphs int    // number of glyphs, notdef excluded
+
+	// Table sizes in bytes, record headers included. They add up to TotalBytes.
+	HeadBytes  int
+	CmapBytes  int
+	LocaBytes  int
+	GlyfBytes  int
+	KernBytes  int
+	TotalBytes int
+
+	AvgGlyphBytes float64 // average glyf record size
+	MaxGlyphBytes int
+
+	Largest []GlyphStats // the largest glyf records, largest first
+	Dropped []rune       // runes left to notdef because no usable glyph exists
+	Clipped []rune       // runes whose bitmap was cropped to Options.MaxGlyphSize
+}
+
+// GlyphStats is the size of one glyf record.
+type GlyphStats struct {
+	Size  uint16 // font size in px
+	Rune  rune
+	Bytes int
+}
+
+// TotalStats sums the Stats of several sizes, e.g. those of NewFontsWithStats, into the Stats
+// of all of them: the glyph and byte counts add up, MaxGlyphBytes and Largest are those over
+// all sizes, and Dropped and Clipped hold each rune once. Size is 0.
+func TotalStats(stats map[uint16]*Stats) *Stats {
+	t := &Stats{}
+	var largest []GlyphStats
+	recordBytes := 0.0
+	for _, size := range slices.Sorted(maps.Keys(stats)) {
+		s := stats[size]
+		t.Glyphs += s.Glyphs
+		t.HeadBytes += s.HeadBytes
+		t.CmapBytes += s.CmapBytes
+		t.LocaBytes += s.LocaBytes
+		t.GlyfBytes += s.GlyfBytes
+		t.KernBytes += s.KernBytes
+		t.TotalBytes += s.TotalBytes
+		t.MaxGlyphBytes = max(t.MaxGlyphBytes, s.MaxGlyphBytes)
+		recordBytes += s.AvgGlyphBytes * float64(s.Glyphs)
+		largest = append(largest, s.Largest...)
+		t.Dropped = append(t.Dropped, s.Dropped...)
+		t.Clipped = append(t.Clipped, s.Clipped...)
+	}
+	if t.Glyphs > 0 {
+		t.AvgGlyphBytes = recordBytes / float64(t.Glyphs)
+	}
+	slices.SortStableFunc(largest, func(a, b GlyphStats) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+	t.Largest = largest[:min(statsLargestGlyphs, len(largest))]
+	t.Dropped = slices.Compact(slices.Sorted(slices.Values(t.Dropped)))
+	t.Clipped = slices.Compact(slices.Sorted(slices.Values(t.Clipped)))
+	return t
+}
+
+// newStats collects the statistics of a font while it is encoded. runes[i] maps to
+// glyphIDs[i] and glyphs[id-1] is the record of glyph ID id.
+func newStats(f *Font, runes []rune, glyphIDs []uint16, glyphs [][]byte) *Stats {
+	s := &Stats{
+		Size:      f.HeadTable.FontSize,
+		Glyphs:    len(glyphs),
+		HeadBytes: int(f.HeadTable.Size),
+		CmapBytes: int(f.CmapTable.Size),
+		LocaBytes: int(f.LocaTable.Size),
+		GlyfBytes: int(f.GlyfTable.Size),
+	}
+	if f.KernTable != nil {
+		s.KernBytes = int(f.KernTable.Size)
+	}
+	s.TotalBytes = s.HeadBytes + s.CmapBytes + s.LocaBytes + s.GlyfBytes + s.KernBytes
+	glyphRunes := make([]rune, len(glyphs))
+	for i := len(runes) - 1; i >= 0; i-- {
+		glyphRunes[glyphIDs[i]-1] = runes[i]
+	}
+	sizes := make([]GlyphStats, len(glyphs))
+	total := 0
+	for i, g := range glyphs {
+		sizes[i] = GlyphStats{Size: s.Size, Rune: glyphRunes[i], Bytes: len(g)}
+		total += len(g)
+		s.MaxGlyphBytes = max(s.MaxGlyphBytes, len(g))
+	}
+	if len(glyphs) > 0 {
+		s.AvgGlyphBytes = float64(total) / float64(len(glyphs))
+	}
+	slices.SortStableFunc(sizes, func(a, b GlyphStats) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+	s.Largest = sizes[:min(statsLargestGlyphs, len(sizes))]
+	return s
+}
@@ -0,0 +1,74 @@
+package lvgl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewFontsWithStats(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := append(runeRange(0x20, 0x7E), 0xA9, 0xE9, 0x2265)
+	bins, stats, err := NewFontsWithStats(pf, []uint16{16, 32}, runes, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for size, s := range stats {
+		if s.Size != size || s.Glyphs != len(runes) {
+			t.Fatalf("size %d: stats for size %d with %d glyphs", size, s.Size, s.Glyphs)
+		}
+		if s.HeadBytes+s.CmapBytes+s.LocaBytes+s.GlyfBytes+s.KernBytes != len(bins[size]) || s.TotalBytes != len(bins[size]) {
+			t.Fatalf("size %d: tables add up to %d (total %d), output is %d bytes", size,
+				s.HeadBytes+s.CmapBytes+s.LocaBytes+s.GlyfBytes+s.KernBytes, s.TotalBytes, len(bins[size]))
+		}
+		if len(s.Largest) != statsLargestGlyphs || s.Largest[0].Bytes != s.MaxGlyphBytes {
+			t.Fatalf("size %d: largest = %+v, max %d", size, s.Largest, s.MaxGlyphBytes)
+		}
+		for i := 1; i < len(s.Largest); i++ {
+			if s.Largest[i].Bytes > s.Largest[i-1].Bytes {
+				t.Fatalf("size %d: largest glyphs not sorted: %+v", size, s.Largest)
+			}
+		}
+		if s.AvgGlyphBytes <= 0 || s.AvgGlyphBytes > float64(s.MaxGlyphBytes) {
+			t.Fatalf("size %d: average glyph size %f", size, s.AvgGlyphBytes)
+		}
+		t.Logf("size %d: %+v", size, *s)
+	}
+	if stats[32].GlyfBytes <= stats[16].GlyfBytes {
+		t.Fatalf("32 px glyf (%d bytes) not larger than 16 px (%d bytes)", stats[32].GlyfBytes, stats[16].GlyfBytes)
+	}
+}
+
+func TestTotalStats(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := []rune("aj")
+	bins, stats, err := NewFontsWithStats(pf, []uint16{16, 320}, runes, Options{MaxGlyphSize: 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := TotalStats(stats)
+	if total.Size != 0 || total.Glyphs != stats[16].Glyphs+stats[320].Glyphs {
+		t.Fatalf("size %d, %d glyphs", total.Size, total.Glyphs)
+	}
+	if total.TotalBytes != len(bins[16])+len(bins[320]) ||
+		total.HeadBytes+total.CmapBytes+total.LocaBytes+total.GlyfBytes+total.KernBytes != total.TotalBytes {
+		t.Fatalf("tables add up to %d (total %d), output is %d bytes",
+			total.HeadBytes+total.CmapBytes+total.LocaBytes+total.GlyfBytes+total.KernBytes, total.TotalBytes, len(bins[16])+len(bins[320]))
+	}
+	if total.MaxGlyphBytes != stats[320].MaxGlyphBytes || total.Largest[0] != stats[320].Largest[0] || total.Largest[0].Size != 320 {
+		t.Fatalf("max %d, largest %+v", total.MaxGlyphBytes, total.Largest)
+	}
+	if len(total.Largest) != stats[16].Glyphs+stats[320].Glyphs {
+		t.Fatalf("largest %+v", total.Largest)
+	}
+	records := stats[16].AvgGlyphBytes*float64(stats[16].Glyphs) + stats[320].AvgGlyphBytes*float64(stats[320].Glyphs)
+	if want := records / float64(total.Glyphs); math.Abs(total.AvgGlyphBytes-want) > 1e-9 {
+		t.Fatalf("average glyph size %f, want %f", total.AvgGlyphBytes, want)
+	}
+	if len(total.Dropped) != 0 || string(total.Clipped) != "j" {
+		t.Fatalf("dropped %q, clipped %q", total.Dropped, total.Clipped)
+	}
+
+	if got := TotalStats(nil); got.Glyphs != 0 || got.TotalBytes != 0 || got.AvgGlyphBytes != 0 || len(got.Largest) != 0 {
+		t.Fatalf("no stats: %+v", *got)
+	}
+}
@@ -0,0 +1,161 @@
+package lvgl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ValidationError lists the inconsistencies Validate found in a binary font.
+type ValidationError struct {
+	Findings []string
+}
+
+func (e *ValidationError) Error() string {
+	return "lvgl: invalid binary font: " + strings.Join(e.Findings, "; ")
+}
+
+// Validate checks that the binary font `b` is internally consistent the way the LVGL loader
+// relies on: table sizes match the bytes, cmap data stays inside the cmap table, loca
+// offsets are monotonic within glyf, every mapped codepoint resolves to a decodable glyph
+// and the head bit lengths and loca format fit the glyph records. It returns a *ValidationError listing all findings.
+func Validate(b []byte) error {
+	f, err := Parse(b)
+	if err != nil {
+		return &ValidationError{Findings: []string{err.Error()}}
+	}
+	var findings []string
+	addf := func(format string, args ...any) {
+		findings = append(findings, fmt.Sprintf(format, args...))
+	}
+
+	// head.
+	h := f.Head
+	if int(h.Size) < binary.Size(h) {
+		addf("head size %d smaller than the header (%d)", h.Size, binary.Size(h))
+	}
+	switch h.BitsPerPixel {
+	case 1, 2, 3, 4, 8:
+	default:
+		addf("head bits per pixel %d unsupported", h.BitsPerPixel)
+	}
+	if h.AdvanceWidthFormat > 1 || h.IndexToLocFormat > 1 {
+		addf("head advance width format %d, loca format %d", h.AdvanceWidthFormat, h.IndexToLocFormat)
+	}
+	if h.XyBits > 32 || h.WhBits > 32 || h.AdvanceWidthBits > 32 {
+		addf("head bit lengths xy %d, wh %d, advance %d exceed 32", h.XyBits, h.WhBits, h.AdvanceWidthBits)
+	}
+	if h.WhBits == 0 && len(f.Glyphs) > 1 {
+		addf("head wh bits 0 cannot store glyph sizes")
+	}
+	if f.end != len(b) {
+		addf("tables end at %d, binary is %d bytes", f.end, len(b))
+	}
+	if n := countTables(b); n != int(h.Tables) {
+		addf("head announces %d tables, binary has %d", h.Tables, n)
+	}
+
+	starts := map[string]int{"cmap": int(h.Size), "loca": f.cmapEnd, "glyf": f.locaEnd}
+	if f.Kern != nil {
+		starts["kern"] = f.glyfEnd
+	}
+	for _, name := range []string{"cmap", "loca", "glyf", "kern"} {
+		if start, ok := starts[name]; ok && start%4 != 0 {
+			addf("%s table starts at %d, not aligned to 4", name, start)
+		}
+	}
+
+	// cmap.
+	headersEnd := binary.Size(f.Cmap) + len(f.CmapSubTables)*binary.Size(CmapSubTableHeader{})
+	if int(f.Cmap.Size) < headersEnd {
+		addf("cmap size %d smaller than its %d subtable headers", f.Cmap.Size, len(f.CmapSubTables))
+	}
+	for i, sub := range f.CmapSubTables {
+		if sub.FormatType > CmapFormatSparseTiny {
+			addf("cmap subtable %d: unknown format %d", i, sub.FormatType)
+			continue
+		}
+		if sub.DataOffset != 0 && int(sub.DataOffset) < headersEnd {
+			addf("cmap subtable %d: data offset %d overlaps the headers", i, sub.DataOffset)
+		}
+		if sub.FormatType == CmapFormat0Full && sub.DataEntriesCount != sub.RangeLength {
+			addf("cmap subtable %d: %d entries for range length %d", i, sub.DataEntriesCount, sub.RangeLength)
+		}
+		if i > 0 {
+			prev := f.CmapSubTables[i-1]
+			if sub.RangeStart < prev.RangeStart+uint32(prev.RangeLength) {
+				addf("cmap subtable %d: range %#x overlaps the previous subtable", i, sub.RangeStart)
+			}
+		}
+	}
+
+	// loca.
+	entrySize := 4
+	if h.IndexToLocFormat == 0 {
+		entrySize = 2
+	}
+	// The entries of the declared format, with or without the end offset Font.encode adds,
+	// padded to 4 bytes.
+	locaHeader := binary.Size(f.Loca)
+	if size := int(f.Loca.Size); size != align4(locaHeader+int(f.Loca.EntryCount)*entrySize) &&
+		size != align4(locaHeader+int(f.Loca.EntryCount+1)*entrySize) {
+		addf("loca size %d does not match %d entries of %d bytes", f.Loca.Size, f.Loca.EntryCount, entrySize)
+	}
+	if entrySize == 2 && f.Glyf.Size > math.MaxUint16 {
+		addf("glyf size %d exceeds the 16 bit loca offsets", f.Glyf.Size)
+	}
+	for i := 1; i < len(f.Offsets); i++ {
+		if f.Offsets[i] < f.Offsets[i-1] {
+			addf("loca offset %d (%d) before offset %d (%d)", i, f.Offsets[i], i-1, f.Offsets[i-1])
+		}
+	}
+
+	// glyph records: each is as long as the head bit widths and its bbox make it, the last
+	// one up to the glyf padding. A record of another length was written with other widths.
+	for i := 1; i < len(f.Glyphs); i++ {
+		g := f.Glyphs[i]
+		bits := int(h.AdvanceWidthBits) + 2*int(h.XyBits) + 2*int(h.WhBits) +
+			int(g.BBoxWidth)*int(g.BBoxHeight)*int(h.BitsPerPixel)
+		end := int(f.Glyf.Size)
+		if i+1 < len(f.Offsets) {
+			end = int(f.Offsets[i+1])
+		}
+		if n, want := end-int(f.Offsets[i]), (bits+7)/8; n != want && (i+1 < len(f.Offsets) || align4(int(f.Offsets[i])+want) != end) {
+			addf("glyph %d: record of %d bytes, head bit widths give %d", i, n, want)
+			break
+		}
+	}
+
+	// glyphs reached through the cmap.
+	bad := 0
+	for _, r := range f.Runes() {
+		id, _ := f.GlyphID(r)
+		if id == 0 || int(id) >= len(f.Glyphs) {
+			if bad == 0 {
+				addf("rune %U maps to glyph %d, loca has %d entries", r, id, f.Loca.EntryCount)
+			}
+			bad++
+		}
+	}
+	if bad > 1 {
+		addf("%d more runes map to missing glyphs", bad-1)
+	}
+
+	// kern.
+	for i, p := range f.KernPairs {
+		if p.Left == 0 || p.Right == 0 || int(p.Left) >= len(f.Glyphs) || int(p.Right) >= len(f.Glyphs) {
+			addf("kern pair %d: glyphs %d, %d out of range", i, p.Left, p.Right)
+			break
+		}
+		if i > 0 && (p.Left < f.KernPairs[i-1].Left || p.Left == f.KernPairs[i-1].Left && p.Right <= f.KernPairs[i-1].Right) {
+			addf("kern pair %d not sorted", i)
+			break
+		}
+	}
+
+	if len(findings) > 0 {
+		return &ValidationError{Findings: findings}
+	}
+	return nil
+}
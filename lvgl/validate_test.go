@@ -0,0 +1,131 @@
+package lvgl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mustValidate fails the test when `b` does not pass Validate.
+func mustValidate(t *testing.T, b []byte) {
+	t.Helper()
+	if err := Validate(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	pf := loadGoRegular(t)
+	runes := append(runeRange(0x20, 0x7E), 0xA9, 0xE9, 0x2265)
+	bin, err := NewFont(pf, 16, runes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+	bf, err := Parse(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bf.Runes(); len(got) != len(runes) {
+		t.Fatalf("cmap maps %d runes, want %d", len(got), len(runes))
+	}
+
+	headSize := int(bf.Head.Size)
+	firstSub := headSize + binary.Size(bf.Cmap)
+	locaEntries := int(bf.cmapEnd) + binary.Size(bf.Loca)
+	tests := []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{"truncated", func(b []byte) []byte { return b[:len(b)-3] }},
+		{"trailing bytes", func(b []byte) []byte { return append(b, 0, 0, 0, 0) }},
+		{"cmap glyph out of range", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[firstSub+10:], 5000) // GlyphIdOffset
+			return b
+		}},
+		{"cmap data outside table", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[firstSub+16:], 0xFFFF) // second subtable DataOffset
+			return b
+		}},
+		{"loca not monotonic", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[locaEntries+8:], binary.LittleEndian.Uint32(b[locaEntries+12:])+1)
+			return b
+		}},
+		{"bad bpp", func(b []byte) []byte {
+			b[37] = 5 // BitsPerPixel
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.corrupt(append([]byte(nil), bin...)))
+			var verr *ValidationError
+			if !errors.As(err, &verr) || len(verr.Findings) == 0 {
+				t.Fatalf("Validate = %v, want findings", err)
+			}
+			t.Log(err)
+		})
+	}
+}
+
+func TestValidate_GlyphRecords(t *testing.T) {
+	h := NewHeadTable(loadGoRegular(t), 16)
+	d := &GlyfData{
+		GlyfDataInfo: GlyfDataInfo{AdvanceWidth: 160, BBoxX: 1, BBoxWidth: 2, BBoxHeight: 2},
+		Bitmap:       bytes.NewBuffer([]byte{0x12, 0x34}),
+	}
+	rec, err := d.Encode(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &Font{HeadTable: h}
+	bin, _, err := f.encode([]rune("ab"), []uint16{1, 2}, [][]byte{rec, rec}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustValidate(t, bin)
+
+	// A record longer than the head bit widths make it.
+	bin, _, err = f.encode([]rune("ab"), []uint16{1, 2}, [][]byte{append(rec, 0), rec}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("glyph 1: record of %d bytes, head bit widths give %d", len(rec)+1, len(rec))
+	if err := Validate(bin); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("Validate = %v, want %q", err, want)
+	}
+}
+
+func TestWriteHead_Alignment(t *testing.T) {
+	// A head that grows by a field must still end on a 4 byte boundary.
+	type futureHead struct {
+		HeadTable
+		Extra uint16
+	}
+	for _, head := range []any{&HeadTable{}, &futureHead{}} {
+		buf := &bytes.Buffer{}
+		buf.WriteString("x") // padding is relative to the head start
+		n := writeHead(buf, head)
+		if want := align4(binary.Size(head)); n != want || n < binary.Size(head) {
+			t.Fatalf("%T: wrote %d bytes, want %d", head, n, want)
+		}
+	}
+
+	pf := loadGoRegular(t)
+	for _, size := range []uint16{11, 16, 23} {
+		bin, err := NewFont(pf, size, []rune("Hello, 世界 ≥"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustValidate(t, bin)
+		bf, _ := Parse(bin)
+		for name, start := range map[string]int{"cmap": int(bf.Head.Size), "loca": bf.cmapEnd, "glyf": bf.locaEnd, "end": bf.glyfEnd} {
+			if start%4 != 0 {
+				t.Fatalf("size %d: %s at %d not aligned", size, name, start)
+			}
+		}
+	}
+}
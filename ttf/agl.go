@@ -0,0 +1,312 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GlyphNameToRunes returns the runes glyph `name` stands for following the Adobe Glyph List
+// specification: a suffix after the first period is dropped ("a.sc" is "a"), ligature
+// components are joined by underscores ("f_f_i"), and each component is an AGL name
+// ("afii10017"), a "uniXXXX" name of one or more BMP code points ("uni4E2D", "uni00660066")
+// or a "uXXXX" to "uXXXXXX" name ("u1F600"). Components that are none of these map to
+// nothing, so it returns false only if no component is known, e.g. for "g1234".
+// https://github.com/adobe-type-tools/agl-specification
+func GlyphNameToRunes(name GlyphName) ([]rune, bool) {
+	s, _, _ := strings.Cut(string(name), ".")
+	var runes []rune
+	for _, comp := range strings.Split(s, "_") {
+		runes = append(runes, aglComponentRunes(comp)...)
+	}
+	return runes, len(runes) > 0
+}
+
+// aglComponentRunes returns the runes of ligature component `comp`, nil if it is not known.
+func aglComponentRunes(comp string) []rune {
+	if r, ok := aglNames[GlyphName(comp)]; ok {
+		return []rune{r}
+	}
+	if hex, ok := strings.CutPrefix(comp, "uni"); ok && hex != "" && len(hex)%4 == 0 {
+		runes := make([]rune, 0, len(hex)/4)
+		for i := 0; i < len(hex); i += 4 {
+			r, ok := parseAGLHex(hex[i : i+4])
+			if !ok || r > 0xFFFF {
+				return nil
+			}
+			runes = append(runes, r)
+		}
+		return runes
+	}
+	if hex, ok := strings.CutPrefix(comp, "u"); ok && len(hex) >= 4 && len(hex) <= 6 {
+		if r, ok := parseAGLHex(hex); ok {
+			return []rune{r}
+		}
+	}
+	return nil
+}
+
+// parseAGLHex parses the upper case hexadecimal code point `hex` of a "uni" or "u" glyph name.
+// Surrogates and values past U+10FFFF are rejected.
+func parseAGLHex(hex string) (rune, bool) {
+	if strings.ContainsFunc(hex, func(c rune) bool { return 'a' <= c && c <= 'f' }) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || v > 0x10FFFF || (v >= 0xD800 && v <= 0xDFFF) {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// RuneToGlyphName returns the glyph name for `r` to use in a post table: its Adobe Glyph List
+// name if there is one, e.g. "Aacute", otherwise "uniXXXX" for the BMP and "uXXXXX" above it.
+func RuneToGlyphName(r rune) GlyphName {
+	if name, ok := aglRuneNames()[r]; ok {
+		return name
+	}
+	if r <= 0xFFFF {
+		return GlyphName(fmt.Sprintf("uni%04X", r))
+	}
+	return GlyphName(fmt.Sprintf("u%X", r))
+}
+
+// SynthesizeCmapFromPost returns a rune to glyph map built from the post table glyph names, for
+// fonts without a usable cmap. Names standing for several runes (ligatures) are skipped, and
+// the lowest glyph wins if several are named after the same rune. It returns nil if the post
+// table has no glyph names.
+func (f *Font) SynthesizeCmapFromPost() map[rune]GlyphIndex {
+	return f.postCmap(nil)
+}
+
+// postCmap returns the rune to glyph map of the post table glyph names as described for
+// SynthesizeCmapFromPost, calling `conflict` (if not nil) for each glyph named after a rune
+// already mapped to `kept`. Variants with a suffix like "zero.slash" are expected to share the
+// rune of their base glyph and are not reported.
+func (f *font) postCmap(conflict func(r rune, kept, dropped GlyphIndex)) map[rune]GlyphIndex {
+	if f.post == nil || len(f.post.glyphNames) == 0 {
+		return nil
+	}
+	cmap := make(map[rune]GlyphIndex)
+	for gid, name := range f.post.glyphNames {
+		if gid == 0 {
+			continue // .notdef
+		}
+		runes, ok := GlyphNameToRunes(name)
+		if !ok || len(runes) != 1 {
+			continue
+		}
+		kept, dup := cmap[runes[0]]
+		if !dup {
+			cmap[runes[0]] = GlyphIndex(gid)
+		} else if conflict != nil && !strings.Contains(string(name), ".") {
+			conflict(runes[0], kept, GlyphIndex(gid))
+		}
+	}
+	return cmap
+}
+
+// aglLegacyNames are AGL names superseded by another name for the same rune, e.g. "Gcedilla"
+// by "Gcommaaccent". They are understood but not written.
+var aglLegacyNames = map[GlyphName]bool{
+	"Edot": true, "edot": true, "Idot": true,
+	"Gcedilla": true, "gcedilla": true, "Kcedilla": true, "kcedilla": true,
+	"Lcedilla": true, "lcedilla": true, "Ncedilla": true, "ncedilla": true,
+	"Rcedilla": true, "rcedilla": true,
+}
+
+// aglRuneNames maps runes to their preferred AGL name.
+var aglRuneNames = sync.OnceValue(func() map[rune]GlyphName {
+	m := make(map[rune]GlyphName, len(aglNames))
+	for name, r := range aglNames {
+		if !aglLegacyNames[name] {
+			m[r] = name
+		}
+	}
+	return m
+})
+
+// aglNames maps the glyph names of the Adobe Glyph List For New Fonts (AGLFN), together with
+// the legacy "afii" and Latin names still common in older fonts, to their runes.
+var aglNames = map[GlyphName]rune{
+	"space": 0x0020, "exclam": 0x0021, "quotedbl": 0x0022, "numbersign": 0x0023,
+	"dollar": 0x0024, "percent": 0x0025, "ampersand": 0x0026, "quotesingle": 0x0027,
+	"parenleft": 0x0028, "parenright": 0x0029, "asterisk": 0x002A, "plus": 0x002B,
+	"comma": 0x002C, "hyphen": 0x002D, "period": 0x002E, "slash": 0x002F, "zero": 0x0030,
+	"one": 0x0031, "two": 0x0032, "three": 0x0033, "four": 0x0034, "five": 0x0035,
+	"six": 0x0036, "seven": 0x0037, "eight": 0x0038, "nine": 0x0039, "colon": 0x003A,
+	"semicolon": 0x003B, "less": 0x003C, "equal": 0x003D, "greater": 0x003E, "question": 0x003F,
+	"at": 0x0040, "A": 0x0041, "B": 0x0042, "C": 0x0043, "D": 0x0044, "E": 0x0045, "F": 0x0046,
+	"G": 0x0047, "H": 0x0048, "I": 0x0049, "J": 0x004A, "K": 0x004B, "L": 0x004C, "M": 0x004D,
+	"N": 0x004E, "O": 0x004F, "P": 0x0050, "Q": 0x0051, "R": 0x0052, "S": 0x0053, "T": 0x0054,
+	"U": 0x0055, "V": 0x0056, "W": 0x0057, "X": 0x0058, "Y": 0x0059, "Z": 0x005A,
+	"bracketleft": 0x005B, "backslash": 0x005C, "bracketright": 0x005D, "asciicircum": 0x005E,
+	"underscore": 0x005F, "grave": 0x0060, "a": 0x0061, "b": 0x0062, "c": 0x0063, "d": 0x0064,
+	"e": 0x0065, "f": 0x0066, "g": 0x0067, "h": 0x0068, "i": 0x0069, "j": 0x006A, "k": 0x006B,
+	"l": 0x006C, "m": 0x006D, "n": 0x006E, "o": 0x006F, "p": 0x0070, "q": 0x0071, "r": 0x0072,
+	"s": 0x0073, "t": 0x0074, "u": 0x0075, "v": 0x0076, "w": 0x0077, "x": 0x0078, "y": 0x0079,
+	"z": 0x007A, "braceleft": 0x007B, "bar": 0x007C, "braceright": 0x007D, "asciitilde": 0x007E,
+	"exclamdown": 0x00A1, "cent": 0x00A2, "sterling": 0x00A3, "currency": 0x00A4, "yen": 0x00A5,
+	"brokenbar": 0x00A6, "section": 0x00A7, "dieresis": 0x00A8, "copyright": 0x00A9,
+	"ordfeminine": 0x00AA, "guillemotleft": 0x00AB, "logicalnot": 0x00AC, "registered": 0x00AE,
+	"macron": 0x00AF, "degree": 0x00B0, "plusminus": 0x00B1, "twosuperior": 0x00B2,
+	"threesuperior": 0x00B3, "acute": 0x00B4, "mu": 0x00B5, "paragraph": 0x00B6,
+	"periodcentered": 0x00B7, "cedilla": 0x00B8, "onesuperior": 0x00B9, "ordmasculine": 0x00BA,
+	"guillemotright": 0x00BB, "onequarter": 0x00BC, "onehalf": 0x00BD, "threequarters": 0x00BE,
+	"questiondown": 0x00BF, "Agrave": 0x00C0, "Aacute": 0x00C1, "Acircumflex": 0x00C2,
+	"Atilde": 0x00C3, "Adieresis": 0x00C4, "Aring": 0x00C5, "AE": 0x00C6, "Ccedilla": 0x00C7,
+	"Egrave": 0x00C8, "Eacute": 0x00C9, "Ecircumflex": 0x00CA, "Edieresis": 0x00CB,
+	"Igrave": 0x00CC, "Iacute": 0x00CD, "Icircumflex": 0x00CE, "Idieresis": 0x00CF,
+	"Eth": 0x00D0, "Ntilde": 0x00D1, "Ograve": 0x00D2, "Oacute": 0x00D3, "Ocircumflex": 0x00D4,
+	"Otilde": 0x00D5, "Odieresis": 0x00D6, "multiply": 0x00D7, "Oslash": 0x00D8,
+	"Ugrave": 0x00D9, "Uacute": 0x00DA, "Ucircumflex": 0x00DB, "Udieresis": 0x00DC,
+	"Yacute": 0x00DD, "Thorn": 0x00DE, "germandbls": 0x00DF, "agrave": 0x00E0, "aacute": 0x00E1,
+	"acircumflex": 0x00E2, "atilde": 0x00E3, "adieresis": 0x00E4, "aring": 0x00E5, "ae": 0x00E6,
+	"ccedilla": 0x00E7, "egrave": 0x00E8, "eacute": 0x00E9, "ecircumflex": 0x00EA,
+	"edieresis": 0x00EB, "igrave": 0x00EC, "iacute": 0x00ED, "icircumflex": 0x00EE,
+	"idieresis": 0x00EF, "eth": 0x00F0, "ntilde": 0x00F1, "ograve": 0x00F2, "oacute": 0x00F3,
+	"ocircumflex": 0x00F4, "otilde": 0x00F5, "odieresis": 0x00F6, "divide": 0x00F7,
+	"oslash": 0x00F8, "ugrave": 0x00F9, "uacute": 0x00FA, "ucircumflex": 0x00FB,
+	"udieresis": 0x00FC, "yacute": 0x00FD, "thorn": 0x00FE, "ydieresis": 0x00FF,
+	"Amacron": 0x0100, "amacron": 0x0101, "Abreve": 0x0102, "abreve": 0x0103, "Aogonek": 0x0104,
+	"aogonek": 0x0105, "Cacute": 0x0106, "cacute": 0x0107, "Ccircumflex": 0x0108,
+	"ccircumflex": 0x0109, "Cdotaccent": 0x010A, "cdotaccent": 0x010B, "Ccaron": 0x010C,
+	"ccaron": 0x010D, "Dcaron": 0x010E, "dcaron": 0x010F, "Dcroat": 0x0110, "dcroat": 0x0111,
+	"Emacron": 0x0112, "emacron": 0x0113, "Ebreve": 0x0114, "ebreve": 0x0115, "Edot": 0x0116,
+	"Edotaccent": 0x0116, "edot": 0x0117, "edotaccent": 0x0117, "Eogonek": 0x0118,
+	"eogonek": 0x0119, "Ecaron": 0x011A, "ecaron": 0x011B, "Gcircumflex": 0x011C,
+	"gcircumflex": 0x011D, "Gbreve": 0x011E, "gbreve": 0x011F, "Gdotaccent": 0x0120,
+	"gdotaccent": 0x0121, "Gcedilla": 0x0122, "Gcommaaccent": 0x0122, "gcedilla": 0x0123,
+	"gcommaaccent": 0x0123, "Hcircumflex": 0x0124, "hcircumflex": 0x0125, "Hbar": 0x0126,
+	"hbar": 0x0127, "Itilde": 0x0128, "itilde": 0x0129, "Imacron": 0x012A, "imacron": 0x012B,
+	"Ibreve": 0x012C, "ibreve": 0x012D, "Iogonek": 0x012E, "iogonek": 0x012F, "Idot": 0x0130,
+	"Idotaccent": 0x0130, "dotlessi": 0x0131, "IJ": 0x0132, "ij": 0x0133, "Jcircumflex": 0x0134,
+	"jcircumflex": 0x0135, "Kcedilla": 0x0136, "Kcommaaccent": 0x0136, "kcedilla": 0x0137,
+	"kcommaaccent": 0x0137, "kgreenlandic": 0x0138, "Lacute": 0x0139, "lacute": 0x013A,
+	"Lcedilla": 0x013B, "Lcommaaccent": 0x013B, "lcedilla": 0x013C, "lcommaaccent": 0x013C,
+	"Lcaron": 0x013D, "lcaron": 0x013E, "Ldot": 0x013F, "ldot": 0x0140, "Lslash": 0x0141,
+	"lslash": 0x0142, "Nacute": 0x0143, "nacute": 0x0144, "Ncedilla": 0x0145,
+	"Ncommaaccent": 0x0145, "ncedilla": 0x0146, "ncommaaccent": 0x0146, "Ncaron": 0x0147,
+	"ncaron": 0x0148, "napostrophe": 0x0149, "Eng": 0x014A, "eng": 0x014B, "Omacron": 0x014C,
+	"omacron": 0x014D, "Obreve": 0x014E, "obreve": 0x014F, "Ohungarumlaut": 0x0150,
+	"ohungarumlaut": 0x0151, "OE": 0x0152, "oe": 0x0153, "Racute": 0x0154, "racute": 0x0155,
+	"Rcedilla": 0x0156, "Rcommaaccent": 0x0156, "rcedilla": 0x0157, "rcommaaccent": 0x0157,
+	"Rcaron": 0x0158, "rcaron": 0x0159, "Sacute": 0x015A, "sacute": 0x015B,
+	"Scircumflex": 0x015C, "scircumflex": 0x015D, "Scedilla": 0x015E, "scedilla": 0x015F,
+	"Scaron": 0x0160, "scaron": 0x0161, "Tcedilla": 0x0162, "tcedilla": 0x0163,
+	"Tcaron": 0x0164, "tcaron": 0x0165, "Tbar": 0x0166, "tbar": 0x0167, "Utilde": 0x0168,
+	"utilde": 0x0169, "Umacron": 0x016A, "umacron": 0x016B, "Ubreve": 0x016C, "ubreve": 0x016D,
+	"Uring": 0x016E, "uring": 0x016F, "Uhungarumlaut": 0x0170, "uhungarumlaut": 0x0171,
+	"Uogonek": 0x0172, "uogonek": 0x0173, "Wcircumflex": 0x0174, "wcircumflex": 0x0175,
+	"Ycircumflex": 0x0176, "ycircumflex": 0x0177, "Ydieresis": 0x0178, "Zacute": 0x0179,
+	"zacute": 0x017A, "Zdotaccent": 0x017B, "zdotaccent": 0x017C, "Zcaron": 0x017D,
+	"zcaron": 0x017E, "longs": 0x017F, "florin": 0x0192, "Ohorn": 0x01A0, "ohorn": 0x01A1,
+	"Uhorn": 0x01AF, "uhorn": 0x01B0, "Gcaron": 0x01E6, "gcaron": 0x01E7, "Aringacute": 0x01FA,
+	"aringacute": 0x01FB, "AEacute": 0x01FC, "aeacute": 0x01FD, "Oslashacute": 0x01FE,
+	"oslashacute": 0x01FF, "Scommaaccent": 0x0218, "scommaaccent": 0x0219,
+	"Tcommaaccent": 0x021A, "tcommaaccent": 0x021B, "circumflex": 0x02C6, "caron": 0x02C7,
+	"breve": 0x02D8, "dotaccent": 0x02D9, "ring": 0x02DA, "ogonek": 0x02DB, "tilde": 0x02DC,
+	"hungarumlaut": 0x02DD, "tonos": 0x0384, "dieresistonos": 0x0385, "Alphatonos": 0x0386,
+	"anoteleia": 0x0387, "Epsilontonos": 0x0388, "Etatonos": 0x0389, "Iotatonos": 0x038A,
+	"Omicrontonos": 0x038C, "Upsilontonos": 0x038E, "Omegatonos": 0x038F,
+	"iotadieresistonos": 0x0390, "Alpha": 0x0391, "Beta": 0x0392, "Gamma": 0x0393,
+	"Epsilon": 0x0395, "Zeta": 0x0396, "Eta": 0x0397, "Theta": 0x0398, "Iota": 0x0399,
+	"Kappa": 0x039A, "Lambda": 0x039B, "Mu": 0x039C, "Nu": 0x039D, "Xi": 0x039E,
+	"Omicron": 0x039F, "Pi": 0x03A0, "Rho": 0x03A1, "Sigma": 0x03A3, "Tau": 0x03A4,
+	"Upsilon": 0x03A5, "Phi": 0x03A6, "Chi": 0x03A7, "Psi": 0x03A8, "Iotadieresis": 0x03AA,
+	"Upsilondieresis": 0x03AB, "alphatonos": 0x03AC, "epsilontonos": 0x03AD, "etatonos": 0x03AE,
+	"iotatonos": 0x03AF, "upsilondieresistonos": 0x03B0, "alpha": 0x03B1, "beta": 0x03B2,
+	"gamma": 0x03B3, "delta": 0x03B4, "epsilon": 0x03B5, "zeta": 0x03B6, "eta": 0x03B7,
+	"theta": 0x03B8, "iota": 0x03B9, "kappa": 0x03BA, "lambda": 0x03BB, "nu": 0x03BD,
+	"xi": 0x03BE, "omicron": 0x03BF, "pi": 0x03C0, "rho": 0x03C1, "sigma1": 0x03C2,
+	"sigma": 0x03C3, "tau": 0x03C4, "upsilon": 0x03C5, "phi": 0x03C6, "chi": 0x03C7,
+	"psi": 0x03C8, "omega": 0x03C9, "iotadieresis": 0x03CA, "upsilondieresis": 0x03CB,
+	"omicrontonos": 0x03CC, "upsilontonos": 0x03CD, "omegatonos": 0x03CE, "theta1": 0x03D1,
+	"Upsilon1": 0x03D2, "phi1": 0x03D5, "omega1": 0x03D6, "afii10023": 0x0401,
+	"afii10051": 0x0402, "afii10052": 0x0403, "afii10053": 0x0404, "afii10054": 0x0405,
+	"afii10055": 0x0406, "afii10056": 0x0407, "afii10057": 0x0408, "afii10058": 0x0409,
+	"afii10059": 0x040A, "afii10060": 0x040B, "afii10061": 0x040C, "afii10062": 0x040E,
+	"afii10145": 0x040F, "afii10017": 0x0410, "afii10018": 0x0411, "afii10019": 0x0412,
+	"afii10020": 0x0413, "afii10021": 0x0414, "afii10022": 0x0415, "afii10024": 0x0416,
+	"afii10025": 0x0417, "afii10026": 0x0418, "afii10027": 0x0419, "afii10028": 0x041A,
+	"afii10029": 0x041B, "afii10030": 0x041C, "afii10031": 0x041D, "afii10032": 0x041E,
+	"afii10033": 0x041F, "afii10034": 0x0420, "afii10035": 0x0421, "afii10036": 0x0422,
+	"afii10037": 0x0423, "afii10038": 0x0424, "afii10039": 0x0425, "afii10040": 0x0426,
+	"afii10041": 0x0427, "afii10042": 0x0428, "afii10043": 0x0429, "afii10044": 0x042A,
+	"afii10045": 0x042B, "afii10046": 0x042C, "afii10047": 0x042D, "afii10048": 0x042E,
+	"afii10049": 0x042F, "afii10065": 0x0430, "afii10066": 0x0431, "afii10067": 0x0432,
+	"afii10068": 0x0433, "afii10069": 0x0434, "afii10070": 0x0435, "afii10072": 0x0436,
+	"afii10073": 0x0437, "afii10074": 0x0438, "afii10075": 0x0439, "afii10076": 0x043A,
+	"afii10077": 0x043B, "afii10078": 0x043C, "afii10079": 0x043D, "afii10080": 0x043E,
+	"afii10081": 0x043F, "afii10082": 0x0440, "afii10083": 0x0441, "afii10084": 0x0442,
+	"afii10085": 0x0443, "afii10086": 0x0444, "afii10087": 0x0445, "afii10088": 0x0446,
+	"afii10089": 0x0447, "afii10090": 0x0448, "afii10091": 0x0449, "afii10092": 0x044A,
+	"afii10093": 0x044B, "afii10094": 0x044C, "afii10095": 0x044D, "afii10096": 0x044E,
+	"afii10097": 0x044F, "afii10071": 0x0451, "afii10099": 0x0452, "afii10100": 0x0453,
+	"afii10101": 0x0454, "afii10102": 0x0455, "afii10103": 0x0456, "afii10104": 0x0457,
+	"afii10105": 0x0458, "afii10106": 0x0459, "afii10107": 0x045A, "afii10108": 0x045B,
+	"afii10109": 0x045C, "afii10110": 0x045E, "afii10193": 0x045F, "afii10050": 0x0490,
+	"afii10098": 0x0491, "sheva": 0x05B0, "hatafsegol": 0x05B1, "hatafpatah": 0x05B2,
+	"hatafqamats": 0x05B3, "hiriq": 0x05B4, "tsere": 0x05B5, "segol": 0x05B6, "patah": 0x05B7,
+	"qamats": 0x05B8, "holam": 0x05B9, "qubuts": 0x05BB, "dagesh": 0x05BC, "meteg": 0x05BD,
+	"maqaf": 0x05BE, "rafe": 0x05BF, "paseq": 0x05C0, "shindot": 0x05C1, "sindot": 0x05C2,
+	"sofpasuq": 0x05C3, "alef": 0x05D0, "bet": 0x05D1, "gimel": 0x05D2, "dalet": 0x05D3,
+	"he": 0x05D4, "vav": 0x05D5, "zayin": 0x05D6, "het": 0x05D7, "tet": 0x05D8, "yod": 0x05D9,
+	"finalkaf": 0x05DA, "kaf": 0x05DB, "lamed": 0x05DC, "finalmem": 0x05DD, "mem": 0x05DE,
+	"finalnun": 0x05DF, "nun": 0x05E0, "samekh": 0x05E1, "ayin": 0x05E2, "finalpe": 0x05E3,
+	"pe": 0x05E4, "finaltsadi": 0x05E5, "tsadi": 0x05E6, "qof": 0x05E7, "resh": 0x05E8,
+	"shin": 0x05E9, "tav": 0x05EA, "doublevav": 0x05F0, "vavyod": 0x05F1, "doubleyod": 0x05F2,
+	"Wgrave": 0x1E80, "wgrave": 0x1E81, "Wacute": 0x1E82, "wacute": 0x1E83, "Wdieresis": 0x1E84,
+	"wdieresis": 0x1E85, "Ygrave": 0x1EF2, "ygrave": 0x1EF3, "endash": 0x2013, "emdash": 0x2014,
+	"underscoredbl": 0x2017, "quoteleft": 0x2018, "quoteright": 0x2019,
+	"quotesinglbase": 0x201A, "quotereversed": 0x201B, "quotedblleft": 0x201C,
+	"quotedblright": 0x201D, "quotedblbase": 0x201E, "dagger": 0x2020, "daggerdbl": 0x2021,
+	"bullet": 0x2022, "ellipsis": 0x2026, "perthousand": 0x2030, "minute": 0x2032,
+	"second": 0x2033, "guilsinglleft": 0x2039, "guilsinglright": 0x203A, "exclamdbl": 0x203C,
+	"fraction": 0x2044, "colonmonetary": 0x20A1, "franc": 0x20A3, "lira": 0x20A4,
+	"peseta": 0x20A7, "newsheqelsign": 0x20AA, "dong": 0x20AB, "Euro": 0x20AC,
+	"Ifraktur": 0x2111, "afii61289": 0x2113, "afii61352": 0x2116, "weierstrass": 0x2118,
+	"Rfraktur": 0x211C, "prescription": 0x211E, "trademark": 0x2122, "Omega": 0x2126,
+	"estimated": 0x212E, "aleph": 0x2135, "onethird": 0x2153, "twothirds": 0x2154,
+	"oneeighth": 0x215B, "threeeighths": 0x215C, "fiveeighths": 0x215D, "seveneighths": 0x215E,
+	"arrowleft": 0x2190, "arrowup": 0x2191, "arrowright": 0x2192, "arrowdown": 0x2193,
+	"arrowboth": 0x2194, "arrowupdn": 0x2195, "arrowupdnbse": 0x21A8, "carriagereturn": 0x21B5,
+	"arrowdblleft": 0x21D0, "arrowdblup": 0x21D1, "arrowdblright": 0x21D2,
+	"arrowdbldown": 0x21D3, "arrowdblboth": 0x21D4, "universal": 0x2200, "partialdiff": 0x2202,
+	"existential": 0x2203, "emptyset": 0x2205, "Delta": 0x2206, "gradient": 0x2207,
+	"element": 0x2208, "notelement": 0x2209, "suchthat": 0x220B, "product": 0x220F,
+	"summation": 0x2211, "minus": 0x2212, "asteriskmath": 0x2217, "radical": 0x221A,
+	"proportional": 0x221D, "infinity": 0x221E, "orthogonal": 0x221F, "angle": 0x2220,
+	"logicaland": 0x2227, "logicalor": 0x2228, "intersection": 0x2229, "union": 0x222A,
+	"integral": 0x222B, "therefore": 0x2234, "similar": 0x223C, "congruent": 0x2245,
+	"approxequal": 0x2248, "notequal": 0x2260, "equivalence": 0x2261, "lessequal": 0x2264,
+	"greaterequal": 0x2265, "propersubset": 0x2282, "propersuperset": 0x2283,
+	"notsubset": 0x2284, "reflexsubset": 0x2286, "reflexsuperset": 0x2287, "circleplus": 0x2295,
+	"circlemultiply": 0x2297, "perpendicular": 0x22A5, "dotmath": 0x22C5, "house": 0x2302,
+	"revlogicalnot": 0x2310, "integraltp": 0x2320, "integralbt": 0x2321, "angleleft": 0x2329,
+	"angleright": 0x232A, "SF100000": 0x2500, "SF110000": 0x2502, "SF010000": 0x250C,
+	"SF030000": 0x2510, "SF020000": 0x2514, "SF040000": 0x2518, "SF080000": 0x251C,
+	"SF090000": 0x2524, "SF060000": 0x252C, "SF070000": 0x2534, "SF050000": 0x253C,
+	"SF430000": 0x2550, "SF240000": 0x2551, "SF510000": 0x2552, "SF520000": 0x2553,
+	"SF390000": 0x2554, "SF220000": 0x2555, "SF210000": 0x2556, "SF250000": 0x2557,
+	"SF500000": 0x2558, "SF490000": 0x2559, "SF380000": 0x255A, "SF280000": 0x255B,
+	"SF270000": 0x255C, "SF260000": 0x255D, "SF360000": 0x255E, "SF370000": 0x255F,
+	"SF420000": 0x2560, "SF190000": 0x2561, "SF200000": 0x2562, "SF230000": 0x2563,
+	"SF470000": 0x2564, "SF480000": 0x2565, "SF410000": 0x2566, "SF450000": 0x2567,
+	"SF460000": 0x2568, "SF400000": 0x2569, "SF540000": 0x256A, "SF530000": 0x256B,
+	"SF440000": 0x256C, "upblock": 0x2580, "dnblock": 0x2584, "block": 0x2588,
+	"lfblock": 0x258C, "rtblock": 0x2590, "ltshade": 0x2591, "shade": 0x2592, "dkshade": 0x2593,
+	"filledbox": 0x25A0, "H22073": 0x25A1, "H18543": 0x25AA, "H18551": 0x25AB,
+	"filledrect": 0x25AC, "triagup": 0x25B2, "triagrt": 0x25BA, "triagdn": 0x25BC,
+	"triaglf": 0x25C4, "lozenge": 0x25CA, "circle": 0x25CB, "H18533": 0x25CF,
+	"invbullet": 0x25D8, "invcircle": 0x25D9, "openbullet": 0x25E6, "smileface": 0x263A,
+	"invsmileface": 0x263B, "sun": 0x263C, "female": 0x2640, "male": 0x2642, "spade": 0x2660,
+	"club": 0x2663, "heart": 0x2665, "diamond": 0x2666, "musicalnote": 0x266A,
+	"musicalnotedbl": 0x266B, "fi": 0xFB01, "fl": 0xFB02,
+}
@@ -0,0 +1,87 @@
+package ttf
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGlyphNameToRunes(t *testing.T) {
+	tests := []struct {
+		name GlyphName
+		want []rune
+	}{
+		{"A", []rune{'A'}},
+		{"space", []rune{' '}},
+		{"afii10017", []rune{0x0410}},
+		{"afii61352", []rune{0x2116}},
+		{"Gcommaaccent", []rune{0x0122}},
+		{"Gcedilla", []rune{0x0122}},
+		{"minus", []rune{0x2212}},
+		{"hyphen", []rune{'-'}},
+		{"uni4E2D", []rune{0x4E2D}},
+		{"uni00660066", []rune{'f', 'f'}},
+		{"u1F600", []rune{0x1F600}},
+		{"u10FFFF", []rune{0x10FFFF}},
+		{"a.sc", []rune{'a'}},
+		{"f_f_i", []rune{'f', 'f', 'i'}},
+		{"f_i.alt", []rune{'f', 'i'}},
+		{"uni20AC_foo", []rune{0x20AC}},
+		{"g1234", nil},
+		{"", nil},
+		{".notdef", nil},
+		{"uni4e2d", nil},
+		{"uniD800", nil},
+		{"uni4E2", nil},
+		{"u110000", nil},
+		{"u12", nil},
+		{"u1234567", nil},
+	}
+	for _, tt := range tests {
+		got, ok := GlyphNameToRunes(tt.name)
+		if !slices.Equal(got, tt.want) || ok != (tt.want != nil) {
+			t.Fatalf("%q: got %U %t, want %U", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRuneToGlyphName(t *testing.T) {
+	for r, want := range map[rune]GlyphName{
+		'A': "A", 0x0122: "Gcommaaccent", 0x0116: "Edotaccent", 0x0410: "afii10017",
+		0x4E2D: "uni4E2D", 0x1F600: "u1F600", 0x0394: "uni0394",
+	} {
+		if got := RuneToGlyphName(r); got != want {
+			t.Fatalf("%U: got %q, want %q", r, got, want)
+		}
+	}
+	names := make(map[rune]GlyphName)
+	for name, r := range aglNames {
+		if aglLegacyNames[name] {
+			continue
+		}
+		if other, dup := names[r]; dup {
+			t.Fatalf("%U has two preferred names %q and %q", r, name, other)
+		}
+		names[r] = name
+		if got, ok := GlyphNameToRunes(RuneToGlyphName(r)); !ok || !slices.Equal(got, []rune{r}) {
+			t.Fatalf("%U: %q maps back to %U", r, RuneToGlyphName(r), got)
+		}
+	}
+}
+
+func TestFont_SynthesizeCmapFromPost(t *testing.T) {
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	synth := f.SynthesizeCmapFromPost()
+	if len(synth) < len(cmap)*9/10 {
+		t.Fatalf("synthesized %d entries for a cmap of %d", len(synth), len(cmap))
+	}
+	for r, gid := range synth {
+		if want, ok := cmap[r]; ok && want != gid {
+			t.Fatalf("%U: synthesized %v, cmap has %v", r, gid, want)
+		}
+	}
+	f.post = nil
+	if f.SynthesizeCmapFromPost() != nil {
+		t.Fatal("expected nil without a post table")
+	}
+}
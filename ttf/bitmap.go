@@ -0,0 +1,34 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import "slices"
+
+// bitmapTags are the tables of embedded bitmap strikes: the bitmaps of EBDT located by EBLC,
+// with the scaled strikes of EBSC, and bdat and bloc, their Apple counterparts. The locations
+// are by glyph index, so subsetting drops them.
+var bitmapTags = []Tag{tagEBDT, tagEBLC, tagEBSC, tagBdat, tagBloc}
+
+// bitmapRequires maps each of bitmapTags to the table it is only valid with: the data and the
+// locations of the bitmaps need each other, and the scaled strikes need the locations.
+var bitmapRequires = map[Tag]Tag{tagEBDT: tagEBLC, tagEBLC: tagEBDT, tagEBSC: tagEBLC, tagBdat: tagBloc, tagBloc: tagBdat}
+
+// HasBitmapStrikes returns true if `f` has embedded bitmaps, such as those of pixel fonts or
+// the small sizes of some CJK fonts, in EBDT and EBLC or bdat and bloc. Subset drops them,
+// noting so in the Warnings of the subset, see SubsetOptions.KeepBitmaps. Color bitmaps are
+// reported by HasColorGlyphs.
+func (f *Font) HasBitmapStrikes() bool {
+	return len(f.bitmapTables()) > 0
+}
+
+// bitmapTables returns the tags of the bitmap tables of `f`, see bitmapTags. A font made by
+// subsetting has none.
+func (f *Font) bitmapTables() []Tag {
+	if f.br == nil {
+		return nil
+	}
+	return slices.DeleteFunc(slices.Clone(bitmapTags), func(tag Tag) bool { return !f.trec.HasTag(tag) })
+}
@@ -0,0 +1,114 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// bitmapFixture returns Go Regular with a bitmap strike at 8 ppem like those of pixel fonts:
+// EBLC locating a bitmap of 'A' in EBDT with an index subtable of format 1 and image format 1,
+// as written and parsed again, and its data. Tables `drop` are left out.
+func bitmapFixture(t *testing.T, drop ...Tag) (*Font, []byte) {
+	t.Helper()
+	f := loadGoRegular(t)
+	indices, _ := f.LookupRunes([]rune("A"))
+	gid := uint16(indices[0])
+
+	// The glyph: small metrics (height, width, bearingX, bearingY, advance) and 8 rows of 1 byte.
+	glyph := []byte{8, 8, 0, 8, 8, 0x18, 0x24, 0x42, 0x42, 0x7E, 0x42, 0x42, 0x00}
+	ebdt := append([]byte{0, 2, 0, 0}, glyph...)
+
+	be := binary.BigEndian
+	eblc := be.AppendUint32(nil, 0x00020000)
+	eblc = be.AppendUint32(eblc, 1)
+	// BitmapSize: the index subtable array right after it, 8 bytes for its one entry and
+	// 8+8 for the index subtable.
+	eblc = be.AppendUint32(eblc, 8+48)
+	eblc = be.AppendUint32(eblc, 8+16)
+	eblc = be.AppendUint32(eblc, 1)
+	eblc = be.AppendUint32(eblc, 0)
+	lineMetrics := []byte{8, 0, 8, 8, 1, 0, 0, 0, 0, 0, 0, 0}
+	eblc = append(append(eblc, lineMetrics...), lineMetrics...)
+	eblc = be.AppendUint16(be.AppendUint16(eblc, gid), gid)
+	eblc = append(eblc, 8, 8, 1, 1) // ppemX, ppemY, bitDepth, flags (horizontal metrics).
+	// IndexSubTableArray with the index subtable at 8.
+	eblc = be.AppendUint32(be.AppendUint16(be.AppendUint16(eblc, gid), gid), 8)
+	// IndexSubTable1: the glyph at 4 in EBDT, after the version, and its end.
+	eblc = be.AppendUint32(be.AppendUint16(be.AppendUint16(eblc, 1), 1), 4)
+	eblc = be.AppendUint32(be.AppendUint32(eblc, 0), uint32(len(glyph)))
+
+	for _, t := range []rawTable{{tagEBDT, ebdt}, {tagEBLC, eblc}} {
+		if !slices.Contains(drop, t.tag) {
+			f.rawTables = append(f.rawTables, t)
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, buf.Bytes()
+}
+
+func TestFont_SubsetBitmaps(t *testing.T) {
+	if loadGoRegular(t).HasBitmapStrikes() {
+		t.Fatal("Go Regular: expected no bitmap strikes")
+	}
+	f, data := bitmapFixture(t)
+	if !f.HasBitmapStrikes() {
+		t.Fatal("expected bitmap strikes")
+	}
+	if err := ValidateBytes(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropped together by default.
+	sub, err := f.Subset([]rune("AB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.HasBitmapStrikes() || !slices.Contains(sub.Warnings(), "dropped bitmap tables EBDT, EBLC") {
+		t.Fatalf("subset: HasBitmapStrikes() = %v, warnings %q", sub.HasBitmapStrikes(), sub.Warnings())
+	}
+	var buf bytes.Buffer
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range bitmapTags {
+		if _, ok := tableData(t, buf.Bytes())[tag]; ok {
+			t.Fatalf("subset has %s", tag)
+		}
+	}
+
+	opts := DefaultSubsetOptions()
+	opts.KeepBitmaps = true
+	if _, err := f.SubsetWithOptions([]rune("AB"), opts); !errors.Is(err, ErrBitmapsUnsupported) {
+		t.Fatalf("got error %v, want ErrBitmapsUnsupported", err)
+	}
+	// Without bitmaps there is nothing to keep.
+	if _, err := loadGoRegular(t).SubsetWithOptions([]rune("AB"), opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckBitmapTables(t *testing.T) {
+	for _, drop := range []Tag{tagEBDT, tagEBLC} {
+		_, data := bitmapFixture(t, drop)
+		rep, err := ValidateBytesReport(data, ValidationOptions{})
+		other := bitmapRequires[drop]
+		want := fmt.Sprintf("%s: without %s", other, drop)
+		if err == nil || rep == nil || len(rep.Errors()) != 1 || rep.Errors()[0].String() != want {
+			t.Fatalf("without %s: got %v, want %q", drop, err, want)
+		}
+	}
+}
@@ -7,23 +7,101 @@ package ttf
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"reflect"
+	"slices"
+	"strings"
 )
 
 // byteReader encapsulates io.ReadSeeker with buffering and provides methods to read binary data as
 // needed for truetype fonts.  The buffered reader is used to enhance the performance when reading
 // binary data types one at a time.
+//
+// Fonts are parsed from data held in memory, see newBytesReader, so that readers made by fork
+// read the same data concurrently, each with its own offset, without locks.
 type byteReader struct {
 	rs     io.ReadSeeker
 	reader *bufio.Reader
+	size   int64  // Length of the data of `rs`, -1 if unknown.
+	data   []byte // The data when held in memory, see window.
+
+	// ctx describes what is being read, outermost first, e.g. ["cmap", "subtable 1 (format 4)"].
+	// Read errors are wrapped with it and the offset, see wrapErr.
+	ctx []string
+}
+
+// readError is a failure to read a font, with the context and the offset it happened at.
+type readError struct {
+	context string
+	offset  int64
+	err     error
+}
+
+func (e *readError) Error() string {
+	return fmt.Sprintf("%s at offset %d: %v", e.context, e.offset, e.err)
 }
 
+func (e *readError) Unwrap() error {
+	return e.err
+}
+
+// newByteReader returns a reader of `rs` from its current offset.
 func newByteReader(rs io.ReadSeeker) *byteReader {
-	return &byteReader{
-		rs:     rs,
-		reader: bufio.NewReader(rs),
+	r := &byteReader{
+		rs:   rs,
+		size: -1,
+	}
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err == nil {
+		if end, err := rs.Seek(0, io.SeekEnd); err == nil {
+			r.size = end
+		}
+		if _, err := rs.Seek(cur, io.SeekStart); err != nil {
+			r.size = -1
+		}
 	}
+	r.reader = bufio.NewReader(r.rs)
+	return r
+}
+
+// newBytesReader returns a reader of `data` held in memory, which must not be modified after.
+func newBytesReader(data []byte) *byteReader {
+	r := newByteReader(bytes.NewReader(data))
+	r.data = data
+	return r
+}
+
+// fork returns a reader of the data of `r` at offset 0 for reading on demand after parsing, such
+// as layout tables. Readers of data held in memory give a new reader that can be used
+// concurrently with `r`, other readers give `r` itself.
+func (r *byteReader) fork() *byteReader {
+	if r.data == nil {
+		return r
+	}
+	return newBytesReader(r.data)
+}
+
+// window returns the `length` bytes at `offset` of the data of `r` without copying them, or false
+// if the data is not held in memory or ends before. Appending to the window copies it.
+func (r *byteReader) window(offset, length int64) ([]byte, bool) {
+	if r.data == nil || offset < 0 || length < 0 || offset+length > int64(len(r.data)) {
+		return nil, false
+	}
+	return r.data[offset : offset+length : offset+length], true
+}
+
+// available returns the number of bytes left to read from `r`, or -1 if unknown. Reads of
+// arrays check it first, so that lengths and counts read from damaged fonts do not cause huge
+// allocations.
+func (r *byteReader) available() int64 {
+	if r.size < 0 {
+		return -1
+	}
+	return max(r.size-r.Offset(), 0)
 }
 
 // Offset returns current offset position of `r`.
@@ -33,11 +111,61 @@ func (r byteReader) Offset() int64 {
 	return offset
 }
 
+// setContext starts a new context at the top level, usually a table tag.
+func (r *byteReader) setContext(name string) {
+	r.ctx = append(r.ctx[:0], name)
+}
+
+// pushContext adds a nested context such as "subtable 3" until the matching popContext.
+func (r *byteReader) pushContext(format string, a ...interface{}) {
+	r.ctx = append(r.ctx, fmt.Sprintf(format, a...))
+}
+
+func (r *byteReader) popContext() {
+	if len(r.ctx) > 0 {
+		r.ctx = r.ctx[:len(r.ctx)-1]
+	}
+}
+
+// wrapErr wraps `err` with the current context of `r`, `field` when not empty and the
+// current offset. Errors wrapped already are returned as is, so the innermost context wins.
+func (r byteReader) wrapErr(err error, field string) error {
+	if err == nil {
+		return nil
+	}
+	var re *readError
+	if errors.As(err, &re) {
+		return err
+	}
+	ctx := r.ctx
+	if field != "" {
+		ctx = append(ctx[:len(ctx):len(ctx)], field)
+	}
+	context := strings.Join(ctx, ": ")
+	if context == "" {
+		context = "font"
+	}
+	return &readError{context: context, offset: r.Offset(), err: err}
+}
+
 // SeekTo seeks to offset.
 func (r *byteReader) SeekTo(offset int64) error {
-	_, err := r.rs.Seek(offset, io.SeekStart)
-	if err != nil {
-		return err
+	return r.seekTo(offset, "")
+}
+
+// seekTo seeks to `offset`, the start of `what` when not empty, e.g. a table tag. Errors give
+// the offset sought from and to, and seeking past the end of data of known size is an
+// io.ErrUnexpectedEOF, which the underlying reader would only report on the next read.
+func (r *byteReader) seekTo(offset int64, what string) error {
+	if what != "" {
+		what = " for " + what
+	}
+	if r.size >= 0 && offset > r.size {
+		return fmt.Errorf("seek from %d to %d%s past the end of the data (%d bytes): %w", r.Offset(), offset, what, r.size, io.ErrUnexpectedEOF)
+	}
+	from := r.Offset()
+	if _, err := r.rs.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek from %d to %d%s: %w", from, offset, what, err)
 	}
 	r.reader = bufio.NewReader(r.rs)
 	return nil
@@ -46,15 +174,19 @@ func (r *byteReader) SeekTo(offset int64) error {
 // Skip skips over `n` bytes.
 func (r *byteReader) Skip(n int) error {
 	_, err := r.reader.Discard(n)
-	return err
+	return r.wrapErr(err, fmt.Sprintf("skipping %d bytes", n))
 }
 
 // readBytes reads bytes straight from `r`.
 func (r *byteReader) readBytes(bp *[]byte, length int) error {
+	if left := r.available(); left >= 0 && int64(length) > left {
+		r.reader.Discard(int(left))
+		return r.wrapErr(io.ErrUnexpectedEOF, fmt.Sprintf("%d bytes", length))
+	}
 	*bp = make([]byte, length)
 	_, err := io.ReadFull(r.reader, *bp)
 	if err != nil {
-		return err
+		return r.wrapErr(err, fmt.Sprintf("%d bytes", length))
 	}
 
 	return nil
@@ -64,145 +196,154 @@ func (r *byteReader) readBytes(bp *[]byte, length int) error {
 func (r *byteReader) readSlice(slice interface{}, length int) error {
 	switch t := slice.(type) {
 	case *[]uint8:
-		for i := 0; i < length; i++ {
-			val, err := r.readUint8()
-			if err != nil {
-				return err
-			}
-			*t = append(*t, val)
-		}
+		return readSliceOf(r, t, length)
 	case *[]uint16:
-		for i := 0; i < length; i++ {
-			val, err := r.readUint16()
-			if err != nil {
-				return err
-			}
-			*t = append(*t, val)
-		}
+		return readSliceOf(r, t, length)
 	case *[]int16:
-		for i := 0; i < length; i++ {
-			val, err := r.readInt16()
-			if err != nil {
-				return err
-			}
-			*t = append(*t, val)
-		}
+		return readSliceOf(r, t, length)
 	case *[]offset16:
-		for i := 0; i < length; i++ {
-			val, err := r.readOffset16()
-			if err != nil {
-				return err
-			}
-			*t = append(*t, val)
-		}
+		return readSliceOf(r, t, length)
 	case *[]offset32:
-		for i := 0; i < length; i++ {
-			val, err := r.readOffset32()
-			if err != nil {
-				return err
-			}
-			*t = append(*t, val)
-		}
+		return readSliceOf(r, t, length)
 
 	default:
 		// slog.Error(fmt.Sprintf("Unsupported type: %T (readSlice)", t))
 		return errTypeCheck
 	}
+}
+
+// sliceElem is an element type of the arrays read by readSliceOf and written by writeSliceOf.
+type sliceElem interface {
+	~uint8 | ~int8 | ~uint16 | ~int16 | ~uint32 | ~int32
+}
+
+// readSliceOf reads `length` big endian values from `r` and appends them to `dst`. The whole
+// array is read at once and converted from one buffer.
+func readSliceOf[T sliceElem](r *byteReader, dst *[]T, length int) error {
+	if length <= 0 {
+		return nil
+	}
+	size := binary.Size(T(0))
+	if left := r.available(); left >= 0 && int64(length)*int64(size) > left {
+		// Fail where reading would have, at the end of the data.
+		r.reader.Discard(int(left))
+		return r.wrapErr(io.ErrUnexpectedEOF, fmt.Sprintf("element %d of %d", left/int64(size), length))
+	}
+	b := make([]byte, length*size)
+	if n, err := io.ReadFull(r.reader, b); err != nil {
+		return r.wrapErr(err, fmt.Sprintf("element %d of %d", n/size, length))
+	}
+
+	*dst = slices.Grow(*dst, length)
+	switch size {
+	case 1:
+		for _, v := range b {
+			*dst = append(*dst, T(v))
+		}
+	case 2:
+		for i := 0; i < len(b); i += 2 {
+			*dst = append(*dst, T(binary.BigEndian.Uint16(b[i:])))
+		}
+	case 4:
+		for i := 0; i < len(b); i += 4 {
+			*dst = append(*dst, T(binary.BigEndian.Uint32(b[i:])))
+		}
+	}
 	return nil
 }
 
 // read reads a series of fields from `r`.
 func (r byteReader) read(fields ...interface{}) error {
-	for _, f := range fields {
+	for i, f := range fields {
 		switch t := f.(type) {
-		case **f2dot14:
+		case **F2Dot14:
 			val, err := r.readF2dot14()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = &val
-		case *f2dot14:
+		case *F2Dot14:
 			val, err := r.readF2dot14()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
-		case *fixed:
+		case *Fixed:
 			val, err := r.readFixed()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
-		case *fword:
+		case *FWord:
 			val, err := r.readFword()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *int8:
 			val, err := r.readInt8()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *int16:
 			val, err := r.readInt16()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *int32:
 			val, err := r.readInt32()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *longdatetime:
 			val, err := r.readLongdatetime()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *offset16:
 			val, err := r.readOffset16()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *offset32:
 			val, err := r.readOffset32()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
-		case *ufword:
+		case *UFWord:
 			val, err := r.readUfword()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *uint8:
 			val, err := r.readUint8()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *uint16:
 			val, err := r.readUint16()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
-		case *tag:
+		case *Tag:
 			val, err := r.readTag()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 		case *uint32:
 			val, err := r.readUint32()
 			if err != nil {
-				return err
+				return r.wrapErr(err, fieldName(i, f))
 			}
 			*t = val
 
@@ -214,24 +355,29 @@ func (r byteReader) read(fields ...interface{}) error {
 	return nil
 }
 
-func (r byteReader) readF2dot14() (f2dot14, error) {
+// fieldName describes the `i`th field `f` passed to read, e.g. "field 2 (uint16)".
+func fieldName(i int, f interface{}) string {
+	return fmt.Sprintf("field %d (%s)", i+1, reflect.TypeOf(f).Elem())
+}
+
+func (r byteReader) readF2dot14() (F2Dot14, error) {
 	b := make([]byte, 2)
 	_, err := io.ReadFull(r.reader, b)
 	if err != nil {
 		return 0, err
 	}
 	u16 := binary.BigEndian.Uint16(b)
-	return f2dot14(u16), nil
+	return F2Dot14(u16), nil
 }
 
-func (r byteReader) readFixed() (fixed, error) {
-	var val fixed
+func (r byteReader) readFixed() (Fixed, error) {
+	var val Fixed
 	err := binary.Read(r.reader, binary.BigEndian, &val)
 	return val, err
 }
 
-func (r byteReader) readFword() (fword, error) {
-	var val fword
+func (r byteReader) readFword() (FWord, error) {
+	var val FWord
 	err := binary.Read(r.reader, binary.BigEndian, &val)
 	return val, err
 }
@@ -272,14 +418,14 @@ func (r byteReader) readUint32() (uint32, error) {
 	return val, err
 }
 
-func (r byteReader) readTag() (tag, error) {
-	var val tag
+func (r byteReader) readTag() (Tag, error) {
+	var val Tag
 	err := binary.Read(r.reader, binary.BigEndian, &val)
 	return val, err
 }
 
-func (r byteReader) readUfword() (ufword, error) {
-	var val ufword
+func (r byteReader) readUfword() (UFWord, error) {
+	var val UFWord
 	err := binary.Read(r.reader, binary.BigEndian, &val)
 	return val, err
 }
@@ -0,0 +1,326 @@
+package ttf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// readerKinds make the inputs parser tests run against: an io.ReaderAt, and a reader that is
+// no io.ReaderAt. Parse reads both into memory.
+var readerKinds = []struct {
+	name string
+	new  func(b []byte) io.ReadSeeker
+}{
+	{"ReaderAt", func(b []byte) io.ReadSeeker { return bytes.NewReader(b) }},
+	{"sequential", func(b []byte) io.ReadSeeker { return struct{ io.ReadSeeker }{bytes.NewReader(b)} }},
+}
+
+// forEachReaderKind runs `test` as a subtest for each of readerKinds.
+func forEachReaderKind(t *testing.T, test func(t *testing.T, newReader func(b []byte) io.ReadSeeker)) {
+	for _, kind := range readerKinds {
+		t.Run(kind.name, func(t *testing.T) { test(t, kind.new) })
+	}
+}
+
+func TestParse_ErrorContext(t *testing.T) {
+	forEachReaderKind(t, testParseErrorContext)
+}
+
+func testParseErrorContext(t *testing.T, newReader func(b []byte) io.ReadSeeker) {
+	var buf bytes.Buffer
+	if err := loadGoRegular(t).WriteWithOptions(&buf, WriteOptions{Reserialize: true}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(newReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The writer puts cmap last, so cutting the file inside it leaves every other table intact.
+	cmap := f.trec.trMap[tagCmap]
+	if (int(cmap.offset)+int(cmap.length)+3)&^3 != buf.Len() {
+		t.Fatalf("cmap at %d+%d is not the last table of %d bytes", cmap.offset, cmap.length, buf.Len())
+	}
+
+	tests := []struct {
+		cut  int // Bytes of cmap kept.
+		want []string
+	}{
+		{4, []string{"cmap: encoding record 0: field 1 (uint16)"}},
+		{100, []string{"cmap: subtable 0", "format 4: endCode: element 29 of 97"}},
+	}
+	for _, tt := range tests {
+		end := int(cmap.offset) + tt.cut
+		_, err := Parse(newReader(buf.Bytes()[:end]))
+		if err == nil {
+			t.Fatalf("cut at %d: expected an error", tt.cut)
+		}
+		want := append(tt.want, fmt.Sprintf("at offset %d", end))
+		for _, s := range want {
+			if !strings.Contains(err.Error(), s) {
+				t.Fatalf("cut at %d: error %q does not mention %q", tt.cut, err, s)
+			}
+		}
+		if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("cut at %d: error %q does not wrap an EOF", tt.cut, err)
+		}
+	}
+}
+
+func TestParse_TruncatedGlyf(t *testing.T) {
+	forEachReaderKind(t, testParseTruncatedGlyf)
+}
+
+func testParseTruncatedGlyf(t *testing.T, newReader func(b []byte) io.ReadSeeker) {
+	var buf bytes.Buffer
+	if err := loadGoRegular(t).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(newReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyf := f.trec.trMap[tagGlyf]
+
+	// Cut where glyf starts, the directory still listing all of it.
+	end := int(glyf.offset)
+	_, err = Parse(newReader(buf.Bytes()[:end]))
+	var rangeErr *TableRangeError
+	if !errors.As(err, &rangeErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("cut at glyf: %v", err)
+	}
+	if want := (TableRangeError{tagGlyf, int64(glyf.offset), int64(glyf.length), int64(end)}); *rangeErr != want {
+		t.Fatalf("cut at glyf: got %+v, want %+v", *rangeErr, want)
+	}
+
+	// Cut inside glyf, reading fails at the glyph the file ends in.
+	end += int(glyf.length) / 2
+	_, err = Parse(newReader(buf.Bytes()[:end]))
+	if err == nil || errors.As(err, &rangeErr) || !errors.Is(err, io.ErrUnexpectedEOF) ||
+		!strings.HasPrefix(err.Error(), "glyf: glyph ") || !strings.Contains(err.Error(), fmt.Sprintf("at offset %d", end)) {
+		t.Fatalf("cut inside glyf: %v", err)
+	}
+
+	// A table missing from the directory is no error.
+	if _, has, err := f.seekToTable(f.br, MustTag("GPOS")); has || err != nil {
+		t.Fatalf("GPOS: %t %v", has, err)
+	}
+}
+
+func TestByteReader_SeekTo(t *testing.T) {
+	forEachReaderKind(t, testByteReaderSeekTo)
+}
+
+func testByteReaderSeekTo(t *testing.T, newReader func(b []byte) io.ReadSeeker) {
+	r := newByteReader(newReader(make([]byte, 10)))
+	if err := r.SeekTo(10); err != nil {
+		t.Fatal(err)
+	}
+	err := r.seekTo(20, "glyf")
+	if !errors.Is(err, io.ErrUnexpectedEOF) || err.Error() != "seek from 10 to 20 for glyf past the end of the data (10 bytes): unexpected EOF" {
+		t.Fatalf("past the end: %v", err)
+	}
+	if err := r.SeekTo(-1); err == nil || !strings.HasPrefix(err.Error(), "seek from 10 to -1: ") {
+		t.Fatalf("negative: %v", err)
+	}
+}
+
+func TestReadSliceOf(t *testing.T) {
+	data := []byte{0x00, 0x01, 0xFF, 0xFE, 0x80, 0x00, 0x12, 0x34}
+	var buf bytes.Buffer
+	w := newByteWriter(&buf)
+	for i := 0; i < len(data); i += 2 {
+		if err := w.write(int16(data[i])<<8 | int16(data[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("per value write gave % X", buf.Bytes())
+	}
+
+	r := newByteReader(bytes.NewReader(data))
+	i16 := []int16{7}
+	if err := readSliceOf(r, &i16, 4); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{7, 1, -2, -32768, 0x1234}; !slices.Equal(i16, want) {
+		t.Fatalf("int16: got %v, want %v", i16, want)
+	}
+	r = newByteReader(bytes.NewReader(data))
+	var u32 []offset32
+	if err := readSliceOf(r, &u32, 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := []offset32{0x0001FFFE, 0x80001234}; !slices.Equal(u32, want) {
+		t.Fatalf("offset32: got %X, want %X", u32, want)
+	}
+	r = newByteReader(bytes.NewReader(data))
+	var u8 []uint8
+	if err := readSliceOf(r, &u8, 8); err != nil || !bytes.Equal(u8, data) {
+		t.Fatalf("uint8: got % X, %v", u8, err)
+	}
+
+	r = newByteReader(bytes.NewReader(data[:7]))
+	var u16 []uint16
+	err := readSliceOf(r, &u16, 4)
+	if err == nil || !strings.Contains(err.Error(), "element 3 of 4") {
+		t.Fatalf("short read: got %v", err)
+	}
+
+	for _, src := range [][]int16{nil, i16} {
+		buf.Reset()
+		if err := writeSliceOf(w, src); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.flush(); err != nil {
+			t.Fatal(err)
+		}
+		r = newByteReader(bytes.NewReader(buf.Bytes()))
+		var got []int16
+		if err := readSliceOf(r, &got, len(src)); err != nil || !slices.Equal(got, src) {
+			t.Fatalf("round trip of %v: got %v, %v", src, got, err)
+		}
+	}
+}
+
+func TestWrite_Idempotent(t *testing.T) {
+	forEachReaderKind(t, testWriteIdempotent)
+}
+
+func testWriteIdempotent(t *testing.T, newReader func(b []byte) io.ReadSeeker) {
+	var first, second bytes.Buffer
+	if err := loadGoRegular(t).Write(&first); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(newReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Write(&second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("writing a parsed font again changed it")
+	}
+}
+
+// BenchmarkParseHmtx parses the hmtx table of a CJK font with some 30,000 glyphs.
+func BenchmarkParseHmtx(b *testing.B) {
+	data, err := os.ReadFile("../testdata/NotoSansSC-Bold.ttf")
+	if errors.Is(err, fs.ErrNotExist) {
+		b.Skip("CJK font not available")
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		b.Fatal(err)
+	}
+	tr := f.trec.trMap[tagHmtx]
+	b.SetBytes(int64(tr.length))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := f.parseHmtx(newBytesReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestByteReader_Fork(t *testing.T) {
+	data := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	r := newBytesReader(data)
+	if err := r.SeekTo(4); err != nil {
+		t.Fatal(err)
+	}
+	fork := r.fork()
+	if v, err := fork.readUint16(); err != nil || v != 0x0001 {
+		t.Fatalf("fork read %04X, %v", v, err)
+	}
+	if v, err := r.readUint16(); err != nil || v != 0x0405 {
+		t.Fatalf("forking moved the reader: read %04X, %v", v, err)
+	}
+
+	// A reader of data not held in memory has one offset only.
+	if r := newByteReader(bytes.NewReader(data)); r.fork() != r {
+		t.Fatal("reader of data not held in memory forked")
+	}
+}
+
+// TestFont_ConcurrentReads reads glyphs and tables that are read on demand, layout tables, the
+// table directory and the tables Write copies, from goroutines sharing one font parsed from
+// either kind of reader. Run with -race.
+func TestFont_ConcurrentReads(t *testing.T) {
+	forEachReaderKind(t, testFontConcurrentReads)
+}
+
+func testFontConcurrentReads(t *testing.T, newReader func(b []byte) io.ReadSeeker) {
+	g, layout := layoutFixture(t)
+	var src bytes.Buffer
+	if err := g.Write(&src); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(newReader(src.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.br.data == nil {
+		t.Fatal("font not read into memory")
+	}
+	text := "Hello,world"
+	gids, _ := f.LookupRunes([]rune(text))
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*8)
+	for range 8 {
+		wg.Go(func() {
+			for _, gid := range gids {
+				if _, _, xMax, _, ok := f.GlyphBounds(gid); !ok || xMax <= 0 {
+					errs <- fmt.Errorf("glyph %d: no bounds", gid)
+					return
+				}
+				if data, err := f.GlyphData(gid); err != nil || !bytes.Equal(data, g.glyf.descs[gid].raw) {
+					errs <- fmt.Errorf("glyph %d: data differs, %v", gid, err)
+					return
+				}
+			}
+		})
+		wg.Go(func() {
+			sub, err := f.SubsetWithOptions([]rune(text), SubsetOptions{RetainGIDs: true, Layout: LayoutPassthrough})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(sub.rawTables[0].data, layout[sub.rawTables[0].tag]) {
+				errs <- fmt.Errorf("%s passed through differently", sub.rawTables[0].tag)
+			}
+		})
+		wg.Go(func() {
+			if _, err := f.SizeReport(f); err != nil {
+				errs <- err
+			}
+		})
+		wg.Go(func() {
+			var buf bytes.Buffer
+			if err := f.Write(&buf); err != nil {
+				errs <- err
+			} else if !bytes.Equal(buf.Bytes(), src.Bytes()) {
+				errs <- errors.New("written font differs")
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
@@ -29,6 +29,9 @@ func newByteWriter(w io.Writer) *byteWriter {
 
 func (w *byteWriter) flush() error {
 	b := w.buffer.Bytes()
+	if len(b) == 0 {
+		return nil
+	}
 	n, err := w.w.Write(b)
 	if err != nil {
 		return err
@@ -39,6 +42,11 @@ func (w *byteWriter) flush() error {
 	return nil
 }
 
+// discard drops the current buffer without writing it.
+func (w *byteWriter) discard() {
+	w.buffer.Reset()
+}
+
 // bufferedLen returns the length of the current buffer.
 func (w *byteWriter) bufferedLen() int {
 	return w.buffer.Len()
@@ -46,37 +54,64 @@ func (w *byteWriter) bufferedLen() int {
 
 // checksum returns the checksum of the current buffer.
 func (w *byteWriter) checksum() uint32 {
-	var sum uint32
+	return calcChecksum(w.buffer.Bytes())
+}
 
-	data := w.buffer.Bytes()
+// calcChecksum returns the checksum of table or font data `data`: the sum of its big endian
+// uint32 words, with the final 1-3 bytes padded with zeros to a whole word as per the spec.
+func calcChecksum(data []byte) uint32 {
+	var sum uint32
+	n := len(data) &^ 3
+	for i := 0; i < n; i += 4 {
+		sum += binary.BigEndian.Uint32(data[i:])
+	}
+	if n < len(data) {
+		var last [4]byte
+		copy(last[:], data[n:])
+		sum += binary.BigEndian.Uint32(last[:])
+	}
+	return sum
+}
 
-	if len(data) < 60 {
-		// slog.Debug(fmt.Sprintf("Data: % X", data))
+// The checksums of a font count the checksumAdjustment field of head, at this offset in the
+// table, as 0: head is checksummed before the adjustment is known. The spec only says so for
+// the file checksum, but fontTools and OTS do so for the table record of head too, and so do
+// the Go fonts, so validation, writing and RecalculateChecksums all follow them.
+const headAdjustmentOffset = 8
+
+// tableChecksum returns the checksum of table `tag` with data `data` for its table record,
+// with checksumAdjustment counting as 0 if it is head.
+func tableChecksum(tag Tag, data []byte) uint32 {
+	sum := calcChecksum(data)
+	if tag == tagHead && len(data) >= headAdjustmentOffset+4 {
+		// The field is a whole word of the sum, so subtracting it zeroes it.
+		sum -= binary.BigEndian.Uint32(data[headAdjustmentOffset:])
 	}
-	// slog.Debug(fmt.Sprintf("Data length: %d", len(data)))
-	sum = 0
-
-	for i := 0; i < len(data); i += 4 {
-		a := i
-		b := i + 4
-		if b > len(data) {
-			b = len(data)
-		}
+	return sum
+}
 
-		dup := make([]byte, 4)
-		copy(dup, data[a:b])
+// fileChecksumAdjustment returns the checksumAdjustment of font file `data` with head at
+// `headOffset`: 0xB1B0AFBA minus the checksum of the file with checksumAdjustment counting as 0.
+func fileChecksumAdjustment(data []byte, headOffset int64) uint32 {
+	field := data[headOffset+headAdjustmentOffset : headOffset+headAdjustmentOffset+4]
+	var adjustment [4]byte
+	copy(adjustment[:], field)
+	clear(field)
+	sum := calcChecksum(data)
+	copy(field, adjustment[:])
+	return 0xB1B0AFBA - sum
+}
 
-		if b-a < 4 {
-			for j := 0; j < b-a; j++ {
-				dup = append(dup, 0) //
-			}
+// flushPadded pads the buffer with zeros to a multiple of four bytes and flushes it.
+// Tables are written this way as each must begin on a four byte boundary in the file.
+func (w *byteWriter) flushPadded() error {
+	if rem := w.bufferedLen() % 4; rem != 0 {
+		err := w.writeBytes(make([]byte, 4-rem))
+		if err != nil {
+			return err
 		}
-
-		val := binary.BigEndian.Uint32(dup)
-		sum += val
 	}
-
-	return sum
+	return w.flush()
 }
 
 // writeBytes writes the bytes straight to the buffer.
@@ -98,58 +133,53 @@ func (w *byteWriter) writeSlice(slice interface{}) error {
 
 	switch t := slice.(type) {
 	case []uint8:
-		for _, val := range t {
-			err := w.writeUint8(val)
-			if err != nil {
-				return err
-			}
-		}
+		return w.writeBytes(t)
 	case []uint16:
-		for _, val := range t {
-			err := w.writeUint16(val)
-			if err != nil {
-				return err
-			}
-		}
+		return writeSliceOf(w, t)
 	case []int16:
-		for _, val := range t {
-			err := w.writeInt16(val)
-			if err != nil {
-				return err
-			}
-		}
+		return writeSliceOf(w, t)
 	case []offset16:
-		for _, val := range t {
-			err := w.writeOffset16(val)
-			if err != nil {
-				return err
-			}
-		}
+		return writeSliceOf(w, t)
 	case []offset32:
-		for _, val := range t {
-			err := w.writeOffset32(val)
-			if err != nil {
-				return err
-			}
-		}
+		return writeSliceOf(w, t)
 
 	default:
 		// slog.Error(fmt.Sprintf("Write type check error: %T (slice)", t))
 		return errTypeCheck
 	}
-	return nil
+}
+
+// writeSliceOf writes the values of `src` to `w` (big endian), converted into one buffer.
+func writeSliceOf[T sliceElem](w *byteWriter, src []T) error {
+	size := binary.Size(T(0))
+	b := make([]byte, len(src)*size)
+	switch size {
+	case 1:
+		for i, v := range src {
+			b[i] = byte(v)
+		}
+	case 2:
+		for i, v := range src {
+			binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+		}
+	case 4:
+		for i, v := range src {
+			binary.BigEndian.PutUint32(b[4*i:], uint32(v))
+		}
+	}
+	return w.writeBytes(b)
 }
 
 // Write a series of values to `w`.
 func (w *byteWriter) write(fields ...interface{}) error {
 	for _, f := range fields {
 		switch t := f.(type) {
-		case fixed:
+		case Fixed:
 			err := w.writeFixed(t)
 			if err != nil {
 				return err
 			}
-		case fword:
+		case FWord:
 			err := w.writeFword(t)
 			if err != nil {
 				return err
@@ -169,7 +199,7 @@ func (w *byteWriter) write(fields ...interface{}) error {
 			if err != nil {
 				return err
 			}
-		case ufword:
+		case UFWord:
 			err := w.writeUfword(t)
 			if err != nil {
 				return err
@@ -194,7 +224,7 @@ func (w *byteWriter) write(fields ...interface{}) error {
 			if err != nil {
 				return err
 			}
-		case tag:
+		case Tag:
 			err := w.writeTag(t)
 			if err != nil {
 				return err
@@ -209,7 +239,7 @@ func (w *byteWriter) write(fields ...interface{}) error {
 	return nil
 }
 
-func (w *byteWriter) writeFixed(val fixed) error {
+func (w *byteWriter) writeFixed(val Fixed) error {
 	err := binary.Write(&w.buffer, binary.BigEndian, val)
 	if err != nil {
 		return err
@@ -218,7 +248,7 @@ func (w *byteWriter) writeFixed(val fixed) error {
 	return nil
 }
 
-func (w *byteWriter) writeFword(val fword) error {
+func (w *byteWriter) writeFword(val FWord) error {
 	err := binary.Write(&w.buffer, binary.BigEndian, val)
 	if err != nil {
 		return err
@@ -254,7 +284,7 @@ func (w *byteWriter) writeUint16(vals ...uint16) error {
 	return nil
 }
 
-func (w *byteWriter) writeUfword(val ufword) error {
+func (w *byteWriter) writeUfword(val UFWord) error {
 	err := binary.Write(&w.buffer, binary.BigEndian, val)
 	if err != nil {
 		return err
@@ -281,7 +311,7 @@ func (w *byteWriter) writeUint32(val uint32) error {
 	return nil
 }
 
-func (w *byteWriter) writeTag(val tag) error {
+func (w *byteWriter) writeTag(val Tag) error {
 	err := binary.Write(&w.buffer, binary.BigEndian, val)
 	if err != nil {
 		return err
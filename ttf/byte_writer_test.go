@@ -0,0 +1,454 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/gofont/gosmallcaps"
+)
+
+func TestCalcChecksum(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want uint32
+	}{
+		{nil, 0},
+		{[]byte{0x01}, 0x01000000},
+		{[]byte{0x01, 0x02}, 0x01020000},
+		{[]byte{0x01, 0x02, 0x03}, 0x01020300},
+		{[]byte{0x00, 0x00, 0x00, 0x01, 0xAB}, 0xAB000001},
+		{[]byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC}, 0xACF05678},
+		{[]byte{0x00, 0x00, 0x00, 0x10, 0x01, 0x01, 0x01}, 0x01010110},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x02}, 0x00000001},
+	}
+	for _, tt := range tests {
+		if got := calcChecksum(tt.data); got != tt.want {
+			t.Fatalf("% X: got %08X, want %08X", tt.data, got, tt.want)
+		}
+		padded := append(bytes.Clone(tt.data), make([]byte, (4-len(tt.data)%4)%4)...)
+		if got := calcChecksum(padded); got != tt.want {
+			t.Fatalf("% X padded: got %08X, want %08X", tt.data, got, tt.want)
+		}
+		w := newByteWriter(&bytes.Buffer{})
+		if err := w.writeBytes(tt.data); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.checksum(); got != tt.want {
+			t.Fatalf("% X: byteWriter got %08X, want %08X", tt.data, got, tt.want)
+		}
+	}
+}
+
+// checkDirectory checks the table checksums and checksumAdjustment of font file `data` from
+// scratch, summing the header and table checksums the way fontTools does.
+func checkDirectory(t *testing.T, data []byte) {
+	t.Helper()
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLen := 12 + 16*len(f.trec.list)
+	sum := calcChecksum(data[:headerLen])
+	for _, tr := range f.trec.list {
+		table := bytes.Clone(data[tr.offset : tr.offset+offset32(tr.length)])
+		if tr.tableTag == tagHead {
+			clear(table[8:12])
+		}
+		if got := calcChecksum(table); got != tr.checksum {
+			t.Fatalf("%s (%d bytes): checksum %08X, directory says %08X", tr.tableTag, tr.length, got, tr.checksum)
+		}
+		if tr.offset%4 != 0 {
+			t.Fatalf("%s starts at unaligned offset %d", tr.tableTag, tr.offset)
+		}
+		sum += tr.checksum
+	}
+	head := f.trec.trMap[tagHead]
+	if got, want := 0xB1B0AFBA-sum, binary.BigEndian.Uint32(data[head.offset+8:]); got != want {
+		t.Fatalf("checksumAdjustment %08X, want %08X", want, got)
+	}
+	if err := ValidateBytes(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrite_Checksums(t *testing.T) {
+	checkDirectory(t, goregular.TTF)
+
+	f := loadGoRegular(t)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, buf.Bytes())
+
+	odd := 0
+	for _, tr := range f.trec.list {
+		if tr.length%4 != 0 {
+			odd++
+		}
+	}
+	if odd == 0 {
+		t.Fatal("no table with a partial final block, the test does not cover padding")
+	}
+}
+
+// TestWrite_SubsetChecksums checks the directory and checksumAdjustment of subsets of several
+// fonts, whose tables are partly rewritten and partly copied from the source.
+func TestWrite_SubsetChecksums(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"Go Regular": goregular.TTF, "Go Bold": gobold.TTF, "Go Italic": goitalic.TTF,
+		"Go Mono": gomono.TTF, "Go Smallcaps": gosmallcaps.TTF,
+	} {
+		f, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range []SubsetOptions{DefaultSubsetOptions(), {RetainGIDs: true}} {
+			sub, err := f.SubsetWithOptions([]rune("Hello, world!"), opts)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			var buf bytes.Buffer
+			if err := sub.Write(&buf); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			checkDirectory(t, buf.Bytes())
+		}
+	}
+}
+
+func TestWrite_SkipChecksumAdjustment(t *testing.T) {
+	f := loadGoRegular(t)
+	var full, fast bytes.Buffer
+	if err := f.WriteWithOptions(&full, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteWithOptions(&fast, WriteOptions{SkipChecksumAdjustment: true}); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, full.Bytes())
+
+	g, err := Parse(bytes.NewReader(fast.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := g.trec.trMap[tagHead]
+	if got := binary.BigEndian.Uint32(fast.Bytes()[head.offset+8:]); got != 0 {
+		t.Fatalf("checksumAdjustment %08X, want 0", got)
+	}
+	// The files only differ in checksumAdjustment.
+	data := bytes.Clone(full.Bytes())
+	clear(data[head.offset+8 : head.offset+12])
+	if !bytes.Equal(data, fast.Bytes()) {
+		t.Fatal("files differ beyond checksumAdjustment")
+	}
+	if err := ValidateBytes(fast.Bytes()); err == nil {
+		t.Fatal("font without checksumAdjustment passes validation")
+	}
+}
+
+// TestWrite_LocaFormat checks that head.indexToLocFormat is written as that of the loca offsets,
+// whatever the head of the model says, and that inconsistent loca tables are not written.
+func TestWrite_LocaFormat(t *testing.T) {
+	f := loadGoRegular(t)
+	want := f.glyf.descs
+	for _, format := range []int16{1, 0} {
+		// head keeps the format of the font the loca is rebuilt for.
+		f.loca, _ = buildLoca(f.glyf.descs, format == 0)
+		f.markDirty(tagLoca, tagHead)
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+			t.Fatalf("format %d: %v %v", format, err, rep.Errors())
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.head.indexToLocFormat != format {
+			t.Fatalf("indexToLocFormat %d, want %d", g.head.indexToLocFormat, format)
+		}
+		for gid, desc := range g.glyf.descs {
+			if !bytes.Equal(desc.raw, want[gid].raw) {
+				t.Fatalf("format %d: glyph %d differs", format, gid)
+			}
+		}
+		f = g
+	}
+
+	long, _ := buildLoca(f.glyf.descs, false)
+	for _, tt := range []struct {
+		name string
+		loca locaTable
+		want error
+	}{
+		{"both formats", locaTable{offsetsShort: f.loca.offsetsShort, offsetsLong: long.offsetsLong}, errTypeCheck},
+		{"no offsets", locaTable{}, errTypeCheck},
+		{"offset missing", locaTable{offsetsLong: long.offsetsLong[1:]}, errRangeCheck},
+	} {
+		g := *f
+		g.font = &font{}
+		*g.font = *f.font
+		g.loca = &tt.loca
+		g.markDirty(tagLoca)
+		if err := g.Write(io.Discard); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestWrite_Streams checks that Write hands the font to the writer a table at a time, and so
+// never buffers all of it.
+func TestWrite_Streams(t *testing.T) {
+	f := loadGoRegular(t)
+	var w chunkWriter
+	if err := f.Write(&w); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	glyf := f.trec.trMap[tagGlyf]
+	if !bytes.Equal(w.data, buf.Bytes()) || len(w.chunks) != 1+len(f.tablesToWrite(f.br)) {
+		t.Fatalf("%d bytes in %d writes", len(w.data), len(w.chunks))
+	}
+	if largest := slices.Max(w.chunks); largest != int(glyf.length+3)&^3 {
+		t.Fatalf("largest write of %d bytes, glyf is %d", largest, glyf.length)
+	}
+}
+
+// chunkWriter records the data written to it and the length of each write.
+type chunkWriter struct {
+	data   []byte
+	chunks []int
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.data = append(c.data, p...)
+	c.chunks = append(c.chunks, len(p))
+	return len(p), nil
+}
+
+// BenchmarkWrite writes a 2,000 glyph font, Go Regular with its glyphs repeated, with and
+// without checksumAdjustment. Both cost the same, as the adjustment is summed from the table
+// checksums.
+func BenchmarkWrite(b *testing.B) {
+	const numGlyphs = 2000
+	f := loadGoRegular(b)
+	n := len(f.glyf.descs)
+	for gid := n; gid < numGlyphs; gid++ {
+		f.glyf.descs = append(f.glyf.descs, f.glyf.descs[gid%n])
+		f.hmtx.hMetrics = append(f.hmtx.hMetrics, f.hmtx.hMetrics[gid%n])
+	}
+	f.loca, f.head.indexToLocFormat = buildLoca(f.glyf.descs, false)
+	f.maxp.numGlyphs = numGlyphs
+	f.hhea.numberOfHMetrics = numGlyphs
+
+	for _, opts := range []WriteOptions{{}, {SkipChecksumAdjustment: true}} {
+		b.Run(fmt.Sprintf("SkipChecksumAdjustment=%t", opts.SkipChecksumAdjustment), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := f.WriteWithOptions(io.Discard, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkWrite_CJK writes a CJK font with some 30,000 glyphs to a writer that cannot seek.
+// The writer buffers one table at a time, glyf being the largest, so B/op stays well below
+// font-B, the size of the font, where writing used to buffer the whole font twice.
+func BenchmarkWrite_CJK(b *testing.B) {
+	f, err := ParseFile("../testdata/NotoSansSC-Bold.ttf")
+	if errors.Is(err, fs.ErrNotExist) {
+		b.Skip("CJK font not available")
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	var size countingWriter
+	if err := f.Write(&size); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := f.Write(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(size), "font-B")
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// tableData returns the data of each table of font file `data`.
+func tableData(t *testing.T, data []byte) map[Tag][]byte {
+	t.Helper()
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := make(map[Tag][]byte, len(f.trec.list))
+	for _, tr := range f.trec.list {
+		tables[tr.tableTag] = data[tr.offset : tr.offset+offset32(tr.length)]
+	}
+	return tables
+}
+
+func TestWrite_Passthrough(t *testing.T) {
+	// Go Regular with GSUB and GDEF, which are not parsed.
+	g, layout := layoutFixture(t)
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	src := bytes.Clone(buf.Bytes())
+	f, err := Parse(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.SetNameByID(NameIDFamily, "Went") {
+		t.Fatal("no family name")
+	}
+	buf.Reset()
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, buf.Bytes())
+
+	want, got := tableData(t, src), tableData(t, buf.Bytes())
+	if len(got) != len(want) {
+		t.Fatalf("%d tables, want %d", len(got), len(want))
+	}
+	for tag, data := range want {
+		switch tag {
+		case tagName:
+			if bytes.Equal(got[tag], data) {
+				t.Fatal("name unchanged")
+			}
+		case tagHead:
+			if !bytes.Equal(got[tag][:8], data[:8]) || !bytes.Equal(got[tag][12:], data[12:]) {
+				t.Fatal("head differs beyond checksumAdjustment")
+			}
+		default:
+			if !bytes.Equal(got[tag], data) {
+				t.Fatalf("%s differs", tag)
+			}
+		}
+	}
+	if !bytes.Equal(got[tagGSUB], layout[tagGSUB]) {
+		t.Fatal("GSUB differs from the fixture")
+	}
+	if g, err := Parse(bytes.NewReader(buf.Bytes())); err != nil || g.Name(NameIDFamily) != "Went" {
+		t.Fatalf("family name %q, %v", g.Name(NameIDFamily), err)
+	}
+
+	// Reserializing writes head from the parsed table, and leaves out the tables not parsed.
+	buf.Reset()
+	if err := f.WriteWithOptions(&buf, WriteOptions{Reserialize: true}); err != nil {
+		t.Fatal(err)
+	}
+	got = tableData(t, buf.Bytes())
+	if _, ok := got[tagGSUB]; ok {
+		t.Fatal("GSUB written from the parsed font")
+	}
+}
+
+// TestWrite_HeadLength checks that a head shorter than its 54 bytes is rejected, and that one
+// longer is written with its 54 bytes, checksumAdjustment being patched at a fixed offset.
+func TestWrite_HeadLength(t *testing.T) {
+	withHeadLength := func(length uint32) []byte {
+		data := bytes.Clone(goregular.TTF)
+		for rec := 12; rec < 12+16*int(binary.BigEndian.Uint16(data[4:])); rec += 16 {
+			if string(data[rec:rec+4]) == "head" {
+				binary.BigEndian.PutUint32(data[rec+12:], length)
+			}
+		}
+		return data
+	}
+	for _, length := range []uint32{0, 11, 53} {
+		if _, err := Parse(bytes.NewReader(withHeadLength(length))); !errors.Is(err, errRangeCheck) {
+			t.Fatalf("head of %d bytes: got %v", length, err)
+		}
+	}
+
+	f, err := Parse(bytes.NewReader(withHeadLength(56)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, buf.Bytes())
+	if n := len(tableData(t, buf.Bytes())[tagHead]); n != headLength {
+		t.Fatalf("head of %d bytes written", n)
+	}
+}
+
+// TestWrite_SourceClosed checks that a font parsed from a file writes the same once the file is
+// closed, and that changing the data parsed does not change what is written.
+func TestWrite_SourceClosed(t *testing.T) {
+	var want bytes.Buffer
+	if err := loadGoRegular(t).Write(&want); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "Go-Regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Fatal("written font differs once the file is closed")
+	}
+
+	data := bytes.Clone(goregular.TTF)
+	g, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clear(data)
+	buf.Reset()
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Fatal("written font changed with the data parsed")
+	}
+}
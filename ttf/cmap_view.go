@@ -0,0 +1,42 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"maps"
+	"slices"
+)
+
+// CmapView is a read-only view of the mapping of runes to glyphs of a cmap subtable, see
+// Font.GetCmapView. It shares the mapping of the font without copying it, so that it is cheap
+// to get, and cannot change it. A view stays that of the subtable it was taken of: after
+// RemapCmap, which replaces the subtables, take a new one. The zero CmapView maps no runes.
+type CmapView struct {
+	cmap map[rune]GlyphIndex
+}
+
+// Lookup returns the glyph that `r` maps to. It returns false if `r` is not mapped.
+func (v CmapView) Lookup(r rune) (GlyphIndex, bool) {
+	gid, ok := v.cmap[r]
+	return gid, ok
+}
+
+// Len returns the number of runes mapped.
+func (v CmapView) Len() int {
+	return len(v.cmap)
+}
+
+// Runes returns the runes mapped in ascending order.
+func (v CmapView) Runes() []rune {
+	return slices.Sorted(maps.Keys(v.cmap))
+}
+
+// Each calls `fn` with each rune mapped and its glyph, in no particular order.
+func (v CmapView) Each(fn func(r rune, gid GlyphIndex)) {
+	for r, gid := range v.cmap {
+		fn(r, gid)
+	}
+}
@@ -0,0 +1,67 @@
+package ttf
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFont_GetCmapView(t *testing.T) {
+	f := loadGoRegular(t)
+	view, ok := f.GetCmapView(PlatformWindows, EncodingWindowsUnicodeBMP)
+	internal := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	if !ok || view.Len() != len(internal) {
+		t.Fatalf("view of %d runes %t, want %d", view.Len(), ok, len(internal))
+	}
+	gidA := internal['A']
+	if gid, ok := view.Lookup('A'); !ok || gid != gidA {
+		t.Fatalf("'A': %d %t, want %d", gid, ok, gidA)
+	}
+	runes := view.Runes()
+	if len(runes) != view.Len() || !slices.IsSorted(runes) {
+		t.Fatalf("runes %U", runes)
+	}
+	n := 0
+	view.Each(func(r rune, gid GlyphIndex) {
+		if internal[r] != gid {
+			t.Fatalf("%U: %d, want %d", r, gid, internal[r])
+		}
+		n++
+	})
+	if n != view.Len() {
+		t.Fatalf("Each visited %d of %d runes", n, view.Len())
+	}
+
+	if view, ok := f.GetCmapView(PlatformWindows, EncodingWindowsUnicodeUCS4); ok || view.Len() != 0 || len(view.Runes()) != 0 {
+		t.Fatalf("(3,10): %d runes %t", view.Len(), ok)
+	}
+	if _, ok := (CmapView{}).Lookup('A'); ok {
+		t.Fatal("zero view maps 'A'")
+	}
+
+	// Changing what GetCmap returns no longer changes the font.
+	cmap := f.GetCmap(3, 1)
+	delete(cmap, 'A')
+	cmap['B'] = 0
+	gids, found := f.LookupRunes([]rune("AB"))
+	if len(found) != 2 || gids[0] != gidA || gids[1] == 0 {
+		t.Fatalf("LookupRunes after changing GetCmap: %v %q", gids, found)
+	}
+	sub, err := f.Subset([]rune("AB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := sub.GetCmapView(PlatformWindows, EncodingWindowsUnicodeBMP); !slices.Contains(v.Runes(), 'A') {
+		t.Fatalf("subset maps %q", v.Runes())
+	}
+
+	// A view stays that of its subtable.
+	if err := f.RemapCmap(map[rune]GlyphIndex{'A': 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	if gid, _ := view.Lookup('A'); gid != gidA {
+		t.Fatalf("old view: 'A' maps to %d", gid)
+	}
+	if v, _ := f.GetCmapView(PlatformWindows, EncodingWindowsUnicodeBMP); v.Len() != 1 {
+		t.Fatalf("new view of %d runes", v.Len())
+	}
+}
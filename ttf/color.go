@@ -0,0 +1,200 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ColorFormat flags the formats of the color glyphs of a font, see Font.HasColorGlyphs.
+type ColorFormat uint8
+
+// ColorFormat flags.
+const (
+	ColorCOLRv0 ColorFormat = 1 << iota // Layers of glyphs in the colors of CPAL palettes.
+	ColorCOLRv1                         // Paint graphs with gradients of COLR version 1.
+	ColorSbix                           // Bitmaps of sbix, e.g. PNG, as on Apple platforms.
+	ColorCBDT                           // Bitmaps of CBDT and CBLC, as on Android.
+	ColorSVG                            // SVG documents.
+)
+
+var colorFormatNames = []string{"COLRv0", "COLRv1", "sbix", "CBDT", "SVG"}
+
+// String returns the names of the flags of `c` joined by "|", e.g. "COLRv0|SVG".
+func (c ColorFormat) String() string {
+	var names []string
+	for i, name := range colorFormatNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if rest := c &^ (1<<len(colorFormatNames) - 1); rest != 0 || len(names) == 0 {
+		names = append(names, fmt.Sprintf("ColorFormat(%#x)", uint8(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// colorTags are the tables of color glyphs. They refer to glyphs by index, so subsetting drops
+// them unless SubsetOptions.PreserveColor keeps them.
+var colorTags = []Tag{tagCOLR, tagCPAL, tagSbix, tagCBDT, tagCBLC, tagSVG}
+
+// parseColorFormat returns the formats of the color glyphs of `f` by its tables, reading the
+// version of COLR from `r`.
+func (f *font) parseColorFormat(r *byteReader) (ColorFormat, error) {
+	var c ColorFormat
+	_, has, err := f.seekToTable(r, tagCOLR)
+	if err != nil {
+		return 0, err
+	}
+	if has {
+		var version uint16
+		if err := r.read(&version); err != nil {
+			return 0, err
+		}
+		if version == 0 {
+			c |= ColorCOLRv0
+		} else {
+			c |= ColorCOLRv1
+		}
+	}
+	for _, t := range []struct {
+		tag    Tag
+		format ColorFormat
+	}{{tagSbix, ColorSbix}, {tagCBDT, ColorCBDT}, {tagSVG, ColorSVG}} {
+		if f.trec.HasTag(t.tag) {
+			c |= t.format
+		}
+	}
+	return c, nil
+}
+
+// HasColorGlyphs returns the formats of the color glyphs of `f` and true if it has any. Subset
+// drops them, noting so in the Warnings of the subset, unless SubsetOptions.PreserveColor is
+// set, so that a subset without color glyphs has outlines only.
+func (f *Font) HasColorGlyphs() (ColorFormat, bool) {
+	return f.color, f.color != 0
+}
+
+// colorTables returns the tags of the color tables of `f`, see colorTags.
+func (f *Font) colorTables() []Tag {
+	if f.br == nil {
+		// Made by subsetting, see rawTableData.
+		return slices.DeleteFunc(slices.Clone(colorTags), func(tag Tag) bool {
+			return !slices.ContainsFunc(f.rawTables, func(t rawTable) bool { return t.tag == tag })
+		})
+	}
+	return slices.DeleteFunc(slices.Clone(colorTags), func(tag Tag) bool { return !f.trec.HasTag(tag) })
+}
+
+// colrV0 is the data of a COLR table of version 0: each base glyph drawn as layers of other
+// glyphs filled with colors of the CPAL palettes.
+// https://learn.microsoft.com/en-us/typography/opentype/spec/colr
+type colrV0 struct {
+	layers map[GlyphIndex][]colrLayer // By base glyph.
+}
+
+// colrLayer is a layer of a base glyph of COLR, glyph `gid` filled with palette entry
+// `palette`, 0xFFFF for the text color.
+type colrLayer struct {
+	gid     GlyphIndex
+	palette uint16
+}
+
+// parseColrV0 parses COLR table `data` of version 0 for the glyphs below `numGlyphs`.
+func parseColrV0(data []byte, numGlyphs int) (*colrV0, error) {
+	d := &layoutReader{data: data, numGlyphs: numGlyphs}
+	if version := d.u16(0); d.err == nil && version != 0 {
+		return nil, fmt.Errorf("COLR version %d, not 0: %w", version, errRangeCheck)
+	}
+	numBase, baseOffset := int(d.u16(2)), int(d.u32(4))
+	layerOffset, numLayers := int(d.u32(8)), int(d.u16(12))
+	if !d.has(baseOffset, 6*numBase) || !d.has(layerOffset, 4*numLayers) {
+		return nil, fmt.Errorf("COLR: %w", d.err)
+	}
+
+	colr := &colrV0{layers: make(map[GlyphIndex][]colrLayer, numBase)}
+	for i := range numBase {
+		rec := baseOffset + 6*i
+		gid, first, n := int(d.u16(rec)), int(d.u16(rec+2)), int(d.u16(rec+4))
+		if first+n > numLayers {
+			return nil, fmt.Errorf("COLR: layers %d to %d of glyph %d past %d layers: %w", first, first+n, gid, numLayers, errRangeCheck)
+		}
+		if gid >= numGlyphs {
+			continue
+		}
+		layers := make([]colrLayer, 0, n)
+		for j := first; j < first+n; j++ {
+			layer := colrLayer{GlyphIndex(d.u16(layerOffset + 4*j)), d.u16(layerOffset + 4*j + 2)}
+			if int(layer.gid) >= numGlyphs {
+				return nil, fmt.Errorf("COLR: layer glyph %d of glyph %d past %d glyphs: %w", layer.gid, gid, numGlyphs, errRangeCheck)
+			}
+			layers = append(layers, layer)
+		}
+		colr.layers[GlyphIndex(gid)] = layers
+	}
+	return colr, nil
+}
+
+// layerGlyphs returns the glyphs of the layers of base glyphs `gids`, in order, once each.
+func (c *colrV0) layerGlyphs(gids []GlyphIndex) []GlyphIndex {
+	var layers []GlyphIndex
+	for _, gid := range gids {
+		for _, layer := range c.layers[gid] {
+			if !slices.Contains(layers, layer.gid) {
+				layers = append(layers, layer.gid)
+			}
+		}
+	}
+	return layers
+}
+
+// subset returns the data of a COLR table of version 0 with the base glyphs of `c` in `newGID`,
+// renumbered by it along with their layers, which must be in `newGID` too.
+func (c *colrV0) subset(newGID map[GlyphIndex]GlyphIndex) []byte {
+	type base struct {
+		gid    GlyphIndex
+		layers []colrLayer
+	}
+	var bases []base
+	for gid, layers := range c.layers {
+		if ngid, ok := newGID[gid]; ok {
+			bases = append(bases, base{ngid, layers})
+		}
+	}
+	// Base glyph records are searched by glyph index.
+	slices.SortFunc(bases, func(a, b base) int { return cmp.Compare(a.gid, b.gid) })
+
+	const headerLen = 14
+	numLayers := 0
+	for _, b := range bases {
+		numLayers += len(b.layers)
+	}
+	layerOffset := headerLen + 6*len(bases)
+	data := make([]byte, 0, layerOffset+4*numLayers)
+	data = binary.BigEndian.AppendUint16(data, 0)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(bases)))
+	data = binary.BigEndian.AppendUint32(data, headerLen)
+	data = binary.BigEndian.AppendUint32(data, uint32(layerOffset))
+	data = binary.BigEndian.AppendUint16(data, uint16(numLayers))
+	first := 0
+	for _, b := range bases {
+		data = binary.BigEndian.AppendUint16(data, uint16(b.gid))
+		data = binary.BigEndian.AppendUint16(data, uint16(first))
+		data = binary.BigEndian.AppendUint16(data, uint16(len(b.layers)))
+		first += len(b.layers)
+	}
+	for _, b := range bases {
+		for _, layer := range b.layers {
+			data = binary.BigEndian.AppendUint16(data, uint16(newGID[layer.gid]))
+			data = binary.BigEndian.AppendUint16(data, layer.palette)
+		}
+	}
+	return data
+}
@@ -0,0 +1,150 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// colorFixture returns Go Regular with color glyphs like those of a COLR emoji font: 'A' drawn
+// as 'B' and 'C' in the two colors of a CPAL palette, and 'D' as 'E' in the text color, as
+// written and parsed again, with the glyphs of those runes.
+func colorFixture(t *testing.T, extra ...rawTable) (*Font, map[rune]GlyphIndex) {
+	t.Helper()
+	f := loadGoRegular(t)
+	gids := make(map[rune]GlyphIndex)
+	indices, runes := f.LookupRunes([]rune("ABCDE"))
+	for i, r := range runes {
+		gids[r] = indices[i]
+	}
+
+	be := binary.BigEndian
+	colr := be.AppendUint16(nil, 0)
+	colr = be.AppendUint16(colr, 2)
+	colr = be.AppendUint32(colr, 14)
+	colr = be.AppendUint32(colr, 14+2*6)
+	colr = be.AppendUint16(colr, 3)
+	for _, rec := range [][3]uint16{{uint16(gids['A']), 0, 2}, {uint16(gids['D']), 2, 1}} {
+		colr = be.AppendUint16(be.AppendUint16(be.AppendUint16(colr, rec[0]), rec[1]), rec[2])
+	}
+	for _, layer := range [][2]uint16{{uint16(gids['B']), 0}, {uint16(gids['C']), 1}, {uint16(gids['E']), 0xFFFF}} {
+		colr = be.AppendUint16(be.AppendUint16(colr, layer[0]), layer[1])
+	}
+	// One palette of red and blue, as BGRA.
+	cpal := []byte{0, 0, 0, 2, 0, 1, 0, 2, 0, 0, 0, 14, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0, 0, 0xFF}
+
+	f.rawTables = append(f.rawTables, rawTable{tag: tagCOLR, data: colr}, rawTable{tag: tagCPAL, data: cpal})
+	f.rawTables = append(f.rawTables, extra...)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, gids
+}
+
+func TestFont_HasColorGlyphs(t *testing.T) {
+	if c, ok := loadGoRegular(t).HasColorGlyphs(); ok || c != 0 {
+		t.Fatalf("Go Regular: HasColorGlyphs() = %v, %v", c, ok)
+	}
+	f, _ := colorFixture(t)
+	if c, ok := f.HasColorGlyphs(); !ok || c != ColorCOLRv0 {
+		t.Fatalf("HasColorGlyphs() = %v, %v", c, ok)
+	}
+	f, _ = colorFixture(t, rawTable{tag: tagSbix, data: []byte{0, 1, 0, 1, 0, 0, 0, 0}}, rawTable{tag: tagSVG, data: []byte{0, 0}})
+	if c, ok := f.HasColorGlyphs(); !ok || c != ColorCOLRv0|ColorSbix|ColorSVG || c.String() != "COLRv0|sbix|SVG" {
+		t.Fatalf("HasColorGlyphs() = %v, %v", c, ok)
+	}
+	if s := (ColorCBDT | 0x80).String(); s != "CBDT|ColorFormat(0x80)" {
+		t.Fatalf("String() = %q", s)
+	}
+}
+
+func TestFont_SubsetColor(t *testing.T) {
+	f, gids := colorFixture(t)
+	subset := func(runes string, opts SubsetOptions) (*Font, []byte) {
+		t.Helper()
+		sub, err := f.SubsetWithOptions([]rune(runes), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateBytes(buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		return sub, buf.Bytes()
+	}
+
+	// Stripped by default.
+	sub, data := subset("A", DefaultSubsetOptions())
+	tables := tableData(t, data)
+	if _, ok := tables[tagCOLR]; ok {
+		t.Fatal("expected COLR to be dropped")
+	}
+	if _, ok := tables[tagCPAL]; ok {
+		t.Fatal("expected CPAL to be dropped")
+	}
+	if c, ok := sub.HasColorGlyphs(); ok {
+		t.Fatalf("subset: HasColorGlyphs() = %v, %v", c, ok)
+	}
+	if !slices.Contains(sub.Warnings(), "dropped color tables COLR, CPAL (COLRv0)") {
+		t.Fatalf("subset warnings %q", sub.Warnings())
+	}
+	rep, err := f.SizeReport(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := slices.IndexFunc(rep.Tables, func(ts TableSize) bool { return ts.Tag == tagCOLR }); i < 0 || rep.Tables[i].Result != 0 {
+		t.Fatalf("size report:\n%s", rep)
+	}
+
+	// COLR version 0 is subset with the layer glyphs.
+	opts := DefaultSubsetOptions()
+	opts.PreserveColor = true
+	sub, data = subset("A", opts)
+	if c, ok := sub.HasColorGlyphs(); !ok || c != ColorCOLRv0 || len(sub.Warnings()) != 0 {
+		t.Fatalf("subset: HasColorGlyphs() = %v, %v, warnings %q", c, ok, sub.Warnings())
+	}
+	tables = tableData(t, data)
+	if cpal, err := f.rawTableData(tagCPAL); err != nil || !bytes.Equal(tables[tagCPAL], cpal) {
+		t.Fatalf("subset CPAL is % X, want % X (%v)", tables[tagCPAL], cpal, err)
+	}
+	sub, err = Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices, _ := sub.LookupRunes([]rune("A"))
+	colr, err := parseColrV0(tables[tagCOLR], int(sub.maxp.numGlyphs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := colr.layers[indices[0]]
+	if len(colr.layers) != 1 || len(layers) != 2 || layers[0].palette != 0 || layers[1].palette != 1 {
+		t.Fatalf("subset COLR layers %v", colr.layers)
+	}
+	for i, r := range "BC" {
+		want := f.glyf.descs[gids[r]].raw
+		if got := sub.glyf.descs[layers[i].gid].raw; !bytes.Equal(bytes.TrimRight(got, "\x00"), bytes.TrimRight(want, "\x00")) {
+			t.Fatalf("layer %d is not the glyph of %q", i, r)
+		}
+	}
+
+	// Other formats cannot be preserved yet, but are stripped.
+	f, _ = colorFixture(t, rawTable{tag: tagSbix, data: []byte{0, 1, 0, 1, 0, 0, 0, 0}})
+	if _, err := f.SubsetWithOptions([]rune("A"), opts); !errors.Is(err, ErrColorUnsupported) || !strings.Contains(err.Error(), "COLRv0|sbix") {
+		t.Fatalf("got error %v, want ErrColorUnsupported", err)
+	}
+	sub, _ = subset("A", DefaultSubsetOptions())
+	if !slices.Contains(sub.Warnings(), "dropped color tables COLR, CPAL, sbix (COLRv0|sbix)") {
+		t.Fatalf("subset warnings %q", sub.Warnings())
+	}
+}
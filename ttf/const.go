@@ -13,4 +13,23 @@ var (
 	errInvalidContext = errors.New("invalid context")
 	errRequiredField  = errors.New("required field missing")
 	errNilReceiver    = errors.New("receiver pointer not initialized")
+	errInvalidUTF16   = errors.New("invalid UTF-16")
+	errInvalidOptions = errors.New("invalid options")
 )
+
+// ErrNoCmap is returned when runes are to be looked up in a font without a Unicode, Mac Roman
+// or Windows Symbol cmap subtable, such as a CID-keyed font, see Font.HasCmap. Such fonts can
+// only be subset by glyph index, see Font.SubsetGIDs.
+var ErrNoCmap = errors.New("no cmap to look up runes")
+
+// ErrColorUnsupported is returned when subsetting with SubsetOptions.PreserveColor a font with
+// color glyphs in a format other than COLR version 0, see Font.HasColorGlyphs.
+var ErrColorUnsupported = errors.New("color glyphs cannot be preserved")
+
+// ErrBitmapsUnsupported is returned when subsetting with SubsetOptions.KeepBitmaps a font with
+// embedded bitmaps, see Font.HasBitmapStrikes.
+var ErrBitmapsUnsupported = errors.New("embedded bitmaps cannot be kept")
+
+// ErrRescaleUnsupported is returned by Font.Rescale for fonts with values in font units that it
+// cannot scale, such as CFF outlines or the tables of variable fonts.
+var ErrRescaleUnsupported = errors.New("font cannot be rescaled")
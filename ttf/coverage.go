@@ -0,0 +1,208 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"cmp"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+	"unicode"
+)
+
+// Coverage is the number of characters of a Unicode block or script that a font maps.
+type Coverage struct {
+	Covered int // Characters the cmap maps to a glyph other than .notdef.
+	Total   int // Characters of the block or script.
+}
+
+// Ratio returns the fraction of the characters that are covered, 0 if there are none.
+func (c Coverage) Ratio() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Covered) / float64(c.Total)
+}
+
+// runeRange is a range of runes from `lo` to `hi` inclusive of a Unicode block or script.
+type runeRange struct {
+	lo, hi rune
+	name   string
+}
+
+// findRuneRange returns the range of sorted, disjoint `ranges` that contains `r`.
+func findRuneRange(ranges []runeRange, r rune) (runeRange, bool) {
+	i, found := slices.BinarySearchFunc(ranges, r, func(rr runeRange, r rune) int {
+		switch {
+		case rr.hi < r:
+			return -1
+		case rr.lo > r:
+			return 1
+		}
+		return 0
+	})
+	if !found {
+		return runeRange{}, false
+	}
+	return ranges[i], true
+}
+
+// rangeTableRanges appends the ranges of `table` named `name` to `ranges`.
+func rangeTableRanges(ranges []runeRange, table *unicode.RangeTable, name string) []runeRange {
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			ranges = append(ranges, runeRange{lo, hi, name})
+			return
+		}
+		for r := lo; r <= hi; r += stride {
+			ranges = append(ranges, runeRange{r, r, name})
+		}
+	}
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return ranges
+}
+
+// sortRuneRanges sorts `ranges` and merges adjacent ones of the same name.
+func sortRuneRanges(ranges []runeRange) []runeRange {
+	slices.SortFunc(ranges, func(a, b runeRange) int { return cmp.Compare(a.lo, b.lo) })
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].name == r.name && merged[n-1].hi+1 >= r.lo {
+			merged[n-1].hi = max(merged[n-1].hi, r.hi)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// assignedRanges are the characters assigned in the Unicode version of package unicode other
+// than surrogates, which fonts cannot map, as unnamed ranges.
+var assignedRanges = sync.OnceValue(func() []runeRange {
+	var ranges []runeRange
+	for name, table := range unicode.Categories {
+		// The general categories, not their unions such as "L".
+		if len(name) == 2 && name != "Cs" && name != "LC" {
+			ranges = rangeTableRanges(ranges, table, "")
+		}
+	}
+	return sortRuneRanges(ranges)
+})
+
+// scriptRanges are the ranges of the scripts of package unicode, e.g. "Latin".
+var scriptRanges = sync.OnceValue(func() []runeRange {
+	var ranges []runeRange
+	for _, name := range slices.Sorted(maps.Keys(unicode.Scripts)) {
+		ranges = rangeTableRanges(ranges, unicode.Scripts[name], name)
+	}
+	return sortRuneRanges(ranges)
+})
+
+// blockTotals are the number of assigned characters of each Unicode block with any.
+var blockTotals = sync.OnceValue(func() map[string]int {
+	totals := make(map[string]int)
+	assigned := assignedRanges()
+	for _, block := range unicodeBlocks {
+		i, _ := slices.BinarySearchFunc(assigned, block.lo, func(rr runeRange, r rune) int { return cmp.Compare(rr.hi, r) })
+		for ; i < len(assigned) && assigned[i].lo <= block.hi; i++ {
+			totals[block.name] += int(min(assigned[i].hi, block.hi)-max(assigned[i].lo, block.lo)) + 1
+		}
+	}
+	return totals
+})
+
+// coveredRunes yields the runes that the cmap subtables of `f` searched by LookupRunes map to
+// a glyph other than .notdef, each once in no particular order.
+func (f *Font) coveredRunes() iter.Seq[rune] {
+	return func(yield func(rune) bool) {
+		cmaps := f.lookupCmaps()
+		for i, cmap := range cmaps {
+			for r, gid := range cmap {
+				if gid == 0 {
+					continue
+				}
+				// The first subtable that maps `r` decides, as in lookupRune.
+				if _, ok := lookupRune(cmaps[:i], r); ok {
+					continue
+				}
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// CoverageByBlock returns the coverage of each Unicode block of Unicode 14.0 with assigned
+// characters by the cmap subtables of `f` that LookupRunes searches, by block name, e.g.
+// "Basic Latin" or "CJK Unified Ideographs". Only assigned characters count.
+func (f *Font) CoverageByBlock() map[string]Coverage {
+	totals := blockTotals()
+	coverage := make(map[string]Coverage, len(totals))
+	for name, total := range totals {
+		coverage[name] = Coverage{Total: total}
+	}
+	assigned := assignedRanges()
+	for r := range f.coveredRunes() {
+		if _, ok := findRuneRange(assigned, r); !ok {
+			continue
+		}
+		if block, ok := findRuneRange(unicodeBlocks, r); ok {
+			c := coverage[block.name]
+			c.Covered++
+			coverage[block.name] = c
+		}
+	}
+	return coverage
+}
+
+// CoverageByScript returns the coverage of each script of package unicode like
+// CoverageByBlock, by script name, e.g. "Latin" or "Han". Characters of no specific script are
+// in "Common" and "Inherited".
+func (f *Font) CoverageByScript() map[string]Coverage {
+	ranges := scriptRanges()
+	coverage := make(map[string]Coverage, len(unicode.Scripts))
+	for _, r := range ranges {
+		c := coverage[r.name]
+		c.Total += int(r.hi-r.lo) + 1
+		coverage[r.name] = c
+	}
+	for r := range f.coveredRunes() {
+		if script, ok := findRuneRange(ranges, r); ok {
+			c := coverage[script.name]
+			c.Covered++
+			coverage[script.name] = c
+		}
+	}
+	return coverage
+}
+
+// CoverageOf returns the distinct runes of `text` that the cmap subtables of `f` that
+// LookupRunes searches map to a glyph of the font other than .notdef, and those it does not,
+// each in the order of their first occurrence. Control characters such as '\n' count like any
+// other.
+func (f *Font) CoverageOf(text string) (covered, missing []rune) {
+	cmaps := f.lookupCmaps()
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		if gid, _ := lookupRune(cmaps, r); gid != 0 && f.ValidGID(gid) {
+			covered = append(covered, r)
+		} else {
+			missing = append(missing, r)
+		}
+	}
+	return covered, missing
+}
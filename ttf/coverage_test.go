@@ -0,0 +1,76 @@
+package ttf
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFont_CoverageByBlock(t *testing.T) {
+	f := loadGoRegular(t)
+	blocks := f.CoverageByBlock()
+	for _, tt := range []struct {
+		block string
+		want  Coverage
+	}{
+		// The printable characters and NUL, backspace, tab, carriage return and group separator.
+		{"Basic Latin", Coverage{95 + 5, 128}},
+		{"CJK Unified Ideographs", Coverage{0, 0x9FFF - 0x4E00 + 1}},
+	} {
+		if got := blocks[tt.block]; got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.block, got, tt.want)
+		}
+	}
+	if got := blocks["CJK Unified Ideographs"].Ratio(); got != 0 {
+		t.Fatalf("CJK Unified Ideographs: ratio %v", got)
+	}
+	if _, ok := blocks["High Surrogates"]; ok {
+		t.Fatal("block of surrogates")
+	}
+	scripts := f.CoverageByScript()
+	if got := scripts["Han"]; got.Covered != 0 || got.Total == 0 {
+		t.Fatalf("Han: got %+v", got)
+	}
+	if got := scripts["Latin"]; got.Covered < 52 || got.Covered > got.Total {
+		t.Fatalf("Latin: got %+v", got)
+	}
+
+	// A font mapping 20000 ideographs, as a CJK font does.
+	mapping := make(map[rune]GlyphIndex)
+	for i := range 20000 {
+		mapping[0x4E00+rune(i)] = GlyphIndex(1 + i%700)
+	}
+	if err := f.RemapCmap(mapping, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.CoverageByBlock()["CJK Unified Ideographs"]; got.Covered != 20000 {
+		t.Fatalf("CJK Unified Ideographs: got %+v", got)
+	}
+	if got := f.CoverageByScript()["Han"]; got.Covered != 20000 {
+		t.Fatalf("Han: got %+v", got)
+	}
+}
+
+func TestFont_CoverageOf(t *testing.T) {
+	f := loadGoRegular(t)
+	covered, missing := f.CoverageOf("Hello, 世界! Hello\n")
+	if want := []rune("Helo, !"); !slices.Equal(covered, want) {
+		t.Fatalf("covered %q, want %q", string(covered), string(want))
+	}
+	if want := []rune("世界\n"); !slices.Equal(missing, want) {
+		t.Fatalf("missing %q, want %q", string(missing), string(want))
+	}
+}
+
+func BenchmarkFont_CoverageByBlock(b *testing.B) {
+	f := loadGoRegular(b)
+	mapping := make(map[rune]GlyphIndex)
+	for i := range 20000 {
+		mapping[0x4E00+rune(i)] = GlyphIndex(1 + i%700)
+	}
+	if err := f.RemapCmap(mapping, false); err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		f.CoverageByBlock()
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fontFaceFormats are the file formats that fonts can be written in for FontFaceCSS, in the
+// order browsers should try them: their file extension and CSS format name. Write writes
+// TrueType only; WOFF2 and WOFF go first once there are writers for them.
+var fontFaceFormats = []struct{ ext, format string }{
+	{"ttf", "truetype"},
+}
+
+// fontStretches are the font-stretch percentages of the width classes.
+var fontStretches = map[WidthClass]string{
+	WidthUltraCondensed: "50%",
+	WidthExtraCondensed: "62.5%",
+	WidthCondensed:      "75%",
+	WidthSemiCondensed:  "87.5%",
+	WidthSemiExpanded:   "112.5%",
+	WidthExpanded:       "125%",
+	WidthExtraExpanded:  "150%",
+	WidthUltraExpanded:  "200%",
+}
+
+// FontFaceCSS returns an @font-face rule for each of `fonts`, such as the subsets of Slice, in
+// the same order. Nil fonts, such as those of SubsetAll for unused fonts, are skipped.
+//
+// The font-family is `family`, or the first family name of `fonts` if empty. The src lists a
+// URL for each format that the fonts can be written in, from `urlTemplate` with "{index}"
+// replaced by the index of the font in `fonts` and "{ext}" by the file extension of the format,
+// e.g. "fonts/go-{index}.{ext}". The font-weight, font-style and font-stretch are those of
+// Metadata and WidthClass, and the unicode-range is the runes the font maps other than control
+// characters, which are not rendered. A font that maps no other runes is an error, as its rule
+// would be used for all of them.
+func FontFaceCSS(fonts []*Font, family, urlTemplate string) (string, error) {
+	if family == "" {
+		for _, f := range fonts {
+			if f != nil && f.Metadata().Family != "" {
+				family = f.Metadata().Family
+				break
+			}
+		}
+	}
+	if family == "" {
+		return "", fmt.Errorf("font-family: %w", errRequiredField)
+	}
+
+	var b strings.Builder
+	for i, f := range fonts {
+		if f == nil {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		m := f.Metadata()
+		weight := m.WeightClass
+		if m.Completeness&CompleteOS2 == 0 {
+			weight = uint16(WeightNormal)
+			if m.Bold {
+				weight = uint16(WeightBold)
+			}
+		}
+		style := "normal"
+		if st, ok := f.Style(); ok && st.Oblique {
+			style = "oblique"
+		}
+		if m.Italic {
+			style = "italic"
+		}
+
+		b.WriteString("@font-face {\n")
+		fmt.Fprintf(&b, "  font-family: %s;\n", cssString(family))
+		fmt.Fprintf(&b, "  font-style: %s;\n", style)
+		fmt.Fprintf(&b, "  font-weight: %d;\n", min(max(weight, uint16(WeightThin)), uint16(WeightBlack)))
+		if width, ok := f.WidthClass(); ok && width != WidthNormal {
+			fmt.Fprintf(&b, "  font-stretch: %s;\n", fontStretches[width])
+		}
+		srcs := make([]string, len(fontFaceFormats))
+		for j, format := range fontFaceFormats {
+			url := strings.NewReplacer("{index}", strconv.Itoa(i), "{ext}", format.ext).Replace(urlTemplate)
+			srcs[j] = fmt.Sprintf("url(%s) format(%s)", cssString(url), cssString(format.format))
+		}
+		fmt.Fprintf(&b, "  src: %s;\n", strings.Join(srcs, ", "))
+		ranges := f.unicodeRange()
+		if ranges == "" {
+			// Without a unicode-range the rule would be used for every rune.
+			return "", fmt.Errorf("font %d: unicode-range: no runes: %w", i, errRequiredField)
+		}
+		fmt.Fprintf(&b, "  unicode-range: %s;\n", ranges)
+		b.WriteString("}\n")
+	}
+	return b.String(), nil
+}
+
+// unicodeRange returns the runes of `f` that coveredRunes yields other than control characters
+// as a CSS unicode-range, e.g. "U+20-7E, U+A0", or "" if there are none.
+func (f *Font) unicodeRange() string {
+	var runes []rune
+	for r := range f.coveredRunes() {
+		if !unicode.IsControl(r) {
+			runes = append(runes, r)
+		}
+	}
+	slices.Sort(runes)
+
+	var ranges []string
+	for i := 0; i < len(runes); {
+		j := i
+		for j+1 < len(runes) && runes[j+1] == runes[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("U+%X", runes[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("U+%X-%X", runes[i], runes[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// cssString returns `s` as a double-quoted CSS string.
+func cssString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+			// A hex escape ends at a space.
+			fmt.Fprintf(&b, "\\%X ", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
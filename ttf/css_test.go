@@ -0,0 +1,221 @@
+package ttf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/gomedium"
+)
+
+// cssDeclarations tokenizes `css` as a list of @font-face rules and returns the declarations of
+// each, the tokens of the value by property name. Strings are unquoted.
+func cssDeclarations(t *testing.T, css string) []map[string][]string {
+	t.Helper()
+	var tokens []string
+	for i := 0; i < len(css); {
+		c := css[i]
+		switch {
+		case c == ' ' || c == '\n':
+			i++
+		case strings.IndexByte("{}:;,()", c) >= 0:
+			tokens = append(tokens, css[i:i+1])
+			i++
+		case c == '"':
+			var s strings.Builder
+			j := i + 1
+			for ; j < len(css) && css[j] != '"'; j++ {
+				if css[j] == '\n' {
+					t.Fatalf("newline in string at %d", j)
+				}
+				if css[j] == '\\' {
+					j++
+					// A hex escape of up to six digits ends at a space.
+					k := j
+					for k < len(css) && k-j < 6 && strings.IndexByte("0123456789ABCDEFabcdef", css[k]) >= 0 {
+						k++
+					}
+					if k > j {
+						r, _ := strconv.ParseUint(css[j:k], 16, 32)
+						s.WriteRune(rune(r))
+						if k < len(css) && css[k] == ' ' {
+							k++
+						}
+						j = k - 1
+						continue
+					}
+				}
+				s.WriteByte(css[j])
+			}
+			if j == len(css) {
+				t.Fatalf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, "\""+s.String())
+			i = j + 1
+		default:
+			j := i
+			for j < len(css) && strings.IndexByte(" \n{}:;,()\"", css[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, css[i:j])
+			i = j
+		}
+	}
+
+	var rules []map[string][]string
+	next := func(want string) {
+		t.Helper()
+		if len(tokens) == 0 || want != "" && tokens[0] != want {
+			t.Fatalf("want %q, got %q", want, tokens)
+		}
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 {
+		next("@font-face")
+		next("{")
+		decls := map[string][]string{}
+		for len(tokens) > 0 && tokens[0] != "}" {
+			name := tokens[0]
+			if !isCSSIdent(name) {
+				t.Fatalf("property %q", name)
+			}
+			if _, dup := decls[name]; dup {
+				t.Fatalf("duplicate %s", name)
+			}
+			next("")
+			next(":")
+			var value []string
+			for len(tokens) > 0 && tokens[0] != ";" && tokens[0] != "}" {
+				value = append(value, tokens[0])
+				next("")
+			}
+			next(";")
+			if len(value) == 0 {
+				t.Fatalf("empty %s", name)
+			}
+			decls[name] = value
+		}
+		next("}")
+		rules = append(rules, decls)
+	}
+	return rules
+}
+
+func isCSSIdent(s string) bool {
+	return s != "" && strings.IndexFunc(s, func(r rune) bool { return r != '-' && !unicode.IsLetter(r) }) < 0
+}
+
+func TestFontFaceCSS(t *testing.T) {
+	// Subsets of fonts of other weights than 400, whose OS/2 table the subsets keep.
+	f, err := Parse(bytes.NewReader(gomedium.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subsets, manifest, err := f.Slice([][2]rune{{0, 0x7F}, {0x80, 0xFFFF}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bold, err := Parse(bytes.NewReader(gobolditalic.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bold, err = bold.SubsetWithOptions([]rune("Go Bold Italic"), SubsetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fonts := append(subsets, nil, bold)
+
+	css, err := FontFaceCSS(fonts, "", "fonts/go-{index}.{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := FontFaceCSS(fonts, "", "fonts/go-{index}.{ext}"); again != css {
+		t.Fatalf("not deterministic:\n%s\n%s", css, again)
+	}
+	rules := cssDeclarations(t, css)
+	if len(rules) != 3 {
+		t.Fatalf("%d rules:\n%s", len(rules), css)
+	}
+	for i, rule := range rules {
+		index := []int{0, 1, 3}[i]
+		want := map[string][]string{
+			"font-family": {`"Go Medium`},
+			"font-style":  {"normal"},
+			"font-weight": {"500"},
+			"src":         {"url", "(", fmt.Sprintf(`"fonts/go-%d.ttf`, index), ")", "format", "(", `"truetype`, ")"},
+		}
+		if index == 3 {
+			// Go Bold Italic is of usWeightClass 600, not the 700 of its macStyle.
+			want["font-style"] = []string{"italic"}
+			want["font-weight"] = []string{"600"}
+		}
+		for name, value := range want {
+			if !slices.Equal(rule[name], value) {
+				t.Errorf("rule %d: %s is %q, want %q", i, name, rule[name], value)
+			}
+		}
+		if _, ok := rule["font-stretch"]; ok {
+			t.Errorf("rule %d: font-stretch of a font of normal width", i)
+		}
+
+		// The unicode-range is the runes of the font without control characters.
+		var covered []rune
+		for _, token := range rule["unicode-range"] {
+			if token == "," {
+				continue
+			}
+			lo, hi, isRange := strings.Cut(strings.TrimPrefix(token, "U+"), "-")
+			if !isRange {
+				hi = lo
+			}
+			l, err1 := strconv.ParseUint(lo, 16, 32)
+			h, err2 := strconv.ParseUint(hi, 16, 32)
+			if err1 != nil || err2 != nil || l > h || !strings.HasPrefix(token, "U+") {
+				t.Fatalf("rule %d: unicode-range %q", i, token)
+			}
+			for r := rune(l); r <= rune(h); r++ {
+				covered = append(covered, r)
+			}
+		}
+		var runes []rune
+		if index < len(manifest) {
+			runes = manifest[index].Runes
+		} else {
+			runes = slices.Sorted(bold.coveredRunes())
+		}
+		runes = slices.DeleteFunc(slices.Clone(runes), unicode.IsControl)
+		if !slices.Equal(covered, runes) {
+			t.Errorf("rule %d: unicode-range covers %d runes, want %d", i, len(covered), len(runes))
+		}
+	}
+	if !strings.Contains(css, "unicode-range: U+20-7E;") {
+		t.Errorf("Basic Latin slice:\n%s", css)
+	}
+
+	// Family names are escaped.
+	css, err = FontFaceCSS(fonts[:1], "A \"B\"\\\n", "{index}.{ext}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cssDeclarations(t, css)[0]["font-family"]; !slices.Equal(got, []string{"\"A \"B\"\\\n"}) {
+		t.Fatalf("font-family %q:\n%s", got, css)
+	}
+
+	if _, err := FontFaceCSS([]*Font{nil}, "", "{index}.{ext}"); !errors.Is(err, errRequiredField) {
+		t.Fatalf("no fonts: %v", err)
+	}
+
+	// A font of control characters only has no unicode-range.
+	controls, err := f.SubsetWithOptions([]rune("\r"), SubsetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if css, err := FontFaceCSS([]*Font{subsets[0], controls}, "", "{index}.{ext}"); !errors.Is(err, errRequiredField) || !strings.Contains(err.Error(), "font 1") {
+		t.Fatalf("font without runes: %v\n%s", err, css)
+	}
+}
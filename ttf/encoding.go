@@ -7,6 +7,7 @@ package ttf
 
 import (
 	"encoding/binary"
+	"sync"
 	"unicode/utf8"
 
 	"golang.org/x/text/encoding"
@@ -65,35 +66,53 @@ var macGlyphNames = []GlyphName{
 	"ccaron", "dcroat",
 }
 
-const (
-	platformIDUnicode   int = 0
-	platformIDMacintosh     = 1
-	platformIDWindows       = 3
-)
+// macGlyphNameIndices maps the standard mac glyph names to their index in macGlyphNames.
+var macGlyphNameIndices = sync.OnceValue(func() map[GlyphName]uint16 {
+	m := make(map[GlyphName]uint16, len(macGlyphNames))
+	for i, name := range macGlyphNames {
+		m[name] = uint16(i)
+	}
+	return m
+})
+
+// MacGlyphName returns the name of standard Macintosh glyph `i` (0-257).
+func MacGlyphName(i int) (GlyphName, bool) {
+	if i < 0 || i >= len(macGlyphNames) {
+		return "", false
+	}
+	return macGlyphNames[i], true
+}
+
+// MacGlyphNameIndex returns the standard Macintosh glyph index of `name`, if it is one of
+// the 258 standard names.
+func MacGlyphNameIndex(name GlyphName) (uint16, bool) {
+	i, ok := macGlyphNameIndices()[name]
+	return i, ok
+}
 
 // getCmapEncoding returns the cmapEncoding for the specified `platformID` and platform-specific `encodingID`.
 func getCmapEncoding(platformID, encodingID int) cmapEncoding {
-	switch platformID {
-	case platformIDUnicode:
+	switch PlatformID(platformID) {
+	case PlatformUnicode:
 		return cmapEncodingUCS2
-	case platformIDMacintosh:
+	case PlatformMacintosh:
 		return cmapEncodingMacRoman
-	case platformIDWindows:
-		switch encodingID {
-		case 0: // Symbol
+	case PlatformWindows:
+		switch EncodingID(encodingID) {
+		case EncodingWindowsSymbol:
 			// TODO(gunnsth): Is this correct for symbol?
 			return cmapEncodingUCS2
-		case 1: // Unicode BMP-only (UCS-2)
+		case EncodingWindowsUnicodeBMP: // UCS-2
 			return cmapEncodingUCS2
-		case 2: // Shift-JIS
+		case EncodingWindowsShiftJIS:
 			return cmapEncodingShiftJIS
-		case 3: // PRC
+		case EncodingWindowsPRC:
 			return cmapEncodingPRC
-		case 4: // BigFive
+		case EncodingWindowsBig5:
 			return cmapEncodingBig5
-		case 5: // Johab.
+		case EncodingWindowsWansung, EncodingWindowsJohab: // Korean, not decoded yet.
 			return cmapEncodingJohab
-		case 10: // Unicode UCS-4.
+		case EncodingWindowsUnicodeUCS4:
 			return cmapEncodingUCS4
 		}
 	}
@@ -0,0 +1,117 @@
+package ttf
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMacGlyphName(t *testing.T) {
+	// Spot checks from the Apple 'post' table specification.
+	want := map[int]GlyphName{
+		0:   ".notdef",
+		1:   ".null",
+		2:   "nonmarkingreturn",
+		3:   "space",
+		10:  "quotesingle",
+		36:  "A",
+		61:  "Z",
+		63:  "backslash",
+		68:  "a",
+		92:  "y",
+		97:  "asciitilde",
+		98:  "Adieresis",
+		99:  "Aring",
+		119: "idieresis",
+		120: "ntilde",
+		257: "dcroat",
+	}
+	for i, name := range want {
+		got, ok := MacGlyphName(i)
+		if !ok || got != name {
+			t.Fatalf("MacGlyphName(%d) = %q, %v, want %q", i, got, ok, name)
+		}
+		if ni, ok := MacGlyphNameIndex(name); !ok || int(ni) != i {
+			t.Fatalf("MacGlyphNameIndex(%q) = %d, %v, want %d", name, ni, ok, i)
+		}
+	}
+	for _, i := range []int{-1, 258} {
+		if _, ok := MacGlyphName(i); ok {
+			t.Fatalf("MacGlyphName(%d) should not exist", i)
+		}
+	}
+	if _, ok := MacGlyphNameIndex("uni4E2D"); ok {
+		t.Fatal("uni4E2D is not a standard name")
+	}
+
+	seen := make(map[uint16]bool)
+	for i := range 258 {
+		name, _ := MacGlyphName(i)
+		ni, ok := MacGlyphNameIndex(name)
+		if !ok || int(ni) != i || seen[ni] {
+			t.Fatalf("name %q of index %d maps back to %d, %v", name, i, ni, ok)
+		}
+		seen[ni] = true
+	}
+	if len(macGlyphNameIndices()) != 258 {
+		t.Fatalf("reverse map has %d names, want 258 unique names", len(macGlyphNameIndices()))
+	}
+}
+
+func TestWritePost_Version2(t *testing.T) {
+	f := loadGoRegular(t)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.post.version != 0x00020000 || !slices.Equal(g.post.glyphNames, f.post.glyphNames) {
+		t.Fatalf("post version %#x with %d names, want 2.0 with the %d source names", uint32(g.post.version),
+			len(g.post.glyphNames), len(f.post.glyphNames))
+	}
+	custom := 0
+	for i, ni := range g.post.glyphNameIndex {
+		if std, ok := MacGlyphNameIndex(g.post.glyphNames[i]); ok && ni != std {
+			t.Fatalf("glyph %d %q written as index %d, want standard index %d", i, g.post.glyphNames[i], ni, std)
+		}
+		if ni >= 258 {
+			custom++
+		}
+	}
+	if custom == 0 || custom == len(g.post.glyphNames) {
+		t.Fatalf("%d of %d names written as custom names", custom, len(g.post.glyphNames))
+	}
+
+	// Writing leaves the post table as it is, e.g. for Fingerprint on several goroutines.
+	f.post.version = 0x00025000
+	index := slices.Clone(f.post.glyphNameIndex)
+	if err := f.writePost(newByteWriter(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if f.post.version != 0x00025000 || !slices.Equal(f.post.glyphNameIndex, index) {
+		t.Fatalf("post version %#x after writing, want 2.5 with the index as parsed", uint32(f.post.version))
+	}
+}
+
+// TestWritePost_LongNames checks that glyph names are written up to the 255 bytes a Pascal
+// string holds.
+func TestWritePost_LongNames(t *testing.T) {
+	for _, n := range []int{200, 255, 256} {
+		f := loadGoRegular(t)
+		f.post.version = 0x00020000
+		f.post.glyphNames = slices.Clone(f.post.glyphNames)
+		f.post.glyphNames[1] = GlyphName(strings.Repeat("a", n))
+		err := f.writePost(newByteWriter(nil))
+		if n <= 255 && err != nil {
+			t.Fatalf("name of %d bytes: %v", n, err)
+		}
+		if n > 255 && !errors.Is(err, errRangeCheck) {
+			t.Fatalf("name of %d bytes: %v, want errRangeCheck", n, err)
+		}
+	}
+}
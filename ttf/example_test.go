@@ -0,0 +1,24 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed testdata/Go-Regular.ttf
+var exampleFS embed.FS
+
+func ExampleParseFS() {
+	f, err := ParseFS(exampleFS, "testdata/Go-Regular.ttf")
+	if err != nil {
+		panic(err)
+	}
+	_, missing := f.CoverageOf("Hello, 世界")
+	fmt.Println(string(missing))
+	// Output: 世界
+}
@@ -7,66 +7,177 @@ package ttf
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
-	"math"
+	"maps"
 	"os"
 	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // Font wraps font for outside access.
 type Font struct {
 	br *byteReader
 	*font
+
+	kerning func() (map[[2]GlyphIndex]int16, error) // See KerningPairs, read on first use.
+	metrics func() ([]uint16, []int16)              // See Advances and LSBs, built on first use.
+	symbol  func() map[rune]GlyphIndex              // See GetSymbolCmap, built on first use.
+}
+
+// newFont returns the Font of `fnt`, parsed from `br`, which is nil for a font made by subsetting.
+func newFont(br *byteReader, fnt *font) *Font {
+	f := &Font{br: br, font: fnt}
+	f.kerning = sync.OnceValues(f.readKerning)
+	f.metrics = sync.OnceValues(f.glyphMetrics)
+	f.symbol = sync.OnceValue(f.normalizeSymbolCmap)
+	return f
 }
 
-// Parse parses the truetype font from `rs` and returns a new Font.
+// Parse parses the truetype font from `rs` and returns a new Font. The data of `rs` is read
+// fully into memory, from which the Font reads some tables on demand later on, e.g. Write to
+// copy them, so `rs` can be closed or changed once Parse returns.
 func Parse(rs io.ReadSeeker) (*Font, error) {
-	r := newByteReader(rs)
+	return parse(context.Background(), rs, false)
+}
+
+// ParseContext parses the truetype font from `rs` like Parse, stopping with the error of `ctx`
+// once `ctx` is done, checked between tables and glyphs.
+func ParseContext(ctx context.Context, rs io.ReadSeeker) (*Font, error) {
+	return parse(ctx, rs, false)
+}
+
+// ParseStrict parses the truetype font from `rs` like Parse, but fails on incompatibilities with
+// the specification that Parse tolerates and notes in Warnings, such as a post table whose
+// numGlyphs disagrees with maxp.
+func ParseStrict(rs io.ReadSeeker) (*Font, error) {
+	return parse(context.Background(), rs, true)
+}
+
+func parse(ctx context.Context, rs io.ReadSeeker, strict bool) (*Font, error) {
+	r, err := readFont(rs)
+	if err != nil {
+		return nil, err
+	}
+	return parseBytes(ctx, r, strict)
+}
+
+// readFont reads all of the data of `rs` into memory, so that fonts parsed from it neither
+// depend on `rs` after parsing nor share its offset, and returns a reader of it at the offset
+// of `rs`.
+func readFont(rs io.ReadSeeker) (*byteReader, error) {
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("reading font: %w", err)
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("reading font: %w", err)
+	}
+	data, err := io.ReadAll(rs)
+	if err != nil {
+		return nil, fmt.Errorf("reading font: %w", err)
+	}
+	r := newBytesReader(data)
+	return r, r.SeekTo(cur)
+}
 
-	fnt, err := parseFont(r)
+// parseBytes parses the font of `r`, a reader of data held in memory, see newBytesReader.
+func parseBytes(ctx context.Context, r *byteReader, strict bool) (*Font, error) {
+	fnt, err := parseFont(ctx, r, strict)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Font{
-		br:   r,
-		font: fnt,
-	}, nil
+	return newFont(r, fnt), nil
 }
 
-// ParseFile parses the truetype font from file given by path.
+// Warnings returns the incompatibilities with the specification that were worked around when
+// parsing `f`, and the data lost in making `f`, e.g. the layout tables dropped by subsetting.
+func (f *Font) Warnings() []string {
+	return slices.Clone(f.incompatibilities)
+}
+
+// ParseFile parses the truetype font from file given by path. The file is read fully, as Parse
+// reads its input.
 func ParseFile(filePath string) (*Font, error) {
-	f, err := os.Open(filePath)
+	b, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return parseBytes(context.Background(), newBytesReader(b), false)
+}
 
-	defer f.Close()
-	return Parse(f)
+// ParseFS parses the truetype font `name` of `fsys`, such as an embed.FS, like ParseFile.
+// Errors include `name`.
+func ParseFS(fsys fs.FS, name string) (*Font, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseBytes(context.Background(), newBytesReader(b), false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
 }
 
-// ValidateBytes validates the turetype font represented by the byte stream.
+// ValidateBytes validates the turetype font represented by the byte stream. It fails only on
+// findings of SeverityError, see ValidateBytesReport for the warnings.
 func ValidateBytes(b []byte) error {
+	_, err := ValidateBytesReport(b, ValidationOptions{})
+	return err
+}
+
+// ValidateBytesReport validates the truetype font represented by the byte stream like
+// ValidateBytes with the optional checks of `opts`, and also returns all findings, including
+// the warnings that do not make the font invalid, such as inconsistent vertical metrics. The
+// error is not nil if the report has errors.
+func ValidateBytesReport(b []byte, opts ValidationOptions) (*ValidationReport, error) {
 	r := bytes.NewReader(b)
 	br := newByteReader(r)
-	fnt, err := parseFont(br)
+	fnt, err := parseFont(context.Background(), br, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return fnt.validate(br)
+	return fnt.validate(br, opts)
 }
 
-// GetCmap returns the specific cmap specified by `platformID` and platform-specific `encodingID`.
+// GetCmap returns a copy of the specific cmap specified by `platformID` and platform-specific
+// `encodingID`, e.g. GetCmap(3, 1) for Windows Unicode BMP.
 // If not available, nil is returned. Used in PDF for decoding.
+//
+// Deprecated: Use GetCmapView, which takes a PlatformID and EncodingID and does not copy the
+// mapping.
 func (f *Font) GetCmap(platformID, encodingID int) map[rune]GlyphIndex {
+	return maps.Clone(f.cmapOf(PlatformID(platformID), EncodingID(encodingID)))
+}
+
+// GetCmapView returns a read-only view of the cmap subtable of `platformID` and
+// platform-specific `encodingID`, e.g. GetCmapView(PlatformWindows, EncodingWindowsUnicodeBMP).
+// It returns false if `f` has no such subtable.
+func (f *Font) GetCmapView(platformID PlatformID, encodingID EncodingID) (CmapView, bool) {
+	cmap := f.cmapOf(platformID, encodingID)
+	return CmapView{cmap}, cmap != nil
+}
+
+// cmapOf returns the mapping of the cmap subtable of `platformID` and `encodingID` of `f`, nil
+// if there is none. It is that of the subtable, not a copy.
+func (f *Font) cmapOf(platformID PlatformID, encodingID EncodingID) map[rune]GlyphIndex {
 	if f.cmap == nil {
 		return nil
 	}
 
-	for _, subt := range f.cmap.subtables {
-		if subt.platformID == platformID && subt.encodingID == encodingID {
+	// In file order, so that of two subtables in different formats the first always wins.
+	for _, key := range f.cmap.subtableKeys {
+		subt := f.cmap.subtables[key]
+		if subt.platformID == int(platformID) && subt.encodingID == int(encodingID) {
 			return subt.cmap
 		}
 	}
@@ -74,35 +185,353 @@ func (f *Font) GetCmap(platformID, encodingID int) map[rune]GlyphIndex {
 	return nil
 }
 
-// LookupRunes looks up each rune in `rune` and returns a matching slice of glyph indices.
-// When a rune is not found, a GID of 0 is used (notdef).
+// GetSymbolCmap returns a read-only view of the (3,0) Windows Symbol subtable of `f` keyed by
+// the runes that text uses. It returns false if there is none. Symbol fonts map the codes of
+// their glyphs offset to the private use range 0xF000 to 0xF0FF, which
+// GetCmapView(PlatformWindows, EncodingWindowsSymbol) returns as they are. Here the codes whose
+// low byte is printable ASCII are the ASCII runes, e.g. 0xF041 is 'A', the other codes stay
+// private use runes.
+func (f *Font) GetSymbolCmap() (CmapView, bool) {
+	cmap := f.symbol()
+	return CmapView{cmap}, cmap != nil
+}
+
+// normalizeSymbolCmap returns the (3,0) subtable of `f` keyed by rune for GetSymbolCmap.
+func (f *Font) normalizeSymbolCmap() map[rune]GlyphIndex {
+	raw := f.cmapOf(PlatformWindows, EncodingWindowsSymbol)
+	if raw == nil {
+		return nil
+	}
+	cmap := make(map[rune]GlyphIndex, len(raw))
+	for code, gid := range raw {
+		r := code
+		if code >= 0xF020 && code <= 0xF07E {
+			r = code - 0xF000
+			// Fonts mapping the ASCII code itself too keep that glyph.
+			if _, ok := raw[r]; ok {
+				continue
+			}
+		}
+		cmap[r] = gid
+	}
+	return cmap
+}
+
+// symbolCode returns the code of rune `r` in (3,0) subtable `raw`: `r` if it maps it, the code
+// in the private use range of an ASCII rune mapped there, otherwise `r`.
+func symbolCode(raw map[rune]GlyphIndex, r rune) rune {
+	if _, ok := raw[r]; ok || r < 0x20 || r > 0x7E {
+		return r
+	}
+	if _, ok := raw[0xF000+r]; ok {
+		return 0xF000 + r
+	}
+	return r
+}
+
+// RemapCmap rebuilds the Unicode cmap subtables of `f` from `mapping`, which replaces the
+// current mapping if `replace` is true and is merged into it otherwise, overriding it for the
+// runes it maps. Glyphs and other subtables are left as they are, so writing `f` re-encodes the
+// font. Format 4 subtables map the BMP, a (3,10) format 12 subtable is added for runes outside
+// it if needed.
+func (f *Font) RemapCmap(mapping map[rune]GlyphIndex, replace bool) error {
+	for _, r := range slices.Sorted(maps.Keys(mapping)) {
+		gid := mapping[r]
+		if !utf8.ValidRune(r) {
+			return fmt.Errorf("rune %U is not a Unicode scalar value: %w", r, errRangeCheck)
+		}
+		if !f.ValidGID(gid) {
+			return fmt.Errorf("rune %U maps to %v outside the font: %w", r, gid, errRangeCheck)
+		}
+	}
+	cmap := maps.Clone(mapping)
+	if !replace && f.cmap != nil {
+		cmap = f.cmap.unicodeCmap()
+		maps.Copy(cmap, mapping)
+	}
+	return f.setCmap(rebuildUnicodeCmap(f.cmap, cmap))
+}
+
+// ValidGID reports whether `gid` refers to a glyph of the font.
+func (f *Font) ValidGID(gid GlyphIndex) bool {
+	if f.maxp != nil && int(gid) >= int(f.maxp.numGlyphs) {
+		return false
+	}
+	if f.glyf != nil && int(gid) >= len(f.glyf.descs) {
+		return false
+	}
+	return f.maxp != nil || f.glyf != nil
+}
+
+// GlyphAdvance returns the advance width of glyph `gid` in font units. It returns false if
+// `gid` is not a glyph of the font or the font has no hmtx table.
+func (f *Font) GlyphAdvance(gid GlyphIndex) (uint16, bool) {
+	if !f.ValidGID(gid) {
+		return 0, false
+	}
+	return f.glyphAdvance(gid)
+}
+
+// GlyphBounds returns the bounding box of glyph `gid` in font units from its glyf header. It
+// returns false if `gid` is not a glyph of the font or has no outline, like space. It is safe
+// for concurrent use.
+func (f *Font) GlyphBounds(gid GlyphIndex) (xMin, yMin, xMax, yMax FWord, ok bool) {
+	if !f.ValidGID(gid) || f.glyf == nil || int(gid) >= len(f.glyf.descs) {
+		return 0, 0, 0, 0, false
+	}
+	gd := f.glyf.descs[gid]
+	if !gd.hasOutline() {
+		return 0, 0, 0, 0, false
+	}
+	x0, y0, x1, y1 := glyphBounds([]*glyphDescription{gd})
+	return FWord(x0), FWord(y0), FWord(x1), FWord(y1), true
+}
+
+// GlyphData returns a copy of the data of glyph `gid` in the glyf table as loca locates it,
+// including any padding, e.g. to hash outlines or move glyphs between fonts. It is empty for
+// glyphs without data, like space. Glyphs outside the font and fonts without glyf, such as
+// those with CFF outlines, give an error.
+func (f *Font) GlyphData(gid GlyphIndex) ([]byte, error) {
+	gd, err := f.glyphDescription(gid)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, gd.raw...), nil
+}
+
+// GlyphDataLen returns the length of the data of glyph `gid` that GlyphData returns, without
+// copying it.
+func (f *Font) GlyphDataLen(gid GlyphIndex) (int, error) {
+	gd, err := f.glyphDescription(gid)
+	if err != nil {
+		return 0, err
+	}
+	return len(gd.raw), nil
+}
+
+// glyphDescription returns the glyph `gid` of the glyf table of `f`.
+func (f *Font) glyphDescription(gid GlyphIndex) (*glyphDescription, error) {
+	if f.glyf == nil {
+		return nil, fmt.Errorf("glyph %d: no glyf table: %w", gid, errRequiredField)
+	}
+	if !f.ValidGID(gid) {
+		return nil, fmt.Errorf("glyph %d outside the %d glyphs of the font: %w", gid, len(f.glyf.descs), errRangeCheck)
+	}
+	return f.glyf.descs[gid], nil
+}
+
+// Advances returns the advance width of each glyph in font units, indexed by glyph index, as
+// GlyphAdvance returns it, for layout code that looks up the advances of many glyphs. It is
+// built on first use and shared by all callers, so it must not be modified. It is nil if the
+// font has no hmtx table.
+func (f *Font) Advances() []uint16 {
+	advances, _ := f.metrics()
+	return advances
+}
+
+// LSBs returns the left side bearing of each glyph in font units, indexed by glyph index, like
+// Advances. It must not be modified either.
+func (f *Font) LSBs() []int16 {
+	_, lsbs := f.metrics()
+	return lsbs
+}
+
+// glyphMetrics returns the advance widths and left side bearings of the glyphs of `f` for
+// Advances and LSBs, nil without hmtx.
+func (f *Font) glyphMetrics() ([]uint16, []int16) {
+	if f.hmtx == nil || len(f.hmtx.hMetrics) == 0 {
+		return nil, nil
+	}
+	n := 0
+	if f.maxp != nil {
+		n = int(f.maxp.numGlyphs)
+	}
+	if f.glyf != nil && (f.maxp == nil || len(f.glyf.descs) < n) {
+		n = len(f.glyf.descs)
+	}
+	advances, lsbs := make([]uint16, n), make([]int16, n)
+	for gid := range n {
+		m, _ := f.glyphMetric(GlyphIndex(gid))
+		advances[gid], lsbs[gid] = m.advanceWidth, m.lsb
+	}
+	return advances, lsbs
+}
+
+// ItalicAngle returns the italic angle of the post table in degrees counter-clockwise from
+// the vertical, negative for fonts leaning to the right. It is 0 without a post table.
+func (f *Font) ItalicAngle() float64 {
+	if f.post == nil {
+		return 0
+	}
+	return f.post.italicAngle.Float64()
+}
+
+// CaretSlope returns the slope of the caret of the hhea table as rise over run, 1 and 0 for
+// a vertical caret, and the offset in font units by which the caret is shifted for slanted
+// glyphs. It returns 1, 0, 0 without an hhea table.
+func (f *Font) CaretSlope() (rise, run int16, offset int16) {
+	if f.hhea == nil {
+		return 1, 0, 0
+	}
+	return f.hhea.caretSlopeRise, f.hhea.caretSlopeRun, f.hhea.caretOffset
+}
+
+// CaretAngle returns the angle of the caret from CaretSlope in degrees like ItalicAngle:
+// counter-clockwise from the vertical, negative for a caret leaning to the right. It is 0 for
+// a vertical caret or the invalid slope 0/0.
+func (f *Font) CaretAngle() float64 {
+	if f.hhea == nil {
+		return 0
+	}
+	return f.hhea.caretAngle()
+}
+
+// GaspRanges returns the ranges of sizes of the gasp table, sorted by MaxPPEM, that tell
+// rasterizers when to grid-fit and anti-alias glyphs. It is nil without a gasp table.
+func (f *Font) GaspRanges() []GaspRange {
+	if f.gasp == nil {
+		return nil
+	}
+	return slices.Clone(f.gasp.ranges)
+}
+
+// MetaTags returns the ScriptLangTags of the meta table, such as "Latn" or "zh-Hans": those of
+// the languages that `f` is designed for (dlng) and those that it supports (slng). Both are nil
+// without a meta table.
+func (f *Font) MetaTags() (design, supported []string) {
+	if f.meta == nil {
+		return nil, nil
+	}
+	return f.meta.scriptLangTags(metaTagDlng), f.meta.scriptLangTags(metaTagSlng)
+}
+
+// IsFixedPitch reports whether the post table marks the font as monospaced. It is false
+// without a post table.
+func (f *Font) IsFixedPitch() bool {
+	return f.post != nil && f.post.isFixedPitch != 0
+}
+
+// UnderlinePosition returns the top of the underline in font units, negative below the
+// baseline. It returns false without a post table.
+func (f *Font) UnderlinePosition() (FWord, bool) {
+	if f.post == nil {
+		return 0, false
+	}
+	return f.post.underlinePosition, true
+}
+
+// UnderlineThickness returns the thickness of the underline in font units. It returns false
+// without a post table.
+func (f *Font) UnderlineThickness() (FWord, bool) {
+	if f.post == nil {
+		return 0, false
+	}
+	return f.post.underlineThickness, true
+}
+
+// FontRevision returns the font revision of the head table, e.g. 1.5. It is 0 without a
+// head table.
+func (f *Font) FontRevision() float64 {
+	if f.head == nil {
+		return 0
+	}
+	return f.head.fontRevision.Float64()
+}
+
+// SetFontRevision sets the font revision of the head table to `v`, e.g. 1.5, rounded to 16.16
+// fixed-point. It has no effect without a head table.
+func (f *Font) SetFontRevision(v float64) {
+	if f.head != nil {
+		f.head.fontRevision = FixedFromFloat64(v)
+		f.markDirty(tagHead)
+	}
+}
+
+// Created returns the creation date of the head table. It returns false if there is no head
+// table or the date is unset (0).
+func (f *Font) Created() (time.Time, bool) {
+	if f.head == nil || f.head.created == 0 {
+		return time.Time{}, false
+	}
+	return f.head.created.Time(), true
+}
+
+// Modified returns the modification date of the head table like Created.
+func (f *Font) Modified() (time.Time, bool) {
+	if f.head == nil || f.head.modified == 0 {
+		return time.Time{}, false
+	}
+	return f.head.modified.Time(), true
+}
+
+// SetCreated sets the creation date of the head table to `t`, truncated to seconds. A zero
+// `t` unsets it. It has no effect without a head table.
+func (f *Font) SetCreated(t time.Time) {
+	if f.head != nil {
+		f.head.created = headDate(t)
+		f.markDirty(tagHead)
+	}
+}
+
+// SetModified sets the modification date of the head table like SetCreated.
+func (f *Font) SetModified(t time.Time) {
+	if f.head != nil {
+		f.head.modified = headDate(t)
+		f.markDirty(tagHead)
+	}
+}
+
+// headDate returns `t` as a head table date, 0 for the zero time.
+func headDate(t time.Time) longdatetime {
+	if t.IsZero() {
+		return 0
+	}
+	return makeLongdatetime(t)
+}
+
+// HasCmap reports whether `f` has a cmap subtable to look up runes in, see LookupRunes.
+func (f *Font) HasCmap() bool {
+	return slices.ContainsFunc(f.lookupCmaps(), func(cmap map[rune]GlyphIndex) bool { return cmap != nil })
+}
+
+// LookupRunes looks up the distinct runes of `runes` and returns the glyph indices of those
+// found along with those runes, in ascending order of rune. Runes that are not found are
+// logged and left out, as are runes that a corrupt cmap maps to glyphs outside the font, see
+// ValidGID. `runes` itself is not modified, so it can be used after the call in its original
+// order, e.g. for shaping. See LookupRunesMap for the glyphs by rune.
+// Without a cmap (see HasCmap) all runes are missing, which is not logged.
 func (f *Font) LookupRunes(runes []rune) ([]GlyphIndex, []rune) {
-	slices.Sort(runes)
-	runes = slices.Compact(runes)
-	// Search order (3,1), (1,0), (0,3), (3,10).
-	cmaps := []map[rune]GlyphIndex{
-		f.GetCmap(3, 1),
-		f.GetCmap(1, 0),
-		f.GetCmap(0, 3),
-		f.GetCmap(3, 10),
+	indices, found := f.lookupRunes(runes)
+	var invalid []rune
+	for i := 0; i < len(indices); i++ {
+		if !f.ValidGID(indices[i]) {
+			invalid = append(invalid, found[i])
+			indices, found = slices.Delete(indices, i, i+1), slices.Delete(found, i, i+1)
+			i--
+		}
+	}
+	if len(invalid) > 0 {
+		slog.Warn("LookupRunes runes mapped to glyphs outside the font", "runes", string(invalid), "runes_raw", invalid)
 	}
+	return indices, found
+}
+
+// lookupRunes is LookupRunes that keeps the runes mapped to glyphs outside the font, for
+// Subset to report them.
+func (f *Font) lookupRunes(runes []rune) ([]GlyphIndex, []rune) {
+	runes = slices.Compact(slices.Sorted(slices.Values(runes)))
+	if !f.HasCmap() {
+		return []GlyphIndex{}, []rune{}
+	}
+	cmaps := f.lookupCmaps()
 	indices := make([]GlyphIndex, 0)
 	searchRunes := make([]rune, 0)
 	missRunes := make([]rune, 0)
 	for _, r := range runes {
-		has := false
-		for _, cmap := range cmaps {
-			if cmap == nil {
-				continue
-			}
-			if ind, ok := cmap[r]; ok {
-				indices = append(indices, ind)
-				searchRunes = append(searchRunes, r)
-				has = true
-				break
-			}
-		}
-		if !has {
+		if ind, ok := lookupRune(cmaps, r); ok {
+			indices = append(indices, ind)
+			searchRunes = append(searchRunes, r)
+		} else {
 			missRunes = append(missRunes, r)
 		}
 	}
@@ -112,14 +541,344 @@ func (f *Font) LookupRunes(runes []rune) ([]GlyphIndex, []rune) {
 	return indices, searchRunes
 }
 
-// Subset creates a subset of `f` including only glyph indices specified by `indices`.
-// Returns the new subsetted font, a map of old to new GlyphIndex to GlyphIndex as the removal
-// of glyphs requires reordering.
+// LookupRunesMap looks up `runes` like LookupRunes and returns the glyph index of each rune
+// found, for callers that do not need an order. `runes` is not modified.
+func (f *Font) LookupRunesMap(runes []rune) map[rune]GlyphIndex {
+	indices, found := f.LookupRunes(runes)
+	gids := make(map[rune]GlyphIndex, len(found))
+	for i, r := range found {
+		gids[r] = indices[i]
+	}
+	return gids
+}
+
+// runesOf returns the runes that the cmap of `f` maps to glyphs of sorted `gids`, in ascending
+// order, and their glyphs, as LookupRunes would return them.
+func (f *Font) runesOf(gids []GlyphIndex) ([]GlyphIndex, []rune) {
+	cmaps := f.lookupCmaps()
+	var runes []rune
+	for _, cmap := range cmaps {
+		for r, gid := range cmap {
+			if _, found := slices.BinarySearch(gids, gid); found {
+				runes = append(runes, r)
+			}
+		}
+	}
+	// A rune of several subtables maps to the glyph of the first, which may not be kept.
+	runes = slices.Compact(slices.Sorted(slices.Values(runes)))
+	indices := make([]GlyphIndex, 0, len(runes))
+	found := runes[:0]
+	for _, r := range runes {
+		gid, _ := lookupRune(cmaps, r)
+		if _, ok := slices.BinarySearch(gids, gid); ok {
+			indices = append(indices, gid)
+			found = append(found, r)
+		}
+	}
+	return indices, found
+}
+
+// lookupCmaps returns the cmap subtables searched by LookupRunes, in order: (3,1), (1,0),
+// (0,3), (3,10), and (3,0) as GetSymbolCmap returns it and as it is, so that both ASCII and
+// private use runes find the glyphs of a symbol font. Absent subtables are nil.
+func (f *Font) lookupCmaps() []map[rune]GlyphIndex {
+	return []map[rune]GlyphIndex{
+		f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP),
+		f.cmapOf(PlatformMacintosh, EncodingMacRoman),
+		f.cmapOf(PlatformUnicode, EncodingUnicode20BMP),
+		f.cmapOf(PlatformWindows, EncodingWindowsUnicodeUCS4),
+		f.symbol(),
+		f.cmapOf(PlatformWindows, EncodingWindowsSymbol),
+	}
+}
+
+// lookupRune returns the glyph of `r` in the first of `cmaps` that maps it.
+func lookupRune(cmaps []map[rune]GlyphIndex, r rune) (GlyphIndex, bool) {
+	for _, cmap := range cmaps {
+		if ind, ok := cmap[r]; ok {
+			return ind, true
+		}
+	}
+	return 0, false
+}
+
+// SubsetOptions tunes the output of SubsetWithOptions.
+type SubsetOptions struct {
+	// DropCmap omits the cmap table, for embedding-only output: a font embedded in a document
+	// that maps characters to glyphs itself, such as a PDF CIDFontType2 font with a
+	// CIDToGIDMap, does not need it. The result is not a valid standalone font, validate it
+	// with ValidationOptions.EmbeddingOnly.
+	DropCmap bool
+
+	// RetainGIDs keeps the subset glyphs at their indices in the font and empties the other
+	// glyphs, instead of renumbering the subset glyphs from 1. The number of glyphs stays the
+	// same, as a PDF CIDFontType2 font with an Identity CIDToGIDMap needs, so the glyph codes
+	// of a content stream need no rewriting. Emptied glyphs have zero-length loca ranges and
+	// all glyphs keep their advance widths.
+	RetainGIDs bool
+
+	// Layout selects what happens to the layout tables GDEF, GPOS and GSUB.
+	Layout LayoutPolicy
+
+	// IncludeMandatoryGlyphs keeps glyphs 1 and 2 of the font, conventionally .null and
+	// nonmarkingreturn, right after .notdef, along with the cmap entries of U+0000 and U+000D.
+	// Some rasterizers and validators expect them. Set in DefaultSubsetOptions.
+	IncludeMandatoryGlyphs bool
+
+	// IncludeSpace adds U+0020 and U+00A0 to the runes if the font has them, so that text
+	// without spaces, e.g. in CJK, still gets a space glyph with its advance for the word
+	// spacing of PDF viewers. Set in DefaultSubsetOptions.
+	IncludeSpace bool
+
+	// OptimizeHmtx stores the trailing run of glyphs sharing one advance width, such as the
+	// ideographs of a CJK font, with just their left side bearings, saving 2 of 4 bytes per
+	// glyph of the run. Otherwise every glyph gets a full metric, numberOfHMetrics being the
+	// number of glyphs, which some older consumers require. Set in DefaultSubsetOptions.
+	OptimizeHmtx bool
+
+	// AlignGlyphs pads the data of each glyph to a multiple of four bytes, so that every glyph
+	// starts on a 4-byte boundary in glyf as some rasterizers and the WOFF2 glyf transform
+	// expect. Otherwise glyphs are packed back to back for the smallest glyf, padded to even
+	// lengths only if the loca offsets are short. Set in DefaultSubsetOptions.
+	AlignGlyphs bool
+
+	// TrimMetaLanguages removes the ScriptLangTags of scripts that none of the runes of the
+	// subset are in from the supported languages (slng) of the meta table, e.g. "Cyrl" from a
+	// Latin subset, so that font fallback does not pick the subset for text it cannot show.
+	// Tags without a script, e.g. "en", are kept. Otherwise meta is kept as it is.
+	TrimMetaLanguages bool
+
+	// PreserveColor keeps the color glyphs of a font with COLR version 0 and CPAL, adding the
+	// layer glyphs of the subset glyphs to the subset. Fonts with color glyphs in other
+	// formats give ErrColorUnsupported. Otherwise the color tables are dropped, noted in the
+	// Warnings of the subset, so that it has outlines only.
+	PreserveColor bool
+
+	// KeepBitmaps asks for the embedded bitmap strikes of EBDT and EBLC or of bdat and bloc to
+	// be kept, which is not supported yet: fonts with them give ErrBitmapsUnsupported.
+	// Otherwise the bitmap tables are dropped together, noted in the Warnings of the subset,
+	// and rasterizers draw the outlines at every size.
+	KeepBitmaps bool
+}
+
+// DefaultSubsetOptions returns the options used by Subset.
+func DefaultSubsetOptions() SubsetOptions {
+	return SubsetOptions{IncludeMandatoryGlyphs: true, IncludeSpace: true, OptimizeHmtx: true, AlignGlyphs: true}
+}
+
+// LayoutPolicy selects how subsetting treats the OpenType layout tables GDEF, GPOS and GSUB,
+// which shaping complex scripts such as Arabic and the Indic scripts relies on.
+type LayoutPolicy int
+
+const (
+	// LayoutDrop drops the layout tables, noting them and their features in the Warnings of
+	// the subset.
+	LayoutDrop LayoutPolicy = iota
+
+	// LayoutPassthrough copies the layout tables unchanged. They refer to glyphs by index, so
+	// it requires SubsetOptions.RetainGIDs.
+	LayoutPassthrough
+)
+
+// HasLayoutTables returns true if `f` has any of the layout tables GDEF, GPOS and GSUB. They
+// only survive subsetting with SubsetOptions.RetainGIDs and LayoutPassthrough, so callers
+// embedding text that needs shaping should choose those.
+func (f *Font) HasLayoutTables() bool {
+	if f.br == nil {
+		// Made by subsetting, see layoutTables.
+		layout, _ := f.layoutTables()
+		return len(layout) > 0
+	}
+	return slices.ContainsFunc(layoutTags, f.trec.HasTag)
+}
+
+// layoutTables returns the layout tables of `f`: those passed through for a font made by
+// subsetting, otherwise those of the font file.
+func (f *Font) layoutTables() ([]rawTable, error) {
+	if f.br == nil {
+		return slices.DeleteFunc(slices.Clone(f.rawTables), func(t rawTable) bool {
+			return !slices.Contains(layoutTags, t.tag)
+		}), nil
+	}
+	return f.readRawTables(f.br.fork(), layoutTags)
+}
+
+// Subset creates a subset of `f` with the glyphs of `runes`. The glyphs are renumbered in the
+// subset, see SubsetWithMap for the index of each glyph kept.
+//
+// Subsetting is deterministic: the same font, set of runes and options always give a subset
+// written byte for byte the same, whatever the order of `runes`.
+//
+// Fonts without a cmap to look up `runes` in give ErrNoCmap.
+//
+// The name table of the subset has the family, subfamily, unique ID, full name, PostScript
+// name and typographic family and subfamily records of `f`, on all platforms.
+//
+// A font without hmtx gets one in the subset with the same advance for all glyphs, see
+// GlyphAdvance. Its Warnings note that.
 func (f *Font) Subset(runes []rune) (*Font, error) {
-	indices, runes := f.LookupRunes(runes)
-	if len(indices) == 0 || indices[1] != 0 {
-		indices = slices.Insert(indices, 0, 0)
+	return f.SubsetWithOptions(runes, DefaultSubsetOptions())
+}
+
+// SubsetWithOptions creates a subset of `f` like Subset, tuned by `opts`.
+func (f *Font) SubsetWithOptions(runes []rune, opts SubsetOptions) (*Font, error) {
+	return f.SubsetContext(context.Background(), runes, opts)
+}
+
+// SubsetContext creates a subset of `f` like SubsetWithOptions, stopping with the error of
+// `ctx` once `ctx` is done, checked between tables and glyphs. `f` is left as it is.
+func (f *Font) SubsetContext(ctx context.Context, runes []rune, opts SubsetOptions) (*Font, error) {
+	sub, _, err := f.subset(ctx, runes, nil, opts)
+	return sub, err
+}
+
+// SubsetWithMap creates a subset of `f` like SubsetWithOptions and returns the index in the
+// subset of every glyph of `f` that it keeps, .notdef at 0 among them, as needed to map the
+// glyphs of text set in `f` to those of the subset, e.g. for the CIDToGIDMap of a PDF
+// CIDFontType2 font. With SubsetOptions.RetainGIDs every glyph maps to itself.
+func (f *Font) SubsetWithMap(runes []rune, opts SubsetOptions) (*Font, map[GlyphIndex]GlyphIndex, error) {
+	return f.subset(context.Background(), runes, nil, opts)
+}
+
+// SubsetGIDs creates a subset of `f` with glyphs `gids`, e.g. those that shaping text gave, and
+// returns the index in the subset of every glyph of `f` that it keeps, see SubsetWithMap. The
+// glyphs are kept whether the cmap maps a rune to them or not, such as ligatures and the
+// alternates of GSUB, and the cmap of the subset maps the runes whose glyphs are kept. Fonts
+// without a cmap, see HasCmap, can be subset this way too.
+func (f *Font) SubsetGIDs(gids []GlyphIndex) (*Font, map[GlyphIndex]GlyphIndex, error) {
+	return f.SubsetGIDsWithOptions(gids, DefaultSubsetOptions())
+}
+
+// SubsetGIDsWithOptions creates a subset of `f` like SubsetGIDs, tuned by `opts`.
+func (f *Font) SubsetGIDsWithOptions(gids []GlyphIndex, opts SubsetOptions) (*Font, map[GlyphIndex]GlyphIndex, error) {
+	// Sorted for a deterministic subset, in a copy that is never nil.
+	sorted := append([]GlyphIndex{}, gids...)
+	slices.Sort(sorted)
+	return f.subset(context.Background(), nil, slices.Compact(sorted), opts)
+}
+
+// subset creates the subset of SubsetContext and SubsetGIDs and the map of SubsetWithMap: of the
+// glyphs of `runes`, or of glyphs `gids` if not nil, sorted and distinct.
+func (f *Font) subset(ctx context.Context, runes []rune, gids []GlyphIndex, opts SubsetOptions) (*Font, map[GlyphIndex]GlyphIndex, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("subsetting cancelled: %w", err)
+	}
+	if opts.Layout == LayoutPassthrough && !opts.RetainGIDs {
+		return nil, nil, fmt.Errorf("layout tables can only be passed through with RetainGIDs: %w", errInvalidOptions)
+	}
+	if f.head == nil || f.maxp == nil || f.loca == nil || f.glyf == nil {
+		return nil, nil, fmt.Errorf("subsetting needs head, maxp, loca and glyf: %w", errRequiredField)
+	}
+	if !f.ValidGID(0) {
+		return nil, nil, fmt.Errorf("no glyphs, not even .notdef: %w", errRangeCheck)
+	}
+	if gids == nil && !f.HasCmap() {
+		return nil, nil, ErrNoCmap
+	}
+	if opts.KeepBitmaps && f.HasBitmapStrikes() {
+		return nil, nil, fmt.Errorf("%s: %w", strings.Join(tagNames(f.bitmapTables()), ", "), ErrBitmapsUnsupported)
+	}
+	var colr *colrV0
+	if opts.PreserveColor && f.color != 0 {
+		if f.color != ColorCOLRv0 {
+			return nil, nil, fmt.Errorf("%v: %w", f.color, ErrColorUnsupported)
+		}
+		data, err := f.rawTableData(tagCOLR)
+		if err != nil {
+			return nil, nil, err
+		}
+		if colr, err = parseColrV0(data, int(f.maxp.numGlyphs)); err != nil {
+			return nil, nil, err
+		}
+	}
+	var indices []GlyphIndex
+	if gids == nil {
+		indices, runes = f.lookupRunes(runes)
+		for i, gid := range indices {
+			if !f.ValidGID(gid) {
+				return nil, nil, fmt.Errorf("rune %U maps to %v outside the font: %w", runes[i], gid, errRangeCheck)
+			}
+		}
+	} else {
+		for _, gid := range gids {
+			if !f.ValidGID(gid) {
+				return nil, nil, fmt.Errorf("glyph %d outside the font: %w", gid, errRangeCheck)
+			}
+		}
+		indices, runes = f.runesOf(gids)
+	}
+	var extra []rune
+	if opts.IncludeMandatoryGlyphs {
+		extra = append(extra, 0, '\r')
 	}
+	if opts.IncludeSpace {
+		extra = append(extra, ' ', '\u00A0')
+	}
+	if len(extra) > 0 {
+		cmaps := f.lookupCmaps()
+		for _, r := range extra {
+			gid, ok := lookupRune(cmaps, r)
+			if i, found := slices.BinarySearch(runes, r); ok && !found && f.ValidGID(gid) {
+				runes = slices.Insert(runes, i, r)
+				indices = slices.Insert(indices, i, gid)
+			}
+		}
+	}
+
+	// glyphs lists the glyphs of `f` in the subset, newGID maps them to their subset index.
+	glyphs := []GlyphIndex{0}
+	if opts.IncludeMandatoryGlyphs {
+		for gid := GlyphIndex(1); gid <= 2 && f.ValidGID(gid); gid++ {
+			glyphs = append(glyphs, gid)
+		}
+	}
+	newGID := make(map[GlyphIndex]GlyphIndex, len(gids)+len(indices)+len(glyphs))
+	for i, gid := range glyphs {
+		newGID[gid] = GlyphIndex(i)
+	}
+	for _, gid := range slices.Concat(gids, indices) {
+		if _, ok := newGID[gid]; !ok {
+			newGID[gid] = GlyphIndex(len(glyphs))
+			glyphs = append(glyphs, gid)
+		}
+	}
+	if colr != nil {
+		for _, gid := range colr.layerGlyphs(glyphs) {
+			if _, ok := newGID[gid]; !ok {
+				newGID[gid] = GlyphIndex(len(glyphs))
+				glyphs = append(glyphs, gid)
+			}
+		}
+	}
+	// Composite glyphs need their components, which may be composite themselves.
+	for i := 0; i < len(glyphs); i++ {
+		components, err := f.glyf.descs[glyphs[i]].components()
+		if err != nil {
+			return nil, nil, fmt.Errorf("glyph %d: %w", glyphs[i], err)
+		}
+		for _, gid := range components {
+			if !f.ValidGID(gid) {
+				return nil, nil, fmt.Errorf("component %d of glyph %d outside the font: %w", gid, glyphs[i], errRangeCheck)
+			}
+			if _, ok := newGID[gid]; !ok {
+				newGID[gid] = GlyphIndex(len(glyphs))
+				glyphs = append(glyphs, gid)
+			}
+		}
+	}
+	// order lists the glyph of `f` at each index of the subset, which with RetainGIDs are all
+	// glyphs, those not in the subset being emptied.
+	order := glyphs
+	if opts.RetainGIDs && f.maxp != nil {
+		order = make([]GlyphIndex, f.maxp.numGlyphs)
+		for gid := range order {
+			order[gid] = GlyphIndex(gid)
+		}
+		for _, gid := range glyphs {
+			newGID[gid] = gid
+		}
+	}
+
 	newfnt := font{}
 
 	newfnt.ot = new(offsetTable)
@@ -128,7 +887,7 @@ func (f *Font) Subset(runes []rune) (*Font, error) {
 	newfnt.trec = new(tableRecords)
 	*newfnt.trec = *f.font.trec
 
-	if f.font.cmap != nil {
+	if f.font.cmap != nil && !opts.DropCmap {
 		newfnt.cmap = &cmapTable{
 			version:   f.cmap.version,
 			subtables: make(map[string]*cmapSubtable),
@@ -145,82 +904,34 @@ func (f *Font) Subset(runes []rune) (*Font, error) {
 				charcodes:     make([]CharCode, 0),
 				charcodeToGID: make(map[CharCode]GlyphIndex),
 			}
-			for gid, cc := range runes {
-				newSubt.cmap[cc] = GlyphIndex(gid + 1)
-				newSubt.charcodeToGID[CharCode(cc)] = GlyphIndex(gid + 1)
+			symbol := oldSubt.platformID == int(PlatformWindows) && oldSubt.encodingID == int(EncodingWindowsSymbol)
+			for i, cc := range runes {
+				if symbol {
+					// Back to the code of the rune of GetSymbolCmap, once for 'A' and 0xF041.
+					cc = symbolCode(oldSubt.cmap, cc)
+					if _, dup := newSubt.cmap[cc]; dup {
+						continue
+					}
+				}
+				newSubt.cmap[cc] = newGID[indices[i]]
+				newSubt.charcodeToGID[CharCode(cc)] = newGID[indices[i]]
 				newSubt.charcodes = append(newSubt.charcodes, CharCode(cc))
 			}
+			gids := make([]GlyphIndex, len(newSubt.charcodes))
+			for i, cc := range newSubt.charcodes {
+				gids[i] = newSubt.charcodeToGID[cc]
+			}
 			switch t := oldSubt.ctx.(type) {
 			case cmapSubtableFormat4:
-				newt := cmapSubtableFormat4{}
-				segments := 0
-				i := 0
-				for i < len(newSubt.charcodes) {
-					j := i + 1
-					for ; j < len(newSubt.charcodes); j++ {
-						if int(newSubt.charcodes[j]-newSubt.charcodes[i]) != j-i ||
-							int(newSubt.charcodeToGID[newSubt.charcodes[j]]-newSubt.charcodeToGID[newSubt.charcodes[i]]) != j-i {
-							break
-						}
-					}
-					// from i:j-1 maps to subt.charcodes[i]:subt.charcodes[i]+j-i-1
-					startCode := uint16(newSubt.charcodes[i])
-					endCode := uint16(newSubt.charcodes[i]) + uint16(j-i-1)
-					idDelta := uint16(newSubt.charcodeToGID[newSubt.charcodes[i]]) - uint16(newSubt.charcodes[i])
-
-					newt.startCode = append(newt.startCode, startCode)
-					newt.endCode = append(newt.endCode, endCode)
-					newt.idDelta = append(newt.idDelta, idDelta)
-					newt.idRangeOffset = append(newt.idRangeOffset, 0)
-					segments++
-					i = j
-				}
-
-				if segments > 0 && newt.endCode[segments-1] < 0xFFFF {
-					newt.endCode = append(newt.endCode, 0xFFFF)
-					newt.startCode = append(newt.startCode, 0xFFFF)
-					newt.idDelta = append(newt.idDelta, 1)
-					newt.idRangeOffset = append(newt.idRangeOffset, 0)
-					segments++
-				}
-
-				newt.length = uint16(2*8 + 2*4*segments)
-				newt.language = t.language
-				newt.segCountX2 = uint16(segments * 2)
-				newt.searchRange = 2 * uint16(math.Pow(2, math.Floor(math.Log2(float64(segments)))))
-				newt.entrySelector = uint16(math.Log2(float64(newt.searchRange) / 2.0))
-				newt.rangeShift = uint16(segments*2) - newt.searchRange
-				newSubt.ctx = newt
+				newSubt.ctx = buildCmapFormat4(newSubt.charcodes, gids, t.language)
 			case cmapSubtableFormat12:
-				newt := cmapSubtableFormat12{}
-				groups := 0
-				i := 0
-				for i < len(newSubt.charcodes) {
-					j := i + 1
-					for ; j < len(newSubt.charcodes); j++ {
-						if int(newSubt.charcodes[j]-newSubt.charcodes[i]) != j-i ||
-							int(newSubt.charcodeToGID[newSubt.charcodes[j]]-newSubt.charcodeToGID[newSubt.charcodes[i]]) != j-i {
-							break
-						}
-					}
-					// from i:j-1 maps to subt.charcodes[i]:subt.charcodes[i]+j-i-1
-					startCharCode := uint32(newSubt.charcodes[i])
-					endCharCode := uint32(newSubt.charcodes[i]) + uint32(j-i-1)
-					startGlyphID := uint32(newSubt.charcodeToGID[newSubt.charcodes[i]])
-
-					group := sequentialMapGroup{
-						startCharCode: startCharCode,
-						endCharCode:   endCharCode,
-						startGlyphID:  startGlyphID,
-					}
-					newt.groups = append(newt.groups, group)
-					groups++
-					i = j
-				}
-				newt.length = uint32(2*2 + 3*4 + groups*3*4)
-				newt.language = t.language
-				newt.numGroups = uint32(groups)
-				newSubt.ctx = newt
+				newSubt.ctx = buildCmapFormat12(newSubt.charcodes, gids, t.language)
+			case cmapSubtableFormat0:
+				t.glyphIDArray = subsetGlyphIDs(t.glyphIDArray, 0, oldSubt.platformID, oldSubt.encodingID, newSubt.cmap, newGID)
+				newSubt.ctx = t
+			case cmapSubtableFormat6:
+				t.glyphIDArray = subsetGlyphIDs(t.glyphIDArray, t.firstCode, oldSubt.platformID, oldSubt.encodingID, newSubt.cmap, newGID)
+				newSubt.ctx = t
 			}
 			newfnt.cmap.subtableKeys = append(newfnt.cmap.subtableKeys, name)
 			newfnt.cmap.subtables[name] = newSubt
@@ -228,20 +939,30 @@ func (f *Font) Subset(runes []rune) (*Font, error) {
 		newfnt.cmap.numTables = uint16(len(newfnt.cmap.subtables))
 	}
 
-	// if f.font.name != nil {
-	// 	newfnt.name = &nameTable{}
-	// 	*newfnt.name = *f.font.name
-	// 	for i, record := range newfnt.name.nameRecords {
-	// 		record.data = []byte{0}
-	// 		record.offset = offset16(i)
-	// 		record.length = 1
-	// 	}
-	// }
+	if f.font.name != nil {
+		newfnt.name = f.font.name.subset(subsetNameIDs)
+	}
 
-	// if f.font.os2 != nil {
-	// 	newfnt.os2 = &os2Table{}
-	// 	*newfnt.os2 = *f.font.os2
-	// }
+	// OS/2 refers to runes, not glyphs: only the first and last character index change.
+	if f.font.os2 != nil {
+		newfnt.os2 = new(os2Table)
+		*newfnt.os2 = *f.font.os2
+		first, last := rune(-1), rune(-1)
+		for i, r := range runes {
+			if indices[i] == 0 {
+				continue
+			}
+			if first < 0 || r < first {
+				first = r
+			}
+			last = max(last, r)
+		}
+		if first >= 0 {
+			// Both are capped at 0xFFFF for runes beyond the BMP.
+			newfnt.os2.usFirstCharIndex = uint16(min(first, 0xFFFF))
+			newfnt.os2.usLastCharIndex = uint16(min(last, 0xFFFF))
+		}
+	}
 
 	// if f.font.post != nil {
 	// 	newfnt.post = &postTable{}
@@ -268,27 +989,38 @@ func (f *Font) Subset(runes []rune) (*Font, error) {
 	// 	}
 	// }
 
+	var indexToLocFormat int16
 	if f.font.glyf != nil && f.font.loca != nil {
-		newfnt.loca = new(locaTable)
 		newfnt.glyf = new(glyfTable)
-		for _, gid := range indices {
-			newfnt.glyf.descs = append(newfnt.glyf.descs, f.font.glyf.descs[gid])
-		}
-		isShort := f.font.head.indexToLocFormat == 0
-		if isShort {
-			newfnt.loca.offsetsShort = make([]offset16, len(newfnt.glyf.descs)+1)
-			newfnt.loca.offsetsShort[0] = f.font.loca.offsetsShort[0]
-		} else {
-			newfnt.loca.offsetsLong = make([]offset32, len(newfnt.glyf.descs)+1)
-			newfnt.loca.offsetsLong[0] = f.font.loca.offsetsLong[0]
+		short := f.font.head != nil && f.font.head.indexToLocFormat == 0
+		align := 1
+		if opts.AlignGlyphs {
+			align = 4
+		} else if short {
+			align = 2
 		}
-		for i, desc := range newfnt.glyf.descs {
-			if isShort {
-				newfnt.loca.offsetsShort[i+1] = newfnt.loca.offsetsShort[i] + offset16(len(desc.raw))/2
+		for i, gid := range order {
+			if i%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, nil, fmt.Errorf("subsetting cancelled after %d of %d glyphs: %w", i, len(order), err)
+				}
+			}
+			desc := f.font.glyf.descs[gid]
+			if _, kept := newGID[gid]; !kept {
+				desc = &glyphDescription{}
 			} else {
-				newfnt.loca.offsetsLong[i+1] = newfnt.loca.offsetsLong[i] + offset32(len(desc.raw))
+				if !opts.RetainGIDs && desc.isComposite() {
+					raw, err := remapComponents(desc.raw, newGID)
+					if err != nil {
+						return nil, nil, fmt.Errorf("glyph %d: %w", gid, err)
+					}
+					desc = &glyphDescription{raw: raw}
+				}
+				desc = alignGlyph(desc, align)
 			}
+			newfnt.glyf.descs = append(newfnt.glyf.descs, desc)
 		}
+		newfnt.loca, indexToLocFormat = buildLoca(newfnt.glyf.descs, short)
 	}
 
 	if f.font.hhea != nil {
@@ -299,34 +1031,112 @@ func (f *Font) Subset(runes []rune) (*Font, error) {
 	if f.font.head != nil {
 		newfnt.head = new(headTable)
 		*newfnt.head = *f.font.head
+		if newfnt.glyf != nil {
+			newfnt.head.indexToLocFormat = indexToLocFormat
+			newfnt.head.xMin, newfnt.head.yMin, newfnt.head.xMax, newfnt.head.yMax = glyphBounds(newfnt.glyf.descs)
+		}
 	}
 
-	if f.font.hmtx != nil {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("subsetting cancelled before hmtx: %w", err)
+	}
+	if f.font.hmtx != nil && len(f.font.hmtx.hMetrics) > 0 {
 		newfnt.hmtx = new(hmtxTable)
-		hmLen := len(f.font.hmtx.hMetrics)
-		for _, gid := range indices {
-			newfnt.hmtx.hMetrics = append(newfnt.hmtx.hMetrics, f.font.hmtx.hMetrics[min(hmLen-1, int(gid))])
+		for _, gid := range order {
+			m, _ := f.font.glyphMetric(gid)
+			newfnt.hmtx.hMetrics = append(newfnt.hmtx.hMetrics, m)
 		}
+	} else if newfnt.hhea != nil {
+		newfnt.hmtx = f.font.synthesizeHmtx(newfnt.glyf.descs)
+		newfnt.incompatibilities = append(newfnt.incompatibilities,
+			fmt.Sprintf("synthesized hmtx giving all glyphs advance %d", f.font.defaultAdvance()))
+	}
+	if newfnt.hmtx != nil && opts.OptimizeHmtx {
 		newfnt.optimizeHmtx()
 	}
 
+	// gasp and meta do not refer to glyphs: the subset renders small sizes like `f` and
+	// keeps its languages.
+	newfnt.gasp = f.font.gasp
+	newfnt.meta = f.font.meta
+	if newfnt.meta != nil && opts.TrimMetaLanguages {
+		scripts := make(map[string]bool)
+		ranges := scriptRanges()
+		for i, r := range runes {
+			if script, ok := findRuneRange(ranges, r); ok && indices[i] != 0 {
+				scripts[script.name] = true
+			}
+		}
+		newfnt.meta = newfnt.meta.trimSupported(func(script string) bool { return scripts[script] })
+	}
+
 	if f.font.maxp != nil {
 		newfnt.maxp = new(maxpTable)
 		*newfnt.maxp = *f.font.maxp
 		newfnt.maxp.numGlyphs = uint16(len(newfnt.glyf.descs))
 	}
 
-	subfnt := &Font{
-		br:   nil,
-		font: &newfnt,
+	layout, err := f.layoutTables()
+	if err != nil {
+		return nil, nil, fmt.Errorf("layout tables: %w", err)
+	}
+	if len(layout) > 0 {
+		if opts.Layout == LayoutPassthrough {
+			newfnt.rawTables = append(newfnt.rawTables, layout...)
+		} else {
+			newfnt.incompatibilities = append(newfnt.incompatibilities, "dropped layout tables "+describeLayout(layout))
+		}
+	}
+	if colr != nil {
+		cpal, err := f.rawTableData(tagCPAL)
+		if err != nil {
+			return nil, nil, err
+		}
+		newfnt.rawTables = append(newfnt.rawTables, rawTable{tag: tagCOLR, data: colr.subset(newGID)})
+		if cpal != nil {
+			newfnt.rawTables = append(newfnt.rawTables, rawTable{tag: tagCPAL, data: cpal})
+		}
+		newfnt.color = ColorCOLRv0
+	} else if tags := f.colorTables(); len(tags) > 0 {
+		newfnt.incompatibilities = append(newfnt.incompatibilities,
+			fmt.Sprintf("dropped color tables %s (%v)", strings.Join(tagNames(tags), ", "), f.color))
 	}
-	return subfnt, nil
+	if tags := f.bitmapTables(); len(tags) > 0 {
+		newfnt.incompatibilities = append(newfnt.incompatibilities, "dropped bitmap tables "+strings.Join(tagNames(tags), ", "))
+	}
+
+	return newFont(nil, &newfnt), newGID, nil
+}
+
+// WriteOptions tunes the output of WriteWithOptions.
+type WriteOptions struct {
+	// SkipChecksumAdjustment writes 0 to head.checksumAdjustment, for output that must have
+	// the field 0. It saves nothing: the adjustment is summed from the table checksums that are
+	// computed anyway. The table checksums are still correct and renderers, PDF viewers among
+	// them, ignore the field, but the file fails strict validation, e.g. ValidateBytes.
+	SkipChecksumAdjustment bool
+
+	// Reserialize writes every table of a parsed font from its parsed form. By default the
+	// tables that were not changed, see Write, are copied from the file the font was parsed from.
+	Reserialize bool
 }
 
-// Write writes the font to `w`.
+// Write writes the font to `w`. The tables of a font parsed from a file that were not changed
+// by the methods of Font, such as SetNameByID or RemapCmap, are copied from the file byte for
+// byte, and so are the tables that are not parsed, e.g. GSUB or meta, so that they survive
+// unchanged. The table directory and checksumAdjustment in head are always written anew.
 func (f *Font) Write(w io.Writer) error {
+	return f.WriteWithOptions(w, WriteOptions{})
+}
+
+// WriteWithOptions writes the font to `w` as set by `opts`.
+func (f *Font) WriteWithOptions(w io.Writer, opts WriteOptions) error {
 	bw := newByteWriter(w)
-	err := f.font.write(bw)
+	src := f.br
+	if opts.Reserialize {
+		src = nil
+	}
+	err := f.font.write(bw, opts, src)
 	if err != nil {
 		return err
 	}
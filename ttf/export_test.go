@@ -2,15 +2,24 @@ package ttf
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"log/slog"
 	"maps"
+	"math"
 	"os"
 	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
 	fixed2 "golang.org/x/image/math/fixed"
 )
 
@@ -66,6 +75,35 @@ func TestFont_LookupRunes(t *testing.T) {
 	t.Log(glyphIndices)
 }
 
+func TestFont_LookupRunesInput(t *testing.T) {
+	f := loadGoRegular(t)
+	runes := []rune("Hello, 世界 Hello")
+	input := slices.Clone(runes)
+	gids, found := f.LookupRunes(runes)
+	if !slices.Equal(runes, input) {
+		t.Fatalf("LookupRunes changed its input to %q, want %q", string(runes), string(input))
+	}
+	if !slices.Equal(found, []rune(" ,Helo")) {
+		t.Fatalf("LookupRunes found %q", string(found))
+	}
+
+	m := f.LookupRunesMap(runes)
+	if !slices.Equal(runes, input) {
+		t.Fatalf("LookupRunesMap changed its input to %q, want %q", string(runes), string(input))
+	}
+	if len(m) != len(found) {
+		t.Fatalf("LookupRunesMap() = %v, want %d runes", m, len(found))
+	}
+	for i, r := range found {
+		if m[r] != gids[i] {
+			t.Fatalf("LookupRunesMap()[%q] = %d, want %d", r, m[r], gids[i])
+		}
+	}
+	if _, ok := m['世']; ok {
+		t.Fatal("LookupRunesMap() has a rune that Go Regular lacks")
+	}
+}
+
 func TestFont_Subset(t *testing.T) {
 	tfnt, err := ParseFile("../testdata/NotoSansSC-Bold.ttf")
 	// tfnt, err := ParseFile("../testdata/Ubuntu-Medium.ttf")
@@ -114,3 +152,556 @@ func TestFont_Subset(t *testing.T) {
 
 	TestSubSetDiff(t)
 }
+
+func TestFont_ValidGID(t *testing.T) {
+	f := loadGoRegular(t)
+	n := GlyphIndex(f.maxp.numGlyphs)
+	for gid, want := range map[GlyphIndex]bool{0: true, n - 1: true, n: false, 0xFFFF: false} {
+		if got := f.ValidGID(gid); got != want {
+			t.Fatalf("ValidGID(%v) = %v, want %v", gid, got, want)
+		}
+	}
+	if s := GlyphIndex(1234).String(); s != "GID 1234" {
+		t.Fatalf("GlyphIndex.String() = %q", s)
+	}
+	if s := CharCode(0x4E2D).String(); s != "U+4E2D" {
+		t.Fatalf("CharCode.String() = %q", s)
+	}
+	if s := CharCode(0x41).String(); s != "U+0041" {
+		t.Fatalf("CharCode.String() = %q", s)
+	}
+
+	// A corrupt cmap entry pointing past numGlyphs.
+	for _, subt := range f.cmap.subtables {
+		subt.cmap['A'] = n + 5
+	}
+	_, err := f.Subset([]rune("AB"))
+	if !errors.Is(err, errRangeCheck) || !strings.Contains(err.Error(), "U+0041") {
+		t.Fatalf("Subset error = %v, want a range error naming U+0041", err)
+	}
+	// Lookups leave the rune out.
+	if gids, found := f.LookupRunes([]rune("AB")); string(found) != "B" || len(gids) != 1 || !f.ValidGID(gids[0]) {
+		t.Fatalf("LookupRunes = %v, %q, want only 'B'", gids, found)
+	}
+	if covered, missing := f.CoverageOf("AB"); string(covered) != "B" || string(missing) != "A" {
+		t.Fatalf("CoverageOf = %q, %q", covered, missing)
+	}
+	if i, _, ok := NewFontSet(f).Lookup('A'); ok {
+		t.Fatalf("FontSet.Lookup('A') = font %d", i)
+	}
+}
+
+func TestFont_PostMetrics(t *testing.T) {
+	f := loadGoRegular(t)
+	if got, ok := f.UnderlinePosition(); !ok || got != f.post.underlinePosition {
+		t.Fatalf("UnderlinePosition() = %v, %v", got, ok)
+	}
+	if f.ItalicAngle() != 0 || f.IsFixedPitch() {
+		t.Fatalf("Go Regular: italic angle %v, fixed pitch %v", f.ItalicAngle(), f.IsFixedPitch())
+	}
+
+	f.post.italicAngle = FixedFromFloat64(-12.5)
+	f.post.isFixedPitch = 1
+	f.post.underlineThickness = 75
+	f.markDirty(tagPost)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := Parse(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	} else if f.ItalicAngle() != -12.5 || !f.IsFixedPitch() {
+		t.Fatalf("italic angle %v, fixed pitch %v", f.ItalicAngle(), f.IsFixedPitch())
+	} else if got, ok := f.UnderlineThickness(); !ok || got != 75 {
+		t.Fatalf("UnderlineThickness() = %v, %v", got, ok)
+	}
+
+	f.post = nil
+	if _, ok := f.UnderlinePosition(); ok || f.ItalicAngle() != 0 || f.IsFixedPitch() {
+		t.Fatal("expected zero values without a post table")
+	}
+}
+
+func TestFont_CaretSlope(t *testing.T) {
+	f, err := Parse(bytes.NewReader(goitalic.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rise, run, offset := f.CaretSlope()
+	if rise != 2048 || run != 398 || offset != 0 {
+		t.Fatalf("Go Italic: CaretSlope() = %d, %d, %d", rise, run, offset)
+	}
+	if got := f.CaretAngle(); math.Abs(got-f.ItalicAngle()) > 0.1 {
+		t.Fatalf("Go Italic: CaretAngle() = %v, want about ItalicAngle() %v", got, f.ItalicAngle())
+	}
+
+	// Subset copies hhea and then overwrites numberOfHMetrics only.
+	f.hhea.caretOffset = -7
+	sub, err := f.Subset([]rune("Italic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sub, err = Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rise, run, offset := sub.CaretSlope(); rise != 2048 || run != 398 || offset != -7 {
+		t.Fatalf("subset: CaretSlope() = %d, %d, %d", rise, run, offset)
+	}
+
+	f = loadGoRegular(t)
+	if rise, run, _ := f.CaretSlope(); rise != 1 || run != 0 || f.CaretAngle() != 0 {
+		t.Fatalf("Go Regular: CaretSlope() = %d, %d, CaretAngle() = %v", rise, run, f.CaretAngle())
+	}
+	f.hhea = nil
+	if rise, run, offset := f.CaretSlope(); rise != 1 || run != 0 || offset != 0 || f.CaretAngle() != 0 {
+		t.Fatal("expected a vertical caret without an hhea table")
+	}
+}
+
+func TestFont_GaspRanges(t *testing.T) {
+	f := loadGoRegular(t)
+	if got := f.GaspRanges(); !slices.Equal(got, []GaspRange{{0xFFFF, 15}}) {
+		t.Fatalf("Go Regular: GaspRanges() = %v", got)
+	}
+
+	ranges := []GaspRange{
+		{8, GaspDoGray},
+		{16, GaspGridfit},
+		{0xFFFF, GaspGridfit | GaspDoGray | GaspSymmetricGridfit | GaspSymmetricSmoothing},
+	}
+	f.gasp = &gaspTable{version: 1, ranges: ranges}
+	f.markDirty(tagGasp)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 1, 0, 3, 0, 8, 0, 2, 0, 16, 0, 1, 0xFF, 0xFF, 0, 15}
+	if got := tableData(t, buf.Bytes())[tagGasp]; !bytes.Equal(got, want) {
+		t.Fatalf("gasp written as % X, want % X", got, want)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.GaspRanges(); !slices.Equal(got, ranges) {
+		t.Fatalf("GaspRanges() = %v, want %v", got, ranges)
+	}
+
+	sub, err := f.Subset([]rune("gasp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableData(t, buf.Bytes())[tagGasp]; !bytes.Equal(got, want) {
+		t.Fatalf("subset gasp is % X, want % X", got, want)
+	}
+}
+
+func TestFont_SubsetDropCmap(t *testing.T) {
+	f := loadGoRegular(t)
+	runes := []rune("Hello, PDF")
+	gids, found := f.LookupRunes(slices.Clone(runes))
+	subset := func(opts SubsetOptions) []byte {
+		sub, err := f.SubsetWithOptions(slices.Clone(runes), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	full, data := subset(SubsetOptions{}), subset(SubsetOptions{DropCmap: true})
+	if err := ValidateBytes(full); err != nil {
+		t.Fatalf("with cmap: %v", err)
+	}
+	if len(data) >= len(full) {
+		t.Fatalf("dropping cmap gives %d bytes, %d with it", len(data), len(full))
+	}
+
+	rep, err := ValidateBytesReport(data, ValidationOptions{})
+	if err == nil || len(rep.Errors()) != 1 || rep.Errors()[0].String() != "cmap: required table missing" {
+		t.Fatalf("standalone validation: %v %v", err, rep)
+	}
+	if _, err := ValidateBytesReport(data, ValidationOptions{EmbeddingOnly: true, CheckGlyphs: true}); err != nil {
+		t.Fatalf("embedding-only validation: %v", err)
+	}
+
+	// Consumers of embedded fonts find the glyphs by index: the subset glyphs follow .notdef
+	// in the order of the looked up runes.
+	g, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.cmap != nil || g.trec.HasTag(tagCmap) || len(g.Warnings()) != 0 {
+		t.Fatalf("cmap %v, warnings %q", g.cmap, g.Warnings())
+	}
+	if int(g.maxp.numGlyphs) != len(found)+1 {
+		t.Fatalf("%d glyphs for %d runes", g.maxp.numGlyphs, len(found))
+	}
+	for i, gid := range gids {
+		want, got := f.glyf.descs[gid].raw, g.glyf.descs[i+1].raw
+		if !bytes.Equal(bytes.TrimRight(got, "\x00"), bytes.TrimRight(want, "\x00")) {
+			t.Fatalf("%q: glyph %d differs from glyph %d of the font", found[i], i+1, gid)
+		}
+		if g.hmtx.hMetrics[min(i+1, len(g.hmtx.hMetrics)-1)].advanceWidth != f.hmtx.hMetrics[gid].advanceWidth {
+			t.Fatalf("%q: advance width differs", found[i])
+		}
+	}
+}
+
+func TestFont_RemapCmap(t *testing.T) {
+	f := loadGoRegular(t)
+	orig := maps.Clone(f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP))
+	mac := maps.Clone(f.cmapOf(PlatformMacintosh, EncodingMacRoman))
+	icons := map[rune]GlyphIndex{0xE000: orig['A'], 0xE001: orig['B'], 0x1F600: orig['C']}
+	roundTrip := func(f *Font) *Font {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateBytes(buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	if err := f.RemapCmap(icons, false); err != nil {
+		t.Fatal(err)
+	}
+	g := roundTrip(f)
+	if want := []string{"4,0,3", "6,1,0", "4,3,1", "12,3,10"}; !slices.Equal(g.cmap.subtableKeys, want) {
+		t.Fatalf("subtables %v, want %v", g.cmap.subtableKeys, want)
+	}
+	merged := maps.Clone(orig)
+	maps.Copy(merged, icons)
+	if !maps.Equal(g.cmapOf(PlatformWindows, EncodingWindowsUnicodeUCS4), merged) {
+		t.Fatal("(3,10) does not hold the merged mapping")
+	}
+	delete(merged, 0x1F600)
+	for _, enc := range [][2]int{{0, 3}, {3, 1}} {
+		if got := g.GetCmap(enc[0], enc[1]); !maps.Equal(got, merged) {
+			t.Fatalf("%v: %d entries, want %d", enc, len(got), len(merged))
+		}
+	}
+	if !maps.Equal(g.cmapOf(PlatformMacintosh, EncodingMacRoman), mac) {
+		t.Fatal("(1,0) changed")
+	}
+	for gid, desc := range f.glyf.descs {
+		if !bytes.Equal(g.glyf.descs[gid].raw, desc.raw) {
+			t.Fatalf("glyph %d changed", gid)
+		}
+	}
+
+	if err := g.RemapCmap(icons, true); err != nil {
+		t.Fatal(err)
+	}
+	g = roundTrip(g)
+	for _, enc := range [][2]int{{0, 3}, {3, 1}, {3, 10}} {
+		got := g.GetCmap(enc[0], enc[1])
+		if _, ok := got['A']; ok || got[0xE000] != orig['A'] {
+			t.Fatalf("%v: A -> %d, U+E000 -> %d", enc, got['A'], got[0xE000])
+		}
+	}
+
+	for r, gid := range map[rune]GlyphIndex{0xE002: GlyphIndex(g.maxp.numGlyphs), 0xD800: 1, -1: 1} {
+		if err := g.RemapCmap(map[rune]GlyphIndex{r: gid}, false); !errors.Is(err, errRangeCheck) {
+			t.Fatalf("%U -> %d: got %v", r, gid, err)
+		}
+	}
+	if len(g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)) != 2 {
+		t.Fatal("failed remapping changed the cmap")
+	}
+}
+
+func TestFont_MissingHmtx(t *testing.T) {
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	if adv, ok := f.GlyphAdvance(cmap['A']); !ok || adv != f.hmtx.hMetrics[cmap['A']].advanceWidth {
+		t.Fatalf("'A': advance %d %t", adv, ok)
+	}
+	if _, ok := f.GlyphAdvance(GlyphIndex(f.maxp.numGlyphs)); ok {
+		t.Fatal("advance of a glyph past numGlyphs")
+	}
+	f.hmtx = nil
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	stripped := buf.Bytes()
+
+	if _, err := ParseStrict(bytes.NewReader(stripped)); err == nil || !strings.Contains(err.Error(), "hmtx table missing") {
+		t.Fatalf("strict: %v", err)
+	}
+	rep, err := ValidateBytesReport(stripped, ValidationOptions{})
+	if err == nil || len(rep.Errors()) != 1 || rep.Errors()[0].String() != "hmtx: required table missing" {
+		t.Fatalf("validation: %v %v", err, rep.Errors())
+	}
+	g, err := Parse(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := g.Warnings(); len(w) != 1 || w[0] != "hmtx table missing" {
+		t.Fatalf("warnings %q", w)
+	}
+	if _, ok := g.GlyphAdvance(cmap['A']); ok {
+		t.Fatal("advance without hmtx")
+	}
+
+	sub, err := g.Subset([]rune("AB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("subset: %v %v", err, rep.Errors())
+	}
+	s, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := uint16(g.hhea.advanceWidthMax)
+	for gid := range GlyphIndex(s.maxp.numGlyphs) {
+		if adv, ok := s.GlyphAdvance(gid); !ok || adv != want {
+			t.Fatalf("subset glyph %d: advance %d %t, want %d", gid, adv, ok, want)
+		}
+	}
+	if w := sub.Warnings(); len(w) != 1 || !strings.Contains(w[0], fmt.Sprintf("synthesized hmtx giving all glyphs advance %d", want)) {
+		t.Fatalf("subset warnings %q", w)
+	}
+
+	// Without advanceWidthMax glyphs get half an em.
+	g.hhea.advanceWidthMax = 0
+	sub, err = g.Subset([]rune("A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adv, ok := sub.GlyphAdvance(1); !ok || adv != g.head.unitsPerEm/2 {
+		t.Fatalf("half em: advance %d %t", adv, ok)
+	}
+}
+
+func TestFont_Advances(t *testing.T) {
+	f := loadGoRegular(t)
+	// Glyphs past the hMetrics of an optimized subset share the last advance.
+	sub, err := f.SubsetWithOptions([]rune("0123456789"), SubsetOptions{OptimizeHmtx: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []*Font{f, sub} {
+		advances, lsbs := g.Advances(), g.LSBs()
+		if len(advances) != int(g.maxp.numGlyphs) || len(lsbs) != len(advances) {
+			t.Fatalf("%d advances, %d left side bearings of %d glyphs", len(advances), len(lsbs), g.maxp.numGlyphs)
+		}
+		for gid := range GlyphIndex(g.maxp.numGlyphs) {
+			adv, _ := g.GlyphAdvance(gid)
+			m, _ := g.glyphMetric(gid)
+			if advances[gid] != adv || lsbs[gid] != m.lsb {
+				t.Fatalf("glyph %d: %d %d, want %d %d", gid, advances[gid], lsbs[gid], adv, m.lsb)
+			}
+		}
+		if &g.Advances()[0] != &advances[0] {
+			t.Fatal("advances built again")
+		}
+	}
+	if len(sub.hmtx.hMetrics) >= int(sub.maxp.numGlyphs) {
+		t.Fatalf("%d hMetrics of %d glyphs", len(sub.hmtx.hMetrics), sub.maxp.numGlyphs)
+	}
+
+	f = loadGoRegular(t)
+	f.hmtx = nil
+	if f.Advances() != nil || f.LSBs() != nil {
+		t.Fatal("advances without hmtx")
+	}
+}
+
+// BenchmarkFont_Advances looks up a million advances with GlyphAdvance and by indexing Advances.
+func BenchmarkFont_Advances(b *testing.B) {
+	f := loadGoRegular(b)
+	numGlyphs := GlyphIndex(f.maxp.numGlyphs)
+	const lookups = 1_000_000
+	var sum int
+	b.Run("GlyphAdvance", func(b *testing.B) {
+		for b.Loop() {
+			for i := range lookups {
+				adv, _ := f.GlyphAdvance(GlyphIndex(i) % numGlyphs)
+				sum += int(adv)
+			}
+		}
+	})
+	b.Run("Advances", func(b *testing.B) {
+		for b.Loop() {
+			advances := f.Advances()
+			for i := range lookups {
+				sum += int(advances[GlyphIndex(i)%numGlyphs])
+			}
+		}
+	})
+}
+
+func TestFont_NoCmap(t *testing.T) {
+	f := loadGoRegular(t)
+	if !f.HasCmap() {
+		t.Fatal("Go Regular has no cmap")
+	}
+	f.cmap = nil
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.HasCmap() || g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP) != nil {
+		t.Fatal("cmap after stripping it")
+	}
+
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	indices, runes := g.LookupRunes([]rune("Go"))
+	if len(indices) != 0 || len(runes) != 0 || logs.Len() != 0 {
+		t.Fatalf("got %v %q, logged %q", indices, runes, logs.String())
+	}
+
+	for _, opts := range []SubsetOptions{DefaultSubsetOptions(), {DropCmap: true}} {
+		if _, err := g.SubsetWithOptions([]rune("Go"), opts); !errors.Is(err, ErrNoCmap) {
+			t.Fatalf("%+v: got %v, want ErrNoCmap", opts, err)
+		}
+	}
+}
+
+func TestFont_SubsetContext(t *testing.T) {
+	f := loadGoRegular(t)
+	var before bytes.Buffer
+	if err := f.Write(&before); err != nil {
+		t.Fatal(err)
+	}
+	runes := slices.Collect(maps.Keys(f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	start := time.Now()
+	if _, err := f.SubsetContext(ctx, runes, SubsetOptions{RetainGIDs: true}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("cancelled subset returned after %v", d)
+	}
+	if _, err := ParseContext(ctx, bytes.NewReader(before.Bytes())); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	var after bytes.Buffer
+	if err := f.Write(&after); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Fatal("cancelled subset changed the source font")
+	}
+	if _, err := f.SubsetContext(context.Background(), runes, SubsetOptions{RetainGIDs: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	want, err := ParseFile("testdata/Go-Regular.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantBuf bytes.Buffer
+	if err := want.Write(&wantBuf); err != nil {
+		t.Fatal(err)
+	}
+	for name, fsys := range map[string]fs.FS{
+		"embed": exampleFS,
+		"dir":   os.DirFS("."),
+		"map":   fstest.MapFS{"testdata/Go-Regular.ttf": {Data: goregular.TTF}},
+	} {
+		f, err := ParseFS(fsys, "testdata/Go-Regular.ttf")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), wantBuf.Bytes()) {
+			t.Fatalf("%s: font differs from ParseFile", name)
+		}
+	}
+
+	bad := fstest.MapFS{"fonts/bad.ttf": {Data: []byte("not a font")}}
+	for _, name := range []string{"fonts/bad.ttf", "fonts/missing.ttf"} {
+		if _, err := ParseFS(bad, name); err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("%s: got %v, want an error naming the file", name, err)
+		}
+	}
+}
+
+func TestFont_GlyphData(t *testing.T) {
+	f := loadGoRegular(t)
+	var glyf []byte
+	for gid := range GlyphIndex(f.maxp.numGlyphs) {
+		data, err := f.GlyphData(gid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := f.GlyphDataLen(gid)
+		if err != nil || n != len(data) {
+			t.Fatalf("glyph %d: GlyphDataLen() = %d, %v, want %d", gid, n, err, len(data))
+		}
+		glyf = append(glyf, data...)
+	}
+	want := tableData(t, goregular.TTF)[tagGlyf]
+	if !bytes.Equal(glyf, want) {
+		t.Fatalf("glyphs concatenated to %d bytes, want the %d of glyf", len(glyf), len(want))
+	}
+
+	// A copy, empty for space.
+	indices, _ := f.LookupRunes([]rune(" A"))
+	if data, err := f.GlyphData(indices[0]); err != nil || data == nil || len(data) != 0 {
+		t.Fatalf("space: GlyphData() = %v, %v", data, err)
+	}
+	data, _ := f.GlyphData(indices[1])
+	data[0] ^= 0xFF
+	if again, _ := f.GlyphData(indices[1]); again[0] == data[0] {
+		t.Fatal("GlyphData returned the data of the font rather than a copy")
+	}
+
+	if _, err := f.GlyphData(GlyphIndex(f.maxp.numGlyphs)); !errors.Is(err, errRangeCheck) {
+		t.Fatalf("got error %v, want errRangeCheck", err)
+	}
+	if _, err := f.GlyphDataLen(GlyphIndex(f.maxp.numGlyphs)); !errors.Is(err, errRangeCheck) {
+		t.Fatalf("got error %v, want errRangeCheck", err)
+	}
+	f.glyf = nil
+	if _, err := f.GlyphData(0); !errors.Is(err, errRequiredField) {
+		t.Fatalf("without glyf: got error %v, want errRequiredField", err)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// FingerprintVersion is the version of the serialization that Fingerprint hashes. It changes
+// whenever the fingerprint of a font may change, so that caches keyed by fingerprints can be
+// dropped.
+const FingerprintVersion = 1
+
+// Fingerprint returns a SHA-256 hash of the content of `f`, to key caches of fonts such as
+// subsets embedded in documents.
+//
+// The hash covers a canonical serialization of the tables that `f` keeps, in the order of their
+// tags: head without modified, checksumAdjustment and indexToLocFormat; hhea without
+// numberOfHMetrics; the advance and left side bearing of each glyph rather than hmtx; the data
+// of each glyph in glyph index order without padding rather than glyf and loca; the name records
+// as Write writes them, sorted by platform, encoding, language and name ID without duplicates,
+// but without the string storage; the mappings of each cmap platform and encoding sorted by
+// rune; and the other tables as Write writes them, including those it copies from the font file
+// as they are, e.g. GPOS or kern.
+//
+// So the fingerprint does not change when a font is written again with a new modification date,
+// with its tables in a different order, with other padding of glyphs, loca format or cmap
+// subtable formats, nor with a signature (DSIG), which is left out. It changes when glyphs,
+// metrics, mappings, names or other tables do. For the same FingerprintVersion, the same font
+// has the same fingerprint in every version of this package.
+func (f *Font) Fingerprint() ([32]byte, error) {
+	if f.head == nil || f.maxp == nil {
+		return [32]byte{}, errRequiredField
+	}
+	sections := map[Tag]func(w *byteWriter) error{
+		tagHead: func(w *byteWriter) error {
+			t := *f.head
+			t.modified, t.indexToLocFormat = 0, 0
+			return (&font{head: &t}).writeHead(w)
+		},
+		tagMaxp: f.writeMaxp,
+	}
+	if f.hhea != nil {
+		sections[tagHhea] = func(w *byteWriter) error {
+			t := *f.hhea
+			t.numberOfHMetrics = 0
+			return (&font{hhea: &t}).writeHhea(w)
+		}
+	}
+	if f.hmtx != nil {
+		sections[tagHmtx] = func(w *byteWriter) error {
+			for gid := range int(f.maxp.numGlyphs) {
+				m, _ := f.glyphMetric(GlyphIndex(gid))
+				if err := w.write(m.advanceWidth, m.lsb); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if f.glyf != nil {
+		sections[tagGlyf] = f.fingerprintGlyf
+	}
+	if f.name != nil {
+		sections[tagName] = f.fingerprintName
+	}
+	if f.cmap != nil {
+		sections[tagCmap] = f.fingerprintCmap
+	}
+	// These write nothing without the table.
+	sections[tagCvt], sections[tagFpgm], sections[tagPrep] = f.writeCvt, f.writeFpgm, f.writePrep
+	sections[tagGasp], sections[tagMeta] = f.writeGasp, f.writeMeta
+	sections[tagOS2], sections[tagPost] = f.writeOS2, f.writePost
+	for _, t := range f.rawTables {
+		sections[t.tag] = func(w *byteWriter) error { return w.writeBytes(t.data) }
+	}
+	for _, tag := range f.copiedTables() {
+		sections[tag] = func(w *byteWriter) error {
+			data, err := f.rawTableData(tag)
+			if err != nil {
+				return err
+			}
+			return w.writeBytes(data)
+		}
+	}
+
+	delete(sections, tagDSIG)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "subfont fingerprint %d\n", FingerprintVersion)
+	tags := slices.SortedFunc(maps.Keys(sections), func(a, b Tag) int { return bytes.Compare(a[:], b[:]) })
+	for _, tag := range tags {
+		w := newByteWriter(nil)
+		if err := sections[tag](w); err != nil {
+			return [32]byte{}, fmt.Errorf("%s: %w", tag, err)
+		}
+		data := w.buffer.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(data))))
+		h.Write(data)
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// fingerprintGlyf writes the length and data of each glyph of `f` without padding to `w`.
+func (f *font) fingerprintGlyf(w *byteWriter) error {
+	for _, gd := range f.glyf.descs {
+		data := gd.raw
+		if len(data) > 0 {
+			if n, err := glyphDataLength(data); err == nil && n <= len(data) {
+				data = data[:n]
+			}
+		}
+		if err := w.writeUint32(uint32(len(data))); err != nil {
+			return err
+		}
+		if err := w.writeBytes(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fingerprintName writes the canonical name records of `f`, see canonicalNameRecords, to `w`,
+// each with the length and bytes of its string.
+func (f *font) fingerprintName(w *byteWriter) error {
+	for _, nr := range f.name.canonicalNameRecords() {
+		err := w.write(nr.platformID, nr.encodingID, nr.languageID, nr.nameID, uint16(len(nr.data)))
+		if err != nil {
+			return err
+		}
+		if err := w.writeBytes(nr.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fingerprintCmap writes the mapping of each platform and encoding of the cmap subtables of
+// `f` to `w`, in order of platform and encoding, with the runes of each in ascending order.
+func (f *font) fingerprintCmap(w *byteWriter) error {
+	type encoding struct{ platformID, encodingID int }
+	var encodings []encoding
+	mappings := map[encoding]map[rune]GlyphIndex{}
+	// In file order, so that of two subtables of an encoding the first wins, as in GetCmap.
+	for _, key := range f.cmap.subtableKeys {
+		subt := f.cmap.subtables[key]
+		e := encoding{subt.platformID, subt.encodingID}
+		if _, ok := mappings[e]; !ok {
+			mappings[e] = subt.cmap
+			encodings = append(encodings, e)
+		}
+	}
+	slices.SortFunc(encodings, func(a, b encoding) int {
+		return cmp.Or(cmp.Compare(a.platformID, b.platformID), cmp.Compare(a.encodingID, b.encodingID))
+	})
+	for _, e := range encodings {
+		cmap := mappings[e]
+		if err := w.write(uint16(e.platformID), uint16(e.encodingID), uint32(len(cmap))); err != nil {
+			return err
+		}
+		for _, r := range slices.Sorted(maps.Keys(cmap)) {
+			if err := w.write(uint32(r), uint16(cmap[r])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestFont_Fingerprint(t *testing.T) {
+	fingerprint := func(f *Font) [32]byte {
+		t.Helper()
+		sum, err := f.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	rewrite := func(f *Font) *Font {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	f := loadGoRegular(t)
+	want := fingerprint(f)
+	// Pinned, as the fingerprint of a font only changes with FingerprintVersion.
+	if got := hex.EncodeToString(want[:]); got != goRegularFingerprint {
+		t.Fatalf("Go Regular: fingerprint %s, want %s", got, goRegularFingerprint)
+	}
+
+	// Written again with a new modification date.
+	f.SetModified(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	if got := fingerprint(rewrite(f)); got != want {
+		t.Fatal("fingerprint changed with the modification date")
+	}
+
+	// Subsets with and without padded glyphs, of the font and of the font with the padding of
+	// its glyphs cut off, so with other glyf, loca and head tables.
+	runes := []rune("Hello, fingerprint")
+	aligned, err := f.SubsetWithOptions(runes, DefaultSubsetOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	trimmed := loadGoRegular(t)
+	for gid, gd := range trimmed.glyf.descs {
+		if len(gd.raw) == 0 {
+			continue
+		}
+		n, err := glyphDataLength(gd.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setGlyphData(trimmed, GlyphIndex(gid), gd.raw[:n])
+	}
+	opts := DefaultSubsetOptions()
+	opts.AlignGlyphs = false
+	packed, err := trimmed.SubsetWithOptions(runes, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alignedData, packedData := rewrite(aligned), rewrite(packed)
+	if fingerprint(alignedData) != fingerprint(packedData) {
+		t.Fatal("fingerprint changed with glyph padding")
+	}
+	if alignedData.trec.trMap[tagGlyf].length == packedData.trec.trMap[tagGlyf].length {
+		t.Fatal("glyph padding unchanged")
+	}
+	if fingerprint(aligned) == want {
+		t.Fatal("subset has the fingerprint of the font")
+	}
+
+	// A change of outline, metrics or mapping changes it.
+	gids, _ := f.LookupRunes([]rune{'A'})
+	tests := []struct {
+		name   string
+		modify func(f *Font)
+	}{
+		{"outline", func(f *Font) {
+			raw := bytes.Clone(f.glyf.descs[gids[0]].raw)
+			n, err := glyphDataLength(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw[n-1]++
+			setGlyphData(f, gids[0], raw)
+		}},
+		{"advance", func(f *Font) {
+			f.hmtx.hMetrics[gids[0]].advanceWidth++
+			f.markDirty(tagHmtx)
+		}},
+		{"cmap", func(f *Font) {
+			if err := f.RemapCmap(map[rune]GlyphIndex{'A': gids[0] + 1}, false); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		g := loadGoRegular(t)
+		tt.modify(g)
+		if fingerprint(g) == want || fingerprint(rewrite(g)) == want {
+			t.Fatalf("%s: fingerprint unchanged", tt.name)
+		}
+	}
+}
+
+// TestFont_FingerprintSourceTables checks that the tables Write copies from the font file are
+// hashed, except DSIG.
+func TestFont_FingerprintSourceTables(t *testing.T) {
+	f := loadGoRegular(t)
+	f.setRawTable(tagGPOS, []byte{0, 1, 0, 0, 0, 10, 0, 12, 0, 14, 0, 0, 0, 0, 0, 0})
+	f.setRawTable(tagDSIG, []byte{0, 0, 0, 1, 0, 0, 0, 0})
+	_, data := writeAndParse(t, f)
+
+	fingerprint := func(data []byte) [32]byte {
+		t.Helper()
+		g, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(g.rawTables) != 0 {
+			t.Fatal("tables passed through in a parsed font")
+		}
+		sum, err := g.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+	// modify returns `data` with the last byte of table `tag` changed.
+	modify := func(tag Tag) []byte {
+		t.Helper()
+		g, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr := g.trec.trMap[tag]
+		out := bytes.Clone(data)
+		out[int(tr.offset)+int(tr.length)-1]++
+		return out
+	}
+
+	want := fingerprint(data)
+	if got := fingerprint(modify(tagGPOS)); got == want {
+		t.Fatal("fingerprint unchanged with GPOS")
+	}
+	if got := fingerprint(modify(tagDSIG)); got != want {
+		t.Fatal("fingerprint changed with DSIG")
+	}
+	if sum, err := f.Fingerprint(); err != nil || sum != want {
+		t.Fatalf("fingerprint of the written font differs: %v", err)
+	}
+}
+
+// goRegularFingerprint is the fingerprint of Go Regular of FingerprintVersion 1.
+const goRegularFingerprint = "c5b566735ed77f084219ca13c74e6399dfe495149b78de03d707ddcf1f4053e3"
@@ -7,9 +7,10 @@ package ttf
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
-	"io"
+	"slices"
 )
 
 // Export what UniPDF needs.
@@ -48,12 +49,41 @@ type font struct {
 	cvt  *cvtTable
 	fpgm *fpgmTable
 	prep *prepTable
+	gasp *gaspTable
+	meta *metaTable
 	glyf *glyfTable
 	hmtx *hmtxTable
 	name *nameTable
 	os2  *os2Table
 	post *postTable
 	cmap *cmapTable
+
+	rawTables []rawTable // Written after the tables above, e.g. passed through layout tables.
+
+	color ColorFormat // Formats of the color glyphs, see parseColorFormat.
+
+	// dirty holds the tables changed since parsing, see markDirty. Write copies the others from
+	// the file the font was parsed from.
+	dirty map[Tag]bool
+}
+
+// markDirty notes that tables `tags` of `f` were changed, so that Write serializes them rather
+// than copying them from the file `f` was parsed from.
+func (f *font) markDirty(tags ...Tag) {
+	if f.dirty == nil {
+		f.dirty = make(map[Tag]bool)
+	}
+	for _, tag := range tags {
+		f.dirty[tag] = true
+	}
+}
+
+// hasTable reports whether `f` has table `tag`, passed through or in the file it was parsed from.
+func (f *font) hasTable(tag Tag) bool {
+	if slices.ContainsFunc(f.rawTables, func(t rawTable) bool { return t.tag == tag }) {
+		return true
+	}
+	return f.trec != nil && f.trec.HasTag(tag)
 }
 
 // Returns an error in strict mode, otherwise adds the incompatibility to a list of noted incompatibilities.
@@ -70,398 +100,232 @@ func (f font) numTables() int {
 	return int(f.ot.numTables)
 }
 
-func parseFont(r *byteReader) (*font, error) {
-	f := &font{}
-
-	var err error
-
-	// Load table offsets and records.
-	f.ot, err = f.parseOffsetTable(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.trec, err = f.parseTableRecords(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.head, err = f.parseHead(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.maxp, err = f.parseMaxp(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.hhea, err = f.parseHhea(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.hmtx, err = f.parseHmtx(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.loca, err = f.parseLoca(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.glyf, err = f.parseGlyf(r)
-	if err != nil {
-		return nil, err
-	}
-
-	f.prep, err = f.parsePrep(r)
-	if err != nil {
-		return nil, err
+// cancelCheckInterval is the number of glyphs parsed or subset between checks whether the
+// context of ParseContext or SubsetContext is done.
+const cancelCheckInterval = 1024
+
+// parseFont parses the font in `r`. In `strict` mode incompatibilities with the specification
+// that can be worked around fail parsing, otherwise they are noted, see recordIncompatibilityf.
+// It stops with the error of `ctx` once `ctx` is done, checked between tables and glyphs.
+func parseFont(ctx context.Context, r *byteReader, strict bool) (*font, error) {
+	f := &font{strict: strict}
+
+	// Errors are wrapped with the table being parsed and the offset, see byteReader.wrapErr.
+	tables := []struct {
+		name  string
+		parse func() error
+	}{
+		{"offset table", func() (err error) { f.ot, err = f.parseOffsetTable(r); return err }},
+		{"table records", func() (err error) { f.trec, err = f.parseTableRecords(r); return err }},
+		{"head", func() (err error) { f.head, err = f.parseHead(r); return err }},
+		{"maxp", func() (err error) { f.maxp, err = f.parseMaxp(r); return err }},
+		{"hhea", func() (err error) { f.hhea, err = f.parseHhea(r); return err }},
+		{"hmtx", func() (err error) { f.hmtx, err = f.parseHmtx(r); return err }},
+		{"loca", func() (err error) { f.loca, err = f.parseLoca(r); return err }},
+		{"glyf", func() (err error) { f.glyf, err = f.parseGlyf(ctx, r); return err }},
+		{"prep", func() (err error) { f.prep, err = f.parsePrep(r); return err }},
+		{"name", func() (err error) { f.name, err = f.parseNameTable(r); return err }},
+		{"OS/2", func() (err error) { f.os2, err = f.parseOS2Table(r); return err }},
+		{"post", func() (err error) { f.post, err = f.parsePost(r); return err }},
+		{"cmap", func() (err error) { f.cmap, err = f.parseCmap(r); return err }},
+		{"cvt", func() (err error) { f.cvt, err = f.parseCvt(r); return err }},
+		{"fpgm", func() (err error) { f.fpgm, err = f.parseFpgm(r); return err }},
+		{"gasp", func() (err error) { f.gasp, err = f.parseGasp(r); return err }},
+		{"meta", func() (err error) { f.meta, err = f.parseMeta(r); return err }},
+		{"color tables", func() (err error) { f.color, err = f.parseColorFormat(r); return err }},
+	}
+	for _, t := range tables {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("parsing cancelled before %s: %w", t.name, err)
+		}
+		r.setContext(t.name)
+		if err := t.parse(); err != nil {
+			return nil, r.wrapErr(err, "")
+		}
 	}
 
-	f.name, err = f.parseNameTable(r)
-	if err != nil {
-		return nil, err
-	}
+	return f, nil
+}
 
-	f.os2, err = f.parseOS2Table(r)
-	if err != nil {
-		return nil, err
-	}
+// tableWriter writes table `tag` of a font to the buffer of a byteWriter.
+type tableWriter struct {
+	tag   Tag
+	write func(w *byteWriter) error
+}
 
-	f.post, err = f.parsePost(r)
-	if err != nil {
-		return nil, err
-	}
+// tablesToWrite returns the writers of the tables of `f` in the order they are laid out in the
+// file. NOTE that not all tables that are loaded are written out.
+//
+// With `src`, the file `f` was parsed from, tables that were not changed, see markDirty, are
+// copied from it byte for byte, and so are the tables of the file that are not parsed, e.g.
+// GSUB or meta, after the others. head is always written anew.
+func (f *font) tablesToWrite(src *byteReader) []tableWriter {
+	if src != nil && f.trec != nil {
+		src = src.fork()
+	} else {
+		src = nil
+	}
+	var tables []tableWriter
+	add := func(has bool, tag Tag, write func(w *byteWriter) error) {
+		if !has {
+			return
+		}
+		// write patches checksumAdjustment into head, which must have the length writeHead gives.
+		if src != nil && !f.dirty[tag] && f.trec.HasTag(tag) && tag != tagHead {
+			write = f.copyTable(src, tag)
+		}
+		tables = append(tables, tableWriter{tag, write})
+	}
+	add(true, tagHead, f.writeHead)
+	add(true, tagMaxp, f.writeMaxp)
+	add(f.hhea != nil, tagHhea, f.writeHhea)
+	add(f.hmtx != nil, tagHmtx, f.writeHmtx)
+	add(f.loca != nil, tagLoca, f.writeLoca)
+	add(f.glyf != nil, tagGlyf, f.writeGlyf)
+	add(f.prep != nil, tagPrep, f.writePrep)
+	add(f.cvt != nil, tagCvt, f.writeCvt)
+	add(f.fpgm != nil, tagFpgm, f.writeFpgm)
+	add(f.gasp != nil, tagGasp, f.writeGasp)
+	add(f.name != nil, tagName, f.writeNameTable)
+	add(f.os2 != nil, tagOS2, f.writeOS2)
+	add(f.post != nil, tagPost, f.writePost)
+	add(f.cmap != nil, tagCmap, f.writeCmap)
+	add(f.meta != nil, tagMeta, f.writeMeta)
+	for _, t := range f.rawTables {
+		tables = append(tables, tableWriter{t.tag, func(w *byteWriter) error { return w.writeBytes(t.data) }})
+	}
+	if src != nil {
+		for _, tag := range f.copiedTables() {
+			tables = append(tables, tableWriter{tag, f.copyTable(src, tag)})
+		}
+	}
+	return tables
+}
 
-	f.cmap, err = f.parseCmap(r)
-	if err != nil {
-		return nil, err
-	}
+// parsedTags are the tags of the tables that tablesToWrite writes from the parsed font, whether
+// `f` still has them or not.
+var parsedTags = []Tag{
+	tagHead, tagMaxp, tagHhea, tagHmtx, tagLoca, tagGlyf, tagPrep, tagCvt, tagFpgm, tagGasp,
+	tagName, tagOS2, tagPost, tagCmap, tagMeta,
+}
 
-	f.cvt, err = f.parseCvt(r)
-	if err != nil {
-		return nil, err
+// copiedTables returns the tags of the tables of the file `f` was parsed from that tablesToWrite
+// copies as they are: those that are neither parsed nor replaced by f.rawTables, in file order.
+func (f *font) copiedTables() []Tag {
+	if f.trec == nil {
+		return nil
 	}
-
-	f.fpgm, err = f.parseFpgm(r)
-	if err != nil {
-		return nil, err
+	var tags []Tag
+	for _, tr := range f.trec.list {
+		tag := tr.tableTag
+		if slices.Contains(parsedTags, tag) || slices.Contains(tags, tag) ||
+			slices.ContainsFunc(f.rawTables, func(t rawTable) bool { return t.tag == tag }) {
+			continue
+		}
+		tags = append(tags, tag)
 	}
-
-	return f, nil
+	return tags
 }
 
-// numTablesToWrite returns the number of tables in `f`.
-// Calculates based on the number of tables will be written out.
-// NOTE that not all tables that are loaded are written out.
-func (f *font) numTablesToWrite() int {
-	var num int
-
-	if f.head != nil {
-		num++
-	}
-	if f.maxp != nil {
-		num++
-	}
-	if f.hhea != nil {
-		num++
-	}
-	if f.hmtx != nil {
-		num++
-	}
-	if f.loca != nil {
-		num++
-	}
-	if f.glyf != nil {
-		num++
-	}
-	if f.cvt != nil {
-		num++
-	}
-	if f.fpgm != nil {
-		num++
-	}
-	if f.prep != nil {
-		num++
-	}
-	if f.name != nil {
-		num++
-	}
-	if f.os2 != nil {
-		num++
-	}
-	if f.post != nil {
-		num++
-	}
-	if f.cmap != nil {
-		num++
+// copyTable returns a writer of the data of table `tag` in `src`, the file `f` was parsed from.
+// The table is read on each write, so that it is not held in memory between the two phases of
+// write.
+func (f *font) copyTable(src *byteReader, tag Tag) func(w *byteWriter) error {
+	return func(w *byteWriter) error {
+		tr, has, err := f.seekToTable(src, tag)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return fmt.Errorf("%s: %w", tag, errRequiredField)
+		}
+		var data []byte
+		if err := src.readBytes(&data, int(tr.length)); err != nil {
+			return fmt.Errorf("%s: %w", tag, err)
+		}
+		return w.writeBytes(data)
 	}
-	return num
 }
 
-func (f *font) write(w *byteWriter) error {
-	// slog.Debug("Writing font")
-	numTables := f.numTablesToWrite()
+// write writes `f` to `w` in a single pass that never seeks back, holding at most one table in
+// memory, so that `w` can be a pipe or a network connection. Writing is done in two phases:
+//
+//  1. Layout: each table is serialized to the buffer of `w` and discarded, keeping only its
+//     length and checksum. This gives the offset of every table, and so the table directory.
+//  2. Streaming: the offset table and the directory are written, then each table is serialized
+//     again and written in order, padded to four bytes.
+//
+// checksumAdjustment in head is 0xB1B0AFBA minus the checksum of the whole file. As every part
+// of the file starts on a four byte boundary and padding is zeros, that checksum is the sum of
+// the checksum of the directory and those of the tables, with checksumAdjustment counting as 0
+// as it does in the checksum of head. So it is known after phase 1, and patched into head before
+// head is written, unless skipped.
+//
+// Tables that were not changed are copied from `src` when it is not nil, see tablesToWrite.
+func (f *font) write(w *byteWriter, opts WriteOptions, src *byteReader) error {
+	tables := f.tablesToWrite(src)
+	numTables := len(tables)
+	searchRange, entrySelector, rangeShift := binarySearchParams(numTables, 16)
 	otTable := &offsetTable{
 		sfntVersion:   f.ot.sfntVersion,
 		numTables:     uint16(numTables),
-		searchRange:   f.ot.searchRange,
-		entrySelector: f.ot.entrySelector,
-		rangeShift:    f.ot.rangeShift,
+		searchRange:   searchRange,
+		entrySelector: entrySelector,
+		rangeShift:    rangeShift,
 	}
-	trec := &tableRecords{}
 
-	f.ot.numTables = uint16(numTables)
-
-	// Starting offset after offset table and table records.
-	startOffset := int64(12 + numTables*16)
-
-	// slog.Debug(fmt.Sprintf("==== write\nnumTables: %d\nstartOffset: %d", numTables, startOffset))
-	// slog.Debug("Write 2")
-	// Writing is two phases and is done in a few steps:
-	// 1. Write the content tables: head, hhea, etc in the expected order and keep track of the length, checksum for each.
-	// 2. Generate the table records based on the information.
-	// 3. Write out in final order: offset table, table records, head, ...
-	// 4. Set checkAdjustment of head table based on checksumof entire file
-	// 5. Write the final output
-
-	// Write to buffer to get offsets.
-	var buf bytes.Buffer
-	var headChecksum uint32
-	{
-		bufw := newByteWriter(&buf)
-
-		// head.
-		f.head.checksumAdjustment = 0
-		offset := startOffset
-		err := f.writeHead(bufw)
-		if err != nil {
-			return err
-		}
-		headChecksum = bufw.checksum()
-		trec.Set("head", offset, bufw.bufferedLen(), headChecksum)
-		err = bufw.flush()
-		if err != nil {
+	// Phase 1: lay out the tables after the offset table and table records.
+	trec := &tableRecords{}
+	offset := int64(12 + numTables*16)
+	for _, t := range tables {
+		if err := t.write(w); err != nil {
 			return err
 		}
+		length := w.bufferedLen()
+		trec.SetTag(t.tag, offset, length, tableChecksum(t.tag, w.buffer.Bytes()))
+		offset += int64(length+3) &^ 3
+		w.discard()
+	}
 
-		// maxp.
-		offset = startOffset + bufw.flushedLen
-		err = f.writeMaxp(bufw)
-		if err != nil {
-			return err
-		}
-		trec.Set("maxp", offset, bufw.bufferedLen(), bufw.checksum())
-		err = bufw.flush()
-		if err != nil {
+	// Phase 2: stream the offset table, the table records and the tables.
+	// Create a mock font for writing without modifying the original entries of `f`.
+	mockf := &font{
+		ot:   otTable,
+		trec: trec,
+	}
+	if err := mockf.writeOffsetTable(w); err != nil {
+		return err
+	}
+	if err := mockf.writeTableRecords(w); err != nil {
+		return err
+	}
+	sum := w.checksum()
+	for _, tr := range trec.list {
+		sum += tr.checksum
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := t.write(w); err != nil {
 			return err
 		}
-
-		// hhea.
-		if f.hhea != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeHhea(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("hhea", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// hmtx.
-		if f.hmtx != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeHmtx(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("hmtx", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// loca.
-		if f.loca != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeLoca(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("loca", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// glyf.
-		if f.glyf != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeGlyf(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("glyf", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// prep.
-		if f.prep != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writePrep(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("prep", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// cvt.
-		if f.cvt != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeCvt(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("cvt", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// fpgm.
-		if f.fpgm != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeFpgm(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("fpgm", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// name.
-		if f.name != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeNameTable(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("name", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
+		tr := trec.trMap[t.tag]
+		if w.bufferedLen() != int(tr.length) {
+			return fmt.Errorf("%s: %d bytes written, %d laid out: %w", t.tag, w.bufferedLen(), tr.length, errRangeCheck)
 		}
-
-		// os2.
-		if f.os2 != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeOS2(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("OS/2", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
+		if t.tag == tagHead {
+			adjustment := 0xB1B0AFBA - sum
+			if opts.SkipChecksumAdjustment {
+				adjustment = 0
 			}
+			binary.BigEndian.PutUint32(w.buffer.Bytes()[headAdjustmentOffset:], adjustment)
 		}
-
-		// post
-		if f.post != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writePost(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("post", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-
-		// cmap
-		if f.cmap != nil {
-			offset = startOffset + bufw.flushedLen
-			err = f.writeCmap(bufw)
-			if err != nil {
-				return err
-			}
-			trec.Set("cmap", offset, bufw.bufferedLen(), bufw.checksum())
-			err = bufw.flush()
-			if err != nil {
-				return err
-			}
-		}
-	}
-	// slog.Debug("Write 3")
-
-	// Write the offset and table records to another mock buffer.
-	var bufh bytes.Buffer
-	{
-		bufw := newByteWriter(&bufh)
-		// Create a mock font for writing without modifying the original entries of `f`.
-		mockf := &font{
-			ot:   otTable,
-			trec: trec,
-		}
-
-		err := mockf.writeOffsetTable(bufw)
-		if err != nil {
-			return err
-		}
-
-		err = mockf.writeTableRecords(bufw)
-		if err != nil {
-			return err
-		}
-		err = bufw.flush()
-		if err != nil {
+		if err := w.flushPadded(); err != nil {
 			return err
 		}
 	}
-
-	// Write everything to bufh.
-	_, err := buf.WriteTo(&bufh)
-	if err != nil {
-		return err
-	}
-
-	// Calculate total checksum for the entire font.
-	checksummer := byteWriter{
-		buffer: bufh,
-	}
-	fontChecksum := checksummer.checksum()
-	checksumAdjustment := 0xB1B0AFBA - fontChecksum
-
-	// Set the checksumAdjustment of the head table.
-	data := bufh.Bytes()
-	hoff := startOffset
-	binary.BigEndian.PutUint32(data[hoff+8:hoff+12], checksumAdjustment)
-
-	buffer := bytes.NewBuffer(data)
-	_, err = io.Copy(&w.buffer, buffer)
-	return err
+	return nil
 }
 
 // TableInfo provides readable information regarding a table.
@@ -0,0 +1,89 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// FontSet resolves runes across fonts in order of preference: each rune is rendered by the
+// first font whose cmap maps it to a glyph other than .notdef, so that later fonts are the
+// fallbacks of earlier ones.
+type FontSet struct {
+	fonts []*Font
+}
+
+// NewFontSet returns a FontSet of `fonts`, the most preferred first.
+func NewFontSet(fonts ...*Font) *FontSet {
+	return &FontSet{fonts: fonts}
+}
+
+// Fonts returns the fonts of `s` in order of preference.
+func (s *FontSet) Fonts() []*Font {
+	return s.fonts
+}
+
+// Lookup returns the index in Fonts of the font that renders `r` and its glyph of `r`. It
+// returns false if no font maps `r` to one of its glyphs.
+func (s *FontSet) Lookup(r rune) (fontIndex int, gid GlyphIndex, ok bool) {
+	for i, f := range s.fonts {
+		if gid, _ := lookupRune(f.lookupCmaps(), r); gid != 0 && f.ValidGID(gid) {
+			return i, gid, true
+		}
+	}
+	return -1, 0, false
+}
+
+// FontRun is a run of text rendered by one font of a FontSet.
+type FontRun struct {
+	Font   int          // Index in FontSet.Fonts, -1 for runes that no font maps.
+	Offset int          // Byte offset of Text in the string.
+	Text   string       // The runes of the run.
+	GIDs   []GlyphIndex // Glyph of each rune of Text, 0 where Font is -1.
+}
+
+// LookupString splits `text` into runs of consecutive runes rendered by the same font, see
+// Lookup. The runs cover `text` in order. Bytes that are not valid UTF-8 are looked up as
+// U+FFFD.
+func (s *FontSet) LookupString(text string) []FontRun {
+	var runs []FontRun
+	for offset := 0; offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		font, gid, _ := s.Lookup(r)
+		if n := len(runs); n == 0 || runs[n-1].Font != font {
+			runs = append(runs, FontRun{Font: font, Offset: offset})
+		}
+		offset += size
+		run := &runs[len(runs)-1]
+		run.Text = text[run.Offset:offset]
+		run.GIDs = append(run.GIDs, gid)
+	}
+	return runs
+}
+
+// SubsetAll subsets each font of `s` to the runes of `runes` it renders, see Lookup, with
+// Subset. The subset of a font that renders none of `runes` is nil.
+func (s *FontSet) SubsetAll(runes []rune) ([]*Font, error) {
+	served := make([][]rune, len(s.fonts))
+	for _, r := range runes {
+		if i, _, ok := s.Lookup(r); ok {
+			served[i] = append(served[i], r)
+		}
+	}
+	subsets := make([]*Font, len(s.fonts))
+	for i, f := range s.fonts {
+		if len(served[i]) == 0 {
+			continue
+		}
+		sub, err := f.Subset(served[i])
+		if err != nil {
+			return nil, fmt.Errorf("font %d: %w", i, err)
+		}
+		subsets[i] = sub
+	}
+	return subsets, nil
+}
@@ -0,0 +1,91 @@
+package ttf
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+)
+
+// cjkFixture returns Go Regular with a cmap mapping only the ideographs of 世界你好 and 'H',
+// standing in for a CJK font.
+func cjkFixture(t *testing.T) *Font {
+	t.Helper()
+	f := loadGoRegular(t)
+	if err := f.RemapCmap(map[rune]GlyphIndex{'世': 10, '界': 11, '你': 12, '好': 13, 'H': 14}, true); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFontSet_LookupString(t *testing.T) {
+	latin, cjk := loadGoRegular(t), cjkFixture(t)
+	set := NewFontSet(latin, cjk)
+
+	if i, gid, ok := set.Lookup('界'); !ok || i != 1 || gid != 11 {
+		t.Fatalf("界: font %d glyph %d %t", i, gid, ok)
+	}
+	// The first font wins.
+	want, _ := latin.LookupRunes([]rune{'H'})
+	if i, gid, ok := set.Lookup('H'); !ok || i != 0 || gid != want[0] {
+		t.Fatalf("H: font %d glyph %d %t", i, gid, ok)
+	}
+	if i, _, ok := set.Lookup('ก'); ok || i != -1 {
+		t.Fatalf("ก: font %d %t", i, ok)
+	}
+
+	// Invalid UTF-8 is looked up as U+FFFD, which Go Regular maps.
+	text := "Hello, 世界! 你好ก\xff"
+	runs := set.LookupString(text)
+	wantRuns := []struct {
+		font int
+		text string
+	}{{0, "Hello, "}, {1, "世界"}, {0, "! "}, {1, "你好"}, {-1, "ก"}, {0, "\xff"}}
+	if len(runs) != len(wantRuns) {
+		t.Fatalf("got %d runs %+v, want %d", len(runs), runs, len(wantRuns))
+	}
+	for i, run := range runs {
+		if run.Font != wantRuns[i].font || run.Text != wantRuns[i].text || text[run.Offset:run.Offset+len(run.Text)] != run.Text {
+			t.Fatalf("run %d: got %+v, want %+v", i, run, wantRuns[i])
+		}
+		if len(run.GIDs) != len([]rune(run.Text)) {
+			t.Fatalf("run %d: %d glyphs for %q", i, len(run.GIDs), run.Text)
+		}
+		for j, r := range []rune(run.Text) {
+			if _, gid, _ := set.Lookup(r); run.GIDs[j] != gid {
+				t.Fatalf("run %d: glyph %d of %q is %d, want %d", i, j, r, run.GIDs[j], gid)
+			}
+		}
+	}
+	if runs := set.LookupString(""); len(runs) != 0 {
+		t.Fatalf("empty string: %+v", runs)
+	}
+}
+
+func TestFontSet_SubsetAll(t *testing.T) {
+	bold, err := Parse(bytes.NewReader(gobold.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewFontSet(loadGoRegular(t), cjkFixture(t), bold)
+	subsets, err := set.SubsetAll([]rune("Hello, 世界"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subsets) != 3 || subsets[2] != nil {
+		t.Fatalf("got %d subsets, want a nil subset of the unused font", len(subsets))
+	}
+	// Each subset maps the runes its font renders, and the CJK font not 'H', which the first
+	// font renders.
+	for i, want := range []string{"Hello, ", "世界"} {
+		cmaps := subsets[i].lookupCmaps()
+		for _, r := range want {
+			if gid, _ := lookupRune(cmaps, r); gid == 0 {
+				t.Fatalf("subset %d does not map %q", i, r)
+			}
+		}
+	}
+	if gid, _ := lookupRune(subsets[1].lookupCmaps(), 'H'); gid != 0 {
+		t.Fatal("CJK subset maps H")
+	}
+}
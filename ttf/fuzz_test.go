@@ -0,0 +1,74 @@
+package ttf
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The seed corpus under testdata/fuzz holds small subsets of Go Regular written by this
+// package, with and without cmap, with a rune outside the BMP and with layout tables, and
+// inputs that once crashed. Run a target with e.g. `go test -fuzz=FuzzParse -fuzztime=60s`.
+
+// fuzzRunes are the runes FuzzSubset subsets to: ASCII, Latin-1, a rune outside the BMP
+// and one that is never mapped.
+var fuzzRunes = []rune("Ag 0é \U0001F600￿")
+
+// FuzzParse checks that parsing any input returns an error rather than panicking, and that so
+// do writing a parsed font and parsing the output. Write keeps loca as parsed, so the output of
+// a damaged font need not parse.
+func FuzzParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fnt, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			if fnt != nil {
+				t.Fatal("font returned with an error")
+			}
+			return
+		}
+		var buf bytes.Buffer
+		if err := fnt.Write(&buf); err != nil {
+			return
+		}
+		if g, err := Parse(bytes.NewReader(buf.Bytes())); err != nil && g != nil {
+			t.Fatal("written font returned with an error")
+		}
+	})
+}
+
+// FuzzValidate checks that validating any input returns an error rather than panicking, and
+// that the error agrees with the report.
+func FuzzValidate(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rep, err := ValidateBytesReport(data, ValidationOptions{CheckGlyphs: true})
+		if rep == nil {
+			if err == nil {
+				t.Fatal("neither report nor error")
+			}
+			return
+		}
+		if rep.HasErrors() && err == nil {
+			t.Fatalf("errors %v, but no error returned", rep.Errors())
+		}
+	})
+}
+
+// FuzzSubset checks that subsetting any parsed font to fuzzRunes and writing the subset
+// returns an error rather than panicking.
+func FuzzSubset(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fnt, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, opts := range []SubsetOptions{DefaultSubsetOptions(), {RetainGIDs: true, Layout: LayoutPassthrough}} {
+			sub, err := fnt.SubsetWithOptions(fuzzRunes, opts)
+			if err != nil {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := sub.Write(&buf); err != nil {
+				continue
+			}
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package ttf
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// loadGoRegular parses the Go Regular font shipped with golang.org/x/image.
+func loadGoRegular(t testing.TB) *Font {
+	t.Helper()
+	f, err := Parse(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// writeAndParse writes `f` and parses the output again, returning the parsed font and the
+// output.
+func writeAndParse(t testing.TB, f *Font) (*Font, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, buf.Bytes()
+}
@@ -0,0 +1,174 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import "strconv"
+
+// PlatformID identifies the platform of a cmap subtable or name record.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/name#platform-ids
+type PlatformID uint16
+
+// Platform IDs.
+const (
+	PlatformUnicode   PlatformID = 0
+	PlatformMacintosh PlatformID = 1
+	PlatformISO       PlatformID = 2 // Deprecated.
+	PlatformWindows   PlatformID = 3
+	PlatformCustom    PlatformID = 4
+)
+
+var platformNames = map[PlatformID]string{
+	PlatformUnicode:   "Unicode",
+	PlatformMacintosh: "Macintosh",
+	PlatformISO:       "ISO",
+	PlatformWindows:   "Windows",
+	PlatformCustom:    "Custom",
+}
+
+// String returns the name of `p`, e.g. "Windows", or "Platform 7" if it is not known.
+func (p PlatformID) String() string {
+	if s, ok := platformNames[p]; ok {
+		return s
+	}
+	return "Platform " + strconv.Itoa(int(p))
+}
+
+// EncodingID is the platform-specific encoding of a cmap subtable or name record. Its meaning
+// depends on the PlatformID, see PlatformID.EncodingName.
+type EncodingID uint16
+
+// Encoding IDs of the Unicode platform.
+const (
+	EncodingUnicode10        EncodingID = 0 // Unicode 1.0 semantics, deprecated.
+	EncodingUnicode11        EncodingID = 1 // Unicode 1.1 semantics, deprecated.
+	EncodingUnicodeISO10646  EncodingID = 2 // ISO/IEC 10646 semantics, deprecated.
+	EncodingUnicode20BMP     EncodingID = 3 // Unicode 2.0 and onwards, BMP only.
+	EncodingUnicode20Full    EncodingID = 4 // Unicode 2.0 and onwards, full repertoire.
+	EncodingUnicodeVariation EncodingID = 5 // Unicode variation sequences, cmap format 14.
+	EncodingUnicodeFull      EncodingID = 6 // Unicode full repertoire, cmap format 13.
+)
+
+// Encoding IDs of the Macintosh platform. Only Roman is listed, the other script codes are
+// rarely found in fonts.
+const (
+	EncodingMacRoman EncodingID = 0
+)
+
+// Encoding IDs of the Windows platform.
+const (
+	EncodingWindowsSymbol      EncodingID = 0
+	EncodingWindowsUnicodeBMP  EncodingID = 1
+	EncodingWindowsShiftJIS    EncodingID = 2
+	EncodingWindowsPRC         EncodingID = 3
+	EncodingWindowsBig5        EncodingID = 4
+	EncodingWindowsWansung     EncodingID = 5
+	EncodingWindowsJohab       EncodingID = 6
+	EncodingWindowsUnicodeUCS4 EncodingID = 10
+)
+
+var encodingNames = map[PlatformID]map[EncodingID]string{
+	PlatformUnicode: {
+		EncodingUnicode10:        "Unicode 1.0",
+		EncodingUnicode11:        "Unicode 1.1",
+		EncodingUnicodeISO10646:  "ISO/IEC 10646",
+		EncodingUnicode20BMP:     "Unicode 2.0 BMP",
+		EncodingUnicode20Full:    "Unicode 2.0 full",
+		EncodingUnicodeVariation: "Unicode variation sequences",
+		EncodingUnicodeFull:      "Unicode full",
+	},
+	PlatformMacintosh: {
+		EncodingMacRoman: "Roman",
+	},
+	PlatformWindows: {
+		EncodingWindowsSymbol:      "Symbol",
+		EncodingWindowsUnicodeBMP:  "Unicode BMP",
+		EncodingWindowsShiftJIS:    "ShiftJIS",
+		EncodingWindowsPRC:         "PRC",
+		EncodingWindowsBig5:        "Big5",
+		EncodingWindowsWansung:     "Wansung",
+		EncodingWindowsJohab:       "Johab",
+		EncodingWindowsUnicodeUCS4: "Unicode UCS-4",
+	},
+}
+
+// EncodingName returns the name of encoding `e` on platform `p`, e.g. "Unicode BMP" for
+// (PlatformWindows, EncodingWindowsUnicodeBMP), or "Encoding 7" if it is not known.
+func (p PlatformID) EncodingName(e EncodingID) string {
+	if s, ok := encodingNames[p][e]; ok {
+		return s
+	}
+	return "Encoding " + strconv.Itoa(int(e))
+}
+
+// NameID identifies the string of a name record.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/name#name-ids
+type NameID uint16
+
+// Name IDs.
+const (
+	NameIDCopyright                      NameID = 0
+	NameIDFamily                         NameID = 1
+	NameIDSubfamily                      NameID = 2
+	NameIDUniqueID                       NameID = 3
+	NameIDFullName                       NameID = 4
+	NameIDVersion                        NameID = 5
+	NameIDPostScriptName                 NameID = 6
+	NameIDTrademark                      NameID = 7
+	NameIDManufacturer                   NameID = 8
+	NameIDDesigner                       NameID = 9
+	NameIDDescription                    NameID = 10
+	NameIDVendorURL                      NameID = 11
+	NameIDDesignerURL                    NameID = 12
+	NameIDLicense                        NameID = 13
+	NameIDLicenseURL                     NameID = 14
+	NameIDTypographicFamily              NameID = 16
+	NameIDTypographicSubfamily           NameID = 17
+	NameIDCompatibleFullName             NameID = 18
+	NameIDSampleText                     NameID = 19
+	NameIDPostScriptCIDFindfont          NameID = 20
+	NameIDWWSFamily                      NameID = 21
+	NameIDWWSSubfamily                   NameID = 22
+	NameIDLightBackgroundPalette         NameID = 23
+	NameIDDarkBackgroundPalette          NameID = 24
+	NameIDVariationsPostScriptNamePrefix NameID = 25
+)
+
+var nameIDNames = map[NameID]string{
+	NameIDCopyright:                      "Copyright",
+	NameIDFamily:                         "Family",
+	NameIDSubfamily:                      "Subfamily",
+	NameIDUniqueID:                       "UniqueID",
+	NameIDFullName:                       "FullName",
+	NameIDVersion:                        "Version",
+	NameIDPostScriptName:                 "PostScriptName",
+	NameIDTrademark:                      "Trademark",
+	NameIDManufacturer:                   "Manufacturer",
+	NameIDDesigner:                       "Designer",
+	NameIDDescription:                    "Description",
+	NameIDVendorURL:                      "VendorURL",
+	NameIDDesignerURL:                    "DesignerURL",
+	NameIDLicense:                        "License",
+	NameIDLicenseURL:                     "LicenseURL",
+	NameIDTypographicFamily:              "TypographicFamily",
+	NameIDTypographicSubfamily:           "TypographicSubfamily",
+	NameIDCompatibleFullName:             "CompatibleFullName",
+	NameIDSampleText:                     "SampleText",
+	NameIDPostScriptCIDFindfont:          "PostScriptCIDFindfont",
+	NameIDWWSFamily:                      "WWSFamily",
+	NameIDWWSSubfamily:                   "WWSSubfamily",
+	NameIDLightBackgroundPalette:         "LightBackgroundPalette",
+	NameIDDarkBackgroundPalette:          "DarkBackgroundPalette",
+	NameIDVariationsPostScriptNamePrefix: "VariationsPostScriptNamePrefix",
+}
+
+// String returns the name of `id`, e.g. "PostScriptName", or "NameID 300" for font-specific
+// and unknown IDs.
+func (id NameID) String() string {
+	if s, ok := nameIDNames[id]; ok {
+		return s
+	}
+	return "NameID " + strconv.Itoa(int(id))
+}
@@ -0,0 +1,70 @@
+package ttf
+
+import "testing"
+
+func TestIDs(t *testing.T) {
+	// Values from the OpenType spec, name and cmap tables.
+	platforms := map[PlatformID]uint16{
+		PlatformUnicode: 0, PlatformMacintosh: 1, PlatformISO: 2, PlatformWindows: 3, PlatformCustom: 4,
+	}
+	for id, want := range platforms {
+		if uint16(id) != want {
+			t.Fatalf("%v = %d, want %d", id, id, want)
+		}
+	}
+	encodings := []struct {
+		id   EncodingID
+		want uint16
+	}{
+		{EncodingUnicode10, 0}, {EncodingUnicode11, 1}, {EncodingUnicodeISO10646, 2},
+		{EncodingUnicode20BMP, 3}, {EncodingUnicode20Full, 4}, {EncodingUnicodeVariation, 5},
+		{EncodingUnicodeFull, 6}, {EncodingMacRoman, 0},
+		{EncodingWindowsSymbol, 0}, {EncodingWindowsUnicodeBMP, 1}, {EncodingWindowsShiftJIS, 2},
+		{EncodingWindowsPRC, 3}, {EncodingWindowsBig5, 4}, {EncodingWindowsWansung, 5},
+		{EncodingWindowsJohab, 6}, {EncodingWindowsUnicodeUCS4, 10},
+	}
+	for _, tt := range encodings {
+		if uint16(tt.id) != tt.want {
+			t.Fatalf("encoding %d, want %d", tt.id, tt.want)
+		}
+	}
+	names := []struct {
+		id   NameID
+		want uint16
+	}{
+		{NameIDCopyright, 0}, {NameIDFamily, 1}, {NameIDSubfamily, 2}, {NameIDUniqueID, 3},
+		{NameIDFullName, 4}, {NameIDVersion, 5}, {NameIDPostScriptName, 6}, {NameIDTrademark, 7},
+		{NameIDManufacturer, 8}, {NameIDDesigner, 9}, {NameIDDescription, 10}, {NameIDVendorURL, 11},
+		{NameIDDesignerURL, 12}, {NameIDLicense, 13}, {NameIDLicenseURL, 14},
+		{NameIDTypographicFamily, 16}, {NameIDTypographicSubfamily, 17}, {NameIDCompatibleFullName, 18},
+		{NameIDSampleText, 19}, {NameIDPostScriptCIDFindfont, 20}, {NameIDWWSFamily, 21},
+		{NameIDWWSSubfamily, 22}, {NameIDLightBackgroundPalette, 23}, {NameIDDarkBackgroundPalette, 24},
+		{NameIDVariationsPostScriptNamePrefix, 25},
+	}
+	for _, tt := range names {
+		if uint16(tt.id) != tt.want {
+			t.Fatalf("%v = %d, want %d", tt.id, uint16(tt.id), tt.want)
+		}
+	}
+
+	strs := map[string]string{
+		PlatformWindows.String():                                  "Windows",
+		PlatformID(9).String():                                    "Platform 9",
+		PlatformWindows.EncodingName(EncodingWindowsUnicodeBMP):   "Unicode BMP",
+		PlatformMacintosh.EncodingName(EncodingMacRoman):          "Roman",
+		PlatformUnicode.EncodingName(EncodingUnicode20BMP):        "Unicode 2.0 BMP",
+		PlatformMacintosh.EncodingName(EncodingWindowsUnicodeBMP): "Encoding 1",
+		NameIDPostScriptName.String():                             "PostScriptName",
+		NameID(15).String():                                       "NameID 15",
+	}
+	for got, want := range strs {
+		if got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	f := loadGoRegular(t)
+	if f.GetNameByID(1) != "Go" || f.Name(NameIDFamily) != "Go" || f.GetCmap(3, 1)['A'] == 0 {
+		t.Fatalf("family %q", f.Name(NameIDFamily))
+	}
+}
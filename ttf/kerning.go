@@ -0,0 +1,413 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/bits"
+	"slices"
+)
+
+// KerningPairs returns the kerning of `f` as the adjustment of the advance width of the first
+// glyph of each pair of glyphs, in font units, from the horizontal format 0 subtables of the
+// kern table and the pair adjustment lookups of the kern feature of GPOS, single pairs and
+// class pairs. Pairs of both tables have the GPOS adjustment. Pairs without adjustment are
+// left out.
+//
+// Adjustments of several kern subtables or GPOS lookups add up, as they apply one after
+// another. Only the first subtable of a GPOS lookup that matches a pair applies.
+func (f *Font) KerningPairs() (map[[2]GlyphIndex]int16, error) {
+	pairs, err := f.kerning()
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(pairs), nil
+}
+
+// Kern returns the kerning of glyphs `a` and `b`, see KerningPairs. It returns false if the
+// pair has none or the kerning of `f` cannot be read.
+func (f *Font) Kern(a, b GlyphIndex) (int16, bool) {
+	pairs, err := f.kerning()
+	if err != nil {
+		return 0, false
+	}
+	adj, ok := pairs[[2]GlyphIndex{a, b}]
+	return adj, ok
+}
+
+// readKerning reads the kerning pairs of `f`, see KerningPairs.
+func (f *Font) readKerning() (map[[2]GlyphIndex]int16, error) {
+	if f.maxp == nil {
+		return nil, errRequiredField
+	}
+	numGlyphs := int(f.maxp.numGlyphs)
+	pairs := map[[2]GlyphIndex]int16{}
+	for _, tag := range []Tag{tagKern, tagGPOS} {
+		data, err := f.rawTableData(tag)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		d := &layoutReader{data: data, numGlyphs: numGlyphs}
+		var adjs map[[2]GlyphIndex]int
+		if tag == tagKern {
+			adjs = d.kernPairs()
+		} else {
+			adjs = d.gposKernPairs()
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("%s: %w", tag, d.err)
+		}
+		// Those of GPOS replace those of kern.
+		for pair, adj := range adjs {
+			if adj != 0 {
+				pairs[pair] = int16(min(max(adj, -1<<15), 1<<15-1))
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// rawTableData returns the data of table `tag` of `f`, nil if it has none: the table passed
+// through for a font made by subsetting or set by Rescale, otherwise the table of the font file.
+func (f *Font) rawTableData(tag Tag) ([]byte, error) {
+	for _, t := range f.rawTables {
+		if t.tag == tag {
+			return t.data, nil
+		}
+	}
+	if f.br == nil {
+		return nil, nil
+	}
+	tables, err := f.readRawTables(f.br.fork(), []Tag{tag})
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+	return tables[0].data, nil
+}
+
+// layoutReader reads big-endian fields at offsets of the data of a kern or GPOS table. Reading
+// past the end gives 0 and sets `err` to errRangeCheck, so that a table is read through and
+// checked once.
+type layoutReader struct {
+	data      []byte
+	numGlyphs int // Glyph indices from numGlyphs on are dropped.
+	err       error
+}
+
+// has returns true if the data has `n` bytes at `at`, otherwise it sets `err`.
+func (d *layoutReader) has(at, n int) bool {
+	if at < 0 || n < 0 || at > len(d.data) || n > len(d.data)-at {
+		if d.err == nil {
+			d.err = fmt.Errorf("%d bytes at offset %d of %d: %w", n, at, len(d.data), errRangeCheck)
+		}
+		return false
+	}
+	return true
+}
+
+func (d *layoutReader) u16(at int) uint16 {
+	if !d.has(at, 2) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(d.data[at:])
+}
+
+func (d *layoutReader) u32(at int) uint32 {
+	if !d.has(at, 4) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(d.data[at:])
+}
+
+// kernPairs returns the pairs of the horizontal format 0 subtables of the kern table, in the
+// version of Microsoft or Apple.
+func (d *layoutReader) kernPairs() map[[2]GlyphIndex]int {
+	pairs := map[[2]GlyphIndex]int{}
+	add := func(at, nPairs int, override bool) {
+		if !d.has(at, 6*nPairs) {
+			return
+		}
+		for i := range nPairs {
+			rec := at + 6*i
+			left, right := int(d.u16(rec)), int(d.u16(rec+2))
+			if left >= d.numGlyphs || right >= d.numGlyphs {
+				continue
+			}
+			pair := [2]GlyphIndex{GlyphIndex(left), GlyphIndex(right)}
+			if override {
+				pairs[pair] = 0
+			}
+			pairs[pair] += int(int16(d.u16(rec + 4)))
+		}
+	}
+
+	switch d.u16(0) {
+	case 0:
+		// Subtable: version, length, coverage; nPairs, searchRange, entrySelector, rangeShift.
+		at := 4
+		for range int(d.u16(2)) {
+			length, coverage := int(d.u16(at+2)), d.u16(at+4)
+			if d.err != nil {
+				return nil
+			}
+			// Flags: horizontal 1, minimum 2, cross-stream 4, override 8.
+			if coverage>>8 != 0 {
+				at += length
+				continue
+			}
+			nPairs := int(d.u16(at + 6))
+			if coverage&0x7 == 1 {
+				add(at+14, nPairs, coverage&0x8 != 0)
+			}
+			// The length of a subtable of more than 10921 pairs overflows.
+			at += 14 + 6*nPairs
+		}
+	case 1:
+		// Version 1.0, nTables; subtable: length, coverage, tupleIndex; nPairs, ...
+		if d.u16(2) != 0 {
+			d.err = fmt.Errorf("kern version %#08x: %w", d.u32(0), errTypeCheck)
+			return nil
+		}
+		at := 8
+		for range int(d.u32(4)) {
+			length, coverage := int(d.u32(at)), d.u16(at+4)
+			if d.err != nil {
+				return nil
+			}
+			// Format in the low byte, flags: vertical 0x8000, cross-stream 0x4000, variation 0x2000.
+			if coverage&0xE0FF == 0 {
+				add(at+16, int(d.u16(at+8)), false)
+			}
+			if length < 8 {
+				d.err = fmt.Errorf("kern subtable length %d: %w", length, errRangeCheck)
+				return nil
+			}
+			at += length
+		}
+	default:
+		d.err = fmt.Errorf("kern version %d: %w", d.u16(0), errTypeCheck)
+		return nil
+	}
+	return pairs
+}
+
+// gposKernPairs returns the X advance adjustments of the first glyphs of the pairs of the
+// PairPos lookups of the kern feature of GPOS, of every script and language.
+func (d *layoutReader) gposKernPairs() map[[2]GlyphIndex]int {
+	if major := d.u16(0); major != 1 {
+		if d.err == nil {
+			d.err = fmt.Errorf("GPOS version %d: %w", major, errTypeCheck)
+		}
+		return nil
+	}
+	featureList, lookupList := int(d.u16(6)), int(d.u16(8))
+	var indices []int
+	for i := range int(d.u16(featureList)) {
+		rec := featureList + 2 + 6*i
+		if !d.has(rec, 6) || string(d.data[rec:rec+4]) != "kern" {
+			continue
+		}
+		// Feature: featureParamsOffset, lookupIndexCount, lookupListIndices.
+		feature := featureList + int(d.u16(rec+4))
+		for j := range int(d.u16(feature + 2)) {
+			indices = append(indices, int(d.u16(feature+4+2*j)))
+		}
+	}
+	slices.Sort(indices)
+	indices = slices.Compact(indices)
+
+	pairs := map[[2]GlyphIndex]int{}
+	for _, index := range indices {
+		if index >= int(d.u16(lookupList)) {
+			d.err = fmt.Errorf("lookup %d: %w", index, errRangeCheck)
+			return nil
+		}
+		// Lookup: lookupType, lookupFlag, subTableCount, subtableOffsets.
+		lookup := lookupList + int(d.u16(lookupList+2+2*index))
+		lookupType := d.u16(lookup)
+		adjs := map[[2]GlyphIndex]int{}
+		classed := map[GlyphIndex]bool{}
+		for j := range int(d.u16(lookup + 4)) {
+			subtable := lookup + int(d.u16(lookup+6+2*j))
+			if lookupType == 9 {
+				// Extension: posFormat, extensionLookupType, extensionOffset.
+				if d.u16(subtable+2) != 2 {
+					continue
+				}
+				subtable += int(d.u32(subtable + 4))
+			} else if lookupType != 2 {
+				break
+			}
+			d.pairPos(subtable, adjs, classed)
+		}
+		if d.err != nil {
+			return nil
+		}
+		for pair, adj := range adjs {
+			pairs[pair] += adj
+		}
+	}
+	return pairs
+}
+
+// valueRecordSize returns the size of a ValueRecord of format `valueFormat` and the offset of
+// its XAdvance, -1 if it has none.
+func valueRecordSize(valueFormat uint16) (size, xAdvance int) {
+	xAdvance = -1
+	if valueFormat&0x4 != 0 {
+		xAdvance = 2 * bits.OnesCount16(valueFormat&0x3)
+	}
+	return 2 * bits.OnesCount16(valueFormat&0xFF), xAdvance
+}
+
+// pairPos adds the X advance adjustments of the first glyphs of the PairPos subtable at `at` to
+// `adjs`, except for pairs that earlier subtables of the lookup match: those in `adjs` and those
+// of the first glyphs in `classed`, matched by class pairs.
+func (d *layoutReader) pairPos(at int, adjs map[[2]GlyphIndex]int, classed map[GlyphIndex]bool) {
+	format := d.u16(at)
+	firsts := d.coverage(at + int(d.u16(at+2)))
+	size1, xAdvance := valueRecordSize(d.u16(at + 4))
+	size2, _ := valueRecordSize(d.u16(at + 6))
+	value := func(rec int) int {
+		if xAdvance < 0 {
+			return 0
+		}
+		return int(int16(d.u16(rec + xAdvance)))
+	}
+
+	switch format {
+	case 1:
+		// Coverage, valueFormat1, valueFormat2, pairSetCount, pairSetOffsets.
+		if len(firsts) > int(d.u16(at+8)) {
+			d.err = fmt.Errorf("PairPos: %d pair sets for %d glyphs: %w", d.u16(at+8), len(firsts), errRangeCheck)
+			return
+		}
+		for i, first := range firsts {
+			// PairSet: pairValueCount, PairValueRecords of secondGlyph, valueRecord1, valueRecord2.
+			set := at + int(d.u16(at+10+2*i))
+			n, recSize := int(d.u16(set)), 2+size1+size2
+			if !d.has(set+2, n*recSize) {
+				return
+			}
+			for j := range n {
+				rec := set + 2 + j*recSize
+				second := int(d.u16(rec))
+				pair := [2]GlyphIndex{first, GlyphIndex(second)}
+				if _, ok := adjs[pair]; ok || classed[first] || second >= d.numGlyphs {
+					continue
+				}
+				adjs[pair] = value(rec + 2)
+			}
+		}
+	case 2:
+		// Coverage, valueFormat1, valueFormat2, classDef1Offset, classDef2Offset, class1Count,
+		// class2Count, Class1Records of Class2Records of valueRecord1, valueRecord2.
+		classes1 := d.classes(at + int(d.u16(at+8)))
+		classes2 := d.classes(at + int(d.u16(at+10)))
+		count1, count2 := int(d.u16(at+12)), int(d.u16(at+14))
+		recSize := size1 + size2
+		if !d.has(at+16, count1*count2*recSize) {
+			return
+		}
+		seconds := make([][]GlyphIndex, count2)
+		for gid, class := range classes2 {
+			if int(class) < count2 {
+				seconds[class] = append(seconds[class], GlyphIndex(gid))
+			}
+		}
+		for _, first := range firsts {
+			class1 := int(classes1[first])
+			if classed[first] || class1 >= count1 {
+				continue
+			}
+			classed[first] = true
+			for class2, glyphs := range seconds {
+				adj := value(at + 16 + (class1*count2+class2)*recSize)
+				for _, second := range glyphs {
+					pair := [2]GlyphIndex{first, second}
+					if _, ok := adjs[pair]; !ok && adj != 0 {
+						adjs[pair] = adj
+					}
+				}
+			}
+		}
+	default:
+		d.err = fmt.Errorf("PairPos format %d: %w", format, errTypeCheck)
+	}
+}
+
+// coverage returns the glyphs of the Coverage table at `at` in coverage index order.
+func (d *layoutReader) coverage(at int) []GlyphIndex {
+	var glyphs []GlyphIndex
+	switch format := d.u16(at); format {
+	case 1:
+		// glyphCount, glyphArray.
+		n := int(d.u16(at + 2))
+		if !d.has(at+4, 2*n) {
+			return nil
+		}
+		for i := range n {
+			glyphs = append(glyphs, GlyphIndex(d.u16(at+4+2*i)))
+		}
+	case 2:
+		// rangeCount, RangeRecords of startGlyphID, endGlyphID, startCoverageIndex.
+		n := int(d.u16(at + 2))
+		if !d.has(at+4, 6*n) {
+			return nil
+		}
+		for i := range n {
+			rec := at + 4 + 6*i
+			for gid := int(d.u16(rec)); gid <= int(d.u16(rec+2)) && gid < d.numGlyphs; gid++ {
+				glyphs = append(glyphs, GlyphIndex(gid))
+			}
+		}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("coverage format %d: %w", format, errTypeCheck)
+		}
+	}
+	// Coverage is sorted, so the glyphs before numGlyphs keep their coverage index.
+	return slices.DeleteFunc(glyphs, func(gid GlyphIndex) bool { return int(gid) >= d.numGlyphs })
+}
+
+// classes returns the class of each glyph of the ClassDef table at `at`, 0 for glyphs that it
+// does not list.
+func (d *layoutReader) classes(at int) []uint16 {
+	classes := make([]uint16, d.numGlyphs)
+	switch format := d.u16(at); format {
+	case 1:
+		// startGlyphID, glyphCount, classValueArray.
+		start, n := int(d.u16(at+2)), int(d.u16(at+4))
+		if !d.has(at+6, 2*n) {
+			return classes
+		}
+		for i := range min(n, d.numGlyphs-start) {
+			classes[start+i] = d.u16(at + 6 + 2*i)
+		}
+	case 2:
+		// classRangeCount, ClassRangeRecords of startGlyphID, endGlyphID, class.
+		n := int(d.u16(at + 2))
+		if !d.has(at+4, 6*n) {
+			return classes
+		}
+		for i := range n {
+			rec := at + 4 + 6*i
+			for gid := int(d.u16(rec)); gid <= int(d.u16(rec+2)) && gid < d.numGlyphs; gid++ {
+				classes[gid] = d.u16(rec + 4)
+			}
+		}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("class definition format %d: %w", format, errTypeCheck)
+		}
+	}
+	return classes
+}
@@ -0,0 +1,194 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// u16s returns `vals` as big-endian uint16s.
+func u16s(vals ...int) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = binary.BigEndian.AppendUint16(b, uint16(v))
+	}
+	return b
+}
+
+// withOffsets returns `header` followed by the uint16 offsets of `parts` from the start of the
+// result and `parts`, e.g. a lookup and its subtables.
+func withOffsets(header []byte, parts ...[]byte) []byte {
+	at := len(header) + 2*len(parts)
+	b := slices.Clone(header)
+	for _, p := range parts {
+		b = append(b, u16s(at)...)
+		at += len(p)
+	}
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// pairPosFormat1 returns a PairPos subtable of single pairs of `first`, adjusting its X advance.
+func pairPosFormat1(first GlyphIndex, pairs ...[2]int) []byte {
+	set := u16s(len(pairs))
+	for _, p := range pairs {
+		set = append(set, u16s(p[0], p[1])...)
+	}
+	// posFormat, coverageOffset, valueFormat1, valueFormat2, pairSetCount, pairSetOffsets.
+	header := u16s(1, 12+len(set), 0x4, 0, 1, 12)
+	return slices.Concat(header, set, u16s(1, 1, int(first)))
+}
+
+// kerningFixture returns Go Regular with a kern table and a GPOS table, and the glyphs of the
+// runes it kerns.
+func kerningFixture(t *testing.T) (*Font, map[rune]GlyphIndex) {
+	t.Helper()
+	f := loadGoRegular(t)
+	g := map[rune]GlyphIndex{}
+	for _, r := range "AVYToeWa" {
+		gids, _ := f.LookupRunes([]rune{r})
+		g[r] = gids[0]
+	}
+	pair := func(a, b rune, adj int) []byte { return u16s(int(g[a]), int(g[b]), adj) }
+
+	kern := slices.Concat(
+		u16s(0, 2),                          // version 0, nTables
+		u16s(0, 14+3*6, 0x0001, 3, 0, 0, 0), // horizontal
+		pair('A', 'V', -80), pair('T', 'o', -60), pair('W', 'a', -40),
+		u16s(0, 14+6, 0x0005, 1, 0, 0, 0), // cross-stream, ignored
+		pair('A', 'V', 999),
+	)
+
+	// Class pairs of T and e and o, with XPlacement and XAdvance values.
+	e, o := int(g['e']), int(g['o'])
+	if e > o {
+		e, o = o, e
+	}
+	classPairs := slices.Concat(
+		// posFormat, coverageOffset, valueFormat1, valueFormat2, classDef1Offset,
+		// classDef2Offset, class1Count, class2Count.
+		u16s(2, 32, 0x5, 0, 38, 46, 2, 2),
+		u16s(0, 0, 0, 0, 0, 0, 7, -70),
+		u16s(1, 1, int(g['T'])),
+		u16s(1, int(g['T']), 1, 1),
+		u16s(2, 2, e, e, 1, o, o, 1),
+	)
+	extension := slices.Concat(u16s(1, 2), binary.BigEndian.AppendUint32(nil, 8), classPairs)
+
+	lookups := withOffsets(u16s(3),
+		withOffsets(u16s(2, 0, 3),
+			pairPosFormat1(g['A'], [2]int{int(g['V']), -100}, [2]int{int(g['Y']), -50}),
+			pairPosFormat1(g['T'], [2]int{int(g['o']), -5}),
+			// Matched by the first subtable.
+			pairPosFormat1(g['A'], [2]int{int(g['V']), -999}),
+		),
+		withOffsets(u16s(9, 0, 1), extension),
+		// Not of the kern feature.
+		withOffsets(u16s(2, 0, 1), pairPosFormat1(g['W'], [2]int{int(g['a']), 500})),
+	)
+	features := slices.Concat(
+		u16s(2),
+		[]byte("kern"), u16s(14),
+		[]byte("mark"), u16s(22),
+		u16s(0, 2, 1, 0),
+		u16s(0, 1, 2),
+	)
+	gpos := slices.Concat(u16s(1, 0, 10, 12, 12+len(features)), u16s(0), features, lookups)
+
+	f.rawTables = []rawTable{{tagGPOS, gpos}, {tagKern, kern}}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, g
+}
+
+func TestFont_KerningPairs(t *testing.T) {
+	f, g := kerningFixture(t)
+	want := map[[2]GlyphIndex]int16{
+		{g['A'], g['V']}: -100, // GPOS over kern.
+		{g['A'], g['Y']}: -50,
+		{g['T'], g['o']}: -75, // Two lookups.
+		{g['T'], g['e']}: -70,
+		{g['W'], g['a']}: -40, // kern only.
+	}
+	pairs, err := f.KerningPairs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("got %d pairs %v, want %v", len(pairs), pairs, want)
+	}
+	for pair, adj := range want {
+		if got, ok := f.Kern(pair[0], pair[1]); !ok || got != adj || pairs[pair] != adj {
+			t.Errorf("%v: got %d %t, want %d", pair, got, ok, adj)
+		}
+	}
+	if adj, ok := f.Kern(g['V'], g['A']); ok {
+		t.Fatalf("V A: %d", adj)
+	}
+	// The pairs are a copy.
+	delete(pairs, [2]GlyphIndex{g['A'], g['V']})
+	if _, ok := f.Kern(g['A'], g['V']); !ok {
+		t.Fatal("KerningPairs returned the lookup")
+	}
+
+	// Subsetting drops kern, GPOS passes through.
+	sub, err := f.SubsetWithOptions([]rune("AVWa"), SubsetOptions{RetainGIDs: true, Layout: LayoutPassthrough})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adj, _ := sub.Kern(g['A'], g['V']); adj != -100 {
+		t.Fatalf("subset: A V %d", adj)
+	}
+	if adj, ok := sub.Kern(g['W'], g['a']); ok {
+		t.Fatalf("subset: W a %d", adj)
+	}
+
+	if pairs, err := loadGoRegular(t).KerningPairs(); err != nil || len(pairs) != 0 {
+		t.Fatalf("Go Regular: %v %v", pairs, err)
+	}
+}
+
+func TestFont_KerningPairsInvalid(t *testing.T) {
+	f := loadGoRegular(t)
+	apple := slices.Concat(
+		u16s(1, 0, 0, 1), // version 1.0, nTables
+		u16s(0, 16+6, 0, 0, 1, 0, 0, 0),
+		u16s(36, 57, -30),
+	)
+	f.rawTables = []rawTable{{tagKern, apple}}
+	g := newFont(nil, f.font)
+	if adj, ok := g.Kern(36, 57); !ok || adj != -30 {
+		t.Fatalf("Apple kern: %d %t", adj, ok)
+	}
+
+	for _, tt := range []struct {
+		name string
+		tag  Tag
+		data []byte
+		want error
+	}{
+		{"truncated kern", tagKern, u16s(0, 1, 0, 20, 1, 1), errRangeCheck},
+		{"kern version", tagKern, u16s(2, 0), errTypeCheck},
+		{"GPOS version", tagGPOS, u16s(2, 0, 10, 10, 10), errTypeCheck},
+		{"truncated GPOS", tagGPOS, u16s(1, 0, 10, 12, 30, 0, 1, 'k'<<8|'e', 'r'<<8|'n', 8), errRangeCheck},
+	} {
+		f.rawTables = []rawTable{{tt.tag, tt.data}}
+		g := newFont(nil, f.font)
+		if _, err := g.KerningPairs(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		if _, ok := g.Kern(36, 57); ok {
+			t.Errorf("%s: kerned", tt.name)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ttf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNames_Lang(t *testing.T) {
+	f := loadGoRegular(t)
+	add := func(platformID PlatformID, encodingID EncodingID, languageID uint16, value string) {
+		nr := &nameRecord{
+			platformID: uint16(platformID),
+			encodingID: uint16(encodingID),
+			languageID: languageID,
+			nameID:     uint16(NameIDFamily),
+		}
+		nr.setDecoded(value)
+		f.name.nameRecords = append(f.name.nameRecords, nr)
+	}
+	add(PlatformMacintosh, EncodingMacRoman, 2, "Gehen")
+	add(PlatformWindows, EncodingWindowsUnicodeBMP, 0x0411, "ゴー")
+	add(PlatformWindows, EncodingWindowsUnicodeBMP, 0x0804, "走")
+	add(PlatformWindows, EncodingWindowsUnicodeBMP, 0x8000, "Go CH")
+	add(PlatformUnicode, EncodingUnicode20BMP, 0, "Go Unicode")
+	f.name.format = 1
+	f.name.langTagRecords = []*langTagRecord{{data: StringToUTF16("de-CH")}}
+	f.markDirty(tagName)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	langs := map[string]string{}
+	for _, nr := range f.Names() {
+		if nr.NameID == NameIDFamily {
+			langs[nr.Value] = nr.Lang
+		}
+	}
+	for value, want := range map[string]string{
+		"Go": "en-US", "Gehen": "de", "ゴー": "ja-JP", "走": "zh-CN", "Go CH": "de-CH", "Go Unicode": "",
+	} {
+		if got, ok := langs[value]; !ok || got != want {
+			t.Fatalf("%q: lang %q (found %v), want %q", value, got, ok, want)
+		}
+	}
+
+	for lang, want := range map[string]string{
+		"ja":    "ゴー",
+		"JA-jp": "ゴー",
+		"zh-TW": "走",
+		"de-CH": "Go CH",
+		"de-AT": "Gehen",
+		"fr":    "Go",
+		"":      "Go",
+	} {
+		if got := f.FamilyNameForLang(lang); got != want {
+			t.Fatalf("%q: got %q, want %q", lang, got, want)
+		}
+	}
+	if got := f.NameForLang(NameIDLicenseURL+100, "en"); got != "" {
+		t.Fatalf("missing name ID gave %q", got)
+	}
+
+	for _, tt := range []struct {
+		platformID PlatformID
+		languageID uint16
+		want       string
+	}{
+		{PlatformWindows, 0x0409, "en-US"},
+		{PlatformWindows, 0x0C04, "zh-HK"},
+		{PlatformWindows, 0x7FFF, ""},
+		{PlatformMacintosh, 0, "en"},
+		{PlatformMacintosh, 19, "zh-Hant"},
+		{PlatformMacintosh, 33, "zh-Hans"},
+		{PlatformMacintosh, 150, "az-Latn"},
+		{PlatformUnicode, 0, ""},
+		{PlatformWindows, 0x8001, ""},
+	} {
+		if got := languageTag(tt.platformID, tt.languageID, nil); got != tt.want {
+			t.Fatalf("%v %#04x: got %q, want %q", tt.platformID, tt.languageID, got, tt.want)
+		}
+	}
+}
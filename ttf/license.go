@@ -0,0 +1,44 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import "strings"
+
+// LicenseInfo holds the names of a font that a compliance review needs. Each is the record in
+// English if there is one, else the first record with the name ID, with its language in Lang,
+// and a zero NameRecord if there is none.
+type LicenseInfo struct {
+	Copyright    NameRecord // Name ID 0.
+	License      NameRecord // Name ID 13, the license description.
+	LicenseURL   NameRecord // Name ID 14.
+	Manufacturer NameRecord // Name ID 8.
+	Designer     NameRecord // Name ID 9.
+}
+
+// LicenseInfo returns the copyright, license, manufacturer and designer names of `f`, see
+// VendorID for the vendor of OS/2.
+func (f *Font) LicenseInfo() LicenseInfo {
+	name := func(id NameID) NameRecord {
+		nr, _ := f.nameRecordForLang(id, "en-US")
+		return nr
+	}
+	return LicenseInfo{
+		Copyright:    name(NameIDCopyright),
+		License:      name(NameIDLicense),
+		LicenseURL:   name(NameIDLicenseURL),
+		Manufacturer: name(NameIDManufacturer),
+		Designer:     name(NameIDDesigner),
+	}
+}
+
+// VendorID returns the four-character identifier of the font vendor, OS/2.achVendID, without
+// trailing spaces or NULs, e.g. "GOOG". It is "" without an OS/2 table.
+func (f *Font) VendorID() string {
+	if f.os2 == nil {
+		return ""
+	}
+	return strings.TrimRight(string(f.os2.achVendID[:]), " \x00")
+}
@@ -0,0 +1,78 @@
+package ttf
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// oflLicense is the license description of fonts under the SIL Open Font License.
+const oflLicense = "This Font Software is licensed under the SIL Open Font License, Version 1.1. " +
+	"This license is available with a FAQ at: https://openfontlicense.org"
+
+func TestFont_LicenseInfo(t *testing.T) {
+	f := loadGoRegular(t)
+	// Replace the license of Go Regular with the OFL, and give its URL only in French.
+	f.name.nameRecords = slices.DeleteFunc(f.name.nameRecords, func(nr *nameRecord) bool {
+		return NameID(nr.nameID) == NameIDLicense
+	})
+	for _, r := range []struct {
+		lang  uint16
+		id    NameID
+		value string
+	}{
+		{0x0409, NameIDLicense, oflLicense},
+		{0x040C, NameIDLicense, "Ce logiciel de police est sous licence SIL Open Font License, version 1.1."},
+		{0x040C, NameIDLicenseURL, "https://openfontlicense.org"},
+	} {
+		nr := &nameRecord{platformID: uint16(PlatformWindows), encodingID: uint16(EncodingWindowsUnicodeBMP),
+			languageID: r.lang, nameID: uint16(r.id)}
+		nr.setDecoded(r.value)
+		f.name.nameRecords = append(f.name.nameRecords, nr)
+	}
+	f.os2.achVendID = makeTag("SIL ")
+	f.markDirty(tagName, tagOS2)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := g.LicenseInfo()
+	for _, tt := range []struct {
+		name       string
+		got        NameRecord
+		value      string
+		lang       string
+		platformID PlatformID
+	}{
+		{"copyright", info.Copyright, "Copyright (c) 2016 by Bigelow & Holmes Inc.. All rights reserved.", "en-US", PlatformWindows},
+		{"license", info.License, oflLicense, "en-US", PlatformWindows},
+		{"license URL", info.LicenseURL, "https://openfontlicense.org", "fr-FR", PlatformWindows},
+		{"manufacturer", info.Manufacturer, "Bigelow & Holmes Inc.", "en-US", PlatformWindows},
+		{"designer", info.Designer, "Kris Holmes and Charles Bigelow", "en-US", PlatformWindows},
+	} {
+		if tt.got.Value != tt.value || tt.got.Lang != tt.lang || tt.got.PlatformID != tt.platformID {
+			t.Fatalf("%s: got %+v, want %q in %s", tt.name, tt.got, tt.value, tt.lang)
+		}
+	}
+	if got := g.VendorID(); got != "SIL" {
+		t.Fatalf("vendor ID %q", got)
+	}
+
+	// Go Regular has no license URL and a vendor ID of NULs.
+	f = loadGoRegular(t)
+	if got := f.LicenseInfo().LicenseURL; got != (NameRecord{}) {
+		t.Fatalf("license URL %+v", got)
+	}
+	if got := f.VendorID(); got != "" {
+		t.Fatalf("vendor ID %q", got)
+	}
+	f.name, f.os2 = nil, nil
+	if got := f.LicenseInfo(); got != (LicenseInfo{}) || f.VendorID() != "" {
+		t.Fatalf("without name and OS/2: %+v %q", got, f.VendorID())
+	}
+}
@@ -0,0 +1,207 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import "strconv"
+
+// Style bits of head.macStyle and OS/2.fsSelection.
+const (
+	macStyleBold              = 1 << 0
+	macStyleItalic            = 1 << 1
+	fsSelectionItalic         = 1 << 0
+	fsSelectionBold           = 1 << 5
+	fsSelectionRegular        = 1 << 6
+	fsSelectionUseTypoMetrics = 1 << 7
+	fsSelectionOblique        = 1 << 9
+)
+
+// WeightClass is the visual weight of a font, OS/2.usWeightClass, from 100 (thin) to 900
+// (black). Values between the named classes are valid, e.g. 250 between extra-light and
+// light.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/os2#usweightclass
+type WeightClass uint16
+
+// Weight classes.
+const (
+	WeightThin       WeightClass = 100
+	WeightExtraLight WeightClass = 200
+	WeightLight      WeightClass = 300
+	WeightNormal     WeightClass = 400
+	WeightMedium     WeightClass = 500
+	WeightSemiBold   WeightClass = 600
+	WeightBold       WeightClass = 700
+	WeightExtraBold  WeightClass = 800
+	WeightBlack      WeightClass = 900
+)
+
+var weightClassNames = map[WeightClass]string{
+	WeightThin:       "Thin",
+	WeightExtraLight: "ExtraLight",
+	WeightLight:      "Light",
+	WeightNormal:     "Normal",
+	WeightMedium:     "Medium",
+	WeightSemiBold:   "SemiBold",
+	WeightBold:       "Bold",
+	WeightExtraBold:  "ExtraBold",
+	WeightBlack:      "Black",
+}
+
+// String returns the name of `w`, e.g. "SemiBold", or "Weight 250" between the named classes.
+func (w WeightClass) String() string {
+	if s, ok := weightClassNames[w]; ok {
+		return s
+	}
+	return "Weight " + strconv.Itoa(int(w))
+}
+
+// WidthClass is the relative width of a font, OS/2.usWidthClass, from 1 (ultra-condensed) to
+// 9 (ultra-expanded).
+// https://docs.microsoft.com/en-us/typography/opentype/spec/os2#uswidthclass
+type WidthClass uint16
+
+// Width classes.
+const (
+	WidthUltraCondensed WidthClass = iota + 1
+	WidthExtraCondensed
+	WidthCondensed
+	WidthSemiCondensed
+	WidthNormal
+	WidthSemiExpanded
+	WidthExpanded
+	WidthExtraExpanded
+	WidthUltraExpanded
+)
+
+var widthClassNames = map[WidthClass]string{
+	WidthUltraCondensed: "UltraCondensed",
+	WidthExtraCondensed: "ExtraCondensed",
+	WidthCondensed:      "Condensed",
+	WidthSemiCondensed:  "SemiCondensed",
+	WidthNormal:         "Normal",
+	WidthSemiExpanded:   "SemiExpanded",
+	WidthExpanded:       "Expanded",
+	WidthExtraExpanded:  "ExtraExpanded",
+	WidthUltraExpanded:  "UltraExpanded",
+}
+
+// String returns the name of `w`, e.g. "Condensed", or "Width 12" if it is not a class.
+func (w WidthClass) String() string {
+	if s, ok := widthClassNames[w]; ok {
+		return s
+	}
+	return "Width " + strconv.Itoa(int(w))
+}
+
+// WeightClass returns OS/2.usWeightClass, clamped to WeightThin to WeightBlack. Parsing warns
+// about a value out of that range, see Font.Warnings. It returns false without an OS/2 table.
+func (f *Font) WeightClass() (WeightClass, bool) {
+	if f.os2 == nil {
+		return 0, false
+	}
+	return min(max(WeightClass(f.os2.usWeightClass), WeightThin), WeightBlack), true
+}
+
+// WidthClass returns OS/2.usWidthClass, clamped to WidthUltraCondensed to WidthUltraExpanded
+// like WeightClass. It returns false without an OS/2 table.
+func (f *Font) WidthClass() (WidthClass, bool) {
+	if f.os2 == nil {
+		return 0, false
+	}
+	return min(max(WidthClass(f.os2.usWidthClass), WidthUltraCondensed), WidthUltraExpanded), true
+}
+
+// Style holds the style bits of OS/2.fsSelection.
+type Style struct {
+	Italic         bool // Italic glyphs.
+	Bold           bool // Bold glyphs.
+	Regular        bool // Neither italic nor bold.
+	Oblique        bool // Slanted glyphs, distinct from italic ones from OS/2 version 4.
+	UseTypoMetrics bool // Line spacing from sTypoAscender, sTypoDescender and sTypoLineGap.
+}
+
+// Style returns the style bits of OS/2.fsSelection. It returns false without an OS/2 table,
+// see Metadata for the style with head.macStyle.
+func (f *Font) Style() (Style, bool) {
+	if f.os2 == nil {
+		return Style{}, false
+	}
+	fs := f.os2.fsSelection
+	return Style{
+		Italic:         fs&fsSelectionItalic != 0,
+		Bold:           fs&fsSelectionBold != 0,
+		Regular:        fs&fsSelectionRegular != 0,
+		Oblique:        fs&fsSelectionOblique != 0,
+		UseTypoMetrics: fs&fsSelectionUseTypoMetrics != 0,
+	}, true
+}
+
+// Completeness flags the tables that the fields of a Metadata come from and that the font
+// has. The fields of a missing table are zero.
+type Completeness uint8
+
+// Completeness flags.
+const (
+	CompleteName Completeness = 1 << iota // Family, Subfamily, FullName, PostScriptName and Version.
+	CompleteOS2                           // WeightClass, WidthClass, and Italic and Bold.
+	CompleteHead                          // Italic and Bold without OS/2, and Revision.
+)
+
+// Metadata describes a font for cataloging.
+type Metadata struct {
+	Family         string // Typographic family (name ID 16), else family (name ID 1).
+	Subfamily      string // Typographic subfamily (name ID 17), else subfamily (name ID 2).
+	FullName       string // Name ID 4, e.g. "Go Bold Italic".
+	PostScriptName string // Name ID 6, e.g. "Go-BoldItalic".
+	Version        string // Name ID 5, e.g. "Version 2.010; ttfautohint (v1.8.3)".
+
+	WeightClass uint16  // OS/2.usWeightClass, e.g. 400 for regular and 700 for bold.
+	WidthClass  uint16  // OS/2.usWidthClass, 1 (ultra-condensed) to 9 (ultra-expanded), 5 for normal.
+	Revision    float64 // head.fontRevision, e.g. 2.01.
+
+	// Italic and Bold are the style bits of OS/2.fsSelection, which Windows and CSS font
+	// matching go by, and those of head.macStyle, which macOS goes by, only without an OS/2
+	// table. The two should agree, and where they do not, OS/2 wins.
+	Italic bool
+	Bold   bool
+
+	Completeness Completeness
+}
+
+// Metadata returns the names, weight, width, style and version of `f`. Tables that are
+// missing leave their fields zero and their flag of Completeness unset.
+func (f *Font) Metadata() Metadata {
+	var m Metadata
+	if f.name != nil {
+		m.Completeness |= CompleteName
+		m.Family = f.preferredName(NameIDTypographicFamily, NameIDFamily)
+		m.Subfamily = f.preferredName(NameIDTypographicSubfamily, NameIDSubfamily)
+		m.FullName = f.Name(NameIDFullName)
+		m.PostScriptName = f.Name(NameIDPostScriptName)
+		m.Version = f.Name(NameIDVersion)
+	}
+	if f.head != nil {
+		m.Completeness |= CompleteHead
+		m.Revision = f.head.fontRevision.Float64()
+		m.Italic = f.head.macStyle&macStyleItalic != 0
+		m.Bold = f.head.macStyle&macStyleBold != 0
+	}
+	if f.os2 != nil {
+		m.Completeness |= CompleteOS2
+		m.WeightClass = f.os2.usWeightClass
+		m.WidthClass = f.os2.usWidthClass
+		m.Italic = f.os2.fsSelection&fsSelectionItalic != 0
+		m.Bold = f.os2.fsSelection&fsSelectionBold != 0
+	}
+	return m
+}
+
+// preferredName returns name `id` of `f`, or name `fallback` if `f` has no name `id`.
+func (f *Font) preferredName(id, fallback NameID) string {
+	if s := f.Name(id); s != "" {
+		return s
+	}
+	return f.Name(fallback)
+}
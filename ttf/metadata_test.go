@@ -0,0 +1,168 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFont_Metadata(t *testing.T) {
+	parse := func(data []byte) *Font {
+		f, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	all := CompleteName | CompleteOS2 | CompleteHead
+
+	tests := []struct {
+		name   string
+		data   []byte
+		modify func(f *Font)
+		want   Metadata
+	}{
+		{"Go Regular", goregular.TTF, nil, Metadata{
+			Family: "Go", Subfamily: "Regular", FullName: "Go Regular", PostScriptName: "GoRegular",
+			WeightClass: 400, WidthClass: 5, Completeness: all,
+		}},
+		{"Go Bold Italic", gobolditalic.TTF, nil, Metadata{
+			Family: "Go", Subfamily: "Bold Italic", FullName: "Go Bold Italic", PostScriptName: "Go-BoldItalic",
+			WeightClass: 600, WidthClass: 5, Italic: true, Bold: true, Completeness: all,
+		}},
+		// OS/2 wins over a head.macStyle that disagrees, and head.macStyle is used without OS/2.
+		{"macStyle bold", goregular.TTF, func(f *Font) { f.head.macStyle = macStyleBold }, Metadata{
+			Family: "Go", Subfamily: "Regular", FullName: "Go Regular", PostScriptName: "GoRegular",
+			WeightClass: 400, WidthClass: 5, Completeness: all,
+		}},
+		{"fsSelection regular", gobolditalic.TTF, func(f *Font) { f.os2.fsSelection = 1 << 6 }, Metadata{
+			Family: "Go", Subfamily: "Bold Italic", FullName: "Go Bold Italic", PostScriptName: "Go-BoldItalic",
+			WeightClass: 600, WidthClass: 5, Completeness: all,
+		}},
+		{"no OS/2", gobolditalic.TTF, func(f *Font) { f.os2 = nil }, Metadata{
+			Family: "Go", Subfamily: "Bold Italic", FullName: "Go Bold Italic", PostScriptName: "Go-BoldItalic",
+			Italic: true, Bold: true, Completeness: CompleteName | CompleteHead,
+		}},
+		{"no OS/2 and head", gobolditalic.TTF, func(f *Font) { f.os2, f.head = nil, nil }, Metadata{
+			Family: "Go", Subfamily: "Bold Italic", FullName: "Go Bold Italic", PostScriptName: "Go-BoldItalic",
+			Completeness: CompleteName,
+		}},
+		{"no name", goregular.TTF, func(f *Font) { f.name = nil }, Metadata{
+			WeightClass: 400, WidthClass: 5, Completeness: CompleteOS2 | CompleteHead,
+		}},
+		// Typographic names take precedence over names 1 and 2.
+		{"typographic names", gobolditalic.TTF, func(f *Font) {
+			for id, s := range map[NameID]string{NameIDTypographicFamily: "Go Text", NameIDTypographicSubfamily: "Heavy Oblique"} {
+				f.name.nameRecords = append(f.name.nameRecords, &nameRecord{platformID: uint16(PlatformWindows),
+					encodingID: uint16(EncodingWindowsUnicodeBMP), languageID: 0x409, nameID: uint16(id)})
+				f.name.nameRecords[len(f.name.nameRecords)-1].setDecoded(s)
+			}
+		}, Metadata{
+			Family: "Go Text", Subfamily: "Heavy Oblique", FullName: "Go Bold Italic", PostScriptName: "Go-BoldItalic",
+			WeightClass: 600, WidthClass: 5, Italic: true, Bold: true, Completeness: all,
+		}},
+	}
+	for _, tt := range tests {
+		f := parse(tt.data)
+		if tt.modify != nil {
+			tt.modify(f)
+		}
+		got := f.Metadata()
+		if tt.want.Completeness&CompleteName != 0 {
+			tt.want.Version = "Version 2.010; ttfautohint (v1.8.3)"
+		}
+		if tt.want.Completeness&CompleteHead != 0 {
+			tt.want.Revision = f.head.fontRevision.Float64()
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFont_WeightWidthClass(t *testing.T) {
+	f, err := Parse(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// withClasses returns Go Regular with usWeightClass `weight` and usWidthClass `width`.
+	withClasses := func(weight, width uint16) []byte {
+		data := bytes.Clone(goregular.TTF)
+		os2 := data[f.trec.trMap[tagOS2].offset:]
+		binary.BigEndian.PutUint16(os2[4:], weight)
+		binary.BigEndian.PutUint16(os2[6:], width)
+		fixChecksums(t, data)
+		return data
+	}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		weight       WeightClass
+		width        WidthClass
+		weightString string
+		warnings     []string
+	}{
+		{"Go Regular", goregular.TTF, WeightNormal, WidthNormal, "Normal", nil},
+		{"nonstandard weight", withClasses(250, 3), 250, WidthCondensed, "Weight 250", nil},
+		{"too light and narrow", withClasses(0, 0), WeightThin, WidthUltraCondensed, "Thin",
+			[]string{"OS/2 usWeightClass 0 outside 100 to 900", "OS/2 usWidthClass 0 outside 1 to 9"}},
+		{"too heavy and wide", withClasses(1000, 12), WeightBlack, WidthUltraExpanded, "Black",
+			[]string{"OS/2 usWeightClass 1000 outside 100 to 900", "OS/2 usWidthClass 12 outside 1 to 9"}},
+	}
+	for _, tt := range tests {
+		f, err := Parse(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		weight, ok := f.WeightClass()
+		if !ok || weight != tt.weight || weight.String() != tt.weightString {
+			t.Fatalf("%s: weight class %d %q %t, want %d %q", tt.name, weight, weight, ok, tt.weight, tt.weightString)
+		}
+		if width, ok := f.WidthClass(); !ok || width != tt.width {
+			t.Fatalf("%s: width class %v %t, want %v", tt.name, width, ok, tt.width)
+		}
+		if !slices.Equal(f.Warnings(), tt.warnings) {
+			t.Fatalf("%s: warnings %q, want %q", tt.name, f.Warnings(), tt.warnings)
+		}
+		if _, err := ParseStrict(bytes.NewReader(tt.data)); (err != nil) != (tt.warnings != nil) {
+			t.Fatalf("%s: strict: %v", tt.name, err)
+		}
+	}
+
+	f.os2 = nil
+	if _, ok := f.WeightClass(); ok {
+		t.Fatal("weight class without OS/2")
+	}
+	if _, ok := f.Style(); ok {
+		t.Fatal("style without OS/2")
+	}
+}
+
+func TestFont_Style(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want Style
+	}{
+		{"Go Regular", goregular.TTF, Style{Regular: true}},
+		{"Go Bold Italic", gobolditalic.TTF, Style{Italic: true, Bold: true}},
+	} {
+		f, err := Parse(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := f.Style(); !ok || got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+		f.os2.fsSelection |= fsSelectionOblique | fsSelectionUseTypoMetrics
+		tt.want.Oblique, tt.want.UseTypoMetrics = true, true
+		if got, _ := f.Style(); got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Panose is the PANOSE classification of a font, OS/2.panose, a digit of each of 10 aspects
+// of the design. The meaning of the digits after FamilyKind depends on FamilyKind. In every
+// family kind, 0 is "Any" and 1 is "No Fit".
+// https://monotype.github.io/panose/pan2.htm
+type Panose struct {
+	FamilyKind      uint8
+	SerifStyle      uint8
+	Weight          uint8
+	Proportion      uint8
+	Contrast        uint8
+	StrokeVariation uint8
+	ArmStyle        uint8
+	Letterform      uint8
+	Midline         uint8
+	XHeight         uint8
+}
+
+// Digits that mean the same in every family kind, and the one family kind whose other digits
+// Panose names.
+const (
+	panoseAny       = 0
+	panoseNoFit     = 1
+	panoseLatinText = 2
+)
+
+// panoseLatinTextNames are the names of the digits of the Latin Text family kind, by digit.
+var panoseLatinTextNames = [10][]string{
+	{"Any", "No Fit", "Latin Text", "Latin Hand Written", "Latin Decorative", "Latin Symbol"},
+	{"Any", "No Fit", "Cove", "Obtuse Cove", "Square Cove", "Obtuse Square Cove", "Square", "Thin", "Oval",
+		"Exaggerated", "Triangle", "Normal Sans", "Obtuse Sans", "Perpendicular Sans", "Flared", "Rounded"},
+	{"Any", "No Fit", "Very Light", "Light", "Thin", "Book", "Medium", "Demi", "Bold", "Heavy", "Black", "Extra Black"},
+	{"Any", "No Fit", "Old Style", "Modern", "Even Width", "Extended", "Condensed", "Very Extended", "Very Condensed",
+		"Monospaced"},
+	{"Any", "No Fit", "None", "Very Low", "Low", "Medium Low", "Medium", "Medium High", "High", "Very High"},
+	{"Any", "No Fit", "No Variation", "Gradual/Diagonal", "Gradual/Transitional", "Gradual/Vertical",
+		"Gradual/Horizontal", "Rapid/Vertical", "Rapid/Horizontal", "Instant/Vertical", "Instant/Horizontal"},
+	{"Any", "No Fit", "Straight Arms/Horizontal", "Straight Arms/Wedge", "Straight Arms/Vertical",
+		"Straight Arms/Single Serif", "Straight Arms/Double Serif", "Non-Straight/Horizontal", "Non-Straight/Wedge",
+		"Non-Straight/Vertical", "Non-Straight/Single Serif", "Non-Straight/Double Serif"},
+	{"Any", "No Fit", "Normal/Contact", "Normal/Weighted", "Normal/Boxed", "Normal/Flattened", "Normal/Rounded",
+		"Normal/Off Center", "Normal/Square", "Oblique/Contact", "Oblique/Weighted", "Oblique/Boxed",
+		"Oblique/Flattened", "Oblique/Rounded", "Oblique/Off Center", "Oblique/Square"},
+	{"Any", "No Fit", "Standard/Trimmed", "Standard/Pointed", "Standard/Serifed", "High/Trimmed", "High/Pointed",
+		"High/Serifed", "Constant/Trimmed", "Constant/Pointed", "Constant/Serifed", "Low/Trimmed", "Low/Pointed",
+		"Low/Serifed"},
+	{"Any", "No Fit", "Constant/Small", "Constant/Standard", "Constant/Large", "Ducking/Small", "Ducking/Standard",
+		"Ducking/Large"},
+}
+
+// panoseDigitNames are the names of the digits after FamilyKind in String.
+var panoseDigitNames = [10]string{"", "serif style", "weight", "proportion", "contrast", "stroke variation",
+	"arm style", "letterform", "midline", "x-height"}
+
+// panoseWeights weigh the digits in Distance: those that decide the overall look of text, serifs,
+// weight, proportion, contrast and letterform, more than the details.
+var panoseWeights = [10]float64{0, 3, 3, 3, 2, 1, 1, 2, 1, 1}
+
+// Panose returns the PANOSE classification of OS/2. It returns false without an OS/2 table.
+func (f *Font) Panose() (Panose, bool) {
+	if f.os2 == nil || len(f.os2.panose10) != 10 {
+		return Panose{}, false
+	}
+	b := f.os2.panose10
+	return Panose{b[0], b[1], b[2], b[3], b[4], b[5], b[6], b[7], b[8], b[9]}, true
+}
+
+// Bytes returns the digits of `p` in the order of OS/2.panose.
+func (p Panose) Bytes() [10]byte {
+	return [10]byte{p.FamilyKind, p.SerifStyle, p.Weight, p.Proportion, p.Contrast, p.StrokeVariation,
+		p.ArmStyle, p.Letterform, p.Midline, p.XHeight}
+}
+
+// String names the digits of `p` of the Latin Text family kind, e.g. "Latin Text (serif style
+// Normal Sans, weight Medium, ...)", and lists the raw digits of other family kinds, e.g.
+// "Family kind 3 (03 02 ...)".
+func (p Panose) String() string {
+	b := p.Bytes()
+	if p.FamilyKind != panoseLatinText {
+		return fmt.Sprintf("Family kind %d (% X)", p.FamilyKind, b)
+	}
+	parts := make([]string, 0, len(b)-1)
+	for i := 1; i < len(b); i++ {
+		name := fmt.Sprintf("%d", b[i])
+		if names := panoseLatinTextNames[i]; int(b[i]) < len(names) {
+			name = names[b[i]]
+		}
+		parts = append(parts, panoseDigitNames[i]+" "+name)
+	}
+	return "Latin Text (" + strings.Join(parts, ", ") + ")"
+}
+
+// Distance returns how much `p` and `other` differ, 0 for classifications that match and at
+// most 17 for ones of the same family kind, +Inf for different family kinds. Each digit adds its
+// weight in panoseWeights if the digits differ, unless either is Any. Weight and contrast
+// are ordered from light to heavy and from no to very high contrast, and add the fraction of
+// their weight that the digits are apart. Digits are only compared for equality outside the
+// Latin Text family kind.
+func (p Panose) Distance(other Panose) float64 {
+	a, b := p.Bytes(), other.Bytes()
+	if a[0] != b[0] && a[0] != panoseAny && b[0] != panoseAny {
+		return math.Inf(1)
+	}
+	latin := a[0] == panoseLatinText || b[0] == panoseLatinText
+	var d float64
+	for i := 1; i < len(a); i++ {
+		x, y := a[i], b[i]
+		switch {
+		case x == y || x == panoseAny || y == panoseAny:
+		case x != panoseNoFit && y != panoseNoFit && latin && (i == 2 || i == 4):
+			// Ordered from 2 to the last digit of the enumeration.
+			span := float64(len(panoseLatinTextNames[i]) - 3)
+			d += panoseWeights[i] * min(math.Abs(float64(x)-float64(y))/span, 1)
+		default:
+			d += panoseWeights[i]
+		}
+	}
+	return d
+}
@@ -0,0 +1,87 @@
+package ttf
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFont_Panose(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Panose
+	}{
+		{"Go Regular", goregular.TTF, Panose{2, 11, 6, 0, 0, 0, 0, 0, 0, 0}},
+		{"Go Bold", gobold.TTF, Panose{2, 11, 7, 3, 5, 0, 0, 0, 0, 4}},
+		{"Go Mono", gomono.TTF, Panose{2, 6, 6, 9, 5, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		f, err := Parse(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := f.Panose(); !ok || got != tt.want {
+			t.Fatalf("%s: got %v %t, want %v", tt.name, got, ok, tt.want)
+		}
+		f.os2 = nil
+		if _, ok := f.Panose(); ok {
+			t.Fatalf("%s: PANOSE without OS/2", tt.name)
+		}
+	}
+}
+
+func TestPanose_String(t *testing.T) {
+	tests := []struct {
+		p    Panose
+		want string
+	}{
+		{Panose{2, 11, 7, 3, 5, 0, 0, 0, 0, 4}, "Latin Text (serif style Normal Sans, weight Demi, proportion Modern, " +
+			"contrast Medium Low, stroke variation Any, arm style Any, letterform Any, midline Any, x-height Constant/Large)"},
+		{Panose{2, 2, 6, 3, 5, 4, 5, 2, 3, 20}, "Latin Text (serif style Cove, weight Medium, proportion Modern, " +
+			"contrast Medium Low, stroke variation Gradual/Transitional, arm style Straight Arms/Single Serif, " +
+			"letterform Normal/Contact, midline Standard/Pointed, x-height 20)"},
+		{Panose{3, 2, 4, 6, 3, 5, 4, 6, 3, 4}, "Family kind 3 (03 02 04 06 03 05 04 06 03 04)"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Fatalf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestPanose_Distance(t *testing.T) {
+	// Published classifications of Arial, Arial Bold and Times New Roman.
+	arial := Panose{2, 11, 6, 4, 2, 2, 2, 2, 2, 4}
+	arialBold := Panose{2, 11, 7, 4, 2, 2, 2, 2, 2, 4}
+	times := Panose{2, 2, 6, 3, 5, 4, 5, 2, 3, 4}
+	script := Panose{3, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+
+	tests := []struct {
+		name string
+		a, b Panose
+		want float64
+	}{
+		{"same", arial, arial, 0},
+		{"bolder", arial, arialBold, 3.0 / 9},
+		{"sans and serif", arial, times, 3 + 3 + 2*3.0/7 + 1 + 1 + 1},
+		{"any", arial, Panose{FamilyKind: 2}, 0},
+		{"no fit", Panose{2, 1, 1, 1, 1, 1, 1, 1, 1, 1}, arial, 17},
+		{"family kinds", arial, script, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Distance(tt.b); math.Abs(got-tt.want) > 1e-9 && got != tt.want {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if got, want := tt.b.Distance(tt.a), tt.a.Distance(tt.b); got != want {
+			t.Fatalf("%s: not symmetric, %v and %v", tt.name, got, want)
+		}
+	}
+	if arial.Distance(arialBold) >= arial.Distance(times) {
+		t.Fatal("Arial Bold is less similar to Arial than Times New Roman")
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Repair fixes defects of `f` that can be derived from the rest of the font, so that it can be
+// written as a valid font. It currently rebuilds loca, see RebuildLoca, and then recalculates
+// the checksums, see RecalculateChecksums.
+func (f *Font) Repair() error {
+	if err := f.RebuildLoca(); err != nil {
+		return err
+	}
+	return f.RecalculateChecksums()
+}
+
+// RecalculateChecksums sets the checksum of every table record of `f`, and checksumAdjustment
+// of the head table, to those of `f` as Write serializes it, e.g. after a table was changed in
+// memory. Records of tables that Write leaves out keep their checksum. Offsets and lengths are
+// left as they are, as they locate the tables in the file `f` was parsed from.
+func (f *Font) RecalculateChecksums() error {
+	if f.trec == nil || f.head == nil {
+		return errRequiredField
+	}
+	data, written, err := f.writtenTableRecords()
+	if err != nil {
+		return err
+	}
+	// Records are shared with the fonts made from `f`, so they are replaced, not changed.
+	trec := &tableRecords{}
+	for _, tr := range f.trec.list {
+		checksum := tr.checksum
+		if w, ok := written.trMap[tr.tableTag]; ok {
+			checksum = w.checksum
+		}
+		trec.SetTag(tr.tableTag, int64(tr.offset), int(tr.length), checksum)
+	}
+	f.trec = trec
+	head := written.trMap[tagHead]
+	f.head.checksumAdjustment = binary.BigEndian.Uint32(data[head.offset+headAdjustmentOffset:])
+	f.markDirty(tagHead)
+	return nil
+}
+
+// TableChecksum returns the checksum of table `tag`, e.g. "glyf" or "cvt ", in the table
+// records of `f`: as read from the file `f` was parsed from, or as set by RecalculateChecksums.
+func (f *Font) TableChecksum(tag string) (uint32, error) {
+	t, err := ParseTag(tag)
+	if err != nil {
+		return 0, err
+	}
+	if f.trec == nil {
+		return 0, errRequiredField
+	}
+	tr, ok := f.trec.trMap[t]
+	if !ok {
+		return 0, fmt.Errorf("no %s table: %w", t, errRequiredField)
+	}
+	return tr.checksum, nil
+}
+
+// RebuildLoca rebuilds the loca table from the glyph boundaries found by decoding the glyf table
+// of the parsed font from the start, for fonts whose loca offsets are off. Glyphs that loca
+// gives no data are kept empty, every other glyph takes the length its description declares
+// plus padding to an even length, which short offsets require and most fonts use throughout.
+// indexToLocFormat is switched to long offsets if short ones cannot represent the result.
+func (f *Font) RebuildLoca() error {
+	if f.glyf == nil || f.loca == nil || f.head == nil || f.maxp == nil || f.br == nil {
+		return errRequiredField
+	}
+	r := f.br.fork()
+	tr, has, err := f.seekToTable(r, tagGlyf)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errRequiredField
+	}
+	var data []byte
+	if err := r.readBytes(&data, int(tr.length)); err != nil {
+		return err
+	}
+
+	numGlyphs := int(f.maxp.numGlyphs)
+	descs := make([]*glyphDescription, numGlyphs)
+	offsets := make([]int, 0, numGlyphs+1)
+	pos := 0
+	for i := 0; i < numGlyphs; i++ {
+		offsets = append(offsets, pos)
+		_, length, err := f.GetGlyphDataOffset(GlyphIndex(i))
+		if err != nil {
+			return err
+		}
+		if length == 0 {
+			descs[i] = &glyphDescription{}
+			continue
+		}
+		if pos >= len(data) {
+			return fmt.Errorf("glyph %d starts past the end of glyf (%d bytes): %w", i, len(data), errRangeCheck)
+		}
+		n, err := glyphDataLength(data[pos:])
+		if err != nil {
+			return fmt.Errorf("glyph %d at %d: %w", i, pos, err)
+		}
+		end := glyphPaddedEnd(data, pos+n)
+		descs[i] = &glyphDescription{raw: data[pos:end]}
+		pos = end
+	}
+	offsets = append(offsets, pos)
+	if len(offsets) != numGlyphs+1 {
+		return fmt.Errorf("rebuilt %d loca entries for %d glyphs: %w", len(offsets), numGlyphs, errRangeCheck)
+	}
+
+	short := f.head.indexToLocFormat == 0 && pos <= 2*math.MaxUint16
+	for _, off := range offsets {
+		short = short && off%2 == 0
+	}
+	loca := &locaTable{}
+	if short {
+		loca.offsetsShort = make([]offset16, len(offsets))
+		for i, off := range offsets {
+			loca.offsetsShort[i] = offset16(off / 2)
+		}
+	} else {
+		loca.offsetsLong = make([]offset32, len(offsets))
+		for i, off := range offsets {
+			loca.offsetsLong[i] = offset32(off)
+		}
+		f.head.indexToLocFormat = 1
+	}
+	f.loca = loca
+	f.glyf.descs = descs
+	// head gives the loca format.
+	f.markDirty(tagLoca, tagGlyf, tagHead)
+	return nil
+}
+
+// glyphPaddedEnd returns where the next glyph starts after a glyph description of `data` ending
+// at `end`: on the next even offset, or the next multiple of four if the two bytes there are
+// zero padding.
+func glyphPaddedEnd(data []byte, end int) int {
+	end += end % 2
+	if end%4 != 0 && end+2 <= len(data) && data[end] == 0 && data[end+1] == 0 {
+		end += 2
+	}
+	return min(end, len(data))
+}
+
+// RebuildCmapFromNames replaces the cmap table of `f` with one synthesized from the post table
+// glyph names (see SynthesizeCmapFromPost), for fonts whose cmap is missing or unusable. It
+// holds a (3,1) format 4 subtable, and a (3,10) format 12 subtable if a name stands for a rune
+// outside the BMP. Glyphs named after a rune already taken by a lower glyph are left unmapped
+// and noted in Warnings.
+func (f *Font) RebuildCmapFromNames() error {
+	if f.maxp == nil {
+		return errRequiredField
+	}
+	var conflicts []string
+	cmap := f.postCmap(func(r rune, kept, dropped GlyphIndex) {
+		conflicts = append(conflicts, fmt.Sprintf("glyphs %d and %d are both named for %U, keeping %d", kept, dropped, r, kept))
+	})
+	if len(cmap) == 0 {
+		return fmt.Errorf("no glyph names standing for runes: %w", errRequiredField)
+	}
+
+	if err := f.setCmap(rebuildUnicodeCmap(nil, cmap)); err != nil {
+		return err
+	}
+	f.incompatibilities = append(f.incompatibilities, conflicts...)
+	return nil
+}
@@ -0,0 +1,231 @@
+package ttf
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlyphDataLength(t *testing.T) {
+	f := loadGoRegular(t)
+	for gid, desc := range f.glyf.descs {
+		if len(desc.raw) == 0 {
+			continue
+		}
+		n, err := glyphDataLength(desc.raw)
+		if err != nil {
+			t.Fatalf("glyph %d: %v", gid, err)
+		}
+		if len(desc.raw)-n > 3 || slices.ContainsFunc(desc.raw[n:], func(b byte) bool { return b != 0 }) {
+			t.Fatalf("glyph %d: length %d of %d bytes, padding % X", gid, n, len(desc.raw), desc.raw[n:])
+		}
+		if _, err := glyphDataLength(desc.raw[:n-1]); err == nil {
+			t.Fatalf("glyph %d: no error for data cut to %d bytes", gid, n-1)
+		}
+	}
+}
+
+func TestFont_RebuildLoca(t *testing.T) {
+	for _, format := range []int16{0, 1} {
+		f := loadGoRegular(t)
+		if format == 1 {
+			f.head.indexToLocFormat = 1
+			f.loca.offsetsLong = make([]offset32, len(f.loca.offsetsShort))
+			for i, off := range f.loca.offsetsShort {
+				f.loca.offsetsLong[i] = 2 * offset32(off)
+			}
+			f.loca.offsetsShort = nil
+		}
+		want := make([][]byte, len(f.glyf.descs))
+		for i, desc := range f.glyf.descs {
+			want[i] = desc.raw
+		}
+		wantLoca := *f.loca
+
+		// Shift all but the first offset by 2 bytes, as if glyph 0 had grown without loca
+		// following.
+		f.loca.offsetsShort = slices.Clone(f.loca.offsetsShort)
+		for i := 1; i < len(f.loca.offsetsShort); i++ {
+			f.loca.offsetsShort[i]++
+		}
+		f.loca.offsetsLong = slices.Clone(f.loca.offsetsLong)
+		for i := 1; i < len(f.loca.offsetsLong); i++ {
+			f.loca.offsetsLong[i] += 2
+		}
+		// The offsets are shifted against glyf as written from the glyph descriptions.
+		f.markDirty(tagHead, tagLoca, tagGlyf)
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err == nil {
+			t.Fatalf("format %d: expected glyph errors with a shifted loca", format)
+		}
+
+		f, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Repair(); err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		for i, desc := range f.glyf.descs {
+			if !bytes.Equal(desc.raw, want[i]) {
+				t.Fatalf("format %d: glyph %d: got % X, want % X", format, i, desc.raw, want[i])
+			}
+		}
+		if f.head.indexToLocFormat != format || !slices.Equal(f.loca.offsetsShort, wantLoca.offsetsShort) ||
+			!slices.Equal(f.loca.offsetsLong, wantLoca.offsetsLong) {
+			t.Fatalf("format %d: rebuilt loca differs from the original", format)
+		}
+		buf = bytes.Buffer{}
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+			t.Fatalf("format %d: repaired font: %v %v", format, err, rep.Errors())
+		}
+	}
+}
+
+func TestFont_RecalculateChecksums(t *testing.T) {
+	f := loadGoRegular(t)
+	want, err := f.TableChecksum("glyf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.trec.trMap[tagGlyf].checksum ^= 1
+	if got, _ := f.TableChecksum("glyf"); got == want {
+		t.Fatal("checksum not corrupted")
+	}
+	sub, err := f.Subset([]rune("A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.RecalculateChecksums(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.TableChecksum("glyf"); got != want^1 {
+		t.Fatalf("recalculating the subset changed the font: %08X", got)
+	}
+
+	if err := f.RecalculateChecksums(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("%v %v", err, rep.Errors())
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range g.trec.list {
+		got, err := f.TableChecksum(string(tr.tableTag[:]))
+		if err != nil || got != tr.checksum {
+			t.Fatalf("%s: checksum %08X %v, written %08X", tr.tableTag, got, err, tr.checksum)
+		}
+	}
+	if got, _ := f.TableChecksum("glyf"); got != want {
+		t.Fatalf("glyf: checksum %08X, want %08X", got, want)
+	}
+	if f.head.checksumAdjustment != g.head.checksumAdjustment {
+		t.Fatalf("checksumAdjustment %08X, written %08X", f.head.checksumAdjustment, g.head.checksumAdjustment)
+	}
+
+	if _, err := f.TableChecksum("cvt"); err == nil {
+		t.Fatal("no error for a 3-byte tag")
+	}
+	if _, err := f.TableChecksum("GPOS"); !errors.Is(err, errRequiredField) {
+		t.Fatalf("GPOS: %v", err)
+	}
+}
+
+func TestFont_RebuildCmapFromNames(t *testing.T) {
+	f := loadGoRegular(t)
+	synth := f.SynthesizeCmapFromPost()
+	f.cmap = nil
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	stripped, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stripped.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP) != nil {
+		t.Fatal("fixture has a cmap")
+	}
+	if err := stripped.RebuildCmapFromNames(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stripped.cmap.subtableKeys) != 1 || len(stripped.Warnings()) != 0 {
+		t.Fatalf("subtables %v, warnings %q", stripped.cmap.subtableKeys, stripped.Warnings())
+	}
+	st := stripped.cmap.subtables["4,3,1"].ctx.(cmapSubtableFormat4)
+	if st.endCode[len(st.endCode)-1] != 0xFFFF || !slices.IsSorted(st.endCode) {
+		t.Fatalf("endCode %v", st.endCode)
+	}
+	runes := slices.Sorted(maps.Keys(synth))
+	gids, found := stripped.LookupRunes(slices.Clone(runes))
+	if !slices.Equal(found, runes) {
+		t.Fatalf("found %d of %d runes", len(found), len(runes))
+	}
+	for i, r := range found {
+		if gids[i] != synth[r] {
+			t.Fatalf("%U: glyph %d, want %d", r, gids[i], synth[r])
+		}
+	}
+
+	// `stripped` reads the tables it copies from the old data.
+	buf = bytes.Buffer{}
+	if err := stripped.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, buf.Bytes())
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP), synth) {
+		t.Fatal("written cmap differs from the synthesized one")
+	}
+
+	// A name taken twice keeps the lower glyph, and a name outside the BMP adds format 12.
+	a, b := synth['A'], synth['B']
+	g.post.glyphNames[b] = "A"
+	g.post.glyphNames[synth['C']] = "u1F600"
+	g.markDirty(tagPost)
+	if err := g.RebuildCmapFromNames(); err != nil {
+		t.Fatal(err)
+	}
+	cmap := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeUCS4)
+	if cmap['A'] != a || cmap[0x1F600] != synth['C'] {
+		t.Fatalf("A -> %d, U+1F600 -> %d", cmap['A'], cmap[0x1F600])
+	}
+	if _, ok := cmap['B']; ok {
+		t.Fatal("B is still mapped")
+	}
+	if _, ok := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)[0x1F600]; ok {
+		t.Fatal("format 4 maps a rune outside the BMP")
+	}
+	if w := g.Warnings(); len(w) != 1 || !strings.Contains(w[0], "U+0041") {
+		t.Fatalf("warnings %q", w)
+	}
+	buf = bytes.Buffer{}
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkDirectory(t, buf.Bytes())
+
+	g.post = nil
+	if err := g.RebuildCmapFromNames(); err == nil {
+		t.Fatal("expected an error without glyph names")
+	}
+}
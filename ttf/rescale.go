@@ -0,0 +1,625 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// rescaleUnsupported are the tables with values in font units that Rescale cannot scale: CFF
+// outlines, vertical origins, baselines, math layout and the tables of variable fonts.
+var rescaleUnsupported = []Tag{
+	tagCFF, tagCFF2, tagSVG, MustTag("VORG"), MustTag("BASE"), MustTag("MATH"),
+	MustTag("fvar"), MustTag("gvar"), MustTag("cvar"), MustTag("HVAR"), MustTag("VVAR"), MustTag("MVAR"),
+}
+
+// Rescale changes the unitsPerEm of `f` to `newUPM`, scaling every value in font units by
+// newUPM/unitsPerEm rounded half to even: the glyph outlines and their bounding boxes, the
+// advances and side bearings of hmtx and vmtx, the bounding box of head, the metrics of hhea,
+// vhea, OS/2 and the underline of post, the kerning of kern and the values and anchors of
+// GPOS, and the ligature carets of GDEF.
+//
+// The points of a glyph are scaled from their absolute coordinates, so rounding errors do not
+// add up along its contours. Those of the advances add up along a line of text though: when
+// they come to a unit or more over all glyphs, that is noted in the Warnings of `f`.
+//
+// Hinting instructions are written for the unitsPerEm of the font, so they are dropped, noted
+// in the Warnings: the cvt, fpgm and prep tables and the instructions of the glyphs. Fonts
+// without glyf, or with tables that cannot be scaled, e.g. CFF or those of variable fonts,
+// give ErrRescaleUnsupported. Values that no longer fit their fields give errRangeCheck. On
+// error `f` is left as it was.
+func (f *Font) Rescale(newUPM uint16) error {
+	if f.head == nil || f.maxp == nil {
+		return errRequiredField
+	}
+	if newUPM < 16 || newUPM > 16384 {
+		return fmt.Errorf("unitsPerEm %d outside 16 to 16384: %w", newUPM, errRangeCheck)
+	}
+	var unsupported []string
+	if f.glyf == nil {
+		unsupported = append(unsupported, "no glyf")
+	}
+	for _, tag := range rescaleUnsupported {
+		if f.hasTable(tag) {
+			unsupported = append(unsupported, tag.String())
+		}
+	}
+	if f.color&ColorCOLRv1 != 0 {
+		unsupported = append(unsupported, "COLR version 1")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s: %w", strings.Join(unsupported, ", "), ErrRescaleUnsupported)
+	}
+	if f.loca == nil {
+		return errRequiredField
+	}
+	if newUPM == f.head.unitsPerEm {
+		return nil
+	}
+
+	s := &rescaler{from: int(f.head.unitsPerEm), to: int(newUPM)}
+	if s.from == 0 {
+		return fmt.Errorf("unitsPerEm 0: %w", errRangeCheck)
+	}
+
+	// The tables are copied before scaling, as they may be shared with fonts made from `f`.
+	descs, instructed, err := s.glyphs(f.glyf.descs)
+	if err != nil {
+		return err
+	}
+	head := *f.head
+	scaleUnits(s, &head.xMin, &head.yMin, &head.xMax, &head.yMax)
+	head.unitsPerEm = newUPM
+	maxp := *f.maxp
+	maxp.maxZones, maxp.maxTwilightPoints, maxp.maxStorage = 1, 0, 0
+	maxp.maxFunctionDefs, maxp.maxInstructionDefs, maxp.maxStackElements, maxp.maxSizeOfInstructions = 0, 0, 0, 0
+
+	var hhea *hheaTable
+	if f.hhea != nil {
+		t := *f.hhea
+		scaleUnits(s, &t.ascender, &t.descender, &t.lineGap, &t.minLeftSideBearing, &t.minRightSideBearing, &t.xMaxExtent)
+		scaleUnits(s, &t.advanceWidthMax)
+		scaleUnits(s, &t.caretOffset)
+		hhea = &t
+	}
+	var hmtx *hmtxTable
+	var drift int // Of the advances, in units of 1/from.
+	if f.hmtx != nil {
+		hmtx = &hmtxTable{hMetrics: slices.Clone(f.hmtx.hMetrics), leftSideBearings: slices.Clone(f.hmtx.leftSideBearings)}
+		for i := range hmtx.hMetrics {
+			m := &hmtx.hMetrics[i]
+			exact := int(m.advanceWidth) * s.to
+			scaleUnits(s, &m.advanceWidth)
+			scaleUnits(s, &m.lsb)
+			drift += int(m.advanceWidth)*s.from - exact
+		}
+		scaleUnits(s, slicePointers(hmtx.leftSideBearings)...)
+	}
+	var os2 *os2Table
+	if f.os2 != nil {
+		t := *f.os2
+		scaleUnits(s, &t.xAvgCharWidth, &t.ySubscriptXSize, &t.ySubscriptYSize, &t.ySubscriptXOffset,
+			&t.ySubscriptYOffset, &t.ySuperscriptXSize, &t.ySuperscriptYSize, &t.ySuperscriptXOffset,
+			&t.ySuperscriptYOffset, &t.yStrikeoutSize, &t.yStrikeoutPosition, &t.sTypoAscender,
+			&t.sTypoDescender, &t.sTypoLineGap, &t.sxHeight, &t.sCapHeight)
+		scaleUnits(s, &t.usWinAscent, &t.usWinDescent)
+		os2 = &t
+	}
+	var post *postTable
+	if f.post != nil {
+		t := *f.post
+		scaleUnits(s, &t.underlinePosition, &t.underlineThickness)
+		post = &t
+	}
+	var raw []rawTable
+	for _, t := range []struct {
+		tag   Tag
+		scale func(d *layoutScaler)
+	}{
+		{tagKern, (*layoutScaler).kern},
+		{tagGPOS, (*layoutScaler).gpos},
+		{tagGDEF, (*layoutScaler).gdef},
+		{tagVhea, (*layoutScaler).vhea},
+		{tagVmtx, (*layoutScaler).vmtx},
+	} {
+		data, err := f.rawTableData(t.tag)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		d := newLayoutScaler(s, data, int(f.maxp.numGlyphs))
+		if t.tag == tagVmtx {
+			vhea, err := f.rawTableData(tagVhea)
+			if err != nil {
+				return err
+			}
+			if len(vhea) < 36 {
+				return fmt.Errorf("vmtx without vhea: %w", errRequiredField)
+			}
+			d.numLongMetrics = int(binary.BigEndian.Uint16(vhea[34:]))
+		}
+		t.scale(d)
+		if d.err != nil {
+			return fmt.Errorf("%s: %w", t.tag, d.err)
+		}
+		raw = append(raw, rawTable{tag: t.tag, data: d.data})
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	short := f.head.indexToLocFormat == 0
+	f.loca, head.indexToLocFormat = buildLoca(descs, short)
+	f.glyf = &glyfTable{descs: descs}
+	f.head, f.maxp, f.hhea, f.hmtx, f.os2, f.post = &head, &maxp, hhea, hmtx, os2, post
+	for _, t := range raw {
+		f.setRawTable(t.tag, t.data)
+	}
+	f.markDirty(tagHead, tagMaxp, tagHhea, tagHmtx, tagLoca, tagGlyf, tagOS2, tagPost)
+
+	var hinting []string
+	for _, t := range []struct {
+		tag Tag
+		has bool
+	}{{tagCvt, f.cvt != nil}, {tagFpgm, f.fpgm != nil}, {tagPrep, f.prep != nil}} {
+		if t.has {
+			hinting = append(hinting, t.tag.String())
+		}
+	}
+	if instructed > 0 {
+		hinting = append(hinting, fmt.Sprintf("the instructions of %d glyphs", instructed))
+	}
+	f.cvt, f.fpgm, f.prep = nil, nil, nil
+	if len(hinting) > 0 {
+		f.incompatibilities = append(f.incompatibilities, "dropped hinting "+strings.Join(hinting, ", "))
+	}
+	if drift <= -s.from || drift >= s.from {
+		f.incompatibilities = append(f.incompatibilities, fmt.Sprintf(
+			"rescaled from %d to %d units per em, the advances drift by %+.2f units in total",
+			s.from, s.to, float64(drift)/float64(s.from)))
+	}
+
+	// Read again from the scaled tables on first use.
+	f.kerning = sync.OnceValues(f.readKerning)
+	f.metrics = sync.OnceValues(f.glyphMetrics)
+	return nil
+}
+
+// rescaler scales values in font units from `from` to `to` units per em. The first value out of
+// the range of its field is kept in `err`.
+type rescaler struct {
+	from, to int
+	err      error
+}
+
+// value returns `v` scaled by to/from, rounded half to even.
+func (s *rescaler) value(v int) int {
+	num := v * s.to
+	q, r := num/s.from, num%s.from
+	if r < 0 {
+		q, r = q-1, r+s.from
+	}
+	if 2*r > s.from || 2*r == s.from && q%2 != 0 {
+		q++
+	}
+	return q
+}
+
+// scaleUnits scales the values of `vs` in place, see rescaler.value.
+func scaleUnits[T ~int16 | ~uint16](s *rescaler, vs ...*T) {
+	for _, v := range vs {
+		scaled := s.value(int(*v))
+		if int(T(scaled)) != scaled {
+			if s.err == nil {
+				s.err = fmt.Errorf("%d scaled to %d out of range: %w", *v, scaled, errRangeCheck)
+			}
+			continue
+		}
+		*v = T(scaled)
+	}
+}
+
+// slicePointers returns pointers to the elements of `vs`.
+func slicePointers[T any](vs []T) []*T {
+	ps := make([]*T, len(vs))
+	for i := range vs {
+		ps[i] = &vs[i]
+	}
+	return ps
+}
+
+// glyphs returns glyph descriptions `descs` scaled without instructions, padded to an even
+// length for short loca offsets, and the number of glyphs that had instructions.
+func (s *rescaler) glyphs(descs []*glyphDescription) ([]*glyphDescription, int, error) {
+	scaled := make([]*glyphDescription, len(descs))
+	instructed := 0
+	for i, gd := range descs {
+		if gd.isEmpty() {
+			scaled[i] = &glyphDescription{}
+			continue
+		}
+		var data []byte
+		var hadInstructions bool
+		var err error
+		if gd.isComposite() {
+			data, hadInstructions, err = s.composite(gd.raw)
+		} else {
+			data, hadInstructions, err = s.simple(gd.raw)
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("glyph %d: %w", i, err)
+		}
+		if hadInstructions {
+			instructed++
+		}
+		scaled[i] = evenGlyph(&glyphDescription{raw: data})
+	}
+	return scaled, instructed, s.err
+}
+
+// simple returns simple glyph description `data` with its points scaled and without
+// instructions, and whether it had any.
+func (s *rescaler) simple(data []byte) ([]byte, bool, error) {
+	g, err := decodeSimpleGlyph(data)
+	if err != nil {
+		return nil, false, err
+	}
+	hadInstructions := len(g.instructions) > 0
+	g.instructions = nil
+	for _, vs := range [][]int{g.xs, g.ys} {
+		for i, v := range vs {
+			vs[i] = s.value(v)
+			if int(int16(vs[i])) != vs[i] && s.err == nil {
+				s.err = fmt.Errorf("coordinate %d scaled to %d out of range: %w", v, vs[i], errRangeCheck)
+			}
+		}
+	}
+	encoded := g.encode()
+	if len(g.xs) == 0 {
+		// No points, but a header that may have a bounding box.
+		for j, v := range s.bounds(data) {
+			binary.BigEndian.PutUint16(encoded[2+2*j:], uint16(v))
+		}
+	}
+	return encoded, hadInstructions, nil
+}
+
+// bounds returns the bounding box of the header of glyph description `data` scaled.
+func (s *rescaler) bounds(data []byte) []int16 {
+	box := make([]int16, 4)
+	for j := range box {
+		box[j] = int16(binary.BigEndian.Uint16(data[2+2*j:]))
+	}
+	scaleUnits(s, slicePointers(box)...)
+	return box
+}
+
+// composite returns composite glyph description `data` with its bounding box and the offsets of
+// its components scaled and without instructions, and whether it had any. Offsets given as
+// bytes are widened to words if they no longer fit.
+func (s *rescaler) composite(data []byte) ([]byte, bool, error) {
+	n, err := compositeDataLength(data)
+	if err != nil {
+		return nil, false, err
+	}
+	be := binary.BigEndian
+	out := slices.Clone(data[:2])
+	for _, v := range s.bounds(data) {
+		out = be.AppendUint16(out, uint16(v))
+	}
+
+	hadInstructions := false
+	for pos := 10; pos < n; {
+		flag := compositeGlyphFlag(be.Uint16(data[pos:]))
+		gid := be.Uint16(data[pos+2:])
+		pos += 4
+		var arg1, arg2 int
+		if flag.IsSet(arg1And2AreWords) {
+			arg1, arg2 = int(int16(be.Uint16(data[pos:]))), int(int16(be.Uint16(data[pos+2:])))
+			pos += 4
+		} else {
+			arg1, arg2 = int(int8(data[pos])), int(int8(data[pos+1]))
+			pos += 2
+		}
+		words := flag.IsSet(arg1And2AreWords)
+		if flag.IsSet(argsAreXYValues) {
+			offset := []int16{int16(arg1), int16(arg2)}
+			scaleUnits(s, slicePointers(offset)...)
+			arg1, arg2 = int(offset[0]), int(offset[1])
+			words = words || arg1 != int(int8(arg1)) || arg2 != int(int8(arg2))
+		}
+		transform := 0
+		switch {
+		case flag.IsSet(weHaveAScale):
+			transform = 2
+		case flag.IsSet(weHaveAnXAndYScale):
+			transform = 4
+		case flag.IsSet(weHaveATwoByTwo):
+			transform = 8
+		}
+		hadInstructions = hadInstructions || flag.IsSet(weHaveInstructions)
+		flag &^= weHaveInstructions
+		if words {
+			flag |= arg1And2AreWords
+		}
+		out = be.AppendUint16(be.AppendUint16(out, uint16(flag)), gid)
+		if words {
+			out = be.AppendUint16(be.AppendUint16(out, uint16(arg1)), uint16(arg2))
+		} else {
+			out = append(out, uint8(arg1), uint8(arg2))
+		}
+		out = append(out, data[pos:pos+transform]...)
+		pos += transform
+		if !flag.IsSet(moreComponents) {
+			break
+		}
+	}
+	return out, hadInstructions, nil
+}
+
+// layoutScaler scales the values in font units of a copy of the data of a layout or metrics
+// table in place. Each value is scaled once, however many times it is referred to, as
+// subtables and anchors may be shared.
+type layoutScaler struct {
+	layoutReader
+	s              *rescaler
+	done           map[int]bool // Offsets of the values scaled.
+	numLongMetrics int          // numOfLongVerMetrics of vhea, for vmtx.
+}
+
+func newLayoutScaler(s *rescaler, data []byte, numGlyphs int) *layoutScaler {
+	return &layoutScaler{
+		layoutReader: layoutReader{data: slices.Clone(data), numGlyphs: numGlyphs},
+		s:            s,
+		done:         map[int]bool{},
+	}
+}
+
+// scale scales the int16 values at offsets `ats`.
+func (d *layoutScaler) scale(ats ...int) {
+	for _, at := range ats {
+		if d.done[at] || !d.has(at, 2) {
+			continue
+		}
+		d.done[at] = true
+		v := int16(binary.BigEndian.Uint16(d.data[at:]))
+		scaleUnits(d.s, &v)
+		binary.BigEndian.PutUint16(d.data[at:], uint16(v))
+	}
+}
+
+// scaleUnsigned scales the uint16 value at offset `at`.
+func (d *layoutScaler) scaleUnsigned(at int) {
+	if d.done[at] || !d.has(at, 2) {
+		return
+	}
+	d.done[at] = true
+	v := binary.BigEndian.Uint16(d.data[at:])
+	scaleUnits(d.s, &v)
+	binary.BigEndian.PutUint16(d.data[at:], v)
+}
+
+// kern scales the values of the format 0 subtables of a kern table, see kernPairs. Subtables of
+// other formats are left as they are.
+func (d *layoutScaler) kern() {
+	pairs := func(at, nPairs int) {
+		if !d.has(at, 6*nPairs) {
+			return
+		}
+		for i := range nPairs {
+			d.scale(at + 6*i + 4)
+		}
+	}
+	switch d.u16(0) {
+	case 0:
+		at := 4
+		for range int(d.u16(2)) {
+			length, coverage := int(d.u16(at+2)), d.u16(at+4)
+			if d.err != nil {
+				return
+			}
+			nPairs := int(d.u16(at + 6))
+			if coverage>>8 == 0 {
+				pairs(at+14, nPairs)
+				at += 14 + 6*nPairs
+			} else {
+				at += length
+			}
+		}
+	case 1:
+		at := 8
+		for range int(d.u32(4)) {
+			length, coverage := int(d.u32(at)), d.u16(at+4)
+			if d.err != nil {
+				return
+			}
+			if coverage&0xFF == 0 {
+				pairs(at+16, int(d.u16(at+8)))
+			}
+			if length < 8 {
+				d.err = fmt.Errorf("kern subtable length %d: %w", length, errRangeCheck)
+				return
+			}
+			at += length
+		}
+	default:
+		d.err = fmt.Errorf("kern version %d: %w", d.u16(0), errTypeCheck)
+	}
+}
+
+// gpos scales the ValueRecords and anchors of the lookups of a GPOS table. Device tables are in
+// pixels and left as they are.
+func (d *layoutScaler) gpos() {
+	if major := d.u16(0); major != 1 {
+		if d.err == nil {
+			d.err = fmt.Errorf("GPOS version %d: %w", major, errTypeCheck)
+		}
+		return
+	}
+	lookupList := int(d.u16(8))
+	if lookupList == 0 {
+		return
+	}
+	for i := range int(d.u16(lookupList)) {
+		lookup := lookupList + int(d.u16(lookupList+2+2*i))
+		lookupType := d.u16(lookup)
+		for j := range int(d.u16(lookup + 4)) {
+			subtable := lookup + int(d.u16(lookup+6+2*j))
+			if lookupType == 9 {
+				d.gposSubtable(d.u16(subtable+2), subtable+int(d.u32(subtable+4)))
+			} else {
+				d.gposSubtable(lookupType, subtable)
+			}
+			if d.err != nil {
+				return
+			}
+		}
+	}
+}
+
+// gposSubtable scales the values of GPOS subtable `at` of lookup type `lookupType`. Contextual
+// lookups refer to other lookups and have no values of their own.
+func (d *layoutScaler) gposSubtable(lookupType uint16, at int) {
+	format := d.u16(at)
+	switch {
+	case lookupType == 1 && format == 1:
+		// posFormat, coverageOffset, valueFormat, valueRecord.
+		d.valueRecord(at+6, d.u16(at+4))
+	case lookupType == 1 && format == 2:
+		// posFormat, coverageOffset, valueFormat, valueCount, valueRecords.
+		valueFormat := d.u16(at + 4)
+		size, _ := valueRecordSize(valueFormat)
+		for i := range int(d.u16(at + 6)) {
+			d.valueRecord(at+8+i*size, valueFormat)
+		}
+	case lookupType == 2 && format == 1:
+		format1, format2 := d.u16(at+4), d.u16(at+6)
+		size1, _ := valueRecordSize(format1)
+		size2, _ := valueRecordSize(format2)
+		for i := range int(d.u16(at + 8)) {
+			set := at + int(d.u16(at+10+2*i))
+			for j := range int(d.u16(set)) {
+				rec := set + 2 + j*(2+size1+size2)
+				d.valueRecord(rec+2, format1)
+				d.valueRecord(rec+2+size1, format2)
+			}
+		}
+	case lookupType == 2 && format == 2:
+		format1, format2 := d.u16(at+4), d.u16(at+6)
+		size1, _ := valueRecordSize(format1)
+		size2, _ := valueRecordSize(format2)
+		n := int(d.u16(at+12)) * int(d.u16(at+14))
+		if !d.has(at+16, n*(size1+size2)) {
+			return
+		}
+		for i := range n {
+			rec := at + 16 + i*(size1+size2)
+			d.valueRecord(rec, format1)
+			d.valueRecord(rec+size1, format2)
+		}
+	case lookupType == 3 && format == 1:
+		// posFormat, coverageOffset, entryExitCount, EntryExitRecords of anchor offsets.
+		for i := range 2 * int(d.u16(at+4)) {
+			d.anchor(at, int(d.u16(at+6+2*i)))
+		}
+	case lookupType >= 4 && lookupType <= 6 && format == 1:
+		// posFormat, markCoverageOffset, base, ligature or mark2 CoverageOffset, markClassCount,
+		// markArrayOffset, and base, ligature or mark2 ArrayOffset.
+		classes := int(d.u16(at + 6))
+		marks := at + int(d.u16(at+8))
+		for i := range int(d.u16(marks)) {
+			// MarkRecords of markClass, markAnchorOffset.
+			d.anchor(marks, int(d.u16(marks+4+4*i)))
+		}
+		array := at + int(d.u16(at+10))
+		if lookupType == 5 {
+			for i := range int(d.u16(array)) {
+				attach := array + int(d.u16(array+2+2*i))
+				d.anchors(attach, int(d.u16(attach))*classes)
+			}
+		} else {
+			d.anchors(array, int(d.u16(array))*classes)
+		}
+	case lookupType == 7 || lookupType == 8:
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("lookup type %d format %d: %w", lookupType, format, errTypeCheck)
+		}
+	}
+}
+
+// valueRecord scales the placements and advances of the ValueRecord at `at` of `valueFormat`.
+func (d *layoutScaler) valueRecord(at int, valueFormat uint16) {
+	for bit := range 4 {
+		if valueFormat&(1<<bit) != 0 {
+			d.scale(at + 2*bits.OnesCount16(valueFormat&(1<<bit-1)))
+		}
+	}
+}
+
+// anchors scales the anchors of the array at `at` of a count and then `n` anchor offsets from
+// `at`, such as a BaseArray or a LigatureAttach.
+func (d *layoutScaler) anchors(at, n int) {
+	for i := range n {
+		d.anchor(at, int(d.u16(at+2+2*i)))
+	}
+}
+
+// anchor scales the coordinates of the anchor at `offset` from `base`, none if `offset` is 0.
+func (d *layoutScaler) anchor(base, offset int) {
+	if offset == 0 {
+		return
+	}
+	// anchorFormat, xCoordinate, yCoordinate; a contour point or device tables follow.
+	d.scale(base+offset+2, base+offset+4)
+}
+
+// gdef scales the coordinates of the caret values of the LigCaretList of a GDEF table.
+func (d *layoutScaler) gdef() {
+	// majorVersion, minorVersion, glyphClassDefOffset, attachListOffset, ligCaretListOffset.
+	carets := int(d.u16(8))
+	if carets == 0 {
+		return
+	}
+	for i := range int(d.u16(carets + 2)) {
+		lig := carets + int(d.u16(carets+4+2*i))
+		for j := range int(d.u16(lig)) {
+			caret := lig + int(d.u16(lig+2+2*j))
+			// Formats 1 and 3 have a coordinate, format 2 a contour point.
+			if format := d.u16(caret); format == 1 || format == 3 {
+				d.scale(caret + 2)
+			}
+		}
+	}
+}
+
+// vhea scales the metrics of a vhea table.
+func (d *layoutScaler) vhea() {
+	// version, vertTypoAscender, vertTypoDescender, vertTypoLineGap, advanceHeightMax,
+	// minTopSideBearing, minBottomSideBearing, yMaxExtent, caretSlopeRise, caretSlopeRun,
+	// caretOffset.
+	d.scale(4, 6, 8)
+	d.scaleUnsigned(10)
+	d.scale(12, 14, 16, 22)
+}
+
+// vmtx scales the advance heights and top side bearings of a vmtx table.
+func (d *layoutScaler) vmtx() {
+	for at := 0; at+2 <= len(d.data); at += 2 {
+		if at < 4*d.numLongMetrics && at%4 == 0 {
+			d.scaleUnsigned(at)
+		} else {
+			d.scale(at)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package ttf
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// exactScale returns `v` font units of 2048 per em in units of 1000 per em, rounded half to
+// even from the exact rational result.
+func exactScale(v int) int {
+	r := big.NewRat(int64(v)*1000, 2048)
+	q := new(big.Int).Div(r.Num(), r.Denom()) // Euclidean, so the floor for a positive denominator.
+	frac := new(big.Rat).Sub(r, new(big.Rat).SetInt(q))
+	switch frac.Cmp(big.NewRat(1, 2)) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return int(q.Int64())
+}
+
+func TestRescaler_Value(t *testing.T) {
+	s := &rescaler{from: 2048, to: 1000}
+	// 128 and 384 are ties, at 62.5 and 187.5.
+	for _, v := range []int{0, 1, 2, 3, 128, 384, -128, -384, 1000, 2048, -2048, 32767, -32768} {
+		if got, want := s.value(v), exactScale(v); got != want {
+			t.Errorf("%d: got %d, want %d", v, got, want)
+		}
+	}
+	if got := s.value(128); got != 62 {
+		t.Fatalf("128: got %d, want 62", got)
+	}
+	if got := s.value(-384); got != -188 {
+		t.Fatalf("-384: got %d, want -188", got)
+	}
+}
+
+func TestFont_Rescale(t *testing.T) {
+	orig := loadGoRegular(t)
+	f := loadGoRegular(t)
+	if orig.head.unitsPerEm != 2048 {
+		t.Fatalf("Go Regular has %d units per em", orig.head.unitsPerEm)
+	}
+	if err := f.Rescale(1000); err != nil {
+		t.Fatal(err)
+	}
+	if f.head.unitsPerEm != 1000 {
+		t.Fatalf("unitsPerEm %d", f.head.unitsPerEm)
+	}
+	if !slices.ContainsFunc(f.Warnings(), func(w string) bool {
+		return strings.HasPrefix(w, "dropped hinting cvt, fpgm, prep, the instructions of ")
+	}) {
+		t.Fatalf("warnings %q", f.Warnings())
+	}
+	drift := new(big.Rat)
+	for _, m := range orig.hmtx.hMetrics {
+		drift.Add(drift, big.NewRat(int64(exactScale(int(m.advanceWidth))), 1))
+		drift.Sub(drift, big.NewRat(int64(m.advanceWidth)*1000, 2048))
+	}
+	if want := "the advances drift by +" + drift.FloatString(2) + " units"; !slices.ContainsFunc(f.Warnings(), func(w string) bool {
+		return strings.HasSuffix(w, want+" in total")
+	}) {
+		t.Fatalf("warnings %q, want one ending in %q", f.Warnings(), want)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []Tag{tagCvt, tagFpgm, tagPrep} {
+		if _, ok := tableData(t, buf.Bytes())[tag]; ok {
+			t.Fatalf("rescaled font has %s", tag)
+		}
+	}
+	scaled, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indices, _ := orig.LookupRunes([]rune("AWgij&é"))
+	for _, gid := range indices {
+		advance, _ := orig.GlyphAdvance(gid)
+		if got, _ := scaled.GlyphAdvance(gid); int(got) != exactScale(int(advance)) {
+			t.Errorf("glyph %d: advance %d, want %d of %d", gid, got, exactScale(int(advance)), advance)
+		}
+		x0, y0, x1, y1, _ := orig.GlyphBounds(gid)
+		want := [4]int{exactScale(int(x0)), exactScale(int(y0)), exactScale(int(x1)), exactScale(int(y1))}
+		if x0, y0, x1, y1, _ := scaled.GlyphBounds(gid); [4]int{int(x0), int(y0), int(x1), int(y1)} != want {
+			t.Errorf("glyph %d: bounds %d %d %d %d, want %v", gid, x0, y0, x1, y1, want)
+		}
+	}
+	if got, want := int(scaled.head.yMax), exactScale(int(orig.head.yMax)); got != want {
+		t.Fatalf("head yMax %d, want %d", got, want)
+	}
+	if got, want := int(scaled.hhea.ascender), exactScale(int(orig.hhea.ascender)); got != want {
+		t.Fatalf("hhea ascender %d, want %d", got, want)
+	}
+	if got, want := int(scaled.post.underlinePosition), exactScale(int(orig.post.underlinePosition)); got != want {
+		t.Fatalf("post underlinePosition %d, want %d", got, want)
+	}
+
+	// Every point of every simple glyph is scaled from its absolute coordinates.
+	for gid, gd := range orig.glyf.descs {
+		if !gd.hasOutline() || !gd.IsSimple() {
+			continue
+		}
+		g, err := decodeSimpleGlyph(gd.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := decodeSimpleGlyph(scaled.glyf.descs[gid].raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sg.instructions) != 0 || !slices.Equal(sg.endPts, g.endPts) || !slices.Equal(sg.flags, g.flags) {
+			t.Fatalf("glyph %d: contours or flags differ", gid)
+		}
+		for i := range g.xs {
+			if sg.xs[i] != exactScale(g.xs[i]) || sg.ys[i] != exactScale(g.ys[i]) {
+				t.Fatalf("glyph %d point %d: %d,%d from %d,%d", gid, i, sg.xs[i], sg.ys[i], g.xs[i], g.ys[i])
+			}
+		}
+	}
+}
+
+func TestFont_RescaleKerning(t *testing.T) {
+	f, g := kerningFixture(t)
+	if err := f.Rescale(1000); err != nil {
+		t.Fatal(err)
+	}
+	// The values of each lookup are scaled, so the two of T o are rounded separately.
+	want := map[[2]GlyphIndex]int16{
+		{g['A'], g['V']}: int16(exactScale(-100)),
+		{g['A'], g['Y']}: int16(exactScale(-50)),
+		{g['T'], g['o']}: int16(exactScale(-5) + exactScale(-70)),
+		{g['T'], g['e']}: int16(exactScale(-70)),
+		{g['W'], g['a']}: int16(exactScale(-40)),
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	scaled, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, font := range []*Font{f, scaled} {
+		pairs, err := font.KerningPairs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pairs) != len(want) {
+			t.Fatalf("got pairs %v, want %v", pairs, want)
+		}
+		for pair, adj := range want {
+			if pairs[pair] != adj {
+				t.Errorf("%v: got %d, want %d", pair, pairs[pair], adj)
+			}
+		}
+	}
+}
+
+func TestFont_RescaleUnsupported(t *testing.T) {
+	f, err := Parse(bytes.NewReader(cffFixture(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Rescale(1000); !errors.Is(err, ErrRescaleUnsupported) || !strings.Contains(err.Error(), "CFF") {
+		t.Fatalf("got error %v, want ErrRescaleUnsupported", err)
+	}
+
+	// Scaling up by 1024 overflows, leaving the font as it was.
+	f = loadGoRegular(t)
+	f.head.unitsPerEm = 16
+	glyf, hmtx := f.glyf, f.hmtx
+	if err := f.Rescale(16384); !errors.Is(err, errRangeCheck) {
+		t.Fatalf("got error %v, want errRangeCheck", err)
+	}
+	if f.head.unitsPerEm != 16 || f.glyf != glyf || f.hmtx != hmtx || f.fpgm == nil || len(f.Warnings()) != 0 {
+		t.Fatal("font changed")
+	}
+}
+
+func TestFont_RescaleComposites(t *testing.T) {
+	f, g := compositeFixture(t)
+	if err := f.Rescale(1000); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	// The byte offsets of the accent are scaled, the instructions of 'ë' dropped.
+	eacute := f.glyf.descs[g['é']].raw
+	if got, want := eacute[len(eacute)-2:], []byte{byte(exactScale(20)), byte(int8(exactScale(-10)))}; !bytes.Equal(got, want) {
+		t.Fatalf("é ends in % X, want % X", got, want)
+	}
+	if n, _ := glyphDataLength(f.glyf.descs[g['ë']].raw); n != 10+8 {
+		t.Fatalf("ë has %d bytes, want 18", n)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseRuneRanges parses a comma separated list of runes and rune ranges such as
+// "U+20-U+7E,0x4E00-0x9FFF,A-Z,€" and returns the runes sorted and deduplicated.
+// A rune is written as U+XXXX or 0xXXXX (hexadecimal) or as the literal character; a
+// range is two runes separated by '-', both ends included. Whitespace around items is
+// ignored; a literal ',' or '-' range end must be written in hex.
+func ParseRuneRanges(spec string) ([]rune, error) {
+	var runes []rune
+	for item := range strings.SplitSeq(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		lo, rest, err := parseRangeRune(item)
+		if err != nil {
+			return nil, fmt.Errorf("rune range %q: %w", item, err)
+		}
+		hi := lo
+		if rest != "" {
+			if rest[0] != '-' {
+				return nil, fmt.Errorf("rune range %q: unexpected %q", item, rest)
+			}
+			if hi, rest, err = parseRangeRune(rest[1:]); err != nil {
+				return nil, fmt.Errorf("rune range %q: %w", item, err)
+			}
+			if rest != "" {
+				return nil, fmt.Errorf("rune range %q: unexpected %q", item, rest)
+			}
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("rune range %q: end before start: %w", item, errRangeCheck)
+		}
+		for r := lo; r <= hi; r++ {
+			runes = append(runes, r)
+		}
+	}
+	slices.Sort(runes)
+	return slices.Compact(runes), nil
+}
+
+// parseRangeRune parses the rune at the start of `s` and returns it with the rest of `s`.
+func parseRangeRune(s string) (rune, string, error) {
+	if s == "" {
+		return 0, "", errRequiredField
+	}
+	if len(s) > 2 && (s[:2] == "U+" || s[:2] == "u+" || s[:2] == "0x" || s[:2] == "0X") {
+		end := 2
+		for end < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+			end++
+		}
+		v, err := strconv.ParseUint(s[2:end], 16, 32)
+		if err != nil {
+			return 0, "", err
+		}
+		if v > utf8.MaxRune {
+			return 0, "", errRangeCheck
+		}
+		return rune(v), s[end:], nil
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError && n <= 1 {
+		return 0, "", errTypeCheck
+	}
+	return r, s[n:], nil
+}
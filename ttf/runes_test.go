@@ -0,0 +1,33 @@
+package ttf
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRuneRanges(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []rune
+	}{
+		{"A-C", []rune("ABC")},
+		{"U+30-U+32, 0x41", []rune("012A")},
+		{"z, a-c ,b", []rune("abcz")},
+		{"€,0x2D", []rune("-€")},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseRuneRanges(tt.spec)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.spec, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%q: got %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+	for _, spec := range []string{"C-A", "AB", "U+110000", "A-", "0x41-0x42x"} {
+		if _, err := ParseRuneRanges(spec); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import "math"
+
+// pdfUnitsPerEm is the size of the glyph space of PDF font descriptors and width arrays.
+const pdfUnitsPerEm = 1000
+
+// Scaler converts font units to pixels or points at a given size, so that all consumers
+// round the same way.
+type Scaler struct {
+	UnitsPerEm int     // Font units per em, head.unitsPerEm.
+	PPEM       float64 // Pixels (or points) per em.
+}
+
+// NewScaler returns a Scaler for a font with `unitsPerEm` units per em at `ppem` pixels per em.
+func NewScaler(unitsPerEm int, ppem float64) Scaler {
+	return Scaler{UnitsPerEm: unitsPerEm, PPEM: ppem}
+}
+
+// PDFScaler returns a Scaler normalizing a font with `unitsPerEm` units per em to the 1000
+// units per em of PDF glyph space.
+func PDFScaler(unitsPerEm int) Scaler {
+	return NewScaler(unitsPerEm, pdfUnitsPerEm)
+}
+
+// Scaler returns a Scaler for `f` at `ppem` pixels per em. Without a head table all values
+// scale to 0.
+func (f *Font) Scaler(ppem float64) Scaler {
+	if f.head == nil {
+		return NewScaler(0, ppem)
+	}
+	return NewScaler(int(f.head.unitsPerEm), ppem)
+}
+
+// Scale returns `v` font units in pixels. It returns 0 if UnitsPerEm is not positive.
+func (s Scaler) Scale(v int) float64 {
+	return funitsToPixels(float64(v), s.UnitsPerEm, s.PPEM)
+}
+
+// ScaleRound returns `v` font units in whole pixels, rounded half away from zero.
+func (s Scaler) ScaleRound(v int) int {
+	return int(math.Round(s.Scale(v)))
+}
+
+// ScaleFixed returns `v` font units in pixels as a 16.16 Fixed, rounded to the nearest 1/65536
+// and saturating like FixedFromFloat64.
+func (s Scaler) ScaleFixed(v int) Fixed {
+	return FixedFromFloat64(s.Scale(v))
+}
@@ -0,0 +1,52 @@
+package ttf
+
+import "testing"
+
+func TestScaler(t *testing.T) {
+	tests := []struct {
+		upem  int
+		ppem  float64
+		v     int
+		scale float64
+		round int
+		fixed Fixed
+	}{
+		{1000, 16, 500, 8, 8, 0x80000},
+		{1000, 16, -150, -2.4, -2, -0x26666},
+		{1000, 10, 50, 0.5, 1, 0x8000},
+		{1000, 10, -50, -0.5, -1, -0x8000},
+		{1024, 12, 1536, 18, 18, 0x120000},
+		{1024, 1, 1536, 1.5, 2, 0x18000},
+		{1024, 1, -1536, -1.5, -2, -0x18000},
+		{1024, 13, 100, 1.26953125, 1, 0x14500},
+		{2048, 16, 1229, 9.6015625, 10, 0x99A00},
+		{2048, 16, -434, -3.390625, -3, -0x36400},
+		{2048, 1, 1, 1.0 / 2048, 0, 0x20},
+		{0, 16, 1000, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		s := NewScaler(tt.upem, tt.ppem)
+		if got := s.Scale(tt.v); got != tt.scale {
+			t.Fatalf("%+v: Scale = %v", tt, got)
+		}
+		if got := s.ScaleRound(tt.v); got != tt.round {
+			t.Fatalf("%+v: ScaleRound = %v", tt, got)
+		}
+		if got := s.ScaleFixed(tt.v); got != tt.fixed {
+			t.Fatalf("%+v: ScaleFixed = %X", tt, got)
+		}
+		if got := FWord(tt.v).Pixels(tt.upem, tt.ppem); got != tt.scale {
+			t.Fatalf("%+v: FWord.Pixels = %v", tt, got)
+		}
+	}
+
+	for upem, want := range map[int]int{1000: 600, 1024: 586, 2048: 293} {
+		if got := PDFScaler(upem).ScaleRound(600); got != want {
+			t.Fatalf("PDF width of 600 units at %d upem = %d, want %d", upem, got, want)
+		}
+	}
+	f := loadGoRegular(t)
+	if s := f.Scaler(12); s.UnitsPerEm != int(f.head.unitsPerEm) || s.PPEM != 12 {
+		t.Fatalf("Font.Scaler = %+v", s)
+	}
+}
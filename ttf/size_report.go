@@ -0,0 +1,114 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// SizeReport compares the file layout of a font with that of a font made from it, such as a
+// subset, to show what the tables and glyphs cost.
+type SizeReport struct {
+	Tables []TableSize // Every table of either font, by tag.
+
+	SourceGlyphs int // Number of glyphs of the source font.
+	ResultGlyphs int // Number of glyphs of the resulting font.
+	SourceSize   int // File size of the source font in bytes.
+	ResultSize   int // File size of the resulting font in bytes.
+}
+
+// TableSize is the length of a table in the source and the resulting font of a SizeReport,
+// 0 where the font does not have it.
+type TableSize struct {
+	Tag    Tag
+	Source int
+	Result int
+}
+
+// Reduction returns the bytes saved by the resulting font.
+func (t TableSize) Reduction() int {
+	return t.Source - t.Result
+}
+
+// Reduction returns the fraction of the source file size saved by the resulting font.
+func (rep *SizeReport) Reduction() float64 {
+	if rep.SourceSize == 0 {
+		return 0
+	}
+	return 1 - float64(rep.ResultSize)/float64(rep.SourceSize)
+}
+
+// String returns `rep` as a table with a row for each font table and a total row.
+func (rep *SizeReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "table\tsource\tresult\tsaved\t\n")
+	for _, t := range rep.Tables {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", t.Tag, t.Source, t.Result, t.Reduction())
+	}
+	fmt.Fprintf(w, "glyphs\t%d\t%d\t%d\t\n", rep.SourceGlyphs, rep.ResultGlyphs, rep.SourceGlyphs-rep.ResultGlyphs)
+	fmt.Fprintf(w, "total\t%d\t%d\t%.1f%%\t\n", rep.SourceSize, rep.ResultSize, 100*rep.Reduction())
+	w.Flush()
+	return b.String()
+}
+
+// SizeReport compares the table sizes and glyph counts of `f` and `result`, typically a subset
+// of `f`. A font parsed from a file is measured as laid out in the file, other fonts as Write
+// lays them out.
+func (f *Font) SizeReport(result *Font) (*SizeReport, error) {
+	srcTables, srcSize, err := f.tableSizes()
+	if err != nil {
+		return nil, err
+	}
+	resTables, resSize, err := result.tableSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &SizeReport{SourceSize: srcSize, ResultSize: resSize}
+	if f.maxp != nil {
+		rep.SourceGlyphs = int(f.maxp.numGlyphs)
+	}
+	if result.maxp != nil {
+		rep.ResultGlyphs = int(result.maxp.numGlyphs)
+	}
+	tags := slices.Concat(slices.Collect(maps.Keys(srcTables)), slices.Collect(maps.Keys(resTables)))
+	slices.SortFunc(tags, func(a, b Tag) int { return bytes.Compare(a[:], b[:]) })
+	for _, tag := range slices.Compact(tags) {
+		rep.Tables = append(rep.Tables, TableSize{Tag: tag, Source: srcTables[tag], Result: resTables[tag]})
+	}
+	return rep, nil
+}
+
+// tableSizes returns the length of each table of `f` and the file size, from the file `f` was
+// parsed from or else from the output of Write.
+func (f *Font) tableSizes() (map[Tag]int, int, error) {
+	r, trec := f.br, f.trec
+	var size int64
+	if r == nil {
+		data, written, err := f.writtenTableRecords()
+		if err != nil {
+			return nil, 0, err
+		}
+		trec, size = written, int64(len(data))
+	} else {
+		var err error
+		if size, err = r.fork().rs.Seek(0, io.SeekEnd); err != nil {
+			return nil, 0, err
+		}
+	}
+	sizes := make(map[Tag]int, len(trec.list))
+	for _, tr := range trec.list {
+		sizes[tr.tableTag] = int(tr.length)
+	}
+	return sizes, int(size), nil
+}
@@ -0,0 +1,50 @@
+package ttf
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFont_SizeReport(t *testing.T) {
+	f := loadGoRegular(t)
+	sub, err := f.Subset([]rune("Hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := f.SizeReport(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.SourceSize != len(goregular.TTF) || rep.SourceGlyphs != int(f.maxp.numGlyphs) || rep.ResultGlyphs != int(sub.maxp.numGlyphs) {
+		t.Fatalf("source %d bytes, %d glyphs, result %d glyphs", rep.SourceSize, rep.SourceGlyphs, rep.ResultGlyphs)
+	}
+	if len(rep.Tables) != len(f.trec.list) {
+		t.Fatalf("%d tables, want %d", len(rep.Tables), len(f.trec.list))
+	}
+	largest := rep.Tables[0]
+	sum := 0
+	for _, ts := range rep.Tables {
+		if ts.Reduction() > largest.Reduction() {
+			largest = ts
+		}
+		if ts.Source != int(f.trec.trMap[ts.Tag].length) {
+			t.Fatalf("%s: source %d bytes", ts.Tag, ts.Source)
+		}
+		sum += ts.Result
+	}
+	if largest.Tag != tagGlyf || rep.Reduction() < 0.8 {
+		t.Fatalf("largest reduction in %s, total %.2f", largest.Tag, rep.Reduction())
+	}
+	if sum >= rep.ResultSize || rep.ResultSize > sum+12+16*len(rep.Tables)+3*len(rep.Tables) {
+		t.Fatalf("result tables of %d bytes in a file of %d", sum, rep.ResultSize)
+	}
+
+	s := rep.String()
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) != len(rep.Tables)+3 || !strings.Contains(lines[len(lines)-1], "total") ||
+		!strings.Contains(s, "glyf") {
+		t.Fatalf("report:\n%s", s)
+	}
+}
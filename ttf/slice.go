@@ -0,0 +1,66 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"fmt"
+	"slices"
+)
+
+// SliceInfo describes a subset made by Font.Slice, e.g. for an @font-face rule with the
+// unicode-range of the runes it maps.
+type SliceInfo struct {
+	Bucket int     // Index of the range in the ranges passed to Slice.
+	Range  [2]rune // The range, first and last rune.
+	Runes  []rune  // Runes of the range that the subset maps, in ascending order.
+}
+
+// Slice subsets `f` once for each of `ranges`, first and last rune, to the runes in the range
+// that `f` maps, as web fonts of large character sets are served as slices that browsers fetch
+// by the unicode-range of their @font-face rules. Ranges without any runes of `f` are skipped,
+// so the manifest tells the range of each subset. Glyphs that the runes of several slices
+// need are in each of them.
+//
+// The subsets are made with SubsetWithOptions with `opts`, or DefaultSubsetOptions if nil,
+// except for IncludeSpace: the space is in the slice of the range that contains it, as a
+// browser looks it up there. With IncludeMandatoryGlyphs every subset maps U+0000 and U+000D.
+func (f *Font) Slice(ranges [][2]rune, opts *SubsetOptions) ([]*Font, []SliceInfo, error) {
+	o := DefaultSubsetOptions()
+	if opts != nil {
+		o = *opts
+	}
+	o.IncludeSpace = false
+	for i, r := range ranges {
+		if r[0] > r[1] {
+			return nil, nil, fmt.Errorf("range %d from %U to %U: %w", i, r[0], r[1], errInvalidOptions)
+		}
+	}
+	if !f.HasCmap() {
+		return nil, nil, ErrNoCmap
+	}
+
+	runes := slices.Sorted(f.coveredRunes())
+	var subsets []*Font
+	var manifest []SliceInfo
+	for i, r := range ranges {
+		start, _ := slices.BinarySearch(runes, r[0])
+		end, found := slices.BinarySearch(runes, r[1])
+		if found {
+			end++
+		}
+		if start == end {
+			continue
+		}
+		bucket := slices.Clone(runes[start:end])
+		sub, err := f.SubsetWithOptions(slices.Clone(bucket), o)
+		if err != nil {
+			return nil, nil, fmt.Errorf("range %d from %U to %U: %w", i, r[0], r[1], err)
+		}
+		subsets = append(subsets, sub)
+		manifest = append(manifest, SliceInfo{Bucket: i, Range: r, Runes: bucket})
+	}
+	return subsets, manifest, nil
+}
@@ -0,0 +1,75 @@
+package ttf
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestFont_Slice(t *testing.T) {
+	// Go Regular with 3000 ideographs, standing in for a CJK font.
+	f := loadGoRegular(t)
+	mapping := make(map[rune]GlyphIndex)
+	for i := range 3000 {
+		mapping[0x4F00+rune(i)] = GlyphIndex(1 + i%700)
+	}
+	if err := f.RemapCmap(mapping, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ranges := [][2]rune{{0x0000, 0x00FF}, {0x4E00, 0x4FFF}, {0xAC00, 0xD7AF}, {0x5000, 0x9FFF}}
+	subsets, manifest, err := f.Slice(ranges, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Hangul range is empty.
+	if len(subsets) != 3 || len(manifest) != 3 {
+		t.Fatalf("got %d subsets and %d manifest entries, want 3", len(subsets), len(manifest))
+	}
+	var all []rune
+	for i, info := range manifest {
+		if want := []int{0, 1, 3}[i]; info.Bucket != want || info.Range != ranges[want] {
+			t.Fatalf("slice %d: bucket %d %U, want %d", i, info.Bucket, info.Range, want)
+		}
+		if !slices.IsSorted(info.Runes) || info.Runes[0] < info.Range[0] || info.Runes[len(info.Runes)-1] > info.Range[1] {
+			t.Fatalf("slice %d: runes %U outside %U", i, info.Runes, info.Range)
+		}
+		all = append(all, info.Runes...)
+
+		// The cmap of each slice maps the runes of the manifest and those of the mandatory
+		// glyphs, U+0000 and U+000D.
+		var buf bytes.Buffer
+		if err := subsets[i].Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateBytes(buf.Bytes()); err != nil {
+			t.Fatalf("slice %d: %v", i, err)
+		}
+		sub, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slices.Sorted(sub.coveredRunes())
+		want := slices.Compact(slices.Sorted(slices.Values(append([]rune{0, '\r'}, info.Runes...))))
+		if !slices.Equal(got, want) {
+			t.Fatalf("slice %d: maps %d runes, want %d", i, len(got), len(want))
+		}
+	}
+	if n := len(manifest[1].Runes) + len(manifest[2].Runes); n != 3000 {
+		t.Fatalf("%d ideographs in the slices, want 3000", n)
+	}
+	// Disjoint, and together all runes of the font in the ranges.
+	slices.Sort(all)
+	if len(slices.Compact(slices.Clone(all))) != len(all) {
+		t.Fatal("slices overlap")
+	}
+	if want := slices.Sorted(f.coveredRunes()); !slices.Equal(all, slices.DeleteFunc(want, func(r rune) bool {
+		return r > 0xFF && r < 0x4E00 || r > 0x9FFF
+	})) {
+		t.Fatal("slices do not cover the font")
+	}
+
+	if _, _, err := f.Slice([][2]rune{{0x100, 0xFF}}, nil); err == nil {
+		t.Fatal("inverted range accepted")
+	}
+}
@@ -0,0 +1,817 @@
+package ttf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestFont_SubsetMandatoryGlyphs(t *testing.T) {
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	if cmap[0] != 1 || cmap['\r'] != 2 {
+		t.Fatalf("Go Regular maps U+0000 to %d and U+000D to %d", cmap[0], cmap['\r'])
+	}
+	subset := func(runes string, opts SubsetOptions) *Font {
+		t.Helper()
+		sub, err := f.SubsetWithOptions([]rune(runes), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, _ := writeAndParse(t, sub)
+		return g
+	}
+
+	g := subset("A", SubsetOptions{IncludeMandatoryGlyphs: true})
+	want := []GlyphIndex{0, 1, 2, cmap['A']}
+	if int(g.maxp.numGlyphs) != len(want) {
+		t.Fatalf("%d glyphs, want %d", g.maxp.numGlyphs, len(want))
+	}
+	for i, gid := range want {
+		if !bytes.Equal(g.glyf.descs[i].raw, f.glyf.descs[gid].raw) {
+			t.Fatalf("glyph %d is not glyph %d of the font", i, gid)
+		}
+		if g.hmtx.hMetrics[min(i, len(g.hmtx.hMetrics)-1)] != f.hmtx.hMetrics[gid] {
+			t.Fatalf("glyph %d: metrics differ from glyph %d of the font", i, gid)
+		}
+	}
+	if got := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP); !maps.Equal(got, map[rune]GlyphIndex{0: 1, '\r': 2, 'A': 3}) {
+		t.Fatalf("cmap %v", got)
+	}
+
+	// Runes of the mandatory glyphs do not duplicate them.
+	g = subset("\rA\x00", SubsetOptions{IncludeMandatoryGlyphs: true})
+	if g.maxp.numGlyphs != 4 {
+		t.Fatalf("%d glyphs", g.maxp.numGlyphs)
+	}
+
+	g = subset("A", SubsetOptions{})
+	if got := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP); g.maxp.numGlyphs != 2 || !maps.Equal(got, map[rune]GlyphIndex{'A': 1}) {
+		t.Fatalf("without mandatory glyphs: %d glyphs, cmap %v", g.maxp.numGlyphs, got)
+	}
+}
+
+func TestFont_SubsetSpace(t *testing.T) {
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	space := cmap[' ']
+	if len(f.glyf.descs[space].raw) != 0 {
+		t.Fatal("the space glyph of Go Regular has an outline")
+	}
+	advance := func(f *Font, gid GlyphIndex) uint16 {
+		return f.hmtx.hMetrics[min(int(gid), len(f.hmtx.hMetrics)-1)].advanceWidth
+	}
+
+	for _, opts := range []SubsetOptions{DefaultSubsetOptions(), {IncludeSpace: true, RetainGIDs: true}} {
+		sub, err := f.SubsetWithOptions([]rune("中文字体Go"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, _ := writeAndParse(t, sub)
+		subCmap := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+		for _, r := range []rune{' ', '\u00A0'} {
+			gid, ok := subCmap[r]
+			if !ok {
+				t.Fatalf("%+v: %U missing", opts, r)
+			}
+			if got, want := advance(g, gid), advance(f, cmap[r]); got != want {
+				t.Fatalf("%+v: %U advance %d, want %d", opts, r, got, want)
+			}
+			if len(g.glyf.descs[gid].raw) != len(f.glyf.descs[cmap[r]].raw) {
+				t.Fatalf("%+v: %U outline changed", opts, r)
+			}
+		}
+	}
+
+	sub, err := f.SubsetWithOptions([]rune("Go"), SubsetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sub.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)[' ']; ok {
+		t.Fatal("space added without IncludeSpace")
+	}
+}
+
+func TestFont_SubsetLayout(t *testing.T) {
+	if loadGoRegular(t).HasLayoutTables() {
+		t.Fatal("Go Regular has layout tables")
+	}
+	f, layout := layoutFixture(t)
+	if !f.HasLayoutTables() {
+		t.Fatal("no layout tables in the fixture")
+	}
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	write := func(f *Font) *Font {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateBytes(buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	sub, err := f.Subset([]rune("AB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dropped layout tables GDEF, GSUB (features ccmp, liga)"
+	if w := sub.Warnings(); len(w) != 1 || w[0] != want {
+		t.Fatalf("warnings %q, want %q", w, want)
+	}
+	if g := write(sub); g.HasLayoutTables() || g.trec.HasTag(tagGSUB) {
+		t.Fatal("layout tables written")
+	}
+
+	_, err = f.SubsetWithOptions([]rune("AB"), SubsetOptions{Layout: LayoutPassthrough})
+	if !errors.Is(err, errInvalidOptions) {
+		t.Fatalf("passthrough without RetainGIDs: got %v", err)
+	}
+
+	sub, err = f.SubsetWithOptions([]rune("AB"), SubsetOptions{Layout: LayoutPassthrough, RetainGIDs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Warnings()) != 0 || !sub.HasLayoutTables() {
+		t.Fatalf("warnings %q", sub.Warnings())
+	}
+	g := write(sub)
+	tables, err := g.layoutTables()
+	if err != nil || len(tables) != len(layout) {
+		t.Fatalf("%d layout tables written, %v", len(tables), err)
+	}
+	for _, table := range tables {
+		if !bytes.Equal(table.data, layout[table.tag]) {
+			t.Fatalf("%s not passed through", table.tag)
+		}
+	}
+	if g.maxp.numGlyphs != f.maxp.numGlyphs {
+		t.Fatalf("%d glyphs, want %d", g.maxp.numGlyphs, f.maxp.numGlyphs)
+	}
+	for r, kept := range map[rune]bool{'A': true, 'B': true, 'Z': false} {
+		gid := cmap[r]
+		raw := g.glyf.descs[gid].raw
+		if kept != (len(raw) > 0) || kept && !bytes.Equal(raw, f.glyf.descs[gid].raw) {
+			t.Fatalf("%q: glyph %d has %d bytes", r, gid, len(raw))
+		}
+		if got, ok := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)[r]; ok != kept || kept && got != gid {
+			t.Fatalf("%q: cmap has %d, %t", r, got, ok)
+		}
+	}
+
+	// Layout tables that cannot be read fail subsetting, whether they are kept or dropped.
+	gsub := f.trec.trMap[tagGSUB]
+	f.br = newBytesReader(f.br.data[:int(gsub.offset)+int(gsub.length)/2])
+	for _, opts := range []SubsetOptions{{}, {Layout: LayoutPassthrough, RetainGIDs: true}} {
+		if _, err := f.SubsetWithOptions([]rune("AB"), opts); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("%+v: truncated GSUB gives %v", opts, err)
+		}
+	}
+}
+
+func TestFont_SubsetDeterministic(t *testing.T) {
+	f, _ := layoutFixture(t)
+	runes := []rune("The quick brown fox jumps over the lazy dog. 0123456789 ÀÉÎÕÜ ß")
+	for _, opts := range []SubsetOptions{
+		DefaultSubsetOptions(),
+		{RetainGIDs: true, Layout: LayoutPassthrough},
+		{DropCmap: true},
+	} {
+		var want [sha256.Size]byte
+		for i := range 20 {
+			shuffled := slices.Clone(runes)
+			rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+			sub, err := f.SubsetWithOptions(shuffled, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := sub.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if sum := sha256.Sum256(buf.Bytes()); i == 0 {
+				want = sum
+			} else if sum != want {
+				t.Fatalf("%+v: run %d gives different output", opts, i)
+			}
+		}
+	}
+
+	// Of two subtables for the same encoding, GetCmap returns the first.
+	first := f.cmap.subtables["4,3,1"]
+	second := newUnicodeCmapSubtable(12, 3, 1, map[rune]GlyphIndex{'A': 1})
+	f.cmap.subtables["12,3,1"] = second
+	f.cmap.subtableKeys = append(f.cmap.subtableKeys, "12,3,1")
+	for range 20 {
+		if got := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP); !maps.Equal(got, first.cmap) {
+			t.Fatal("GetCmap did not return the first subtable")
+		}
+	}
+}
+
+func TestFont_SubsetEmptyGlyphs(t *testing.T) {
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	// 'x' gets a glyph without contours but with a box far outside all others, plus
+	// instructionLength, and 'y' the bare header; '~' is emptied.
+	header := func(withInstructionLength bool) []byte {
+		b := binary.BigEndian.AppendUint16(nil, 0)
+		for _, v := range []int16{-5000, -5000, 5000, 5000} {
+			b = binary.BigEndian.AppendUint16(b, uint16(v))
+		}
+		if withInstructionLength {
+			b = append(b, 0, 0)
+		}
+		return b
+	}
+	setGlyphData(f, cmap['x'], header(true))
+	setGlyphData(f, cmap['y'], header(false))
+	setGlyphData(f, cmap['~'], nil)
+
+	for _, tt := range []struct {
+		runes string
+		opts  SubsetOptions
+	}{
+		// The last glyph of the subsets is '~', or one left out.
+		{"A xy~", SubsetOptions{}},
+		{"A xy~", DefaultSubsetOptions()},
+		{"A xy", SubsetOptions{RetainGIDs: true}},
+	} {
+		sub, err := f.SubsetWithOptions([]rune(tt.runes), tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+			t.Fatalf("%q %+v: %v %v", tt.runes, tt.opts, err, rep.Errors())
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		numGlyphs := int(g.maxp.numGlyphs)
+		end, n, err := g.GetGlyphDataOffset(GlyphIndex(numGlyphs - 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 || len(g.glyf.descs[numGlyphs-1].raw) != 0 {
+			t.Fatalf("%q %+v: last glyph has %d bytes", tt.runes, tt.opts, n)
+		}
+		if glyf := g.trec.trMap[tagGlyf]; end != int64(glyf.length) {
+			t.Fatalf("%q %+v: last loca entry %d, glyf length %d", tt.runes, tt.opts, end, glyf.length)
+		}
+
+		subCmap := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+		if tt.opts.RetainGIDs {
+			subCmap = cmap
+		}
+		for _, r := range "A xy~" {
+			gid, ok := subCmap[r]
+			if !ok {
+				continue
+			}
+			want := f.glyf.descs[cmap[r]]
+			if tt.opts.AlignGlyphs {
+				want = alignGlyph(want, 4)
+			}
+			if !bytes.Equal(g.glyf.descs[gid].raw, want.raw) {
+				t.Fatalf("%q %+v: %q glyph changed", tt.runes, tt.opts, r)
+			}
+			got := g.hmtx.hMetrics[min(int(gid), len(g.hmtx.hMetrics)-1)]
+			if want := f.hmtx.hMetrics[cmap[r]]; got.advanceWidth != want.advanceWidth {
+				t.Fatalf("%q %+v: %q advance %d, want %d", tt.runes, tt.opts, r, got.advanceWidth, want.advanceWidth)
+			}
+		}
+
+		// The head box is that of 'A' and .notdef, the boxes of glyphs without contours do
+		// not count.
+		xMin, yMin, xMax, yMax := glyphBounds([]*glyphDescription{f.glyf.descs[0], f.glyf.descs[cmap['A']]})
+		if got := [4]int16{g.head.xMin, g.head.yMin, g.head.xMax, g.head.yMax}; got != [4]int16{xMin, yMin, xMax, yMax} {
+			t.Fatalf("%q %+v: head box %v, want %v", tt.runes, tt.opts, got, [4]int16{xMin, yMin, xMax, yMax})
+		}
+	}
+
+	// Glyph data of odd length is padded for short loca offsets, without touching the font.
+	odd := &glyphDescription{raw: []byte{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 9}}
+	if gd := evenGlyph(odd); len(gd.raw) != 14 || len(odd.raw) != 13 {
+		t.Fatalf("padded to %d bytes, source now %d", len(gd.raw), len(odd.raw))
+	}
+	if loca, format := buildLoca([]*glyphDescription{odd, {}}, true); format != 1 || loca.offsetsLong[2] != 13 {
+		t.Fatalf("odd length: format %d, loca %v", format, loca.offsetsLong)
+	}
+}
+
+// TestFont_SubsetRetainGIDs checks that a subset with RetainGIDs, as written, has the glyphs of
+// the font at their indices, the others empty, and the advances of the font.
+func TestFont_SubsetRetainGIDs(t *testing.T) {
+	f := loadGoRegular(t)
+	sub, newGID, err := f.SubsetWithMap([]rune("Hi!"), SubsetOptions{RetainGIDs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.maxp.numGlyphs != f.maxp.numGlyphs {
+		t.Fatalf("%d glyphs, want %d", g.maxp.numGlyphs, f.maxp.numGlyphs)
+	}
+
+	advances, want := g.Advances(), f.Advances()
+	for gid := range GlyphIndex(f.maxp.numGlyphs) {
+		start, n, err := g.GetGlyphDataOffset(gid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, kept := newGID[gid]; !kept {
+			if n != 0 {
+				t.Fatalf("glyph %d not in the subset has %d bytes at %d", gid, n, start)
+			}
+		} else if data, _ := f.GlyphData(gid); !bytes.Equal(g.glyf.descs[gid].raw, data) {
+			t.Fatalf("glyph %d changed", gid)
+		}
+		if advances[gid] != want[gid] {
+			t.Fatalf("glyph %d: advance %d, want %d", gid, advances[gid], want[gid])
+		}
+	}
+	if len(newGID) < 4 || len(newGID) == int(f.maxp.numGlyphs) {
+		t.Fatalf("%d glyphs kept of %d", len(newGID), f.maxp.numGlyphs)
+	}
+}
+
+// TestFont_SubsetLocaPreamble subsets a font whose glyf data starts with a preamble, so that
+// its first loca offset is not 0. The subset glyf has no preamble and its loca starts at 0.
+func TestFont_SubsetLocaPreamble(t *testing.T) {
+	const preamble = 4
+	f := loadGoRegular(t)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyf, loca := g.trec.trMap[tagGlyf], g.trec.trMap[tagLoca]
+	if g.head.indexToLocFormat != 0 {
+		t.Fatal("long loca")
+	}
+
+	// Insert the preamble at the start of glyf, moving the tables after it and the glyphs.
+	src := buf.Bytes()
+	data := slices.Concat(src[:glyf.offset], make([]byte, preamble), src[glyf.offset:])
+	for i, tr := range g.trec.list {
+		rec := data[12+16*i:]
+		switch {
+		case tr.tableTag == tagGlyf:
+			binary.BigEndian.PutUint32(rec[12:], tr.length+preamble)
+		case tr.offset > glyf.offset:
+			binary.BigEndian.PutUint32(rec[8:], uint32(tr.offset)+preamble)
+		}
+	}
+	for at := loca.offset; at < loca.offset+offset32(loca.length); at += 2 {
+		binary.BigEndian.PutUint16(data[at:], binary.BigEndian.Uint16(data[at:])+preamble/2)
+	}
+	fixChecksums(t, data)
+	h, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.loca.offsetsShort[0] != preamble/2 {
+		t.Fatalf("loca[0] = %d", h.loca.offsetsShort[0])
+	}
+	if rep, err := ValidateBytesReport(data, ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("doctored font: %v %v", err, rep.Errors())
+	}
+
+	runes := []rune("Ag")
+	gids, _ := h.LookupRunes(runes)
+	sub, err := h.SubsetWithOptions(runes, SubsetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.loca.offsetsShort[0] != 0 {
+		t.Fatalf("subset loca[0] = %d", sub.loca.offsetsShort[0])
+	}
+	buf.Reset()
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("subset: %v %v", err, rep.Errors())
+	}
+	s, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, gid := range gids {
+		if !bytes.Equal(s.glyf.descs[i+1].raw, f.glyf.descs[gid].raw) {
+			t.Fatalf("%q: glyph data differs", runes[i])
+		}
+	}
+}
+
+func TestFont_SubsetAlignGlyphs(t *testing.T) {
+	f := loadGoRegular(t)
+	runes := []rune("Hello, World!")
+	// Go Regular pads its glyphs to 4 bytes, strip that down to the 2 short loca needs.
+	gids, _ := f.LookupRunes(slices.Clone(runes))
+	trimmed := 0
+	for _, gid := range gids {
+		raw := f.glyf.descs[gid].raw
+		if len(raw) == 0 {
+			continue
+		}
+		n, err := glyphDataLength(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n%4 != 0 {
+			setGlyphData(f, gid, raw[:n+n%2])
+			trimmed++
+		}
+	}
+	if trimmed == 0 {
+		t.Fatal("no glyph to trim")
+	}
+	glyfLen := map[bool]uint32{}
+	for _, align := range []bool{false, true} {
+		sub, err := f.SubsetWithOptions(runes, SubsetOptions{AlignGlyphs: align})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+			t.Fatalf("AlignGlyphs %t: %v %v", align, err, rep.Errors())
+		}
+		g, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		glyf := g.trec.trMap[tagGlyf]
+		unaligned := 0
+		for gid := range GlyphIndex(g.maxp.numGlyphs) {
+			offset, n, err := g.GetGlyphDataOffset(gid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if offset%4 != 0 {
+				unaligned++
+			}
+			if gid == GlyphIndex(g.maxp.numGlyphs-1) && offset+n != int64(glyf.length) {
+				t.Fatalf("AlignGlyphs %t: last loca entry %d, glyf length %d", align, offset+n, glyf.length)
+			}
+		}
+		if align != (unaligned == 0) {
+			t.Fatalf("AlignGlyphs %t: %d glyphs not on a 4-byte boundary", align, unaligned)
+		}
+		glyfLen[align] = glyf.length
+	}
+	if glyfLen[false] >= glyfLen[true] {
+		t.Fatalf("glyf of %d bytes packed, %d aligned", glyfLen[false], glyfLen[true])
+	}
+}
+
+// TestFont_SubsetShortHmtx subsets a font whose hMetrics only cover .notdef, so that the
+// left side bearings of all other glyphs are in leftSideBearings.
+func TestFont_SubsetShortHmtx(t *testing.T) {
+	f := loadGoRegular(t)
+	want := make([]longHorMetric, f.maxp.numGlyphs)
+	for gid := range want {
+		want[gid], _ = f.glyphMetric(GlyphIndex(gid))
+		want[gid].advanceWidth = f.hmtx.hMetrics[0].advanceWidth
+	}
+	f.hmtx.leftSideBearings = make([]int16, len(want)-1)
+	for gid := 1; gid < len(want); gid++ {
+		f.hmtx.leftSideBearings[gid-1] = want[gid].lsb
+	}
+	f.hmtx.hMetrics = f.hmtx.hMetrics[:1]
+	f.hhea.numberOfHMetrics = 1
+	f.markDirty(tagHmtx, tagHhea)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.hmtx.hMetrics) != 1 {
+		t.Fatalf("%d hMetrics", len(g.hmtx.hMetrics))
+	}
+
+	runes := []rune("AVgj")
+	gids, _ := g.LookupRunes(runes)
+	sub, err := g.SubsetWithOptions(runes, SubsetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lsbs := map[int16]bool{}
+	for i, gid := range gids {
+		got, _ := s.glyphMetric(GlyphIndex(i + 1))
+		if got != want[gid] {
+			t.Fatalf("%q: metric %+v, want %+v", runes[i], got, want[gid])
+		}
+		lsbs[got.lsb] = true
+	}
+	if len(lsbs) < 2 {
+		t.Fatalf("left side bearings %v all alike", lsbs)
+	}
+}
+
+func TestFont_SubsetOptimizeHmtx(t *testing.T) {
+	f := loadGoRegular(t)
+	// The digits share an advance width, so all but the first need no full metric.
+	runes := []rune("0123456789")
+	var subs []*Font
+	for _, optimize := range []bool{false, true} {
+		sub, err := f.SubsetWithOptions(runes, SubsetOptions{OptimizeHmtx: optimize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		s, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 11
+		if optimize {
+			want = 2
+		}
+		if int(s.hhea.numberOfHMetrics) != want || len(s.hmtx.hMetrics) != want {
+			t.Fatalf("OptimizeHmtx %t: numberOfHMetrics %d, %d hMetrics, want %d", optimize, s.hhea.numberOfHMetrics, len(s.hmtx.hMetrics), want)
+		}
+		subs = append(subs, s)
+	}
+	gids, _ := f.LookupRunes(runes)
+	for gid := range GlyphIndex(subs[0].maxp.numGlyphs) {
+		full, _ := subs[0].glyphMetric(gid)
+		short, _ := subs[1].glyphMetric(gid)
+		if full != short {
+			t.Fatalf("glyph %d: %+v and %+v", gid, full, short)
+		}
+		if gid == 0 {
+			continue
+		}
+		if want, _ := f.glyphMetric(gids[gid-1]); full != want {
+			t.Fatalf("glyph %d: %+v, want %+v", gid, full, want)
+		}
+		if adv, ok := subs[1].GlyphAdvance(gid); !ok || adv != full.advanceWidth {
+			t.Fatalf("glyph %d: advance %d %t", gid, adv, ok)
+		}
+	}
+}
+
+func TestFont_SubsetWithMap(t *testing.T) {
+	f := loadGoRegular(t)
+	outline := func(f *Font, gid GlyphIndex) []byte {
+		t.Helper()
+		data, err := f.GlyphData(gid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) == 0 {
+			return data
+		}
+		// Without the padding of the subset.
+		n, err := glyphDataLength(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data[:n]
+	}
+
+	retain := DefaultSubsetOptions()
+	retain.RetainGIDs = true
+	for _, opts := range []SubsetOptions{DefaultSubsetOptions(), retain} {
+		runes := []rune("Hello, wörld")
+		sub, newGID, err := f.SubsetWithMap(runes, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gid, ok := newGID[0]; !ok || gid != 0 {
+			t.Fatalf("RetainGIDs %t: .notdef maps to %d, %t", opts.RetainGIDs, gid, ok)
+		}
+		numGlyphs := int(sub.maxp.numGlyphs)
+		seen := map[GlyphIndex]bool{}
+		for old, gid := range newGID {
+			if int(gid) >= numGlyphs || seen[gid] || opts.RetainGIDs && gid != old {
+				t.Fatalf("RetainGIDs %t: glyph %d maps to %d of %d glyphs", opts.RetainGIDs, old, gid, numGlyphs)
+			}
+			seen[gid] = true
+		}
+		if !opts.RetainGIDs && len(newGID) != numGlyphs {
+			t.Fatalf("map of %d glyphs for %d in the subset", len(newGID), numGlyphs)
+		}
+
+		var buf bytes.Buffer
+		if err := sub.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		written, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range runes {
+			old, _ := f.LookupRunes([]rune{r})
+			gid, ok := newGID[old[0]]
+			if !ok {
+				t.Fatalf("%q: glyph %d not in the map", r, old[0])
+			}
+			if now, _ := written.LookupRunes([]rune{r}); len(now) != 1 || now[0] != gid {
+				t.Fatalf("%q: subset cmap gives %v, the map %d", r, now, gid)
+			}
+			if !bytes.Equal(outline(written, gid), outline(f, old[0])) {
+				t.Fatalf("%q: glyph %d of the subset is not glyph %d", r, gid, old[0])
+			}
+		}
+	}
+
+	if _, _, err := f.SubsetWithMap([]rune("A"), SubsetOptions{Layout: LayoutPassthrough}); !errors.Is(err, errInvalidOptions) {
+		t.Fatalf("got error %v, want errInvalidOptions", err)
+	}
+}
+
+func TestFont_SubsetGIDs(t *testing.T) {
+	f := loadGoRegular(t)
+	byRune := f.LookupRunesMap([]rune("AB"))
+	// A glyph without a rune, such as a ligature or an alternate that shaping gives.
+	mapped := map[GlyphIndex]bool{}
+	for _, cmap := range f.lookupCmaps() {
+		for _, gid := range cmap {
+			mapped[gid] = true
+		}
+	}
+	unmapped := GlyphIndex(3)
+	for mapped[unmapped] || !f.glyf.descs[unmapped].hasOutline() {
+		unmapped++
+	}
+
+	gids := []GlyphIndex{byRune['B'], unmapped, byRune['A'], byRune['B']}
+	input := slices.Clone(gids)
+	sub, newGID, err := f.SubsetGIDs(gids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(gids, input) {
+		t.Fatalf("SubsetGIDs changed its input to %v", gids)
+	}
+	var buf bytes.Buffer
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	written, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, gid := range input {
+		ngid, ok := newGID[gid]
+		if !ok {
+			t.Fatalf("glyph %d not kept", gid)
+		}
+		want, _ := f.GlyphData(gid)
+		if got, _ := written.GlyphData(ngid); !bytes.Equal(got[:len(want)], want) {
+			t.Fatalf("glyph %d of the subset is not glyph %d", ngid, gid)
+		}
+	}
+	// The cmap maps the runes of the glyphs kept, and those only: others at most to .notdef, as
+	// the Mac Roman subtable maps every code.
+	if got := written.LookupRunesMap([]rune("ABC")); got['A'] != newGID[byRune['A']] || got['B'] != newGID[byRune['B']] || got['C'] != 0 {
+		t.Fatalf("subset cmap gives %v", got)
+	}
+
+	// The same set gives the same subset.
+	again, _, err := f.SubsetGIDs([]GlyphIndex{unmapped, byRune['A'], byRune['B']})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf2 bytes.Buffer
+	if err := again.Write(&buf2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatal("subset depends on the order of the glyphs")
+	}
+
+	if _, _, err := f.SubsetGIDs([]GlyphIndex{GlyphIndex(f.maxp.numGlyphs)}); !errors.Is(err, errRangeCheck) {
+		t.Fatalf("got error %v, want errRangeCheck", err)
+	}
+
+	// Composite glyphs keep their components.
+	c, g := compositeFixture(t)
+	_, newGID, err = c.SubsetGIDs([]GlyphIndex{g['ë']})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range "eé´ë" {
+		if _, ok := newGID[g[r]]; !ok {
+			t.Fatalf("composite: glyph of %q not kept", r)
+		}
+	}
+
+	// Without a cmap.
+	f.cmap = nil
+	f = newFont(nil, f.font)
+	if _, err := f.Subset([]rune("AB")); !errors.Is(err, ErrNoCmap) {
+		t.Fatalf("got error %v, want ErrNoCmap", err)
+	}
+	opts := DefaultSubsetOptions()
+	opts.DropCmap = true
+	sub, newGID, err = f.SubsetGIDsWithOptions(input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// .notdef, the two mandatory glyphs and those asked for.
+	if len(newGID) != 3+3 || sub.HasCmap() {
+		t.Fatalf("without cmap: %v, HasCmap() = %t", newGID, sub.HasCmap())
+	}
+}
+
+func TestFont_SubsetNames(t *testing.T) {
+	f := loadGoRegular(t)
+	// Not ASCII, to tell Mac Roman from UTF-16BE.
+	if !f.SetNameByID(NameIDFamily, "Gö") {
+		t.Fatal("no family name")
+	}
+	sub, err := f.Subset([]rune("Hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := sub.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.name == nil {
+		t.Fatal("subset has no name table")
+	}
+
+	type key struct{ platformID, encodingID, languageID, nameID uint16 }
+	records := make(map[key][]byte)
+	for _, nr := range g.name.nameRecords {
+		if !slices.Contains(subsetNameIDs, NameID(nr.nameID)) {
+			t.Fatalf("subset has name %d", nr.nameID)
+		}
+		records[key{nr.platformID, nr.encodingID, nr.languageID, nr.nameID}] = nr.data
+	}
+	for _, nr := range f.name.nameRecords {
+		data, ok := records[key{nr.platformID, nr.encodingID, nr.languageID, nr.nameID}]
+		if slices.Contains(subsetNameIDs, NameID(nr.nameID)) && (!ok || !bytes.Equal(data, nr.data)) {
+			t.Fatalf("name %d of platform %d is % X, want % X", nr.nameID, nr.platformID, data, nr.data)
+		}
+	}
+	mac := records[key{uint16(PlatformMacintosh), uint16(EncodingMacRoman), 0, uint16(NameIDFamily)}]
+	win := records[key{uint16(PlatformWindows), uint16(EncodingWindowsUnicodeBMP), 0x409, uint16(NameIDFamily)}]
+	if !bytes.Equal(mac, []byte{'G', 0x9A}) || !bytes.Equal(win, []byte{0, 'G', 0, 0xF6}) {
+		t.Fatalf("family is % X in Mac Roman, % X in UTF-16BE", mac, win)
+	}
+	if got := g.Name(NameIDFamily); got != "Gö" {
+		t.Fatalf("family %q", got)
+	}
+	if got := g.Name(NameIDPostScriptName); got != "GoRegular" {
+		t.Fatalf("PostScript name %q", got)
+	}
+}
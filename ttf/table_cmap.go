@@ -10,6 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math"
+	"slices"
 )
 
 // cmapTable represents a Character to Glyph Index Mapping Table (cmap).
@@ -40,7 +43,7 @@ func (f *font) parseCmap(r *byteReader) (*cmapTable, error) {
 		return nil, errRequiredField
 	}
 
-	tr, has, err := f.seekToTable(r, "cmap")
+	tr, has, err := f.seekToTable(r, tagCmap)
 	if err != nil {
 		return nil, err
 	}
@@ -58,15 +61,19 @@ func (f *font) parseCmap(r *byteReader) (*cmapTable, error) {
 
 	for i := 0; i < int(t.numTables); i++ {
 		var enc encodingRecord
+		r.pushContext("encoding record %d", i)
 		err = r.read(&enc.platformID, &enc.encodingID, &enc.offset)
 		if err != nil {
 			return nil, err
 		}
+		r.popContext()
 		t.encodingRecords = append(t.encodingRecords, enc)
 	}
 
 	// Process the encoding subtables.
-	for _, enc := range t.encodingRecords {
+	for i, enc := range t.encodingRecords {
+		r.pushContext("subtable %d (platform %d, encoding %d)", i, enc.platformID, enc.encodingID)
+
 		// Seek to the subtable.
 		err = r.SeekTo(int64(tr.offset) + int64(enc.offset))
 		if err != nil {
@@ -81,6 +88,7 @@ func (f *font) parseCmap(r *byteReader) (*cmapTable, error) {
 		}
 
 		// slog.Debug(fmt.Sprintf("Format: %d", format))
+		r.pushContext("format %d", format)
 		var cmap *cmapSubtable
 		switch format {
 		case 0:
@@ -93,12 +101,16 @@ func (f *font) parseCmap(r *byteReader) (*cmapTable, error) {
 			cmap, err = f.parseCmapSubtableFormat12(r, int(enc.platformID), int(enc.encodingID))
 		default:
 			// slog.Debug(fmt.Sprintf("Unsupported cmap format %d", format))
+			r.popContext()
+			r.popContext()
 			continue
 		}
 		if err != nil {
 			// slog.Debug(fmt.Sprintf("Error: %v", err))
 			return nil, err
 		}
+		r.popContext()
+		r.popContext()
 		if cmap != nil {
 			key := fmt.Sprintf("%d,%d,%d", format, enc.platformID, enc.encodingID)
 			t.subtables[key] = cmap
@@ -147,7 +159,7 @@ func (f *font) parseCmapSubtableFormat0(r *byteReader, platformID, encodingID in
 		}
 	}
 
-	err = r.readSlice(&st.glyphIDArray, 256)
+	err = readSliceOf(r, &st.glyphIDArray, 256)
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +214,7 @@ func writeCmapSubtableFormat0(subtable *cmapSubtable, w *byteWriter) error {
 		return err
 	}
 
-	return w.writeSlice(subt.glyphIDArray)
+	return writeSliceOf(w, subt.glyphIDArray)
 }
 
 // cmapSubtableFormat4 represents cmap data format 4: Segment mapping to delta values.
@@ -234,8 +246,13 @@ func (f *font) parseCmapSubtableFormat4(r *byteReader, platformID, encodingID in
 	}
 
 	segCount := int(st.segCountX2 / 2)
+	readArray := func(name string, arr *[]uint16, length int) error {
+		r.pushContext("%s", name)
+		defer r.popContext()
+		return readSliceOf(r, arr, length)
+	}
 
-	err = r.readSlice(&st.endCode, segCount)
+	err = readArray("endCode", &st.endCode, segCount)
 	if err != nil {
 		return nil, err
 	}
@@ -244,28 +261,28 @@ func (f *font) parseCmapSubtableFormat4(r *byteReader, platformID, encodingID in
 		return nil, err
 	}
 
-	err = r.readSlice(&st.startCode, segCount)
+	err = readArray("startCode", &st.startCode, segCount)
 	if err != nil {
 		return nil, err
 	}
-	err = r.readSlice(&st.idDelta, segCount)
+	err = readArray("idDelta", &st.idDelta, segCount)
 	if err != nil {
 		return nil, err
 	}
 
-	err = r.readSlice(&st.idRangeOffset, segCount)
+	err = readArray("idRangeOffset", &st.idRangeOffset, segCount)
 	if err != nil {
 		return nil, err
 	}
 
-	glyphIDArrLen := int(st.length-uint16(2*8+2*4*segCount)) / 2
+	glyphIDArrLen := (int(st.length) - (2*8 + 2*4*segCount)) / 2
 	// slog.Debug(fmt.Sprintf("Parsing cmap format 4, segCount: %d", segCount))
 	// slog.Debug(fmt.Sprintf("Table len: %d", st.length))
 	// slog.Debug(fmt.Sprintf("glyphIDArrLen: %d", glyphIDArrLen))
 	if glyphIDArrLen < 0 {
 		return nil, errors.New("invalid length")
 	}
-	err = r.readSlice(&st.glyphIDArray, glyphIDArrLen)
+	err = readArray("glyphIdArray", &st.glyphIDArray, glyphIDArrLen)
 	if err != nil {
 		return nil, err
 	}
@@ -279,10 +296,11 @@ func (f *font) parseCmapSubtableFormat4(r *byteReader, platformID, encodingID in
 	charcodeMap := make(map[CharCode]GlyphIndex, f.maxp.numGlyphs)
 	// slog.Debug(fmt.Sprintf("Number of glyphs in font: %d\n", f.maxp.numGlyphs))
 	for i := 0; i < segCount-1; i++ {
-		c1 := st.startCode[i]
-		c2 := st.endCode[i]
+		// The codes are ints so that a segment ending at 0xFFFF ends the loop.
+		c1 := int(st.startCode[i])
+		c2 := int(st.endCode[i])
 		d := st.idDelta[i]
-		rangeOffset := st.idRangeOffset[i]
+		rangeOffset := int(st.idRangeOffset[i])
 
 		// slog.Debug(fmt.Sprintf("Segment %d/%d, c1: %d, c2: %d, d: %d, rangeOffset: %d", i+1, segCount, c1, c2, d, rangeOffset))
 
@@ -290,17 +308,18 @@ func (f *font) parseCmapSubtableFormat4(r *byteReader, platformID, encodingID in
 			var gid uint16
 
 			if rangeOffset == 0 {
-				gid = (c + d) & 0xFFFF
+				// Glyph indices are modulo 65536.
+				gid = uint16(c) + d
 			} else {
-				index := int(rangeOffset/2 + (c - c1) + uint16(i) - uint16(len(st.idRangeOffset)))
+				index := rangeOffset/2 + (c - c1) + i - len(st.idRangeOffset)
 
-				if index >= len(st.glyphIDArray) {
+				if index < 0 || index >= len(st.glyphIDArray) {
 					// slog.Debug(fmt.Sprintf("c1=%d c=%d c2=%d", c1, c, c2))
 					// slog.Debug(fmt.Sprintf("ERROR: index outside bounds (%d/%d)", index, len(st.glyphIDArray)))
 					return nil, errors.New("outside bounds")
 				}
 				if st.glyphIDArray[index] != 0 {
-					gid = (st.glyphIDArray[index] + d) & 0xFFFF
+					gid = st.glyphIDArray[index] + d
 				} else {
 					gid = 0
 				}
@@ -347,7 +366,11 @@ func writeCmapSubtableFormat4(subtable *cmapSubtable, w *byteWriter) error {
 	format = 4
 	// TODO(gunnsth): Not the place to generate this?  Somewhere else should have ability to generate
 	//       based on character codes.
-	subt.length = 7*2 + subt.segCountX2 + 2 + 3*subt.segCountX2 + 2*uint16(len(subt.glyphIDArray))
+	length := 2*8 + 2*4*len(subt.endCode) + 2*len(subt.glyphIDArray)
+	if length > math.MaxUint16 {
+		return fmt.Errorf("format 4 subtable of %d bytes: %w", length, errRangeCheck)
+	}
+	subt.length = uint16(length)
 	err := w.write(format, subt.length, subt.language)
 	if err != nil {
 		return err
@@ -356,28 +379,28 @@ func writeCmapSubtableFormat4(subtable *cmapSubtable, w *byteWriter) error {
 	if err != nil {
 		return err
 	}
-	err = w.writeSlice(subt.endCode)
+	err = writeSliceOf(w, subt.endCode)
 	if err != nil {
 		return err
 	}
 	err = w.write(subt.reservedPad)
 	if err != nil {
-		return nil
+		return err
 	}
-	err = w.writeSlice(subt.startCode)
+	err = writeSliceOf(w, subt.startCode)
 	if err != nil {
-		return nil
+		return err
 	}
-	err = w.writeSlice(subt.idDelta)
+	err = writeSliceOf(w, subt.idDelta)
 	if err != nil {
-		return nil
+		return err
 	}
-	err = w.writeSlice(subt.idRangeOffset)
+	err = writeSliceOf(w, subt.idRangeOffset)
 	if err != nil {
-		return nil
+		return err
 	}
 	// TODO: Problem: the following slice is not populated.
-	return w.writeSlice(subt.glyphIDArray)
+	return writeSliceOf(w, subt.glyphIDArray)
 }
 
 // cmapSubtableFormat6 represents cmap data format 6: Trimmed table mapping.
@@ -396,7 +419,7 @@ func (f *font) parseCmapSubtableFormat6(r *byteReader, platformID, encodingID in
 		return nil, err
 	}
 
-	err = r.readSlice(&st.glyphIDArray, int(st.entryCount))
+	err = readSliceOf(r, &st.glyphIDArray, int(st.entryCount))
 	if err != nil {
 		return nil, err
 	}
@@ -446,7 +469,27 @@ func writeCmapSubtableFormat6(subtable *cmapSubtable, w *byteWriter) error {
 		return err
 	}
 
-	return w.writeSlice(subt.glyphIDArray)
+	return writeSliceOf(w, subt.glyphIDArray)
+}
+
+// subsetGlyphIDs returns the glyph IDs of the byte encoded character codes from `firstCode` of
+// `glyphIDArray` of a format 0 or 6 subtable of `platformID` and `encodingID` in a subset that
+// maps the runes of `cmap` and renumbers glyphs by `newGID`. Codes of runes that the subset does
+// not map, or of glyphs that it drops, map to .notdef.
+func subsetGlyphIDs[T uint8 | uint16](glyphIDArray []T, firstCode uint16, platformID, encodingID int,
+	cmap map[rune]GlyphIndex, newGID map[GlyphIndex]GlyphIndex) []T {
+	runeDecoder := getCmapEncoding(platformID, encodingID).GetRuneDecoder()
+	gids := make([]T, len(glyphIDArray))
+	for i, gid := range glyphIDArray {
+		r := runeDecoder.DecodeRune(runeDecoder.ToBytes(uint32(firstCode) + uint32(i)))
+		if _, ok := cmap[r]; !ok {
+			continue
+		}
+		if newgid, ok := newGID[GlyphIndex(gid)]; ok && uint64(newgid) == uint64(T(newgid)) {
+			gids[i] = T(newgid)
+		}
+	}
+	return gids
 }
 
 // cmapSubtableFormat12 represents cmap data format 12: Segmented coverage.
@@ -481,6 +524,9 @@ func (f *font) parseCmapSubtableFormat12(r *byteReader, platformID, encodingID i
 			// slog.Debug(fmt.Sprintf("Error: %v", err))
 			return nil, err
 		}
+		if err := checkCharCodeRange(CharCode(group.startCharCode), CharCode(group.endCharCode)); err != nil {
+			return nil, fmt.Errorf("group %d: %w", i, err)
+		}
 		st.groups = append(st.groups, group)
 	}
 
@@ -550,14 +596,230 @@ func writeCmapSubtableFormat12(subtable *cmapSubtable, w *byteWriter) error {
 	return nil
 }
 
+// cmapRuns calls `yield` with the start and length of each run of `codes` and `gids` where
+// both increase by one from entry to entry, the unit of format 4 segments and format 12 groups.
+// `codes` must be sorted ascending.
+func cmapRuns(codes []CharCode, gids []GlyphIndex, yield func(start, n int)) {
+	for i := 0; i < len(codes); {
+		j := i + 1
+		for ; j < len(codes); j++ {
+			if int(codes[j]-codes[i]) != j-i || int(gids[j])-int(gids[i]) != j-i {
+				break
+			}
+		}
+		yield(i, j-i)
+		i = j
+	}
+}
+
+// buildCmapFormat4 returns a format 4 subtable mapping `codes`, sorted ascending, to `gids`.
+// Each range of consecutive codes becomes one segment per run, or one segment looking its
+// glyphs up in glyphIdArray where that is smaller. Codes outside the BMP and 0xFFFF, which
+// the final segment is reserved for, are left out.
+func buildCmapFormat4(codes []CharCode, gids []GlyphIndex, language uint16) cmapSubtableFormat4 {
+	n, _ := slices.BinarySearch(codes, 0xFFFF)
+	codes, gids = codes[:n], gids[:n]
+
+	var t cmapSubtableFormat4
+	// arrayStart is the glyphIdArray index of each segment, -1 for a delta segment.
+	var arrayStart []int
+	addDeltas := func(codes []CharCode, gids []GlyphIndex) {
+		cmapRuns(codes, gids, func(start, n int) {
+			t.startCode = append(t.startCode, uint16(codes[start]))
+			t.endCode = append(t.endCode, uint16(codes[start]+CharCode(n-1)))
+			// Glyph indices are modulo 65536, so any delta maps the code to its glyph.
+			t.idDelta = append(t.idDelta, uint16(gids[start])-uint16(codes[start]))
+			arrayStart = append(arrayStart, -1)
+		})
+	}
+	for i := 0; i < len(codes); {
+		j := i + 1
+		for j < len(codes) && codes[j] == codes[j-1]+1 {
+			j++
+		}
+		runs := 0
+		cmapRuns(codes[i:j], gids[i:j], func(int, int) { runs++ })
+		// A segment is 8 bytes, a glyphIdArray entry 2.
+		if runs == 1 || 8*runs <= 8+2*(j-i) {
+			addDeltas(codes[i:j], gids[i:j])
+		} else {
+			t.startCode = append(t.startCode, uint16(codes[i]))
+			t.endCode = append(t.endCode, uint16(codes[j-1]))
+			t.idDelta = append(t.idDelta, 0)
+			arrayStart = append(arrayStart, len(t.glyphIDArray))
+			for _, gid := range gids[i:j] {
+				t.glyphIDArray = append(t.glyphIDArray, uint16(gid))
+			}
+		}
+		i = j
+	}
+	// The last segment must be 0xFFFF to 0xFFFF.
+	t.startCode = append(t.startCode, 0xFFFF)
+	t.endCode = append(t.endCode, 0xFFFF)
+	t.idDelta = append(t.idDelta, 1)
+	arrayStart = append(arrayStart, -1)
+
+	segments := len(t.endCode)
+	// idRangeOffset is the byte offset from itself to the segment's first glyphIdArray entry.
+	t.idRangeOffset = make([]uint16, segments)
+	for i, start := range arrayStart {
+		if start >= 0 {
+			t.idRangeOffset[i] = uint16(2 * (segments - i + start))
+		}
+	}
+	// The writer rejects subtables longer than 65535 bytes.
+	t.length = uint16(min(2*8+2*4*segments+2*len(t.glyphIDArray), math.MaxUint16))
+	t.language = language
+	t.segCountX2 = uint16(segments * 2)
+	t.searchRange, t.entrySelector, t.rangeShift = binarySearchParams(segments, 2)
+	return t
+}
+
+// buildCmapFormat12 returns a format 12 subtable mapping `codes`, sorted ascending, to `gids`
+// with one group per run.
+func buildCmapFormat12(codes []CharCode, gids []GlyphIndex, language uint32) cmapSubtableFormat12 {
+	var t cmapSubtableFormat12
+	cmapRuns(codes, gids, func(start, n int) {
+		t.groups = append(t.groups, sequentialMapGroup{
+			startCharCode: uint32(codes[start]),
+			endCharCode:   uint32(codes[start]) + uint32(n-1),
+			startGlyphID:  uint32(gids[start]),
+		})
+	})
+	t.length = uint32(2*2 + 3*4 + len(t.groups)*3*4)
+	t.language = language
+	t.numGroups = uint32(len(t.groups))
+	return t
+}
+
+// newUnicodeCmapSubtable returns a Unicode subtable of `format` (4 or 12) mapping the runes of
+// `cmap` to their glyphs, with the character codes being the runes. Format 4 only maps the BMP
+// up to 0xFFFE.
+func newUnicodeCmapSubtable(format, platformID, encodingID int, cmap map[rune]GlyphIndex) *cmapSubtable {
+	runes := slices.Sorted(maps.Keys(cmap))
+	if format == 4 {
+		n, _ := slices.BinarySearch(runes, 0xFFFF)
+		runes = runes[:n]
+	}
+	subt := &cmapSubtable{
+		format:        format,
+		platformID:    platformID,
+		encodingID:    encodingID,
+		cmap:          make(map[rune]GlyphIndex, len(runes)),
+		runes:         runes,
+		charcodes:     make([]CharCode, len(runes)),
+		charcodeToGID: make(map[CharCode]GlyphIndex, len(runes)),
+	}
+	gids := make([]GlyphIndex, len(runes))
+	for i, r := range runes {
+		gids[i] = cmap[r]
+		subt.cmap[r] = gids[i]
+		subt.charcodes[i] = CharCode(r)
+		subt.charcodeToGID[CharCode(r)] = gids[i]
+	}
+	if format == 4 {
+		subt.ctx = buildCmapFormat4(subt.charcodes, gids, 0)
+	} else {
+		subt.ctx = buildCmapFormat12(subt.charcodes, gids, 0)
+	}
+	return subt
+}
+
+// isUnicode returns true if `subt` maps Unicode code points to glyphs.
+func (subt *cmapSubtable) isUnicode() bool {
+	switch PlatformID(subt.platformID) {
+	case PlatformUnicode:
+		return subt.encodingID <= int(EncodingUnicode20Full)
+	case PlatformWindows:
+		return subt.encodingID == int(EncodingWindowsUnicodeBMP) || subt.encodingID == int(EncodingWindowsUnicodeUCS4)
+	}
+	return false
+}
+
+// unicodeCmap returns the runes mapped by the Unicode subtables of `t`, taking each rune from
+// the first subtable mapping it.
+func (t *cmapTable) unicodeCmap() map[rune]GlyphIndex {
+	cmap := make(map[rune]GlyphIndex)
+	for _, key := range t.subtableKeys {
+		subt := t.subtables[key]
+		if !subt.isUnicode() {
+			continue
+		}
+		for r, gid := range subt.cmap {
+			if _, ok := cmap[r]; !ok {
+				cmap[r] = gid
+			}
+		}
+	}
+	return cmap
+}
+
+// rebuildUnicodeCmap returns a cmap table with the subtables of `t` (may be nil), the Unicode
+// ones rebuilt from `cmap`: subtables of format 12 keep it, the others become format 4 mapping
+// the BMP. Without Unicode subtables a (3,1) format 4 subtable is added. A (3,10) format 12
+// subtable is added if `cmap` maps runes outside the BMP and no subtable of format 12 does.
+func rebuildUnicodeCmap(t *cmapTable, cmap map[rune]GlyphIndex) *cmapTable {
+	newt := &cmapTable{subtables: make(map[string]*cmapSubtable)}
+	add := func(subt *cmapSubtable) {
+		key := fmt.Sprintf("%d,%d,%d", subt.format, subt.platformID, subt.encodingID)
+		if _, dup := newt.subtables[key]; !dup {
+			newt.subtableKeys = append(newt.subtableKeys, key)
+		}
+		newt.subtables[key] = subt
+	}
+	hasUnicode, hasFull := false, false
+	if t != nil {
+		newt.version = t.version
+		for _, key := range t.subtableKeys {
+			subt := t.subtables[key]
+			if !subt.isUnicode() {
+				add(subt)
+				continue
+			}
+			hasUnicode = true
+			format := 4
+			if subt.format == 12 {
+				format = 12
+				hasFull = true
+			}
+			add(newUnicodeCmapSubtable(format, subt.platformID, subt.encodingID, cmap))
+		}
+	}
+	if !hasUnicode {
+		add(newUnicodeCmapSubtable(4, int(PlatformWindows), int(EncodingWindowsUnicodeBMP), cmap))
+	}
+	if !hasFull && len(cmap) > 0 && slices.Max(slices.Collect(maps.Keys(cmap))) > 0xFFFF {
+		add(newUnicodeCmapSubtable(12, int(PlatformWindows), int(EncodingWindowsUnicodeUCS4), cmap))
+	}
+	newt.numTables = uint16(len(newt.subtables))
+	return newt
+}
+
+// setCmap checks the structure of the Unicode subtables of cmap table `t` (see checkCmap) and
+// installs it in `f`.
+func (f *font) setCmap(t *cmapTable) error {
+	check := &font{maxp: f.maxp, cmap: &cmapTable{subtables: t.subtables}}
+	for _, key := range t.subtableKeys {
+		if t.subtables[key].isUnicode() {
+			check.cmap.subtableKeys = append(check.cmap.subtableKeys, key)
+		}
+	}
+	rep := &ValidationReport{}
+	check.checkCmap(rep, ProfileDefault)
+	if errs := rep.Errors(); len(errs) > 0 {
+		return fmt.Errorf("rebuilt cmap: %s", errs[0])
+	}
+	f.cmap = t
+	f.markDirty(tagCmap)
+	return nil
+}
+
 func (f *font) writeCmap(w *byteWriter) error {
 	if f.cmap == nil {
 		return nil
 	}
 	t := f.cmap
 
-	err := w.write(t.version, t.numTables)
-
 	// Write the cmap subtables to an in-memory mock buffer to calculate offsets.
 	var mockBuffer bytes.Buffer
 	mockWriter := newByteWriter(&mockBuffer)
@@ -601,7 +863,14 @@ func (f *font) writeCmap(w *byteWriter) error {
 			encodingRecords = append(encodingRecords, rec)
 		}
 	}
-	err = mockWriter.flush()
+	err := mockWriter.flush()
+	if err != nil {
+		return err
+	}
+
+	// numTables counts the subtables written, not those parsed, of which some may be in formats
+	// that are not written.
+	err = w.write(t.version, uint16(len(encodingRecords)))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,178 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseCmapFormat12_CharCodeRange(t *testing.T) {
+	f := loadGoRegular(t)
+	for _, tt := range []struct {
+		name       string
+		start, end uint32
+		err        error
+	}{
+		{"valid", 0x1F600, 0x1F601, nil},
+		{"reversed", 0x1F601, 0x1F600, errRangeCheck},
+		{"beyond Unicode", 0x10FFFF, 0x110000, errRangeCheck},
+		{"wrapping", 0xFFFFFFFF, 0xFFFFFFFF, errRangeCheck},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The subtable after its format: reserved, length, language, one group.
+			data := binary.BigEndian.AppendUint16(nil, 0)
+			for _, v := range []uint32{28, 0, 1, tt.start, tt.end, 1} {
+				data = binary.BigEndian.AppendUint32(data, v)
+			}
+			_, err := f.parseCmapSubtableFormat12(newBytesReader(data), 3, 10)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestFont_MaxGlyphs grows Go Regular to 65535 glyphs, the most a font can have, with the
+// last a copy of 'A' and 20000 private use runes mapped to scattered glyphs, and checks that
+// it is written, parsed, looked up and subset without any glyph index wrapping around.
+func TestFont_MaxGlyphs(t *testing.T) {
+	const (
+		numGlyphs = math.MaxUint16
+		last      = GlyphIndex(numGlyphs - 1)
+		scattered = 20000
+	)
+	f := loadGoRegular(t)
+	a := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)['A']
+	for _, lsb := range f.hmtx.leftSideBearings {
+		f.hmtx.hMetrics = append(f.hmtx.hMetrics, longHorMetric{f.hmtx.hMetrics[len(f.hmtx.hMetrics)-1].advanceWidth, lsb})
+	}
+	f.hmtx.leftSideBearings = nil
+	for gid := len(f.glyf.descs); gid < numGlyphs; gid++ {
+		f.glyf.descs = append(f.glyf.descs, &glyphDescription{})
+		f.hmtx.hMetrics = append(f.hmtx.hMetrics, longHorMetric{advanceWidth: uint16(gid)})
+	}
+	f.glyf.descs[last] = f.glyf.descs[a]
+	f.hmtx.hMetrics[last] = f.hmtx.hMetrics[a]
+	f.loca, f.head.indexToLocFormat = buildLoca(f.glyf.descs, true)
+	f.maxp.numGlyphs = numGlyphs
+	f.hhea.numberOfHMetrics = numGlyphs
+	f.post = nil // Its glyph names are for 712 glyphs.
+	f.markDirty(tagHead, tagMaxp, tagHhea, tagHmtx, tagLoca, tagGlyf)
+
+	// Descending glyphs make one-code runs, too many for format 4 segments alone, and
+	// U+3000 to `last` needs an idDelta that wraps around.
+	mapping := map[rune]GlyphIndex{'Z': last, 0xF0000: last}
+	for i := range scattered {
+		mapping[0x3000+rune(i)] = last - GlyphIndex(2*i)
+	}
+	if err := f.RemapCmap(mapping, false); err != nil {
+		t.Fatal(err)
+	}
+	g, data := writeAndParse(t, f)
+	if rep, err := ValidateBytesReport(data, ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("validation: %v %v", err, rep.Errors())
+	}
+	if g.maxp.numGlyphs != numGlyphs || !g.ValidGID(last) {
+		t.Fatalf("%d glyphs", g.maxp.numGlyphs)
+	}
+	if adv, ok := g.GlyphAdvance(last - 1); !ok || adv != numGlyphs-2 {
+		t.Fatalf("advance of glyph %d: %d %t", last-1, adv, ok)
+	}
+	cmap := g.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	for r, gid := range mapping {
+		if r < 0x10000 && cmap[r] != gid {
+			t.Fatalf("%U maps to %d, want %d", r, cmap[r], gid)
+		}
+	}
+	if gids, _ := g.LookupRunes([]rune{'Z', 0x3000, 0x3000 + scattered - 1, 0xF0000}); !slices.Equal(gids, []GlyphIndex{last, last, last - 2*(scattered-1), last}) {
+		t.Fatalf("looked up %v", gids)
+	}
+
+	for _, opts := range []SubsetOptions{{}, {RetainGIDs: true}} {
+		sub, err := g.SubsetWithOptions([]rune{'Z', 0x3001, 0xF0000}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, _ := writeAndParse(t, sub)
+		want := 3
+		if opts.RetainGIDs {
+			want = numGlyphs
+		}
+		if int(s.maxp.numGlyphs) != want {
+			t.Fatalf("RetainGIDs %t: %d glyphs, want %d", opts.RetainGIDs, s.maxp.numGlyphs, want)
+		}
+		gids, _ := s.LookupRunes([]rune{'Z', 0xF0000})
+		if len(gids) != 2 || gids[0] != gids[1] || !bytes.Equal(s.glyf.descs[gids[0]].raw, g.glyf.descs[a].raw) {
+			t.Fatalf("RetainGIDs %t: 'Z' and U+F0000 map to %v", opts.RetainGIDs, gids)
+		}
+	}
+}
+
+// iconFixture returns Go Regular as a symbol font, with only a (3,0) cmap subtable mapping
+// 0xF041 and 0xF042 to the glyphs of 'A' and 'B', 0xF020 to that of the space and 0xF0A5 to
+// glyph 100.
+func iconFixture(t *testing.T) (*Font, map[rune]GlyphIndex) {
+	t.Helper()
+	f := loadGoRegular(t)
+	gids, _ := f.LookupRunes([]rune(" AB"))
+	raw := map[rune]GlyphIndex{0xF020: gids[0], 0xF041: gids[1], 0xF042: gids[2], 0xF0A5: 100}
+	subt := newUnicodeCmapSubtable(4, int(PlatformWindows), int(EncodingWindowsSymbol), raw)
+	f.cmap = &cmapTable{numTables: 1, subtableKeys: []string{"4,3,0"}, subtables: map[string]*cmapSubtable{"4,3,0": subt}}
+	f.markDirty(tagCmap)
+	g, _ := writeAndParse(t, f)
+	return g, raw
+}
+
+func TestFont_SymbolCmap(t *testing.T) {
+	if _, ok := loadGoRegular(t).GetSymbolCmap(); ok {
+		t.Fatal("symbol cmap of Go Regular")
+	}
+	f, raw := iconFixture(t)
+	if got := f.cmapOf(PlatformWindows, EncodingWindowsSymbol); !maps.Equal(got, raw) {
+		t.Fatalf("raw cmap %v, want %v", got, raw)
+	}
+	want := map[rune]GlyphIndex{' ': raw[0xF020], 'A': raw[0xF041], 'B': raw[0xF042], 0xF0A5: 100}
+	view, ok := f.GetSymbolCmap()
+	got := map[rune]GlyphIndex{}
+	view.Each(func(r rune, gid GlyphIndex) { got[r] = gid })
+	if !ok || !maps.Equal(got, want) {
+		t.Fatalf("symbol cmap %v, want %v", got, want)
+	}
+
+	// Both ASCII and private use runes are looked up.
+	if !f.HasCmap() {
+		t.Fatal("no cmap")
+	}
+	runes := []rune{'A', 0xF042, 0xF0A5, 'C'}
+	gids, found := f.LookupRunes(runes)
+	if !slices.Equal(gids, []GlyphIndex{raw[0xF041], raw[0xF042], 100}) || !slices.Equal(found, []rune{'A', 0xF042, 0xF0A5}) {
+		t.Fatalf("LookupRunes: %v %U", gids, found)
+	}
+
+	// Subsets keep the codes of the symbol subtable, 'A' and 0xF041 being the same.
+	sub, err := f.Subset([]rune{'A', 0xF041, 'B'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := writeAndParse(t, sub)
+	symbol := s.cmapOf(PlatformWindows, EncodingWindowsSymbol)
+	for _, code := range []rune{0xF041, 0xF042} {
+		gid, ok := symbol[code]
+		if !ok || gid == 0 {
+			t.Fatalf("subset: %U maps to %d %t in %v", code, gid, ok, symbol)
+		}
+		if view, _ := s.GetSymbolCmap(); view.cmap[code-0xF000] != gid {
+			t.Fatalf("subset: %q not normalized", code-0xF000)
+		}
+	}
+	if _, ok := symbol['A']; ok {
+		t.Fatalf("subset maps 'A' in %v", symbol)
+	}
+	if _, ok := symbol[0xF0A5]; ok {
+		t.Fatalf("subset maps %U", 0xF0A5)
+	}
+}
@@ -15,7 +15,7 @@ type cvtTable struct {
 }
 
 func (f *font) parseCvt(r *byteReader) (*cvtTable, error) {
-	tr, has, err := f.seekToTable(r, "cvt")
+	tr, has, err := f.seekToTable(r, tagCvt)
 	if err != nil {
 		return nil, err
 	}
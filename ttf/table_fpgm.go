@@ -11,7 +11,7 @@ type fpgmTable struct {
 }
 
 func (f *font) parseFpgm(r *byteReader) (*fpgmTable, error) {
-	tr, has, err := f.seekToTable(r, "fpgm")
+	tr, has, err := f.seekToTable(r, tagFpgm)
 	if err != nil {
 		return nil, err
 	}
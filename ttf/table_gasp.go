@@ -0,0 +1,86 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+// gaspTable represents the grid-fitting and scan-conversion procedure table (gasp).
+// It tells rasterizers whether to grid-fit and anti-alias glyphs for ranges of sizes in ppem.
+// https://learn.microsoft.com/en-us/typography/opentype/spec/gasp
+type gaspTable struct {
+	version uint16
+	ranges  []GaspRange // Sorted by MaxPPEM, the last one up to 0xFFFF.
+}
+
+// GaspRange is a range of sizes of the gasp table: from the MaxPPEM of the previous range,
+// exclusive, up to MaxPPEM in pixels per em, glyphs are rendered as set by Behavior.
+type GaspRange struct {
+	MaxPPEM  uint16
+	Behavior GaspBehavior
+}
+
+// GaspBehavior flags how glyphs are rendered at the sizes of a GaspRange.
+type GaspBehavior uint16
+
+// GaspBehavior flags. The symmetric flags are for ClearType and need gasp version 1.
+const (
+	GaspGridfit            GaspBehavior = 1 << iota // Grid-fit with the hinting instructions.
+	GaspDoGray                                      // Anti-alias in grayscale.
+	GaspSymmetricGridfit                            // Grid-fit with ClearType in both directions.
+	GaspSymmetricSmoothing                          // Anti-alias with ClearType in both directions.
+)
+
+func (f *font) parseGasp(r *byteReader) (*gaspTable, error) {
+	tr, has, err := f.seekToTable(r, tagGasp)
+	if err != nil {
+		return nil, err
+	}
+	if !has || tr == nil {
+		// slog.Debug("gasp table absent")
+		return nil, nil
+	}
+
+	t := &gaspTable{}
+	var numRanges uint16
+	err = r.read(&t.version, &numRanges)
+	if err != nil {
+		return nil, err
+	}
+	if fit := max((int(tr.length)-4)/4, 0); int(numRanges) > fit {
+		err := f.recordIncompatibilityf("numRanges %d past the gasp length %d, truncated", numRanges, tr.length)
+		if err != nil {
+			return nil, err
+		}
+		numRanges = uint16(fit)
+	}
+
+	t.ranges = make([]GaspRange, numRanges)
+	for i := range t.ranges {
+		var behavior uint16
+		err = r.read(&t.ranges[i].MaxPPEM, &behavior)
+		if err != nil {
+			return nil, err
+		}
+		t.ranges[i].Behavior = GaspBehavior(behavior)
+	}
+	return t, nil
+}
+
+func (f *font) writeGasp(w *byteWriter) error {
+	if f.gasp == nil {
+		return nil
+	}
+
+	err := w.write(f.gasp.version, uint16(len(f.gasp.ranges)))
+	if err != nil {
+		return err
+	}
+	for _, rng := range f.gasp.ranges {
+		err = w.write(rng.MaxPPEM, uint16(rng.Behavior))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,9 +7,12 @@ package ttf
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 )
 
 // glyfTable represents the Glyph Data table (glyf).
@@ -30,13 +33,19 @@ type glyfTable struct {
 	descs []*glyphDescription
 }
 
-func (f *font) parseGlyf(r *byteReader) (*glyfTable, error) {
-	if f.maxp == nil || f.loca == nil {
+func (f *font) parseGlyf(ctx context.Context, r *byteReader) (*glyfTable, error) {
+	if f.maxp == nil {
 		// slog.Debug("required field missing (glyf)")
 		return nil, errRequiredField
 	}
+	if f.loca == nil && !f.trec.HasTag(tagGlyf) {
+		return nil, nil // Fonts with CFF outlines have neither loca nor glyf.
+	}
+	if f.loca == nil {
+		return nil, errRequiredField
+	}
 
-	tr, has, err := f.seekToTable(r, "glyf")
+	tr, has, err := f.seekToTable(r, tagGlyf)
 	if err != nil {
 		// slog.Debug(fmt.Sprintf("ERROR: %v", err))
 		return nil, err
@@ -52,7 +61,13 @@ func (f *font) parseGlyf(r *byteReader) (*glyfTable, error) {
 	// slog.Debug(fmt.Sprintf("Loca offset format: %d", f.head.indexToLocFormat))
 
 	for i := 0; i < int(f.maxp.numGlyphs); i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("parsing cancelled after %d of %d glyphs: %w", i, f.maxp.numGlyphs, err)
+			}
+		}
 		gid := GlyphIndex(i)
+		r.pushContext("glyph %d", i)
 		gdOffset, gdLen, err := f.GetGlyphDataOffset(gid)
 		if err != nil {
 			// slog.Debug(fmt.Sprintf("ERROR: %v", err))
@@ -62,23 +77,36 @@ func (f *font) parseGlyf(r *byteReader) (*glyfTable, error) {
 		if gdOffset > int64(tr.length) {
 			// slog.Debug(fmt.Sprintf("gid: %d, gdOffset: %d, tr len: %d, gd len: %d", gid, gdOffset, tr.length, gdLen))
 			// slog.Debug(fmt.Sprintf("Range check error (glyf): %d > %d", gdOffset, tr.length))
-			return nil, errRangeCheck
+			return nil, fmt.Errorf("data offset %d past table length %d: %w", gdOffset, tr.length, errRangeCheck)
 		}
-
-		err = r.SeekTo(int64(tr.offset) + gdOffset)
-		if err != nil {
-			// slog.Debug(fmt.Sprintf("ERROR: %v", err))
-			return nil, err
+		if gdLen < 0 {
+			return nil, fmt.Errorf("data at %d has negative length %d: %w", gdOffset, gdLen, errRangeCheck)
+		}
+		if end := gdOffset + gdLen; end > int64(tr.length) {
+			// Kept readable for RebuildLoca, which finds the glyph boundaries itself.
+			err := f.recordIncompatibilityf("glyph %d ends at %d past the glyf length %d, truncated", i, end, tr.length)
+			if err != nil {
+				return nil, err
+			}
+			gdLen = int64(tr.length) - gdOffset
 		}
 
-		var desc glyphDescription
-		desc.raw = make([]byte, gdLen)
-		err = r.readBytes(&desc.raw, int(gdLen))
-		if err != nil {
-			// slog.Debug(fmt.Sprintf("ERROR: %v", err))
-			return nil, err
+		// Glyphs of data held in memory are not copied, but read from it when used.
+		raw, ok := r.window(int64(tr.offset)+gdOffset, gdLen)
+		if !ok {
+			err = r.SeekTo(int64(tr.offset) + gdOffset)
+			if err != nil {
+				// slog.Debug(fmt.Sprintf("ERROR: %v", err))
+				return nil, err
+			}
+			err = r.readBytes(&raw, int(gdLen))
+			if err != nil {
+				// slog.Debug(fmt.Sprintf("ERROR: %v", err))
+				return nil, err
+			}
 		}
-		glyf.descs = append(glyf.descs, &desc)
+		r.popContext()
+		glyf.descs = append(glyf.descs, &glyphDescription{raw: raw})
 	}
 
 	return glyf, nil
@@ -99,6 +127,61 @@ type glyphHeader struct {
 	yMax             int16
 }
 
+// isEmpty reports whether `gd` has no data at all, like space in most fonts. Such a glyph has
+// no header and so no bounding box, but still an advance in hmtx.
+func (gd *glyphDescription) isEmpty() bool {
+	return len(gd.raw) == 0
+}
+
+// hasOutline reports whether `gd` has contours or components, i.e. whether its bounding box
+// means anything. Some fonts give glyphs without contours a header with a non-zero box.
+func (gd *glyphDescription) hasOutline() bool {
+	return len(gd.raw) >= 10 && int16(binary.BigEndian.Uint16(gd.raw)) != 0
+}
+
+// isComposite reports whether `gd` is made of components, i.e. has a negative numberOfContours.
+func (gd *glyphDescription) isComposite() bool {
+	return len(gd.raw) >= 10 && int16(binary.BigEndian.Uint16(gd.raw)) < 0
+}
+
+// evenGlyph returns `gd` with its data padded to an even length as short loca offsets require.
+func evenGlyph(gd *glyphDescription) *glyphDescription {
+	return alignGlyph(gd, 2)
+}
+
+// alignGlyph returns `gd` with its data padded with zeros to a multiple of `align` bytes. The
+// data of `gd` is copied rather than extended in place as it may be shared with other fonts.
+func alignGlyph(gd *glyphDescription, align int) *glyphDescription {
+	pad := (align - len(gd.raw)%align) % align
+	if pad == 0 {
+		return gd
+	}
+	return &glyphDescription{raw: append(slices.Clip(gd.raw), make([]byte, pad)...)}
+}
+
+// glyphBounds returns the union of the bounding boxes of the glyphs of `descs` that have an
+// outline, as head records it, or all zeros if none has.
+func glyphBounds(descs []*glyphDescription) (xMin, yMin, xMax, yMax int16) {
+	first := true
+	for _, gd := range descs {
+		if !gd.hasOutline() {
+			continue
+		}
+		box := [4]int16{}
+		for i := range box {
+			box[i] = int16(binary.BigEndian.Uint16(gd.raw[2+2*i:]))
+		}
+		if first {
+			xMin, yMin, xMax, yMax = box[0], box[1], box[2], box[3]
+			first = false
+			continue
+		}
+		xMin, yMin = min(xMin, box[0]), min(yMin, box[1])
+		xMax, yMax = max(xMax, box[2]), max(yMax, box[3])
+	}
+	return xMin, yMin, xMax, yMax
+}
+
 // parse deserializes the glyph description data.
 func (gd *glyphDescription) parse() error {
 	if gd.header != nil {
@@ -203,6 +286,309 @@ func (gd *glyphDescription) parseComposite(r *byteReader) error {
 	return nil
 }
 
+// Simple glyph flags.
+const (
+	onCurvePoint         = 0x01
+	xShortVector         = 0x02
+	yShortVector         = 0x04
+	repeatFlag           = 0x08
+	xIsSameOrPositiveXSV = 0x10
+	yIsSameOrPositiveYSV = 0x20
+	overlapSimple        = 0x40
+)
+
+// simpleGlyph is a simple glyph description decoded into its contours and the absolute
+// coordinates of its points, see decodeSimpleGlyph.
+type simpleGlyph struct {
+	endPts       []uint16 // Index of the last point of each contour.
+	instructions []byte
+	flags        []uint8 // On curve and overlap flags of each point, the coding flags cleared.
+	xs, ys       []int
+}
+
+// decodeSimpleGlyph decodes the simple glyph description at the start of `data`, with a header
+// of a non-negative numberOfContours.
+func decodeSimpleGlyph(data []byte) (*simpleGlyph, error) {
+	n, err := glyphDataLength(data)
+	if err != nil {
+		return nil, err
+	}
+	data = data[:n]
+	numberOfContours := int(int16(binary.BigEndian.Uint16(data)))
+	if numberOfContours < 0 {
+		return nil, fmt.Errorf("composite glyph: %w", errTypeCheck)
+	}
+	g := &simpleGlyph{endPts: make([]uint16, numberOfContours)}
+	pos := 10
+	for i := range g.endPts {
+		g.endPts[i] = binary.BigEndian.Uint16(data[pos:])
+		pos += 2
+	}
+	numPoints := 0
+	if numberOfContours > 0 {
+		numPoints = int(g.endPts[numberOfContours-1]) + 1
+	}
+	instructionLength := int(binary.BigEndian.Uint16(data[pos:]))
+	g.instructions = slices.Clone(data[pos+2 : pos+2+instructionLength])
+	pos += 2 + instructionLength
+
+	// glyphDataLength checked that the flags and coordinates are in `data`.
+	coding := make([]uint8, 0, numPoints)
+	for len(coding) < numPoints {
+		flag := data[pos]
+		pos++
+		repeat := 1
+		if flag&repeatFlag != 0 {
+			repeat += int(data[pos])
+			pos++
+		}
+		for range min(repeat, numPoints-len(coding)) {
+			coding = append(coding, flag)
+		}
+	}
+	coords := func(short, same uint8) []int {
+		vs := make([]int, numPoints)
+		v := 0
+		for i, flag := range coding {
+			switch {
+			case flag&short != 0:
+				d := int(data[pos])
+				pos++
+				if flag&same == 0 {
+					d = -d
+				}
+				v += d
+			case flag&same == 0:
+				v += int(int16(binary.BigEndian.Uint16(data[pos:])))
+				pos += 2
+			}
+			vs[i] = v
+		}
+		return vs
+	}
+	g.xs = coords(xShortVector, xIsSameOrPositiveXSV)
+	g.ys = coords(yShortVector, yIsSameOrPositiveYSV)
+	g.flags = make([]uint8, numPoints)
+	for i, flag := range coding {
+		g.flags[i] = flag & (onCurvePoint | overlapSimple)
+	}
+	return g, nil
+}
+
+// bounds returns the bounding box of the points of `g`, all zeros without points.
+func (g *simpleGlyph) bounds() (xMin, yMin, xMax, yMax int) {
+	if len(g.xs) == 0 {
+		return 0, 0, 0, 0
+	}
+	return slices.Min(g.xs), slices.Min(g.ys), slices.Max(g.xs), slices.Max(g.ys)
+}
+
+// encode returns the glyph description of `g` with a header of the bounding box of its points,
+// coding the coordinates as deltas in the fewest bytes and runs of flags with repeat counts.
+// The coordinates must fit in an int16.
+func (g *simpleGlyph) encode() []byte {
+	be := binary.BigEndian
+	xMin, yMin, xMax, yMax := g.bounds()
+	data := be.AppendUint16(nil, uint16(len(g.endPts)))
+	for _, v := range []int{xMin, yMin, xMax, yMax} {
+		data = be.AppendUint16(data, uint16(int16(v)))
+	}
+	for _, end := range g.endPts {
+		data = be.AppendUint16(data, end)
+	}
+	data = be.AppendUint16(data, uint16(len(g.instructions)))
+	data = append(data, g.instructions...)
+
+	coding := make([]uint8, len(g.xs))
+	var xData, yData []byte
+	delta := func(d int, short, same uint8, out []byte) (uint8, []byte) {
+		switch {
+		case d == 0:
+			return same, out
+		case d > 0 && d <= 0xFF:
+			return short | same, append(out, uint8(d))
+		case d < 0 && d >= -0xFF:
+			return short, append(out, uint8(-d))
+		}
+		return 0, be.AppendUint16(out, uint16(int16(d)))
+	}
+	x, y := 0, 0
+	for i := range g.xs {
+		var xFlag, yFlag uint8
+		xFlag, xData = delta(g.xs[i]-x, xShortVector, xIsSameOrPositiveXSV, xData)
+		yFlag, yData = delta(g.ys[i]-y, yShortVector, yIsSameOrPositiveYSV, yData)
+		coding[i] = g.flags[i] | xFlag | yFlag
+		x, y = g.xs[i], g.ys[i]
+	}
+	for i := 0; i < len(coding); {
+		run := 1
+		for i+run < len(coding) && coding[i+run] == coding[i] && run < 0x100 {
+			run++
+		}
+		if run > 1 {
+			data = append(data, coding[i]|repeatFlag, uint8(run-1))
+		} else {
+			data = append(data, coding[i])
+		}
+		i += run
+	}
+	return append(append(data, xData...), yData...)
+}
+
+// glyphDataLength returns the length of the glyph description at the start of `data` as
+// described by the description itself, without padding: header, contours, instructions, flags
+// and coordinates of a simple glyph, or the component records and instructions of a composite.
+func glyphDataLength(data []byte) (int, error) {
+	if len(data) < 10 {
+		return 0, fmt.Errorf("%d bytes too short for a glyph header: %w", len(data), errRangeCheck)
+	}
+	numberOfContours := int(int16(binary.BigEndian.Uint16(data)))
+	if numberOfContours < 0 {
+		return compositeDataLength(data)
+	}
+	pos := 10 + 2*numberOfContours
+	if pos+2 > len(data) {
+		return 0, fmt.Errorf("%d contours past the end: %w", numberOfContours, errRangeCheck)
+	}
+	numPoints := 0
+	if numberOfContours > 0 {
+		numPoints = int(binary.BigEndian.Uint16(data[pos-2:])) + 1
+	}
+	pos += 2 + int(binary.BigEndian.Uint16(data[pos:]))
+	coords := 0
+	for p := 0; p < numPoints; {
+		if pos >= len(data) {
+			return 0, fmt.Errorf("flags of point %d past the end: %w", p, errRangeCheck)
+		}
+		flag := data[pos]
+		pos++
+		repeat := 1
+		if flag&repeatFlag != 0 {
+			if pos >= len(data) {
+				return 0, fmt.Errorf("repeat count of point %d past the end: %w", p, errRangeCheck)
+			}
+			repeat += int(data[pos])
+			pos++
+		}
+		size := 0
+		if flag&xShortVector != 0 {
+			size++
+		} else if flag&xIsSameOrPositiveXSV == 0 {
+			size += 2
+		}
+		if flag&yShortVector != 0 {
+			size++
+		} else if flag&yIsSameOrPositiveYSV == 0 {
+			size += 2
+		}
+		coords += repeat * size
+		p += repeat
+	}
+	pos += coords
+	if pos > len(data) {
+		return 0, fmt.Errorf("coordinates end at %d past the end %d: %w", pos, len(data), errRangeCheck)
+	}
+	return pos, nil
+}
+
+// compositeDataLength returns the length of composite glyph description `data`, see
+// glyphDataLength.
+func compositeDataLength(data []byte) (int, error) {
+	pos := 10
+	instructions := false
+	for more := true; more; {
+		if pos+4 > len(data) {
+			return 0, fmt.Errorf("component past the end: %w", errRangeCheck)
+		}
+		flag := compositeGlyphFlag(binary.BigEndian.Uint16(data[pos:]))
+		pos += componentLength(flag)
+		instructions = instructions || flag.IsSet(weHaveInstructions)
+		more = flag.IsSet(moreComponents)
+	}
+	if instructions {
+		if pos+2 > len(data) {
+			return 0, fmt.Errorf("instruction length past the end: %w", errRangeCheck)
+		}
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos:]))
+	}
+	if pos > len(data) {
+		return 0, fmt.Errorf("components end at %d past the end %d: %w", pos, len(data), errRangeCheck)
+	}
+	return pos, nil
+}
+
+// componentLength returns the length of a component record with flags `flag`: the flags, the
+// glyph index, the arguments and the transformation.
+func componentLength(flag compositeGlyphFlag) int {
+	n := 4 + 2
+	if flag.IsSet(arg1And2AreWords) {
+		n += 2
+	}
+	switch {
+	case flag.IsSet(weHaveAScale):
+		n += 2
+	case flag.IsSet(weHaveAnXAndYScale):
+		n += 4
+	case flag.IsSet(weHaveATwoByTwo):
+		n += 8
+	}
+	return n
+}
+
+// componentIndexOffsets returns the offsets of the glyph indices of the components of composite
+// glyph description `data`, in order.
+func componentIndexOffsets(data []byte) ([]int, error) {
+	if _, err := compositeDataLength(data); err != nil {
+		return nil, err
+	}
+	var offsets []int
+	for pos, more := 10, true; more; {
+		flag := compositeGlyphFlag(binary.BigEndian.Uint16(data[pos:]))
+		offsets = append(offsets, pos+2)
+		pos += componentLength(flag)
+		more = flag.IsSet(moreComponents)
+	}
+	return offsets, nil
+}
+
+// components returns the glyphs that `gd` is composed of, in order, nil for a simple or empty
+// glyph.
+func (gd *glyphDescription) components() ([]GlyphIndex, error) {
+	if !gd.isComposite() {
+		return nil, nil
+	}
+	data := gd.raw
+	offsets, err := componentIndexOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	gids := make([]GlyphIndex, len(offsets))
+	for i, at := range offsets {
+		gids[i] = GlyphIndex(binary.BigEndian.Uint16(data[at:]))
+	}
+	return gids, nil
+}
+
+// remapComponents returns a copy of composite glyph description `data` with the glyph index of
+// each component replaced by its index in `newGID`, which must have them all. Instructions are
+// kept, as they refer to points rather than glyphs.
+func remapComponents(data []byte, newGID map[GlyphIndex]GlyphIndex) ([]byte, error) {
+	offsets, err := componentIndexOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	remapped := slices.Clone(data)
+	for _, at := range offsets {
+		gid := GlyphIndex(binary.BigEndian.Uint16(data[at:]))
+		ngid, ok := newGID[gid]
+		if !ok {
+			return nil, fmt.Errorf("component glyph %d not in the subset: %w", gid, errRangeCheck)
+		}
+		binary.BigEndian.PutUint16(remapped[at:], uint16(ngid))
+	}
+	return remapped, nil
+}
+
 type compositeGlyph struct {
 	components   []compositeComponent
 	instructions []uint8
@@ -215,9 +601,9 @@ type compositeComponent struct {
 	argument2  uint16 // uint8, int8, uint16 or int16.
 
 	// Optional transformation flags.
-	scale          *f2dot14 // same scale for x and y.
-	scaleX, scaleY *f2dot14 // x and y scales
-	a, b, c, d     *f2dot14 // 2x2
+	scale          *F2Dot14 // same scale for x and y.
+	scaleX, scaleY *F2Dot14 // x and y scales
+	a, b, c, d     *F2Dot14 // 2x2
 }
 
 type compositeGlyphFlag uint16
@@ -796,9 +1182,9 @@ type compositeGlyphDescriptionComponent struct {
 	argument2  uint16 // uint8, int8, uint16 or int16.
 
 	// Optional transformation flags.
-	scale          *f2dot14 // same scale for x and y.
-	scaleX, scaleY *f2dot14 // x and y scales
-	a, b, c, d     *f2dot14 // 2x2
+	scale          *F2Dot14 // same scale for x and y.
+	scaleX, scaleY *F2Dot14 // x and y scales
+	a, b, c, d     *F2Dot14 // 2x2
 }
 
 // gdLen is the length of the glyph data record according to the loca table.
@@ -833,21 +1219,21 @@ func (f *font) parseCompositeGlyphDescription(r *byteReader, gdLen int64) (*comp
 		}
 
 		if compositeGlyphFlag(comp.flags).IsSet(weHaveAScale) {
-			var scale f2dot14
+			var scale F2Dot14
 			err := r.read(&scale)
 			if err != nil {
 				return nil, err
 			}
 			comp.scale = &scale
 		} else if compositeGlyphFlag(comp.flags).IsSet(weHaveAnXAndYScale) {
-			var scaleX, scaleY f2dot14
+			var scaleX, scaleY F2Dot14
 			err := r.read(&scaleX, &scaleY)
 			if err != nil {
 				return nil, err
 			}
 			comp.scaleX, comp.scaleY = &scaleX, &scaleY
 		} else if compositeGlyphFlag(comp.flags).IsSet(weHaveATwoByTwo) {
-			var a, b, c, d f2dot14
+			var a, b, c, d F2Dot14
 			err := r.read(&a, &b, &c, &d)
 			if err != nil {
 				return nil, err
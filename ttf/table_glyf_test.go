@@ -0,0 +1,81 @@
+package ttf
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// compositeFixture returns Go Regular, which has no composite glyphs, with accented letters
+// made composite as in most fonts: 'é' of 'e' and the acute accent with byte offsets, and 'ë'
+// of that 'é', nested, with word offsets and instructions. It is written and parsed again.
+func compositeFixture(t *testing.T) (*Font, map[rune]GlyphIndex) {
+	t.Helper()
+	f := loadGoRegular(t)
+	g := map[rune]GlyphIndex{}
+	indices, runes := f.LookupRunes([]rune("eé´ë"))
+	for i, r := range runes {
+		g[r] = indices[i]
+	}
+	// numberOfContours -1 and the bounding box of 'e'.
+	header := func(base GlyphIndex) []byte {
+		return slices.Concat(u16s(-1), f.glyf.descs[base].raw[2:10])
+	}
+	const (
+		words, xy, more, instructions = 0x1, 0x2, 0x20, 0x100
+	)
+	eacute := slices.Concat(header(g['e']),
+		u16s(xy|more, int(g['e'])), []byte{0, 0},
+		u16s(xy, int(g['´'])), []byte{20, 0xF6})
+	ediaeresis := slices.Concat(header(g['e']),
+		u16s(words|xy|instructions, int(g['é']), 0, 0),
+		u16s(2), []byte{0xB0, 0x00}) // PUSHB[0] 0
+	f.glyf.descs[g['é']] = evenGlyph(&glyphDescription{raw: eacute})
+	f.glyf.descs[g['ë']] = evenGlyph(&glyphDescription{raw: ediaeresis})
+	f.loca, f.head.indexToLocFormat = buildLoca(f.glyf.descs, f.head.indexToLocFormat == 0)
+	f.markDirty(tagGlyf, tagLoca, tagHead)
+	f, _ = writeAndParse(t, f)
+	return f, g
+}
+
+func TestFont_SubsetComposites(t *testing.T) {
+	f, g := compositeFixture(t)
+	retain := DefaultSubsetOptions()
+	retain.RetainGIDs = true
+	for _, opts := range []SubsetOptions{DefaultSubsetOptions(), retain} {
+		sub, newGID, err := f.SubsetWithMap([]rune("ë"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The components are kept, those of the nested composite too.
+		for _, r := range "eé´ë" {
+			if _, ok := newGID[g[r]]; !ok {
+				t.Fatalf("RetainGIDs %t: glyph of %q not kept", opts.RetainGIDs, r)
+			}
+		}
+		written, data := writeAndParse(t, sub)
+		if err := ValidateBytes(data); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range "éë" {
+			desc := written.glyf.descs[newGID[g[r]]]
+			components, err := desc.components()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := f.glyf.descs[g[r]].components()
+			for i := range want {
+				want[i] = newGID[want[i]]
+			}
+			if !slices.Equal(components, want) {
+				t.Fatalf("RetainGIDs %t: %q has components %v, want %v", opts.RetainGIDs, r, components, want)
+			}
+		}
+		// Only the glyph indices change, the instructions are kept.
+		got := written.glyf.descs[newGID[g['ë']]].raw
+		if n, _ := glyphDataLength(got); !bytes.Equal(got[n-4:n], []byte{0, 2, 0xB0, 0x00}) {
+			t.Fatalf("RetainGIDs %t: ë is % X", opts.RetainGIDs, got)
+		}
+	}
+}
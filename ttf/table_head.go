@@ -7,6 +7,7 @@ package ttf
 
 import (
 	"errors"
+	"fmt"
 )
 
 // Font header.
@@ -14,7 +15,7 @@ import (
 type headTable struct {
 	majorVersion       uint16 // 00 01
 	minorVersion       uint16 // 00 00
-	fontRevision       fixed  // 00 01 CA 3D
+	fontRevision       Fixed  // 00 01 CA 3D
 	checksumAdjustment uint32 // 00 00 00 00
 	magicNumber        uint32 // 5F 0F 3C F5
 	flags              uint16
@@ -32,10 +33,13 @@ type headTable struct {
 	glyphDataFormat    int16
 }
 
+// headLength is the length of the head table, version 1.0 being the only one.
+const headLength = 54
+
 // parse the font's *head* table from `r` in the context of `f`.
 // TODO(gunnsth): Read the table as bytes first and then process? Probably easier in terms of checksumming etc.
 func (f *font) parseHead(r *byteReader) (*headTable, error) {
-	_, has, err := f.seekToTable(r, "head")
+	tr, has, err := f.seekToTable(r, tagHead)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +47,9 @@ func (f *font) parseHead(r *byteReader) (*headTable, error) {
 		// Does not have head.
 		return nil, nil
 	}
+	if tr.length < headLength {
+		return nil, fmt.Errorf("head of %d bytes, want %d: %w", tr.length, headLength, errRangeCheck)
+	}
 
 	t := &headTable{}
 	err = r.read(&t.majorVersion, &t.minorVersion, &t.fontRevision)
@@ -72,11 +79,21 @@ func (f *font) parseHead(r *byteReader) (*headTable, error) {
 	return t, r.read(&t.macStyle, &t.lowestRecPPEM, &t.fontDirectionHint, &t.indexToLocFormat, &t.glyphDataFormat)
 }
 
+// writeHead writes the head table of `f` to `w`, with checksumAdjustment 0 until it is known,
+// see headAdjustmentOffset, and the indexToLocFormat of the loca offsets that writeLoca writes.
 func (f *font) writeHead(w *byteWriter) error {
 	if f.head == nil {
 		return errRequiredField
 	}
-	t := f.head
+	t := *f.head
+	t.checksumAdjustment = 0
+	if f.loca != nil && f.maxp != nil {
+		format, err := f.loca.indexToLocFormat(int(f.maxp.numGlyphs))
+		if err != nil {
+			return err
+		}
+		t.indexToLocFormat = format
+	}
 	err := w.write(t.majorVersion, t.minorVersion, t.fontRevision, t.checksumAdjustment, t.magicNumber)
 	if err != nil {
 		return err
@@ -5,28 +5,39 @@
 
 package ttf
 
+import "math"
+
 // hheaTable represents the horizontal header table (hhea).
 // This table contains information for horizontal layout.
 // https://docs.microsoft.com/en-us/typography/opentype/spec/hhea
 type hheaTable struct {
 	majorVersion        uint16
 	minorVersion        uint16
-	ascender            fword
-	descender           fword
-	lineGap             fword
-	advanceWidthMax     ufword
-	minLeftSideBearing  fword
-	minRightSideBearing fword
-	xMaxExtent          fword
+	ascender            FWord
+	descender           FWord
+	lineGap             FWord
+	advanceWidthMax     UFWord
+	minLeftSideBearing  FWord
+	minRightSideBearing FWord
+	xMaxExtent          FWord
 	caretSlopeRise      int16
 	caretSlopeRun       int16
 	caretOffset         int16
+	reserved            [4]int16 // Written as 0.
 	metricDataFormat    int16
 	numberOfHMetrics    uint16 // Number of hMetric entries in 'hmtx' table.
 }
 
+// caretAngle returns the angle of the caret slope in degrees, see Font.CaretAngle.
+func (t *hheaTable) caretAngle() float64 {
+	if t.caretSlopeRun == 0 {
+		return 0
+	}
+	return -math.Atan2(float64(t.caretSlopeRun), float64(t.caretSlopeRise)) * 180 / math.Pi
+}
+
 func (f *font) parseHhea(r *byteReader) (*hheaTable, error) {
-	_, has, err := f.seekToTable(r, "hhea")
+	_, has, err := f.seekToTable(r, tagHhea)
 	if err != nil {
 		return nil, err
 	}
@@ -56,8 +67,7 @@ func (f *font) parseHhea(r *byteReader) (*hheaTable, error) {
 		return nil, err
 	}
 
-	// Skip over reserved bytes.
-	err = r.Skip(4 * 2)
+	err = r.read(&t.reserved[0], &t.reserved[1], &t.reserved[2], &t.reserved[3])
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,8 @@
 
 package ttf
 
+import "encoding/binary"
+
 type hmtxTable struct {
 	hMetrics         []longHorMetric // length is numberOfHMetrics from hhea table.
 	leftSideBearings []int16         // length is (numGlyphs - numberOfHmetrics) from maxp and hhea tables.
@@ -21,31 +23,34 @@ func (f *font) parseHmtx(r *byteReader) (*hmtxTable, error) {
 		return nil, errRequiredField
 	}
 
-	_, has, err := f.seekToTable(r, "hmtx")
+	_, has, err := f.seekToTable(r, tagHmtx)
 	if err != nil {
 		return nil, err
 	}
 	if !has {
-		// slog.Debug("hmtx table absent")
-		return nil, nil
+		// Glyph advances are unknown, see GlyphAdvance. Subset synthesizes hmtx.
+		return nil, f.recordIncompatibilityf("hmtx table missing")
 	}
 
 	t := &hmtxTable{}
 
+	// The longHorMetric records are read as advanceWidth, lsb pairs in one go.
 	numberOfHMetrics := int(f.hhea.numberOfHMetrics)
-	for i := 0; i < numberOfHMetrics; i++ {
-		var lhm longHorMetric
-		err := r.read(&lhm.advanceWidth, &lhm.lsb)
-		if err != nil {
-			return nil, err
+	var pairs []uint16
+	err = readSliceOf(r, &pairs, 2*numberOfHMetrics)
+	if err != nil {
+		return nil, err
+	}
+	if numberOfHMetrics > 0 {
+		t.hMetrics = make([]longHorMetric, numberOfHMetrics)
+		for i := range t.hMetrics {
+			t.hMetrics[i] = longHorMetric{advanceWidth: pairs[2*i], lsb: int16(pairs[2*i+1])}
 		}
-
-		t.hMetrics = append(t.hMetrics, lhm)
 	}
 
 	lsbLen := int(f.maxp.numGlyphs) - numberOfHMetrics
 	if lsbLen > 0 {
-		err = r.readSlice(&t.leftSideBearings, lsbLen)
+		err = readSliceOf(r, &t.leftSideBearings, lsbLen)
 		if err != nil {
 			return nil, err
 		}
@@ -54,29 +59,74 @@ func (f *font) parseHmtx(r *byteReader) (*hmtxTable, error) {
 	return t, nil
 }
 
-// optimizeHmtx optimizes the htmx table.
-func (f *font) optimizeHmtx() {
-	i := len(f.hmtx.hMetrics) - 1
-	if i <= 0 {
-		return
+// glyphAdvance returns the advance width of glyph `gid`, which glyphs past the hMetrics share
+// with the last one. It returns false without hmtx.
+func (f *font) glyphAdvance(gid GlyphIndex) (uint16, bool) {
+	m, ok := f.glyphMetric(gid)
+	return m.advanceWidth, ok
+}
+
+// glyphMetric returns the advance width and left side bearing of glyph `gid`. Glyphs past the
+// hMetrics share the advance of the last one and have their own left side bearing in
+// leftSideBearings. It returns false without hmtx.
+func (f *font) glyphMetric(gid GlyphIndex) (longHorMetric, bool) {
+	if f.hmtx == nil || len(f.hmtx.hMetrics) == 0 {
+		return longHorMetric{}, false
+	}
+	n := len(f.hmtx.hMetrics)
+	m := f.hmtx.hMetrics[min(int(gid), n-1)]
+	if i := int(gid) - n; i >= 0 && i < len(f.hmtx.leftSideBearings) {
+		m.lsb = f.hmtx.leftSideBearings[i]
+	}
+	return m, true
+}
+
+// defaultAdvance returns the advance width given to all glyphs of a font without hmtx:
+// hhea.advanceWidthMax if set, otherwise half an em.
+func (f *font) defaultAdvance() uint16 {
+	if f.hhea != nil && f.hhea.advanceWidthMax > 0 {
+		return uint16(f.hhea.advanceWidthMax)
 	}
-	lastWidth := f.hmtx.hMetrics[i].advanceWidth
-	j := i - 1
-	for j >= 0 && f.hmtx.hMetrics[j].advanceWidth == lastWidth {
-		j--
+	if f.head != nil {
+		return f.head.unitsPerEm / 2
 	}
-	numStrip := i - j - 1
-	if numStrip == 0 {
-		return
+	return 0
+}
+
+// synthesizeHmtx returns an hmtx table for glyphs `descs` of a font without one, giving every
+// glyph the defaultAdvance and its xMin as left side bearing.
+func (f *font) synthesizeHmtx(descs []*glyphDescription) *hmtxTable {
+	t := &hmtxTable{hMetrics: make([]longHorMetric, len(descs))}
+	advance := f.defaultAdvance()
+	for i, gd := range descs {
+		t.hMetrics[i].advanceWidth = advance
+		if gd.hasOutline() {
+			t.hMetrics[i].lsb = int16(binary.BigEndian.Uint16(gd.raw[2:]))
+		}
 	}
+	return t
+}
 
-	f.hhea.numberOfHMetrics = uint16(j + 2)
-	var lsbPrepend []int16
-	for k := j + 2; k <= i; k++ {
-		lsbPrepend = append(lsbPrepend, f.hmtx.hMetrics[k].lsb)
+// optimizeHmtx moves the left side bearings of the trailing run of hMetrics with the advance
+// of the last one, but for the first of the run, to leftSideBearings, and sets
+// hhea.numberOfHMetrics to the hMetrics left, see SubsetOptions.OptimizeHmtx.
+func (f *font) optimizeHmtx() {
+	if n := len(f.hmtx.hMetrics); n > 1 {
+		last := f.hmtx.hMetrics[n-1].advanceWidth
+		first := n - 1 // First of the run.
+		for first > 0 && f.hmtx.hMetrics[first-1].advanceWidth == last {
+			first--
+		}
+		lsbs := make([]int16, 0, n-1-first+len(f.hmtx.leftSideBearings))
+		for _, m := range f.hmtx.hMetrics[first+1:] {
+			lsbs = append(lsbs, m.lsb)
+		}
+		f.hmtx.leftSideBearings = append(lsbs, f.hmtx.leftSideBearings...)
+		f.hmtx.hMetrics = f.hmtx.hMetrics[:first+1]
+	}
+	if f.hhea != nil {
+		f.hhea.numberOfHMetrics = uint16(len(f.hmtx.hMetrics))
 	}
-	f.hmtx.leftSideBearings = append(lsbPrepend, f.hmtx.leftSideBearings...)
-	f.hmtx.hMetrics = f.hmtx.hMetrics[0 : j+2]
 }
 
 // writeHmtx writes the font's hmtx table  to `w`.
@@ -85,12 +135,14 @@ func (f *font) writeHmtx(w *byteWriter) error {
 		return nil
 	}
 
+	pairs := make([]uint16, 0, 2*len(f.hmtx.hMetrics))
 	for _, lhm := range f.hmtx.hMetrics {
-		err := w.write(lhm.advanceWidth, lhm.lsb)
-		if err != nil {
-			return err
-		}
+		pairs = append(pairs, lhm.advanceWidth, uint16(lhm.lsb))
+	}
+	err := writeSliceOf(w, pairs)
+	if err != nil {
+		return err
 	}
 
-	return w.writeSlice(f.hmtx.leftSideBearings)
+	return writeSliceOf(w, f.hmtx.leftSideBearings)
 }
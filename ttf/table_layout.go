@@ -0,0 +1,99 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// rawTable is a table kept as its bytes and written out verbatim.
+type rawTable struct {
+	tag  Tag
+	data []byte
+}
+
+// setRawTable sets the data of table `tag` of `f` to be written verbatim, replacing that of a
+// table with the same tag passed through or in the file `f` was parsed from.
+func (f *font) setRawTable(tag Tag, data []byte) {
+	// The list may be shared with fonts made from `f`.
+	i := slices.IndexFunc(f.rawTables, func(t rawTable) bool { return t.tag == tag })
+	if i < 0 {
+		f.rawTables = append(slices.Clip(f.rawTables), rawTable{tag: tag, data: data})
+		return
+	}
+	f.rawTables = slices.Clone(f.rawTables)
+	f.rawTables[i].data = data
+}
+
+// layoutTags are the OpenType layout tables. They refer to glyphs by index, so they are only
+// valid in subsets that keep the glyph indices of the font.
+var layoutTags = []Tag{tagGDEF, tagGPOS, tagGSUB}
+
+// readRawTables reads the tables of `f` with `tags` from `r`, skipping the absent ones.
+func (f *font) readRawTables(r *byteReader, tags []Tag) ([]rawTable, error) {
+	var tables []rawTable
+	for _, tag := range tags {
+		tr, has, err := f.seekToTable(r, tag)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			continue
+		}
+		t := rawTable{tag: tag}
+		if err := r.readBytes(&t.data, int(tr.length)); err != nil {
+			return nil, fmt.Errorf("%s: %w", tag, err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// layoutFeatures returns the sorted, distinct feature tags of the FeatureList of GSUB or GPOS
+// table `data`, or nil if it cannot be read.
+func layoutFeatures(data []byte) []Tag {
+	// Header: majorVersion, minorVersion, scriptListOffset, featureListOffset, ...
+	if len(data) < 10 {
+		return nil
+	}
+	at := int(binary.BigEndian.Uint16(data[6:]))
+	if at == 0 || at+2 > len(data) {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(data[at:]))
+	// FeatureRecord: featureTag, featureOffset.
+	records := data[at+2:]
+	if 6*count > len(records) {
+		return nil
+	}
+	features := make([]Tag, count)
+	for i := range features {
+		copy(features[i][:], records[6*i:])
+	}
+	slices.SortFunc(features, func(a, b Tag) int { return strings.Compare(string(a[:]), string(b[:])) })
+	return slices.Compact(features)
+}
+
+// describeLayout describes layout tables `tables` for a warning, listing the features of GSUB
+// and GPOS, e.g. "GDEF, GPOS (features kern, mark)".
+func describeLayout(tables []rawTable) string {
+	var parts []string
+	for _, t := range tables {
+		s := t.tag.String()
+		if features := layoutFeatures(t.data); len(features) > 0 {
+			names := make([]string, len(features))
+			for i, feature := range features {
+				names[i] = feature.String()
+			}
+			s += " (features " + strings.Join(names, ", ") + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,25 @@
+package ttf
+
+import "testing"
+
+// layoutFixture returns Go Regular with a GDEF table and a GSUB table with features liga and
+// ccmp, the table data being returned too.
+func layoutFixture(t *testing.T) (*Font, map[Tag][]byte) {
+	t.Helper()
+	gsub := []byte{
+		0, 1, 0, 0, // version 1.0
+		0, 10, 0, 12, 0, 38, // ScriptList, FeatureList and LookupList offsets
+		0, 0, // ScriptList: no scripts
+		0, 3, // FeatureList: 3 features
+		'l', 'i', 'g', 'a', 0, 20,
+		'c', 'c', 'm', 'p', 0, 20,
+		'l', 'i', 'g', 'a', 0, 20,
+		0, 0, 0, 0, // Feature: no parameters or lookups
+		0, 0, // LookupList: no lookups
+	}
+	gdef := []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	f := loadGoRegular(t)
+	f.rawTables = []rawTable{{tagGDEF, gdef}, {tagGSUB, gsub}}
+	g, _ := writeAndParse(t, f)
+	return g, map[Tag][]byte{tagGDEF: gdef, tagGSUB: gsub}
+}
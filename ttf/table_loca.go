@@ -7,6 +7,8 @@ package ttf
 
 import (
 	"errors"
+	"fmt"
+	"math"
 )
 
 // locaTable represents the Index to Location (loca) table.
@@ -29,8 +31,11 @@ func (f *font) GetGlyphDataOffset(gid GlyphIndex) (offset int64, len int64, err
 		return 0, 0, errRangeCheck
 	}
 
-	short := f.head.indexToLocFormat == 0
-	if short {
+	format, err := f.loca.indexToLocFormat(int(f.maxp.numGlyphs))
+	if err != nil {
+		return 0, 0, err
+	}
+	if format == 0 {
 		offset1 := 2 * int64(f.loca.offsetsShort[gid])
 		offset2 := 2 * int64(f.loca.offsetsShort[gid+1])
 		return offset1, offset2 - offset1, nil
@@ -47,7 +52,7 @@ func (f *font) parseLoca(r *byteReader) (*locaTable, error) {
 		return nil, errRequiredField
 	}
 
-	_, has, err := f.seekToTable(r, "loca")
+	_, has, err := f.seekToTable(r, tagLoca)
 	if err != nil {
 		return nil, err
 	}
@@ -67,14 +72,14 @@ func (f *font) parseLoca(r *byteReader) (*locaTable, error) {
 	isShort := f.head.indexToLocFormat == 0
 
 	if isShort {
-		err := r.readSlice(&loca.offsetsShort, numGlyphs+1)
+		err := readSliceOf(r, &loca.offsetsShort, numGlyphs+1)
 		if err != nil {
 			return nil, err
 		}
 		return loca, nil
 	}
 
-	err = r.readSlice(&loca.offsetsLong, numGlyphs+1)
+	err = readSliceOf(r, &loca.offsetsLong, numGlyphs+1)
 	if err != nil {
 		return nil, err
 	}
@@ -95,19 +100,67 @@ func (f *font) parseLoca(r *byteReader) (*locaTable, error) {
 	return loca, nil
 }
 
+// writeLoca writes the loca table of `f` to `w` in the format of the offsets it has, which
+// writeHead gives as indexToLocFormat.
 func (f *font) writeLoca(w *byteWriter) error {
-	if f.loca == nil || f.head == nil || f.maxp == nil {
+	if f.loca == nil || f.maxp == nil {
 		return errRequiredField
 	}
-	numGlyphs := int(f.maxp.numGlyphs)
-	isShort := f.head.indexToLocFormat == 0
+	format, err := f.loca.indexToLocFormat(int(f.maxp.numGlyphs))
+	if err != nil {
+		return err
+	}
+	if format == 0 {
+		return writeSliceOf(w, f.loca.offsetsShort)
+	}
+	return writeSliceOf(w, f.loca.offsetsLong)
+}
 
-	t := f.loca
-	if isShort {
-		if numGlyphs+1 != len(t.offsetsShort) {
-			// slog.Debug("Unexpected length")
+// indexToLocFormat returns the format of the offsets of `t`, 0 for short and 1 for long ones.
+// It is an error for `t` to have both or neither, or other than `numGlyphs`+1 offsets: fonts
+// written from it could not be read.
+func (t *locaTable) indexToLocFormat(numGlyphs int) (int16, error) {
+	short, long := len(t.offsetsShort), len(t.offsetsLong)
+	var format int16
+	n := short
+	switch {
+	case short > 0 && long > 0:
+		return 0, fmt.Errorf("loca has both %d short and %d long offsets: %w", short, long, errTypeCheck)
+	case short == 0 && long == 0:
+		return 0, fmt.Errorf("loca has no offsets: %w", errTypeCheck)
+	case long > 0:
+		format, n = 1, long
+	}
+	if n != numGlyphs+1 {
+		return 0, fmt.Errorf("loca has %d offsets for %d glyphs: %w", n, numGlyphs, errRangeCheck)
+	}
+	return format, nil
+}
+
+// buildLoca returns the loca table for glyph descriptions `descs` written back to back from the
+// start of glyf, and the indexToLocFormat it takes. Empty glyphs, e.g. space, take no bytes and
+// so share their offset with the next glyph; the last entry is the glyf length. Short offsets
+// are used if `short` is set and every offset is even and in range, the caller pads the glyph
+// data to even lengths for that (see evenGlyph).
+func buildLoca(descs []*glyphDescription, short bool) (*locaTable, int16) {
+	offsets := make([]int, len(descs)+1)
+	for i, desc := range descs {
+		offsets[i+1] = offsets[i] + len(desc.raw)
+		short = short && len(desc.raw)%2 == 0
+	}
+	short = short && offsets[len(descs)] <= 2*math.MaxUint16
+
+	loca := &locaTable{}
+	if short {
+		loca.offsetsShort = make([]offset16, len(offsets))
+		for i, off := range offsets {
+			loca.offsetsShort[i] = offset16(off / 2)
 		}
-		return w.writeSlice(t.offsetsShort)
+		return loca, 0
+	}
+	loca.offsetsLong = make([]offset32, len(offsets))
+	for i, off := range offsets {
+		loca.offsetsLong[i] = offset32(off)
 	}
-	return w.writeSlice(t.offsetsLong)
+	return loca, 1
 }
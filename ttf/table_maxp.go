@@ -9,7 +9,7 @@ package ttf
 // This table establishes the memory requirements for the font.
 type maxpTable struct {
 	// Version 0.5 and above:
-	version   fixed
+	version   Fixed
 	numGlyphs uint16
 
 	// Version 1.0 and above:
@@ -28,8 +28,15 @@ type maxpTable struct {
 	maxComponentDepth     uint16
 }
 
+// The versions of maxp: 0.5 has numGlyphs only and is for fonts with CFF outlines, 1.0 adds the
+// maxima for TrueType outlines.
+const (
+	maxpVersion05 Fixed = 0x00005000
+	maxpVersion10 Fixed = 0x00010000
+)
+
 func (f *font) parseMaxp(r *byteReader) (*maxpTable, error) {
-	_, has, err := f.seekToTable(r, "maxp")
+	_, has, err := f.seekToTable(r, tagMaxp)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +52,10 @@ func (f *font) parseMaxp(r *byteReader) (*maxpTable, error) {
 		return nil, err
 	}
 
-	if t.version < 0x00010000 {
+	if t.version == maxpVersion05 {
+		return t, nil
+	}
+	if t.version < maxpVersion10 {
 		// slog.Debug("Range check error")
 		return nil, errRangeCheck
 	}
@@ -63,17 +73,26 @@ func (f *font) parseMaxp(r *byteReader) (*maxpTable, error) {
 	return t, r.read(&t.maxStackElements, &t.maxSizeOfInstructions, &t.maxComponentElements, &t.maxComponentDepth)
 }
 
+// writeMaxp writes maxp in the version it was read in, or in version 0.5 if `f` is written with
+// CFF outlines only.
 func (f *font) writeMaxp(w *byteWriter) error {
 	if f.maxp == nil {
 		return errRequiredField
 	}
 	t := f.maxp
-	err := w.write(t.version, t.numGlyphs)
+	version := t.version
+	if f.glyf == nil && f.hasCFF() {
+		version = maxpVersion05
+	}
+	err := w.write(version, t.numGlyphs)
 	if err != nil {
 		return err
 	}
 
-	if t.version < 0x00010000 {
+	if version == maxpVersion05 {
+		return nil
+	}
+	if version < maxpVersion10 {
 		// slog.Debug("Range check error")
 		return errRangeCheck
 	}
@@ -90,3 +109,8 @@ func (f *font) writeMaxp(w *byteWriter) error {
 
 	return w.write(t.maxStackElements, t.maxSizeOfInstructions, t.maxComponentElements, t.maxComponentDepth)
 }
+
+// hasCFF reports whether `f` has CFF or CFF2 outlines, which are not parsed but passed through.
+func (f *font) hasCFF() bool {
+	return f.hasTable(tagCFF) || f.hasTable(tagCFF2)
+}
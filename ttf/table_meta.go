@@ -0,0 +1,208 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"slices"
+	"strings"
+)
+
+// metaTable represents the metadata table (meta).
+// Its data maps hold metadata by tag, such as the languages that the font is designed for (dlng)
+// and those it supports (slng), which font fallback on Android and CSS font matching go by.
+// https://learn.microsoft.com/en-us/typography/opentype/spec/meta
+type metaTable struct {
+	version  uint32
+	flags    uint32
+	dataMaps []metaDataMap // In the order of the file, written back in the same order.
+}
+
+// metaDataMap is a data map of the meta table, its data written after the data map records.
+type metaDataMap struct {
+	tag  Tag
+	data []byte
+}
+
+var (
+	metaTagDlng = MustTag("dlng")
+	metaTagSlng = MustTag("slng")
+)
+
+func (f *font) parseMeta(r *byteReader) (*metaTable, error) {
+	tr, has, err := f.seekToTable(r, tagMeta)
+	if err != nil {
+		return nil, err
+	}
+	if !has || tr == nil {
+		// slog.Debug("meta table absent")
+		return nil, nil
+	}
+
+	t := &metaTable{}
+	var reserved, dataMapsCount uint32
+	err = r.read(&t.version, &t.flags, &reserved, &dataMapsCount)
+	if err != nil {
+		return nil, err
+	}
+	if fit := max((int64(tr.length)-16)/12, 0); int64(dataMapsCount) > fit {
+		err := f.recordIncompatibilityf("dataMapsCount %d past the meta length %d, truncated", dataMapsCount, tr.length)
+		if err != nil {
+			return nil, err
+		}
+		dataMapsCount = uint32(fit)
+	}
+
+	type record struct {
+		tag                Tag
+		dataOffset, length uint32
+	}
+	records := make([]record, dataMapsCount)
+	for i := range records {
+		err = r.read(&records[i].tag, &records[i].dataOffset, &records[i].length)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, rec := range records {
+		if int64(rec.dataOffset)+int64(rec.length) > int64(tr.length) {
+			err := f.recordIncompatibilityf("%s data at %d+%d past the meta length %d, dropped", rec.tag, rec.dataOffset, rec.length, tr.length)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = r.SeekTo(int64(tr.offset) + int64(rec.dataOffset))
+		if err != nil {
+			return nil, err
+		}
+		m := metaDataMap{tag: rec.tag}
+		err = r.readBytes(&m.data, int(rec.length))
+		if err != nil {
+			return nil, err
+		}
+		t.dataMaps = append(t.dataMaps, m)
+	}
+	return t, nil
+}
+
+func (f *font) writeMeta(w *byteWriter) error {
+	if f.meta == nil {
+		return nil
+	}
+	t := f.meta
+
+	err := w.write(t.version, t.flags, uint32(0), uint32(len(t.dataMaps)))
+	if err != nil {
+		return err
+	}
+	offset := 16 + 12*len(t.dataMaps)
+	for _, m := range t.dataMaps {
+		err = w.write(m.tag, uint32(offset), uint32(len(m.data)))
+		if err != nil {
+			return err
+		}
+		offset += len(m.data)
+	}
+	for _, m := range t.dataMaps {
+		err = w.writeBytes(m.data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptLangTags returns the ScriptLangTags of the data map `tag` of `t`, e.g. dlng, which is
+// a comma-separated list of them, nil without the data map.
+func (t *metaTable) scriptLangTags(tag Tag) []string {
+	i := slices.IndexFunc(t.dataMaps, func(m metaDataMap) bool { return m.tag == tag })
+	if i < 0 {
+		return nil
+	}
+	var tags []string
+	for s := range strings.SplitSeq(string(t.dataMaps[i].data), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			tags = append(tags, s)
+		}
+	}
+	return tags
+}
+
+// trimSupported returns a copy of `t` with the slng ScriptLangTags of scripts that `covered`
+// reports false for removed, keeping those of other or unknown scripts, see
+// scriptLangTagScripts. Without any left, slng is dropped.
+func (t *metaTable) trimSupported(covered func(script string) bool) *metaTable {
+	i := slices.IndexFunc(t.dataMaps, func(m metaDataMap) bool { return m.tag == metaTagSlng })
+	if i < 0 {
+		return t
+	}
+	tags := slices.DeleteFunc(t.scriptLangTags(metaTagSlng), func(tag string) bool {
+		scripts := scriptLangTagScripts(tag)
+		return len(scripts) > 0 && !slices.ContainsFunc(scripts, covered)
+	})
+	trimmed := &metaTable{version: t.version, flags: t.flags, dataMaps: slices.Clone(t.dataMaps)}
+	if len(tags) == 0 {
+		trimmed.dataMaps = slices.Delete(trimmed.dataMaps, i, i+1)
+	} else {
+		trimmed.dataMaps[i] = metaDataMap{tag: metaTagSlng, data: []byte(strings.Join(tags, ", "))}
+	}
+	return trimmed
+}
+
+// scriptLangTagScripts returns the scripts of package unicode, e.g. "Latin", written in by the
+// ISO 15924 script subtag of ScriptLangTag `tag`, e.g. "Cyrl" of "sr-Cyrl" or "Hans", any one
+// of which is enough. It is nil without a script subtag or for scripts not in isoScripts.
+func scriptLangTagScripts(tag string) []string {
+	for sub := range strings.SplitSeq(tag, "-") {
+		if len(sub) == 4 {
+			return isoScripts[strings.ToUpper(sub[:1])+strings.ToLower(sub[1:])]
+		}
+	}
+	return nil
+}
+
+// isoScripts maps ISO 15924 script codes to the scripts of package unicode that they cover.
+// The codes for combinations of scripts, such as Jpan, list each one.
+var isoScripts = map[string][]string{
+	"Arab": {"Arabic"},
+	"Armn": {"Armenian"},
+	"Beng": {"Bengali"},
+	"Bopo": {"Bopomofo"},
+	"Cher": {"Cherokee"},
+	"Cyrl": {"Cyrillic"},
+	"Deva": {"Devanagari"},
+	"Ethi": {"Ethiopic"},
+	"Geor": {"Georgian"},
+	"Grek": {"Greek"},
+	"Gujr": {"Gujarati"},
+	"Guru": {"Gurmukhi"},
+	"Hang": {"Hangul"},
+	"Hani": {"Han"},
+	"Hans": {"Han"},
+	"Hant": {"Han"},
+	"Hebr": {"Hebrew"},
+	"Hira": {"Hiragana"},
+	"Hrkt": {"Hiragana", "Katakana"},
+	"Jpan": {"Han", "Hiragana", "Katakana"},
+	"Kana": {"Katakana"},
+	"Khmr": {"Khmer"},
+	"Knda": {"Kannada"},
+	"Kore": {"Hangul", "Han"},
+	"Laoo": {"Lao"},
+	"Latn": {"Latin"},
+	"Mlym": {"Malayalam"},
+	"Mong": {"Mongolian"},
+	"Mymr": {"Myanmar"},
+	"Orya": {"Oriya"},
+	"Sinh": {"Sinhala"},
+	"Syrc": {"Syriac"},
+	"Taml": {"Tamil"},
+	"Telu": {"Telugu"},
+	"Thaa": {"Thaana"},
+	"Thai": {"Thai"},
+	"Tibt": {"Tibetan"},
+	"Yiii": {"Yi"},
+}
@@ -0,0 +1,83 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// metaFixture returns Go Regular with a meta table like that of Noto Sans, with the design
+// and supported languages and a binary data map, as written and parsed again, and its data.
+func metaFixture(t *testing.T) (*Font, []byte) {
+	t.Helper()
+	f := loadGoRegular(t)
+	f.meta = &metaTable{version: 1, dataMaps: []metaDataMap{
+		{tag: metaTagDlng, data: []byte("Latn, Grek, Cyrl")},
+		{tag: metaTagSlng, data: []byte("Latn, Grek, Cyrl, Hans, en, sr-Cyrl")},
+		{tag: MustTag("appl"), data: []byte{0, 1, 2}},
+	}}
+	f.markDirty(tagMeta)
+	f, data := writeAndParse(t, f)
+	if err := ValidateBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	return f, data
+}
+
+func TestFont_MetaTags(t *testing.T) {
+	if design, supported := loadGoRegular(t).MetaTags(); design != nil || supported != nil {
+		t.Fatalf("Go Regular: MetaTags() = %q, %q", design, supported)
+	}
+
+	f, data := metaFixture(t)
+	design, supported := f.MetaTags()
+	if !slices.Equal(design, []string{"Latn", "Grek", "Cyrl"}) ||
+		!slices.Equal(supported, []string{"Latn", "Grek", "Cyrl", "Hans", "en", "sr-Cyrl"}) {
+		t.Fatalf("MetaTags() = %q, %q", design, supported)
+	}
+	meta := tableData(t, data)[tagMeta]
+	if len(meta) != 16+3*12+16+35+3 || binary.BigEndian.Uint32(meta[12:]) != 3 {
+		t.Fatalf("meta written as % X", meta)
+	}
+
+	subset := func(runes string, opts SubsetOptions) (*Font, []byte) {
+		sub, err := f.SubsetWithOptions([]rune(runes), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writeAndParse(t, sub)
+	}
+	// Kept as it is by default.
+	if _, data := subset("Hello", DefaultSubsetOptions()); !bytes.Equal(tableData(t, data)[tagMeta], meta) {
+		t.Fatalf("subset meta is % X, want % X", tableData(t, data)[tagMeta], meta)
+	}
+
+	opts := DefaultSubsetOptions()
+	opts.TrimMetaLanguages = true
+	tests := []struct {
+		runes string
+		want  []string
+	}{
+		{"Hello", []string{"Latn", "en"}},
+		{"Hello Ωμέγα мир", []string{"Latn", "Grek", "Cyrl", "en", "sr-Cyrl"}},
+		{"中文", []string{"en"}}, // Go Regular has no Han, so not even Hans is kept.
+		{"123", []string{"en"}},
+	}
+	for _, tt := range tests {
+		sub, _ := subset(tt.runes, opts)
+		design, supported := sub.MetaTags()
+		if !slices.Equal(design, []string{"Latn", "Grek", "Cyrl"}) || !slices.Equal(supported, tt.want) {
+			t.Fatalf("%q: MetaTags() = %q, %q, want supported %q", tt.runes, design, supported, tt.want)
+		}
+		if m := sub.meta.dataMaps[2]; m.tag != MustTag("appl") || !bytes.Equal(m.data, []byte{0, 1, 2}) {
+			t.Fatalf("%q: appl data map is %v", tt.runes, m)
+		}
+	}
+
+	f.meta.dataMaps[1].data = []byte("Hans")
+	sub, _ := subset("Hello", opts)
+	if _, supported := sub.MetaTags(); supported != nil {
+		t.Fatalf("expected slng to be dropped, got %q", supported)
+	}
+}
@@ -7,9 +7,12 @@ package ttf
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
@@ -49,20 +52,45 @@ type nameRecord struct {
 }
 
 // GetNameByID returns the first entry according to the name table with `nameID`.
-// An empty string is returned otherwise (nothing found).
+// An empty string is returned otherwise (nothing found). Name takes a NameID.
 func (f *font) GetNameByID(nameID int) string {
+	return f.Name(NameID(nameID))
+}
+
+// Name returns the first entry of the name table with `nameID`, e.g. NameIDFamily, or an empty
+// string if there is none.
+func (f *font) Name(nameID NameID) string {
 	if f == nil || f.name == nil {
 		// slog.Debug("ERROR: Font or name not set")
 		return ""
 	}
 	for _, nr := range f.name.nameRecords {
-		if int(nr.nameID) == nameID {
+		if NameID(nr.nameID) == nameID {
 			return nr.Decoded()
 		}
 	}
 	return ""
 }
 
+// SetNameByID sets every entry of the name table with `nameID` to `value`, encoded for the
+// platform of the record. Returns false if there is no such entry.
+func (f *font) SetNameByID(nameID NameID, value string) bool {
+	if f == nil || f.name == nil {
+		return false
+	}
+	found := false
+	for _, nr := range f.name.nameRecords {
+		if NameID(nr.nameID) == nameID {
+			nr.setDecoded(value)
+			found = true
+		}
+	}
+	if found {
+		f.markDirty(tagName)
+	}
+	return found
+}
+
 // GetNameRecords returns name records as map of language ID
 // that contais name ID and it's value.
 func (f *font) GetNameRecords() map[uint16]map[uint16]string {
@@ -79,6 +107,78 @@ func (f *font) GetNameRecords() map[uint16]map[uint16]string {
 	return nameRecords
 }
 
+// NameRecord is a decoded string of the name table.
+type NameRecord struct {
+	PlatformID PlatformID
+	EncodingID EncodingID
+	LanguageID uint16
+	NameID     NameID
+	Lang       string // BCP-47 language tag, e.g. "en-US", or "" if not known.
+	Value      string
+}
+
+// Names returns the records of the name table in table order with their strings decoded.
+func (f *font) Names() []NameRecord {
+	if f == nil || f.name == nil {
+		return nil
+	}
+	names := make([]NameRecord, 0, len(f.name.nameRecords))
+	for _, nr := range f.name.nameRecords {
+		names = append(names, NameRecord{
+			PlatformID: PlatformID(nr.platformID),
+			EncodingID: EncodingID(nr.encodingID),
+			LanguageID: nr.languageID,
+			NameID:     NameID(nr.nameID),
+			Lang:       languageTag(PlatformID(nr.platformID), nr.languageID, f.name.langTagRecords),
+			Value:      nr.Decoded(),
+		})
+	}
+	return names
+}
+
+// NameForLang returns the string with `nameID` best matching BCP-47 language tag `lang`, e.g.
+// "ja" or "zh-TW": the first record with that exact tag (ignoring case), else with the same
+// base language, else in English (en-US first), else the first record with `nameID`.
+// An empty string is returned if there is no record with `nameID`.
+func (f *font) NameForLang(nameID NameID, lang string) string {
+	nr, _ := f.nameRecordForLang(nameID, lang)
+	return nr.Value
+}
+
+// nameRecordForLang returns the record with `nameID` best matching `lang` as NameForLang does.
+// It returns false if there is no record with `nameID`.
+func (f *font) nameRecordForLang(nameID NameID, lang string) (NameRecord, bool) {
+	base, _, _ := strings.Cut(lang, "-")
+	var best NameRecord
+	bestScore := -1
+	for _, nr := range f.Names() {
+		if nr.NameID != nameID {
+			continue
+		}
+		tagBase, _, _ := strings.Cut(nr.Lang, "-")
+		score := 0
+		switch {
+		case nr.Lang != "" && strings.EqualFold(nr.Lang, lang):
+			score = 4
+		case tagBase != "" && strings.EqualFold(tagBase, base):
+			score = 3
+		case strings.EqualFold(nr.Lang, "en-US"):
+			score = 2
+		case strings.EqualFold(tagBase, "en"):
+			score = 1
+		}
+		if score > bestScore {
+			best, bestScore = nr, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// FamilyNameForLang returns the family name (NameIDFamily) for `lang` as NameForLang does.
+func (f *font) FamilyNameForLang(lang string) string {
+	return f.NameForLang(NameIDFamily, lang)
+}
+
 // numPrintables returns the number of printable runes in `str`
 func numPrintables(str string) int {
 	printables := 0
@@ -106,21 +206,11 @@ func makePrintable(str string) string {
 // Decoded attempts to decode the underlying data and convert to a string.
 // NOTE: Works in many cases but often has some -garbage- around texts.
 func (nr nameRecord) Decoded() string {
-	switch nr.platformID {
-	case 0: // unicode
-		// TODO(gunnsth): Untested as have not encountered this yet.
-		dup := make([]byte, len(nr.data))
-		copy(dup, nr.data)
-		var decoded bytes.Buffer
-
-		for len(dup) > 0 {
-			r, size := utf8.DecodeRune(dup)
-			dup = dup[size:]
-			decoded.WriteRune(r)
-		}
-
-		return makePrintable(decoded.String())
-	case 1: // macintosh
+	switch PlatformID(nr.platformID) {
+	case PlatformUnicode:
+		// Strings of the Unicode platform are UTF-16BE like those of Windows.
+		return makePrintable(UTF16ToString(nr.data))
+	case PlatformMacintosh:
 		var decoded bytes.Buffer
 		for _, val := range nr.data {
 			decoded.WriteRune(charmap.Macintosh.DecodeByte(val))
@@ -136,12 +226,12 @@ func (nr nameRecord) Decoded() string {
 		*/
 		return makePrintable(macs)
 
-	case 3: // windows
+	case PlatformWindows:
 		// When building a Unicode font for Windows, the platform ID should be 3 and the encoding ID should be 1,
 		// and the referenced string data must be encoded in UTF-16BE. When building a symbol font for Windows,
 		// the platform ID should be 3 and the encoding ID should be 0, and the referenced string data must be
 		// encoded in UTF-16BE. (https://docs.microsoft.com/en-us/typography/opentype/spec/name).
-		if nr.encodingID == 0 || nr.encodingID == 1 {
+		if enc := EncodingID(nr.encodingID); enc == EncodingWindowsSymbol || enc == EncodingWindowsUnicodeBMP {
 			if len(nr.data) > 0 {
 				decoded := UTF16ToString(nr.data)
 				return makePrintable(decoded)
@@ -152,8 +242,110 @@ func (nr nameRecord) Decoded() string {
 	return makePrintable(string(nr.data))
 }
 
+// setDecoded encodes `value` for the platform of the record and stores it as the record data:
+// UTF-16BE for Unicode and Windows, Mac Roman for Macintosh with '?' for unmappable runes.
+func (nr *nameRecord) setDecoded(value string) {
+	switch PlatformID(nr.platformID) {
+	case PlatformMacintosh:
+		data := make([]byte, 0, len(value))
+		for _, r := range value {
+			b, ok := charmap.Macintosh.EncodeRune(r)
+			if !ok {
+				b = '?'
+			}
+			data = append(data, b)
+		}
+		nr.data = data
+	default:
+		nr.data = StringToUTF16(value)
+	}
+	nr.length = uint16(len(nr.data))
+}
+
+// compareNameRecords orders name records `a` and `b` by platform, encoding, language and
+// name ID, as the name table requires.
+func compareNameRecords(a, b *nameRecord) int {
+	return cmp.Or(
+		cmp.Compare(a.platformID, b.platformID),
+		cmp.Compare(a.encodingID, b.encodingID),
+		cmp.Compare(a.languageID, b.languageID),
+		cmp.Compare(a.nameID, b.nameID),
+	)
+}
+
+// canonicalNameRecords returns copies of the name records of `t` as writeNameTable writes them:
+// sorted by compareNameRecords, of records with the same platform, encoding, language and name
+// ID only the last one, and with the strings of the Unicode and Windows platforms in UTF-16BE,
+// see canonicalData.
+func (t *nameTable) canonicalNameRecords() []*nameRecord {
+	records := make([]*nameRecord, 0, len(t.nameRecords))
+	for _, nr := range t.nameRecords {
+		c := *nr
+		c.data = nr.canonicalData()
+		c.length = uint16(len(c.data))
+		records = append(records, &c)
+	}
+	slices.SortStableFunc(records, compareNameRecords)
+	canonical := records[:0]
+	for _, nr := range records {
+		if n := len(canonical); n > 0 && compareNameRecords(canonical[n-1], nr) == 0 {
+			canonical[n-1] = nr
+			continue
+		}
+		canonical = append(canonical, nr)
+	}
+	return canonical
+}
+
+// canonicalData returns the string data of `nr` in UTF-16BE for the Unicode and Windows
+// platforms, which require it. Data that is not UTF-16, such as the 8-bit strings some tools
+// write, is taken as UTF-8 if valid, otherwise as Latin-1, and converted.
+func (nr *nameRecord) canonicalData() []byte {
+	if p := PlatformID(nr.platformID); p != PlatformUnicode && p != PlatformWindows {
+		return nr.data
+	}
+	if _, err := decodeUTF16(nr.data); err == nil {
+		return nr.data
+	}
+	if utf8.Valid(nr.data) {
+		return StringToUTF16(string(nr.data))
+	}
+	runes := make([]rune, len(nr.data))
+	for i, b := range nr.data {
+		runes[i] = rune(b)
+	}
+	return StringToUTF16(string(runes))
+}
+
+// subsetNameIDs are the name IDs of the records subsetting keeps, those that font pickers and
+// PDF viewers identify a font by: family, subfamily, unique ID, full name, PostScript name and
+// the typographic family and subfamily.
+var subsetNameIDs = []NameID{
+	NameIDFamily, NameIDSubfamily, NameIDUniqueID, NameIDFullName, NameIDPostScriptName,
+	NameIDTypographicFamily, NameIDTypographicSubfamily,
+}
+
+// subset returns a copy of `t` with the records of `nameIDs` only, on all platforms, and the
+// language tags they may refer to. The strings are shared with `t`.
+func (t *nameTable) subset(nameIDs []NameID) *nameTable {
+	sub := &nameTable{format: t.format}
+	for _, nr := range t.nameRecords {
+		if slices.Contains(nameIDs, NameID(nr.nameID)) {
+			c := *nr
+			sub.nameRecords = append(sub.nameRecords, &c)
+		}
+	}
+	for _, ltr := range t.langTagRecords {
+		c := *ltr
+		sub.langTagRecords = append(sub.langTagRecords, &c)
+	}
+	sub.count = uint16(len(sub.nameRecords))
+	sub.langTagCount = uint16(len(sub.langTagRecords))
+	return sub
+}
+
 func (f *font) parseNameTable(r *byteReader) (*nameTable, error) {
-	tr, has, err := f.seekToTable(r, "name")
+	tr, has, err := f.seekToTable(r, tagName)
 	if err != nil {
 		return nil, err
 	}
@@ -177,11 +369,13 @@ func (f *font) parseNameTable(r *byteReader) (*nameTable, error) {
 
 	for i := 0; i < int(t.count); i++ {
 		var nr nameRecord
+		r.pushContext("name record %d", i)
 		err = r.read(&nr.platformID, &nr.encodingID, &nr.languageID, &nr.nameID, &nr.length, &nr.offset)
 		if err != nil {
 			return nil, err
 		}
 		// slog.Debug(fmt.Sprintf("name record %d: %v/%v/%v/%v/%v/%v", i, nr.platformID, nr.encodingID, nr.languageID, nr.nameID, nr.length, nr.offset))
+		r.popContext()
 		t.nameRecords = append(t.nameRecords, &nr)
 	}
 
@@ -202,7 +396,8 @@ func (f *font) parseNameTable(r *byteReader) (*nameTable, error) {
 	}
 
 	// Get the actual string data.
-	for _, nr := range t.nameRecords {
+	for i, nr := range t.nameRecords {
+		r.pushContext("name record %d string", i)
 		if int(t.stringOffset)+int(nr.offset)+int(nr.length) > int(tr.length) {
 			// slog.Debug(fmt.Sprintf("%v> %v", int(t.stringOffset)+int(nr.offset)+int(nr.length), int(tr.length)))
 			// slog.Debug("name string offset outside table")
@@ -220,6 +415,7 @@ func (f *font) parseNameTable(r *byteReader) (*nameTable, error) {
 			// slog.Debug(fmt.Sprintf("Error: %v", err))
 			return nil, err
 		}
+		r.popContext()
 	}
 
 	for _, ltr := range t.langTagRecords {
@@ -248,32 +444,47 @@ func (f *font) parseNameTable(r *byteReader) (*nameTable, error) {
 	return t, nil
 }
 
+// writeNameTable writes the name table in canonical form, see canonicalNameRecords, with each
+// distinct string stored once.
 func (f *font) writeNameTable(w *byteWriter) error {
 	if f.name == nil {
 		slog.Debug("name is nil")
 		return nil
 	}
-	t := f.name
+	// Counts and offsets are set on copies, as writing must not change the font.
+	t := *f.name
+	t.langTagRecords = make([]*langTagRecord, 0, len(f.name.langTagRecords))
+	for _, ltr := range f.name.langTagRecords {
+		c := *ltr
+		t.langTagRecords = append(t.langTagRecords, &c)
+	}
+	records := t.canonicalNameRecords()
 
 	// Preprocess: Write to buffer and update offsets.
 	var buf bytes.Buffer
 	{
 		bufw := newByteWriter(&buf)
-		for _, nr := range t.nameRecords {
-			nr.offset = offset16(bufw.bufferedLen())
-			nr.length = uint16(len(nr.data))
-			err := bufw.writeSlice(nr.data)
-			if err != nil {
+		offsets := make(map[string]offset16)
+		store := func(data []byte) (offset16, error) {
+			if offset, ok := offsets[string(data)]; ok {
+				return offset, nil
+			}
+			offset := offset16(bufw.bufferedLen())
+			offsets[string(data)] = offset
+			return offset, bufw.writeSlice(data)
+		}
+		for _, nr := range records {
+			var err error
+			if nr.offset, err = store(nr.data); err != nil {
 				return err
 			}
 		}
 		for _, ltr := range t.langTagRecords {
-			ltr.offset = offset16(bufw.bufferedLen())
-			ltr.length = uint16(len(ltr.data))
-			err := bufw.writeSlice(ltr.data)
-			if err != nil {
+			var err error
+			if ltr.offset, err = store(ltr.data); err != nil {
 				return err
 			}
+			ltr.length = uint16(len(ltr.data))
 		}
 		err := bufw.flush()
 		if err != nil {
@@ -283,7 +494,7 @@ func (f *font) writeNameTable(w *byteWriter) error {
 	slog.Debug(fmt.Sprintf("Buffer length: %d", buf.Len()))
 
 	// Update count and stringOffsets (calculated).
-	t.count = uint16(len(t.nameRecords))
+	t.count = uint16(len(records))
 	t.langTagCount = uint16(len(t.langTagRecords))
 
 	// 2+2+2+count*(6*2) + (format=1) 2+langTagCount*2
@@ -298,7 +509,7 @@ func (f *font) writeNameTable(w *byteWriter) error {
 		return err
 	}
 
-	for _, nr := range t.nameRecords {
+	for _, nr := range records {
 		err = w.write(nr.platformID, nr.encodingID, nr.languageID, nr.nameID, nr.length, nr.offset)
 		if err != nil {
 			return err
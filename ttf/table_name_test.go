@@ -0,0 +1,49 @@
+package ttf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteNameTable_Canonical(t *testing.T) {
+	record := func(platformID, encodingID, languageID, nameID uint16, value string) *nameRecord {
+		nr := &nameRecord{platformID: platformID, encodingID: encodingID, languageID: languageID, nameID: nameID}
+		nr.setDecoded(value)
+		return nr
+	}
+	f := &font{name: &nameTable{nameRecords: []*nameRecord{
+		record(3, 1, 0x409, 1, "Old"),
+		record(1, 0, 0, 1, "Go"),
+		record(3, 1, 0x409, 2, "Regular"),
+		record(3, 1, 0x411, 1, "Go"),
+		record(3, 1, 0x409, 1, "Go"), // Replaces "Old".
+		{platformID: 0, encodingID: 3, nameID: 4, length: 3, data: []byte("Go!")}, // 8-bit, not UTF-16.
+	}}}
+	want := []byte{
+		0, 0, 0, 5, 0, 66, // format, count, stringOffset
+		0, 0, 0, 3, 0, 0, 0, 4, 0, 6, 0, 0, // Unicode "Go!" in UTF-16BE
+		0, 1, 0, 0, 0, 0, 0, 1, 0, 2, 0, 6, // Macintosh "Go"
+		0, 3, 0, 1, 0x04, 0x09, 0, 1, 0, 4, 0, 8, // Windows en-US "Go"
+		0, 3, 0, 1, 0x04, 0x09, 0, 2, 0, 14, 0, 12, // Windows en-US "Regular"
+		0, 3, 0, 1, 0x04, 0x11, 0, 1, 0, 4, 0, 8, // Windows ja-JP "Go", sharing the string of en-US
+		0, 'G', 0, 'o', 0, '!',
+		'G', 'o',
+		0, 'G', 0, 'o',
+		0, 'R', 0, 'e', 0, 'g', 0, 'u', 0, 'l', 0, 'a', 0, 'r',
+	}
+	var buf bytes.Buffer
+	w := newByteWriter(&buf)
+	if err := f.writeNameTable(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("name table\n% X\nwant\n% X", buf.Bytes(), want)
+	}
+	// The records of the font are left as they are.
+	if len(f.name.nameRecords) != 6 || f.Name(NameIDFamily) != "Old" {
+		t.Fatalf("name records changed to %d, family %q", len(f.name.nameRecords), f.Name(NameIDFamily))
+	}
+}
@@ -5,6 +5,8 @@
 
 package ttf
 
+import "math/bits"
+
 type offsetTable struct {
 	sfntVersion   uint32
 	numTables     uint16
@@ -31,9 +33,30 @@ func (f *font) parseOffsetTable(r *byteReader) (*offsetTable, error) {
 		return nil, err
 	}
 
+	// Many fonts get these wrong and they are not needed for reading, so only note it.
+	sr, es, rs := binarySearchParams(int(ot.numTables), 16)
+	if ot.searchRange != sr || ot.entrySelector != es || ot.rangeShift != rs {
+		err = f.recordIncompatibilityf("offset table searchRange/entrySelector/rangeShift %d/%d/%d, want %d/%d/%d for %d tables",
+			ot.searchRange, ot.entrySelector, ot.rangeShift, sr, es, rs, ot.numTables)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ot, nil
 }
 
+// binarySearchParams returns the searchRange, entrySelector and rangeShift of a binary search
+// header over `n` entries of `size` bytes each.
+func binarySearchParams(n, size int) (searchRange, entrySelector, rangeShift uint16) {
+	if n <= 0 {
+		return 0, 0, 0
+	}
+	es := bits.Len(uint(n)) - 1
+	sr := size << es
+	return uint16(sr), uint16(es), uint16(n*size - sr)
+}
+
 func (f *font) writeOffsetTable(w *byteWriter) error {
 	if f.ot == nil {
 		return errRequiredField
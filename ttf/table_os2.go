@@ -29,7 +29,7 @@ type os2Table struct {
 	ulUnicodeRange2     uint32  // Bits 32-63.
 	ulUnicodeRange3     uint32  // Bits 64-95.
 	ulUnicodeRange4     uint32  // Bits 96-127.
-	achVendID           tag
+	achVendID           Tag
 	fsSelection         uint16
 	usFirstCharIndex    uint16
 	usLastCharIndex     uint16
@@ -56,7 +56,7 @@ type os2Table struct {
 }
 
 func (f *font) parseOS2Table(r *byteReader) (*os2Table, error) {
-	_, has, err := f.seekToTable(r, "OS/2")
+	_, has, err := f.seekToTable(r, tagOS2)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +75,18 @@ func (f *font) parseOS2Table(r *byteReader) (*os2Table, error) {
 		// slog.Debug("OS/2 table version range error")
 		return nil, errRangeCheck
 	}
+	if t.usWeightClass < uint16(WeightThin) || t.usWeightClass > uint16(WeightBlack) {
+		err := f.recordIncompatibilityf("OS/2 usWeightClass %d outside %d to %d", t.usWeightClass, WeightThin, WeightBlack)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.usWidthClass < uint16(WidthUltraCondensed) || t.usWidthClass > uint16(WidthUltraExpanded) {
+		err := f.recordIncompatibilityf("OS/2 usWidthClass %d outside %d to %d", t.usWidthClass, WidthUltraCondensed, WidthUltraExpanded)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	err = r.read(&t.ySubscriptXSize, &t.ySubscriptYSize, &t.ySubscriptXOffset, &t.ySubscriptYOffset)
 	if err != nil {
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 )
 
 // postTable represents a PostScript (post) table.
@@ -22,10 +24,10 @@ import (
 //   - other versions do not contain post glyph name data.
 type postTable struct {
 	// header (all versions).
-	version            fixed
-	italicAngle        fixed // in degrees.
-	underlinePosition  fword
-	underlineThickness fword
+	version            Fixed
+	italicAngle        Fixed // in degrees.
+	underlinePosition  FWord
+	underlineThickness FWord
 	isFixedPitch       uint32
 	minMemType42       uint32
 	maxMemType42       uint32
@@ -59,7 +61,7 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 		return nil, errRequiredField
 	}
 
-	tr, has, err := f.seekToTable(r, "post")
+	tr, has, err := f.seekToTable(r, tagPost)
 	if err != nil {
 		return nil, err
 	}
@@ -83,15 +85,11 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 	// slog.Debug(fmt.Sprintf("Version: %v %v 0x%X", t.version, t.version.Float64(), t.version))
 	switch uint32(t.version) {
 	case 0x00010000: // 1.0 - font files contains exactly the 258 standard Macintosh glyphs.
-		if t.numGlyphs != 258 {
-			// slog.Debug("Should have the mac number of glyph names")
-			// TODO(gunnsth): If this is too strict, can just set the first 258 glyphnames.
-			return nil, errRangeCheck
-		}
-		t.glyphNames = make([]GlyphName, int(t.numGlyphs))
-		for i := range macGlyphNames {
-			t.glyphNames[i] = macGlyphNames[i]
+		n, err := f.postNumGlyphs(len(macGlyphNames))
+		if err != nil {
+			return nil, err
 		}
+		t.glyphNames = slices.Clone(macGlyphNames[:n])
 
 	case 0x00020000: // 2.0
 		// slog.Debug("Version: 2.0")
@@ -100,9 +98,9 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 			return nil, err
 		}
 		// slog.Debug(fmt.Sprintf("numGlyphs: %d", t.numGlyphs))
-		if t.numGlyphs != f.maxp.numGlyphs {
-			// slog.Debug(fmt.Sprintf("post numGlyphs != maxp.numGlyphs (%d != %d)", t.numGlyphs, f.maxp.numGlyphs))
-			return nil, errRangeCheck
+		n, err := f.postNumGlyphs(int(t.numGlyphs))
+		if err != nil {
+			return nil, err
 		}
 		err = r.readSlice(&t.glyphNameIndex, int(t.numGlyphs))
 		if err != nil {
@@ -117,17 +115,19 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 		// slog.Debug(fmt.Sprintf("newGlyphs: %d", newGlyphs))
 		var names []string
 		for i := 0; i < newGlyphs; i++ {
+			r.pushContext("glyph name %d", i)
 			if r.Offset()-start >= int64(tr.length) {
 				// slog.Debug("ERROR: Reading outside post table")
 				// slog.Debug(fmt.Sprintf("%d > %d", r.Offset()-start, tr.length))
 				return nil, errors.New("reading outside table")
 			}
-			var numChars int8
+			var numChars uint8 // Pascal string length, up to 255.
 			err = r.read(&numChars)
 			if err != nil {
 				return nil, err
 			}
 			if numChars == 0 {
+				r.popContext()
 				break
 			}
 
@@ -138,6 +138,7 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 				return nil, err
 			}
 
+			r.popContext()
 			names = append(names, string(name))
 		}
 		if len(names) != newGlyphs {
@@ -145,8 +146,8 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 			return nil, errors.New("mismatching number of names loaded")
 		}
 
-		t.glyphNames = make([]GlyphName, int(t.numGlyphs))
-		for i := 0; i < int(t.numGlyphs); i++ {
+		t.glyphNames = make([]GlyphName, n)
+		for i := 0; i < n; i++ {
 			var name GlyphName
 
 			ni := t.glyphNameIndex[i]
@@ -172,16 +173,16 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 		if err != nil {
 			return nil, err
 		}
-		if t.numGlyphs != f.maxp.numGlyphs {
-			// slog.Debug(fmt.Sprintf("post numGlyphs != maxp.numGlyphs (%d != %d)", t.numGlyphs, f.maxp.numGlyphs))
-			return nil, errRangeCheck
+		n, err := f.postNumGlyphs(int(t.numGlyphs))
+		if err != nil {
+			return nil, err
 		}
 		err = r.readSlice(&t.offsets, int(t.numGlyphs))
 		if err != nil {
 			return nil, err
 		}
-		t.glyphNames = make([]GlyphName, int(t.numGlyphs))
-		for i := 0; i < int(t.numGlyphs); i++ {
+		t.glyphNames = make([]GlyphName, n)
+		for i := 0; i < n; i++ {
 			nameIndex := i + 1 + int(t.offsets[i])
 			if nameIndex < 0 || nameIndex > 257 {
 				slog.Debug(fmt.Sprintf("ERROR: name index outside range (%d)", nameIndex))
@@ -200,22 +201,39 @@ func (f *font) parsePost(r *byteReader) (*postTable, error) {
 	return t, nil
 }
 
+// postNumGlyphs returns the number of glyph names to use from a post table with names for
+// `numGlyphs` glyphs. It notes a disagreement with maxp.numGlyphs as an incompatibility and
+// uses the smaller of the two.
+func (f *font) postNumGlyphs(numGlyphs int) (int, error) {
+	maxpNumGlyphs := int(f.maxp.numGlyphs)
+	if numGlyphs != maxpNumGlyphs {
+		err := f.recordIncompatibilityf("post has names for %d glyphs, maxp.numGlyphs is %d", numGlyphs, maxpNumGlyphs)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", err, errRangeCheck)
+		}
+	}
+	return min(numGlyphs, maxpNumGlyphs), nil
+}
+
 func (f *font) writePost(w *byteWriter) error {
 	if f.post == nil {
 		return nil
 	}
 	t := f.post
 
-	// TODO(gunnsth): Write out with v1.0 or v2.0.
+	// The version is chosen on a copy, as writing must not change the font.
 	version := t.version
-	if version != 0x00010000 {
+	switch {
+	case version == 0x00010000:
+	case (version == 0x00020000 || version == 0x00025000) && len(t.glyphNames) > 0:
+		// Written as 2.0, which can represent any names.
+		version = 0x00020000
+	default:
 		// Include no postscript data.
-		// TODO(gunnsth): support writing v2.0.
 		version = 0x00030000
-		t.version = version
 	}
 
-	err := w.write(t.version, t.italicAngle, t.underlinePosition, t.underlineThickness, t.isFixedPitch)
+	err := w.write(version, t.italicAngle, t.underlinePosition, t.underlineThickness, t.isFixedPitch)
 	if err != nil {
 		return err
 	}
@@ -223,6 +241,56 @@ func (f *font) writePost(w *byteWriter) error {
 	if err != nil {
 		return err
 	}
+	if version == 0x00020000 {
+		return t.writeNames(w)
+	}
+
+	return nil
+}
 
+// writeNames writes the version 2.0 glyph names: standard Macintosh names by index, other
+// names once each as Pascal strings after the index array. The index is built apart from that
+// of `t`, which is left as parsed.
+func (t *postTable) writeNames(w *byteWriter) error {
+	glyphNameIndex := make([]uint16, len(t.glyphNames))
+	var custom []GlyphName
+	customIndex := make(map[GlyphName]uint16)
+	for i, name := range t.glyphNames {
+		if name == "" {
+			continue // .notdef
+		}
+		if ni, ok := MacGlyphNameIndex(name); ok {
+			glyphNameIndex[i] = ni
+			continue
+		}
+		ni, ok := customIndex[name]
+		if !ok {
+			if len(name) > math.MaxUint8 || len(custom) >= 32767-258 {
+				return errRangeCheck
+			}
+			ni = uint16(258 + len(custom))
+			customIndex[name] = ni
+			custom = append(custom, name)
+		}
+		glyphNameIndex[i] = ni
+	}
+	err := w.write(uint16(len(glyphNameIndex)))
+	if err != nil {
+		return err
+	}
+	err = w.writeSlice(glyphNameIndex)
+	if err != nil {
+		return err
+	}
+	for _, name := range custom {
+		err = w.write(uint8(len(name)))
+		if err != nil {
+			return err
+		}
+		err = w.writeBytes([]byte(name))
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
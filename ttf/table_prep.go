@@ -15,7 +15,7 @@ type prepTable struct {
 }
 
 func (f *font) parsePrep(r *byteReader) (*prepTable, error) {
-	tr, has, err := f.seekToTable(r, "prep")
+	tr, has, err := f.seekToTable(r, tagPrep)
 	if err != nil {
 		return nil, err
 	}
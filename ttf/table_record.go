@@ -8,13 +8,16 @@ package ttf
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
+	"slices"
 	"strings"
 )
 
 // tableRecord represents table records, including name (tag) and file offset, size
 // and checksum for integrity checking.
 type tableRecord struct {
-	tableTag tag      // len=4
+	tableTag Tag      // len=4
 	checksum uint32   // len=4
 	offset   offset32 // len=4
 	length   uint32   // len=4
@@ -28,19 +31,64 @@ func (tr *tableRecord) write(w *byteWriter) error {
 	return w.write(tr.tableTag, tr.checksum, tr.offset, tr.length)
 }
 
+// Tags of the tables that are parsed and written.
+var (
+	tagHead = MustTag("head")
+	tagMaxp = MustTag("maxp")
+	tagHhea = MustTag("hhea")
+	tagHmtx = MustTag("hmtx")
+	tagLoca = MustTag("loca")
+	tagGlyf = MustTag("glyf")
+	tagPrep = MustTag("prep")
+	tagCvt  = MustTag("cvt ")
+	tagFpgm = MustTag("fpgm")
+	tagGasp = MustTag("gasp")
+	tagMeta = MustTag("meta")
+	tagName = MustTag("name")
+	tagOS2  = MustTag("OS/2")
+	tagPost = MustTag("post")
+	tagCmap = MustTag("cmap")
+	tagGDEF = MustTag("GDEF")
+	tagGPOS = MustTag("GPOS")
+	tagGSUB = MustTag("GSUB")
+	tagKern = MustTag("kern")
+	tagCFF  = MustTag("CFF ")
+	tagCFF2 = MustTag("CFF2")
+	tagCOLR = MustTag("COLR")
+	tagCPAL = MustTag("CPAL")
+	tagSbix = MustTag("sbix")
+	tagCBDT = MustTag("CBDT")
+	tagDSIG = MustTag("DSIG")
+	tagCBLC = MustTag("CBLC")
+	tagSVG  = MustTag("SVG ")
+	tagEBDT = MustTag("EBDT")
+	tagEBLC = MustTag("EBLC")
+	tagEBSC = MustTag("EBSC")
+	tagBdat = MustTag("bdat")
+	tagBloc = MustTag("bloc")
+	tagVhea = MustTag("vhea")
+	tagVmtx = MustTag("vmtx")
+)
+
 // tableRecords represents a set of table records in a truetype font file.
-// Includes a map by table name for quick lookup of records.
+// Includes a map by table tag for quick lookup of records.
 type tableRecords struct {
 	list  []*tableRecord
-	trMap map[string]*tableRecord
+	trMap map[Tag]*tableRecord
 }
 
+// Set sets the record of table `table`, e.g. "cvt", see SetTag.
 func (trs *tableRecords) Set(table string, offset int64, length int, checksum uint32) {
+	trs.SetTag(makeTag(table), offset, length, checksum)
+}
+
+// SetTag sets the record of table `t`, replacing an existing one in place or appending it.
+func (trs *tableRecords) SetTag(t Tag, offset int64, length int, checksum uint32) {
 	if trs.trMap == nil {
-		trs.trMap = map[string]*tableRecord{}
+		trs.trMap = map[Tag]*tableRecord{}
 	}
 	newRec := &tableRecord{
-		tableTag: makeTag(table),
+		tableTag: t,
 		offset:   offset32(offset),
 		length:   uint32(length),
 		checksum: uint32(checksum),
@@ -48,7 +96,7 @@ func (trs *tableRecords) Set(table string, offset int64, length int, checksum ui
 
 	found := false
 	for i := range trs.list {
-		if trs.list[i].tableTag.String() == table {
+		if trs.list[i].tableTag == t {
 			trs.list[i] = newRec
 			found = true
 		}
@@ -56,7 +104,7 @@ func (trs *tableRecords) Set(table string, offset int64, length int, checksum ui
 	if !found {
 		trs.list = append(trs.list, newRec)
 	}
-	trs.trMap[table] = newRec
+	trs.trMap[t] = newRec
 }
 
 func (f *font) parseTableRecords(r *byteReader) (*tableRecords, error) {
@@ -69,33 +117,87 @@ func (f *font) parseTableRecords(r *byteReader) (*tableRecords, error) {
 	}
 
 	if trs.trMap == nil {
-		trs.trMap = map[string]*tableRecord{}
+		trs.trMap = map[Tag]*tableRecord{}
 	}
 
+	dirEnd := offset32(f.ot.Size()) + offset32(numTables)*16
+	firstTable := offset32(math.MaxUint32)
 	for i := 0; i < numTables; i++ {
 		var rec tableRecord
+		r.pushContext("record %d", i)
 		err := rec.read(r)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w (offset table declares %d tables, only %d records present)", err, numTables, i)
 		}
+		if rec.tableTag == (Tag{}) {
+			return nil, r.wrapErr(fmt.Errorf("zero tag: %w", errRangeCheck), "")
+		}
+		r.popContext()
 		trs.list = append(trs.list, &rec)
-		trs.trMap[rec.tableTag.String()] = &rec
+		trs.trMap[rec.tableTag] = &rec
+		firstTable = min(firstTable, rec.offset)
+	}
+	if numTables == 0 {
+		return trs, nil
+	}
+
+	if firstTable < dirEnd {
+		return nil, fmt.Errorf("offset table declares %d tables, directory ending at %d overlaps the first table at offset %d: %w",
+			numTables, dirEnd, firstTable, errRangeCheck)
+	}
+	// Records left out of numTables show as valid records between the directory and the
+	// first table, where there is usually nothing but padding.
+	extra := 0
+	for off := dirEnd; off+16 <= firstTable; off += 16 {
+		var rec tableRecord
+		if rec.read(r) != nil || !rec.tableTag.Valid() || rec.offset < dirEnd {
+			break
+		}
+		extra++
+	}
+	if extra > 0 {
+		return nil, fmt.Errorf("offset table declares %d tables, but %d more records follow before the first table at offset %d: %w",
+			numTables, extra, firstTable, errRangeCheck)
 	}
 
 	return trs, nil
 }
 
-// seekToTable seeks to position font table `tableName` in `r` if it has the table.
+// TableRangeError is returned for a table that the table directory of a font lists, but that
+// the file ends before, as in truncated fonts. Tables that are cut short rather than missing
+// altogether fail where their data ends.
+type TableRangeError struct {
+	Tag    Tag
+	Offset int64 // Offset of the table according to the directory.
+	Length int64 // Length of the table according to the directory.
+	Size   int64 // Size of the file.
+}
+
+func (e *TableRangeError) Error() string {
+	return fmt.Sprintf("table %s at offset %d (%d bytes) starts past the end of the file (%d bytes)", e.Tag, e.Offset, e.Length, e.Size)
+}
+
+// Unwrap returns io.ErrUnexpectedEOF, as reading the table would.
+func (e *TableRangeError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+// seekToTable seeks to position font table `t` in `r` if it has the table.
 // The table record is returned back when successful, otherwise is meaningless.
 // The bool flag indicates that the table exists and should be at that position if there
-// was no error.
-func (f *font) seekToTable(r *byteReader, tableName string) (tr *tableRecord, has bool, err error) {
-	tr, has = f.trec.trMap[tableName]
+// was no error. A table the directory does not list is not an error, one that lies past
+// the end of `r` is a *TableRangeError.
+func (f *font) seekToTable(r *byteReader, t Tag) (tr *tableRecord, has bool, err error) {
+	tr, has = f.trec.trMap[t]
 	if !has {
 		return tr, false, nil
 	}
 
-	err = r.SeekTo(int64(tr.offset))
+	offset := int64(tr.offset)
+	if r.size >= 0 && (offset > r.size || offset == r.size && tr.length > 0) {
+		return tr, false, &TableRangeError{Tag: t, Offset: offset, Length: int64(tr.length), Size: r.size}
+	}
+	err = r.seekTo(offset, t.String())
 	if err != nil {
 		return tr, false, err
 	}
@@ -103,15 +205,39 @@ func (f *font) seekToTable(r *byteReader, tableName string) (tr *tableRecord, ha
 	return tr, true, nil
 }
 
+// writtenTableRecords returns the output of writing `f` and the table records in it.
+func (f *Font) writtenTableRecords() ([]byte, *tableRecords, error) {
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, nil, err
+	}
+	r := newByteReader(bytes.NewReader(buf.Bytes()))
+	fnt := &font{}
+	var err error
+	if fnt.ot, err = fnt.parseOffsetTable(r); err != nil {
+		return nil, nil, err
+	}
+	if fnt.trec, err = fnt.parseTableRecords(r); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), fnt.trec, nil
+}
+
 func (f *font) writeTableRecords(w *byteWriter) error {
 	if f.trec == nil {
 		// slog.Debug("Table records not set")
 		return errRequiredField
 	}
 
-	// slog.Debug(fmt.Sprintf("Writing (len:%d):", len(f.trec.list)))
-	for _, tr := range f.trec.list {
+	// The directory is sorted by tag, as the specification requires, whatever the layout of the
+	// tables.
+	list := slices.Clone(f.trec.list)
+	slices.SortStableFunc(list, func(a, b *tableRecord) int { return bytes.Compare(a.tableTag[:], b.tableTag[:]) })
+	for _, tr := range list {
 		// slog.Debug(fmt.Sprintf("%s - off: %d (len: %d)", tr.tableTag.String(), tr.offset, tr.length))
+		if !tr.tableTag.Valid() {
+			return fmt.Errorf("invalid table tag %q: %w", tr.tableTag[:], errRangeCheck)
+		}
 		err := tr.write(w)
 		if err != nil {
 			return err
@@ -122,7 +248,12 @@ func (f *font) writeTableRecords(w *byteWriter) error {
 
 // HasTable returns true if there is a record of `tableName` in table records `trs`.
 func (trs *tableRecords) HasTable(tableName string) bool {
-	_, has := trs.trMap[strings.TrimSpace(tableName)]
+	return trs.HasTag(makeTag(strings.TrimSpace(tableName)))
+}
+
+// HasTag returns true if there is a record of table `t` in table records `trs`.
+func (trs *tableRecords) HasTag(t Tag) bool {
+	_, has := trs.trMap[t]
 	return has
 }
 
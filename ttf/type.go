@@ -7,7 +7,11 @@ package ttf
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // CharCode is an internal typically 1-2 byte representation of a code. Its meaning depends on encoding context.
@@ -20,6 +24,31 @@ type GlyphName string
 // GlyphIndex or Glyph ID (GID) represent each glyph within a font.
 type GlyphIndex uint16
 
+// String returns `gid` formatted for debug output, e.g. "GID 1234".
+func (gid GlyphIndex) String() string {
+	return "GID " + strconv.Itoa(int(gid))
+}
+
+// String returns `c` formatted like a Unicode code point, e.g. "U+4E2D".
+func (c CharCode) String() string {
+	return fmt.Sprintf("U+%04X", uint32(c))
+}
+
+// maxCharCode is the largest code a cmap subtable maps, that of the last Unicode code point.
+const maxCharCode CharCode = 0x10FFFF
+
+// checkCharCodeRange returns errRangeCheck if the codes `start` to `end` are reversed or go
+// beyond maxCharCode.
+func checkCharCodeRange(start, end CharCode) error {
+	if end < start {
+		return fmt.Errorf("codes %v-%v reversed: %w", start, end, errRangeCheck)
+	}
+	if end > maxCharCode {
+		return fmt.Errorf("code %v beyond %v: %w", end, maxCharCode, errRangeCheck)
+	}
+	return nil
+}
+
 /*
 Types in truetype fonts:
 https://docs.microsoft.com/en-us/typography/opentype/spec/otff
@@ -46,50 +75,167 @@ Offset16  Short offset to a table, same as uint16, NULL offset = 0x0000
 Offset32  Long offset to a table, same as uint32, NULL offset = 0x00000000
 */
 
-type fixed int32
-type fword int16
-type ufword uint16
-type f2dot14 int16
+// Fixed is a 32-bit signed fixed-point number with 16 fractional bits (16.16), e.g. the
+// italic angle in the post table or the font revision in the head table.
+type Fixed int32
+
+// FWord is a signed quantity in font design units (FUnits), e.g. hhea.ascender or
+// post.underlinePosition. Pixels converts it to pixels.
+type FWord int16
+
+// UFWord is an unsigned quantity in font design units (FUnits), e.g. hhea.advanceWidthMax.
+type UFWord uint16
+
+// F2Dot14 is a 16-bit signed fixed-point number with 14 fractional bits (2.14), ranging from
+// -2.0 to +1.99993896484375, e.g. the scales of composite glyph components.
+type F2Dot14 int16
+
+// longdatetime is a date in seconds since 1904-01-01 00:00 UTC, 0 meaning unset.
 type longdatetime int64
-type tag [4]uint8
+
+// macEpochOffset is the number of seconds from 1904-01-01 to the Unix epoch 1970-01-01.
+const macEpochOffset = 2082844800
+
+// Time returns `d` as a time in UTC.
+func (d longdatetime) Time() time.Time {
+	return time.Unix(int64(d)-macEpochOffset, 0).UTC()
+}
+
+// makeLongdatetime returns `t` in seconds since 1904, rounded down to whole seconds.
+func makeLongdatetime(t time.Time) longdatetime {
+	return longdatetime(t.Unix() + macEpochOffset)
+}
+
 type offset16 uint16
 type offset32 uint32
 
-func (t tag) String() string {
-	return strings.TrimSpace(string(t[:]))
+// Tag identifies a table, e.g. "head" or "cvt " (tags shorter than four letters are padded
+// with spaces).
+type Tag [4]uint8
+
+// ParseTag returns `s` as a Tag. As per the spec, a tag is exactly four bytes in the range
+// 0x20 to 0x7E and spaces may only pad it at the end, so "cvt " is valid but "cvt" is not.
+func ParseTag(s string) (Tag, error) {
+	var t Tag
+	if len(s) != len(t) {
+		return t, fmt.Errorf("tag %q is not 4 bytes: %w", s, errRangeCheck)
+	}
+	copy(t[:], s)
+	if !t.Valid() {
+		return t, fmt.Errorf("invalid tag %q: %w", s, errRangeCheck)
+	}
+	return t, nil
+}
+
+// MustTag is like ParseTag but panics if `s` is not a valid tag. It is meant for constant tags.
+func MustTag(s string) Tag {
+	t, err := ParseTag(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Valid reports whether `t` consists of bytes 0x20 to 0x7E with spaces only at the end, and
+// does not start with a space.
+func (t Tag) Valid() bool {
+	space := false
+	for i, b := range t {
+		switch {
+		case b < 0x20 || b > 0x7E:
+			return false
+		case b == ' ':
+			if i == 0 {
+				return false
+			}
+			space = true
+		case space:
+			return false
+		}
+	}
+	return true
+}
+
+// String returns `t` without trailing spaces, e.g. "cvt".
+func (t Tag) String() string {
+	return strings.TrimRight(string(t[:]), " ")
+}
+
+// tagNames returns the String of each of `tags`, e.g. for joining them in a message.
+func tagNames(tags []Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.String()
+	}
+	return names
 }
 
 // Parts returns the integral and decimal portions of `f`.
-func (f fixed) Parts() (uint16, uint16) {
+func (f Fixed) Parts() (uint16, uint16) {
 	b := make([]byte, 4)
 	binary.BigEndian.PutUint32(b, uint32(f))
 	return binary.BigEndian.Uint16(b[0:2]), binary.BigEndian.Uint16(b[2:4])
 }
 
 // Float64 returns `f` as a float64.
-func (f fixed) Float64() float64 {
-	b := make([]byte, 4)
-	binary.BigEndian.PutUint32(b, uint32(f))
-	l, r := binary.BigEndian.Uint16(b[0:2]), binary.BigEndian.Uint16(b[2:4])
-	integral := float64(int16(l))
-	fraction := float64(r) / 65536.0
-	return integral + fraction
+func (f Fixed) Float64() float64 {
+	return float64(f) / (1 << 16)
 }
 
-func makeTag(s string) tag {
-	bb := []byte(s[:])
-	if len(bb) > 4 {
-		// Trim to 4 bytes.
-		bb = bb[:4]
+// FixedFromFloat64 returns `v` rounded to the nearest Fixed, saturating at the range limits
+// of about ±32768. NaN gives 0.
+func FixedFromFloat64(v float64) Fixed {
+	return Fixed(fromFloat64(v, 1<<16, math.MinInt32, math.MaxInt32))
+}
+
+// Float64 returns `f` as a float64.
+func (f F2Dot14) Float64() float64 {
+	return float64(f) / (1 << 14)
+}
+
+// F2Dot14FromFloat64 returns `v` rounded to the nearest F2Dot14, saturating at -2.0 and
+// +1.99993896484375. NaN gives 0.
+func F2Dot14FromFloat64(v float64) F2Dot14 {
+	return F2Dot14(fromFloat64(v, 1<<14, math.MinInt16, math.MaxInt16))
+}
+
+// fromFloat64 returns `v` in fixed-point with `one` as 1.0, rounded and clamped to [lo, hi].
+func fromFloat64(v, one float64, lo, hi int64) int64 {
+	if math.IsNaN(v) {
+		return 0
 	}
-	if len(bb) < 4 {
-		// Pad with spaces to fill 4 bytes.
-		for i := 0; i < 4-len(bb); i++ {
-			bb = append(bb, ' ')
-		}
+	v = math.Round(v * one)
+	if v <= float64(lo) {
+		return lo
 	}
+	if v >= float64(hi) {
+		return hi
+	}
+	return int64(v)
+}
+
+// Pixels returns `v` scaled from font units to pixels at `ppem` pixels per em, for a font
+// with `unitsPerEm` units per em (head.unitsPerEm). It returns 0 if unitsPerEm is not positive.
+func (v FWord) Pixels(unitsPerEm int, ppem float64) float64 {
+	return funitsToPixels(float64(v), unitsPerEm, ppem)
+}
+
+// Pixels returns `v` scaled from font units to pixels like FWord.Pixels.
+func (v UFWord) Pixels(unitsPerEm int, ppem float64) float64 {
+	return funitsToPixels(float64(v), unitsPerEm, ppem)
+}
+
+func funitsToPixels(v float64, unitsPerEm int, ppem float64) float64 {
+	if unitsPerEm <= 0 {
+		return 0
+	}
+	return v * ppem / float64(unitsPerEm)
+}
 
-	var t tag
-	copy(t[:], bb)
+// makeTag returns `s` as a Tag, truncated or padded with spaces to four bytes. It is used by
+// the string-accepting functions and does not validate `s`, see ParseTag.
+func makeTag(s string) Tag {
+	t := Tag{' ', ' ', ' ', ' '}
+	copy(t[:], s)
 	return t
 }
@@ -0,0 +1,195 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestFixed(t *testing.T) {
+	tests := []struct {
+		f Fixed
+		v float64
+	}{
+		{0, 0},
+		{0x00010000, 1},
+		{0x00018000, 1.5},
+		{-0x00018000, -1.5},
+		{-0x000C8000, -12.5},
+		{-1, -1.0 / 65536},
+		{math.MaxInt32, 32768 - 1.0/65536},
+		{math.MinInt32, -32768},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Float64(); got != tt.v {
+			t.Fatalf("%08X: got %v, want %v", uint32(tt.f), got, tt.v)
+		}
+		if got := FixedFromFloat64(tt.v); got != tt.f {
+			t.Fatalf("%v: got %08X, want %08X", tt.v, uint32(got), uint32(tt.f))
+		}
+	}
+	for v, want := range map[float64]Fixed{
+		1e9: math.MaxInt32, -1e9: math.MinInt32, math.NaN(): 0, math.Inf(-1): math.MinInt32,
+		0.1: 6554, -0.1: -6554,
+	} {
+		if got := FixedFromFloat64(v); got != want {
+			t.Fatalf("%v: got %d, want %d", v, got, want)
+		}
+	}
+	if i, d := Fixed(-0x00018000).Parts(); i != 0xFFFE || d != 0x8000 {
+		t.Fatalf("parts of -1.5: %04X %04X", i, d)
+	}
+}
+
+func TestF2Dot14(t *testing.T) {
+	tests := []struct {
+		f F2Dot14
+		v float64
+	}{
+		{0x7FFF, 1.999938964843750},
+		{0x7000, 1.75},
+		{0x0001, 0.000061035156250},
+		{0x0000, 0},
+		{-0x0001, -0.000061035156250},
+		{-0x4000, -1},
+		{-0x8000, -2},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Float64(); got != tt.v {
+			t.Fatalf("%04X: got %v, want %v", uint16(tt.f), got, tt.v)
+		}
+		if got := F2Dot14FromFloat64(tt.v); got != tt.f {
+			t.Fatalf("%v: got %04X, want %04X", tt.v, uint16(got), uint16(tt.f))
+		}
+	}
+	for v, want := range map[float64]F2Dot14{2: 0x7FFF, 5: 0x7FFF, -2.5: -0x8000, math.NaN(): 0} {
+		if got := F2Dot14FromFloat64(v); got != want {
+			t.Fatalf("%v: got %04X, want %04X", v, uint16(got), uint16(want))
+		}
+	}
+}
+
+func TestFWord_Pixels(t *testing.T) {
+	if got := FWord(-150).Pixels(1000, 16); got != -2.4 {
+		t.Fatalf("got %v", got)
+	}
+	if got := UFWord(2048).Pixels(2048, 12.5); got != 12.5 {
+		t.Fatalf("got %v", got)
+	}
+	if got := FWord(100).Pixels(0, 16); got != 0 {
+		t.Fatalf("zero unitsPerEm: got %v", got)
+	}
+
+	f := loadGoRegular(t)
+	head := f.trec.trMap[tagHead].offset
+	revision := Fixed(binary.BigEndian.Uint32(goregular.TTF[head+4:]))
+	if f.FontRevision() != revision.Float64() || f.FontRevision() < 1 {
+		t.Fatalf("font revision %v, want %v", f.FontRevision(), revision.Float64())
+	}
+	if f.ItalicAngle() != 0 {
+		t.Fatalf("italic angle %v", f.ItalicAngle())
+	}
+	f.post.italicAngle = FixedFromFloat64(-12.5)
+	if f.ItalicAngle() != -12.5 {
+		t.Fatalf("italic angle %v", f.ItalicAngle())
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	for _, s := range []string{"head", "cvt ", "OS/2", "a   ", "~~~~"} {
+		tag, err := ParseTag(s)
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if string(tag[:]) != s || tag.String() != strings.TrimRight(s, " ") {
+			t.Fatalf("%q: got %q (%q)", s, tag[:], tag)
+		}
+	}
+	for _, s := range []string{"cvt", "heads", "", "hea\x00", "hea\x7F", "heé", " cvt", "c vt", "\x1Fabc"} {
+		if _, err := ParseTag(s); err == nil {
+			t.Fatalf("%q: expected an error", s)
+		}
+	}
+	if makeTag("a") != MustTag("a   ") || makeTag("cvt") != tagCvt || makeTag("heads") != tagHead {
+		t.Fatal("makeTag does not pad or truncate to 4 bytes")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("MustTag did not panic")
+			}
+		}()
+		MustTag("cvt")
+	}()
+
+	f := loadGoRegular(t)
+	if !f.trec.HasTag(tagCvt) || !f.trec.HasTable("cvt") || f.trec.HasTag(MustTag("GSUB")) {
+		t.Fatal("HasTag/HasTable")
+	}
+	f.trec.list[0].tableTag = Tag{'b', 'a', 'd', 0}
+	if err := f.writeTableRecords(newByteWriter(io.Discard)); err == nil {
+		t.Fatal("expected an error writing an invalid tag")
+	}
+}
+
+func TestLongdatetime(t *testing.T) {
+	tests := []struct {
+		raw  []byte
+		time time.Time
+	}{
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0}, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{[]byte{0, 0, 0, 0, 0x7C, 0x25, 0xB0, 0x80}, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{[]byte{0, 0, 0, 0, 0x7B, 0x4D, 0x46, 0x3C}, time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC)},
+		{[]byte{0, 0, 0, 0, 0xDA, 0x31, 0x91, 0x80}, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, time.Date(1903, 12, 31, 23, 59, 59, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		d := longdatetime(binary.BigEndian.Uint64(tt.raw))
+		if got := d.Time(); !got.Equal(tt.time) {
+			t.Fatalf("% X: got %v, want %v", tt.raw, got, tt.time)
+		}
+		got := binary.BigEndian.AppendUint64(nil, uint64(makeLongdatetime(tt.time)))
+		if !bytes.Equal(got, tt.raw) {
+			t.Fatalf("%v: got % X, want % X", tt.time, got, tt.raw)
+		}
+	}
+
+	f := loadGoRegular(t)
+	tr := f.trec.trMap[tagHead]
+	raw := goregular.TTF[tr.offset:]
+	for name, get := range map[string]func() (time.Time, bool){"created": f.Created, "modified": f.Modified} {
+		at := map[string]int{"created": 20, "modified": 28}[name]
+		want := longdatetime(binary.BigEndian.Uint64(raw[at:])).Time()
+		if got, ok := get(); !ok || !got.Equal(want) {
+			t.Fatalf("%s: got %v %t, want %v", name, got, ok, want)
+		}
+	}
+
+	created := time.Date(1960, 5, 6, 7, 8, 9, 500, time.FixedZone("X", 3600))
+	f.SetCreated(created)
+	f.SetModified(time.Time{})
+	f.SetFontRevision(2.25)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := g.Created(); !ok || !got.Equal(created.Truncate(time.Second)) {
+		t.Fatalf("created: got %v %t", got, ok)
+	}
+	if got, ok := g.Modified(); ok || !got.IsZero() {
+		t.Fatalf("modified: got %v %t, want unset", got, ok)
+	}
+	if got := g.FontRevision(); got != 2.25 {
+		t.Fatalf("font revision: got %v", got)
+	}
+}
@@ -1,7 +1,11 @@
 package ttf
 
 import (
+	"encoding/binary"
+	"fmt"
 	"math"
+	"reflect"
+	"unicode"
 	"unicode/utf16"
 )
 
@@ -18,50 +22,127 @@ type NumT interface {
 // output:
 //
 //	1, true
+//
+// Integer targets accept a value when it, truncated towards zero for floats, lies in the
+// target range: NaN, ±Inf and negative floats for unsigned targets always fail. Float64
+// targets accept everything. A float64 converts to float32 rounded to nearest unless the
+// magnitude overflows float32 or a non-zero value underflows to zero; NaN and ±Inf are kept.
 func ConvNumber[OutT NumT, InT NumT](orig InT) (converted OutT, ok bool) {
-	converted = OutT(orig)
-	switch any(converted).(type) {
-	case float64:
-		return converted, true
-	case float32:
-		f64, isF64 := any(orig).(float64)
-		if !isF64 {
-			return converted, true
+	out, in := reflect.TypeFor[OutT](), reflect.TypeFor[InT]()
+	switch out.Kind() {
+	case reflect.Float64:
+		return OutT(orig), true
+	case reflect.Float32:
+		if in.Kind() != reflect.Float64 {
+			return OutT(orig), true
 		}
-		if math.Abs(f64) < math.MaxFloat32 {
+		f := float64(orig)
+		converted = OutT(orig)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
 			return converted, true
 		}
-		return 0, false
+		if math.Abs(f) > math.MaxFloat32 || (f != 0 && converted == 0) {
+			return 0, false
+		}
+		return converted, true
+	}
+
+	if isFloat := in.Kind() == reflect.Float32 || in.Kind() == reflect.Float64; isFloat {
+		f := math.Trunc(float64(orig))
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		// The bounds are powers of two, exact in float64.
+		lo, hi := 0.0, math.Ldexp(1, out.Bits())
+		if isSigned(out) {
+			lo, hi = -math.Ldexp(1, out.Bits()-1), math.Ldexp(1, out.Bits()-1)
+		} else if float64(orig) < 0 {
+			return 0, false
+		}
+		if f < lo || f >= hi {
+			return 0, false
+		}
+		return OutT(f), true
 	}
+
+	converted = OutT(orig)
 	if (orig < 0) != (converted < 0) {
 		return 0, false
 	}
-	cast := InT(converted)
-	base := orig
-	switch f := any(orig).(type) {
-	case float64:
-		base = InT(math.Trunc(f))
-	case float32:
-		base = InT(math.Trunc(float64(f)))
-	}
-	if cast == base {
+	if InT(converted) == orig {
 		return converted, true
 	}
 	return 0, false
 }
 
+// isSigned reports whether `t` is a signed integer type.
+func isSigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
 // UTF16ToString decodes the UTF-16BE encoded byte slice `b` to a Unicode go string.
+// A leading byte order mark (U+FEFF) is dropped. Malformed input never fails: an unpaired
+// surrogate and a trailing odd byte each decode to U+FFFD. Use UTF16ToStringStrict to
+// reject such input instead.
 func UTF16ToString(b []byte) string {
-	if len(b) == 1 {
-		return string(rune(b[0]))
-	}
+	s, _ := decodeUTF16(b)
+	return s
+}
+
+// UTF16ToStringStrict is UTF16ToString that returns an error for an odd number of bytes
+// or an unpaired surrogate.
+func UTF16ToStringStrict(b []byte) (string, error) {
+	return decodeUTF16(b)
+}
+
+// decodeUTF16 decodes `b` like UTF16ToString and also returns an error for malformed input.
+func decodeUTF16(b []byte) (string, error) {
+	var err error
 	if len(b)%2 != 0 {
-		b = append(b, 0)
+		err = fmt.Errorf("odd length %d: %w", len(b), errInvalidUTF16)
 	}
 	n := len(b) >> 1
 	chars := make([]uint16, n)
 	for i := 0; i < n; i++ {
-		chars[i] = uint16(b[i<<1])<<8 + uint16(b[i<<1+1])
+		chars[i] = binary.BigEndian.Uint16(b[i<<1:])
+	}
+	start := 0 // Byte offset of chars[0] in `b`.
+	if len(chars) > 0 && chars[0] == 0xFEFF {
+		chars = chars[1:]
+		start = 2
+	}
+	runes := make([]rune, 0, len(chars)+1)
+	for i := 0; i < len(chars); i++ {
+		c := rune(chars[i])
+		switch {
+		case utf16.IsSurrogate(c) && i+1 < len(chars) && utf16.DecodeRune(c, rune(chars[i+1])) != unicode.ReplacementChar:
+			runes = append(runes, utf16.DecodeRune(c, rune(chars[i+1])))
+			i++
+		case utf16.IsSurrogate(c):
+			if err == nil {
+				err = fmt.Errorf("unpaired surrogate %#04x at byte %d: %w", c, start+2*i, errInvalidUTF16)
+			}
+			runes = append(runes, unicode.ReplacementChar)
+		default:
+			runes = append(runes, c)
+		}
+	}
+	if len(b)%2 != 0 {
+		runes = append(runes, unicode.ReplacementChar)
+	}
+	return string(runes), err
+}
+
+// StringToUTF16 encodes `s` as UTF-16BE without a BOM, the inverse of UTF16ToString. Runes
+// outside the Basic Multilingual Plane become surrogate pairs, invalid UTF-8 becomes U+FFFD.
+func StringToUTF16(s string) []byte {
+	b := make([]byte, 0, 2*len(s))
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.BigEndian.AppendUint16(b, c)
 	}
-	return string(utf16.Decode(chars))
+	return b
 }
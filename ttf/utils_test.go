@@ -0,0 +1,184 @@
+package ttf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestStringToUTF16(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []byte
+	}{
+		{"", []byte{}},
+		{"Ab", []byte{0x00, 0x41, 0x00, 0x62}},
+		{"中文", []byte{0x4E, 0x2D, 0x65, 0x87}},
+		{"😀", []byte{0xD8, 0x3D, 0xDE, 0x00}},
+		{"\U0010FFFF", []byte{0xDB, 0xFF, 0xDF, 0xFF}},
+		{"\uFFFF\uE000", []byte{0xFF, 0xFF, 0xE0, 0x00}},
+		{"a\xffb", []byte{0x00, 0x61, 0xFF, 0xFD, 0x00, 0x62}},
+	}
+	for _, tt := range tests {
+		got := StringToUTF16(tt.s)
+		if !bytes.Equal(got, tt.want) {
+			t.Fatalf("%q: got % X, want % X", tt.s, got, tt.want)
+		}
+		if len(got)%2 != 0 {
+			t.Fatalf("%q: odd length %d", tt.s, len(got))
+		}
+	}
+	for _, s := range []string{"Regular", "Noto Sans 思源黑体", "emoji 😀🎉 and \U0001F600\U0010FFFF", "\uFFFF"} {
+		if got := UTF16ToString(StringToUTF16(s)); got != s {
+			t.Fatalf("round trip of %q gave %q", s, got)
+		}
+	}
+}
+
+func TestNameRecord_SetDecoded(t *testing.T) {
+	tests := []struct {
+		platformID uint16
+		value      string
+		want       string
+	}{
+		{3, "Noto Sans 思源黑体 😀", "Noto Sans 思源黑体 😀"},
+		{1, "Café", "Café"},
+		{1, "中文", "??"},
+	}
+	for _, tt := range tests {
+		nr := &nameRecord{platformID: tt.platformID, encodingID: 1}
+		nr.setDecoded(tt.value)
+		if int(nr.length) != len(nr.data) {
+			t.Fatalf("%q: length %d for %d bytes", tt.value, nr.length, len(nr.data))
+		}
+		if got := nr.Decoded(); got != tt.want {
+			t.Fatalf("platform %d: %q decoded to %q, want %q", tt.platformID, tt.value, got, tt.want)
+		}
+	}
+
+	f := &font{name: &nameTable{nameRecords: []*nameRecord{
+		{platformID: 1, nameID: 1},
+		{platformID: 3, encodingID: 1, nameID: 1},
+		{platformID: 3, encodingID: 1, nameID: 2},
+	}}}
+	if !f.SetNameByID(1, "Subset") || f.SetNameByID(4, "Full") {
+		t.Fatal("SetNameByID reported the wrong records")
+	}
+	for _, nr := range f.name.nameRecords[:2] {
+		if nr.Decoded() != "Subset" {
+			t.Fatalf("platform %d: got %q", nr.platformID, nr.Decoded())
+		}
+	}
+	var buf bytes.Buffer
+	w := newByteWriter(&buf)
+	if err := f.writeNameTable(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), StringToUTF16("Subset")) {
+		t.Fatal("written name table does not contain the UTF-16 record")
+	}
+}
+
+func TestUTF16ToString(t *testing.T) {
+	tests := []struct {
+		name   string
+		b      []byte
+		want   string
+		strict bool // UTF16ToStringStrict fails
+	}{
+		{"empty", nil, "", false},
+		{"ascii", []byte{0x00, 0x41, 0x00, 0x62}, "Ab", false},
+		{"bom", []byte{0xFE, 0xFF, 0x00, 0x41}, "A", false},
+		{"bom only", []byte{0xFE, 0xFF}, "", false},
+		{"inner feff kept", []byte{0x00, 0x41, 0xFE, 0xFF}, "A\uFEFF", false},
+		{"surrogate pair", []byte{0xD8, 0x3D, 0xDE, 0x00}, "😀", false},
+		{"odd length", []byte{0x00, 0x41, 0x00}, "A\uFFFD", true},
+		{"single byte", []byte{0x41}, "\uFFFD", true},
+		{"lone high surrogate", []byte{0xD8, 0x3D, 0x00, 0x41}, "\uFFFDA", true},
+		{"trailing high surrogate", []byte{0x00, 0x41, 0xD8, 0x3D}, "A\uFFFD", true},
+		{"lone low surrogate", []byte{0xDE, 0x00, 0x00, 0x41}, "\uFFFDA", true},
+		{"swapped pair", []byte{0xDE, 0x00, 0xD8, 0x3D}, "\uFFFD\uFFFD", true},
+		{"high then pair", []byte{0xD8, 0x3D, 0xD8, 0x3D, 0xDE, 0x00}, "\uFFFD😀", true},
+		{"bom and odd", []byte{0xFE, 0xFF, 0x00, 0x41, 0x42}, "A\uFFFD", true},
+		{"bom and lone surrogate", []byte{0xFE, 0xFF, 0x00, 0x41, 0xD8, 0x3D}, "A\uFFFD", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UTF16ToString(tt.b); got != tt.want {
+				t.Fatalf("UTF16ToString(% X) = %q, want %q", tt.b, got, tt.want)
+			}
+			got, err := UTF16ToStringStrict(tt.b)
+			if (err != nil) != tt.strict {
+				t.Fatalf("UTF16ToStringStrict(% X) error %v, want error %v", tt.b, err, tt.strict)
+			}
+			if got != tt.want {
+				t.Fatalf("UTF16ToStringStrict(% X) = %q, want %q", tt.b, got, tt.want)
+			}
+		})
+	}
+
+	// Surrogates are reported at their offset in the input, a BOM included.
+	for b, want := range map[string]string{
+		"\x00\x41\xD8\x3D":         "unpaired surrogate 0xd83d at byte 2",
+		"\xFE\xFF\x00\x41\xD8\x3D": "unpaired surrogate 0xd83d at byte 4",
+	} {
+		if _, err := UTF16ToStringStrict([]byte(b)); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("UTF16ToStringStrict(% X): %v, want %q", b, err, want)
+		}
+	}
+}
+
+func TestConvNumber(t *testing.T) {
+	type result struct {
+		v  any
+		ok bool
+	}
+	conv := func(v any, ok bool) result { return result{v, ok} }
+	nan, inf := math.NaN(), math.Inf(1)
+	tests := []struct {
+		name string
+		got  result
+		want result
+	}{
+		{"int64 to uint8", conv(ConvNumber[uint8](int64(1))), result{uint8(1), true}},
+		{"int64 overflow uint8", conv(ConvNumber[uint8](int64(256))), result{uint8(0), false}},
+		{"negative int to uint16", conv(ConvNumber[uint16](-1)), result{uint16(0), false}},
+		{"uint64 max to int64", conv(ConvNumber[int64](uint64(math.MaxUint64))), result{int64(0), false}},
+		{"min int64", conv(ConvNumber[int64](int64(math.MinInt64))), result{int64(math.MinInt64), true}},
+		{"min int64 to int32", conv(ConvNumber[int32](int64(math.MinInt64))), result{int32(0), false}},
+		{"min int64 to uint64", conv(ConvNumber[uint64](int64(math.MinInt64))), result{uint64(0), false}},
+		{"min int64 float", conv(ConvNumber[int64](float64(math.MinInt64))), result{int64(math.MinInt64), true}},
+		{"2^63 float to int64", conv(ConvNumber[int64](math.Ldexp(1, 63))), result{int64(0), false}},
+		{"2^63 float to uint64", conv(ConvNumber[uint64](math.Ldexp(1, 63))), result{uint64(1 << 63), true}},
+		{"2^64 float to uint64", conv(ConvNumber[uint64](math.Ldexp(1, 64))), result{uint64(0), false}},
+		{"float truncates", conv(ConvNumber[int16](-3.9)), result{int16(-3), true}},
+		{"float edge int8", conv(ConvNumber[int8](127.9)), result{int8(127), true}},
+		{"float over int8", conv(ConvNumber[int8](128.0)), result{int8(0), false}},
+		{"float under int8", conv(ConvNumber[int8](-128.5)), result{int8(-128), true}},
+		{"negative float to uint8", conv(ConvNumber[uint8](-0.5)), result{uint8(0), false}},
+		{"negative float to uint32", conv(ConvNumber[uint32](float32(-1))), result{uint32(0), false}},
+		{"NaN to int", conv(ConvNumber[int](nan)), result{0, false}},
+		{"NaN to uint16", conv(ConvNumber[uint16](float32(nan))), result{uint16(0), false}},
+		{"+Inf to int64", conv(ConvNumber[int64](inf)), result{int64(0), false}},
+		{"-Inf to int32", conv(ConvNumber[int32](-inf)), result{int32(0), false}},
+		{"int to float64", conv(ConvNumber[float64](int64(math.MaxInt64))), result{float64(math.MaxInt64), true}},
+		{"NaN to float64", conv(ConvNumber[float64](nan)), result{nan, true}},
+		{"float64 to float32", conv(ConvNumber[float32](0.1)), result{float32(0.1), true}},
+		{"float64 overflow float32", conv(ConvNumber[float32](1e39)), result{float32(0), false}},
+		{"float64 underflow float32", conv(ConvNumber[float32](1e-50)), result{float32(0), false}},
+		{"float32 max", conv(ConvNumber[float32](float64(math.MaxFloat32))), result{float32(math.MaxFloat32), true}},
+		{"Inf to float32", conv(ConvNumber[float32](-inf)), result{float32(math.Inf(-1)), true}},
+		{"NaN to float32", conv(ConvNumber[float32](nan)), result{float32(nan), true}},
+		{"zero to float32", conv(ConvNumber[float32](0.0)), result{float32(0), true}},
+	}
+	for _, tt := range tests {
+		if tt.got.ok != tt.want.ok || fmt.Sprintf("%T %v", tt.got.v, tt.got.v) != fmt.Sprintf("%T %v", tt.want.v, tt.want.v) {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.name, tt.got.v, tt.got.ok, tt.want.v, tt.want.ok)
+		}
+	}
+}
@@ -7,13 +7,199 @@ package ttf
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"slices"
+	"strings"
 )
 
+// Severity tells whether a Finding makes a font invalid.
+type Severity int
+
+const (
+	// SeverityError marks a defect making the font invalid, such as a checksum mismatch or
+	// corrupt glyph data.
+	SeverityError Severity = iota
+	// SeverityWarning marks a questionable value that real fonts often have and that does
+	// not make the font invalid, but may make it render badly, such as a wrong searchRange.
+	SeverityWarning
+)
+
+// String returns "error" or "warning".
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Finding is a problem found when validating a font.
+type Finding struct {
+	Severity Severity
+	Table    Tag    // The table concerned, zero if the finding concerns the whole font.
+	Message  string // Names the fields and values involved.
+}
+
+// String returns `f` as "table: message".
+func (f Finding) String() string {
+	if f.Table == (Tag{}) {
+		return f.Message
+	}
+	return f.Table.String() + ": " + f.Message
+}
+
+// ValidationReport lists the findings of validating a font.
+type ValidationReport struct {
+	Findings []Finding // In the order found.
+}
+
+// HasErrors reports whether `rep` has findings of SeverityError, which make the font invalid.
+func (rep *ValidationReport) HasErrors() bool {
+	return slices.ContainsFunc(rep.Findings, func(f Finding) bool { return f.Severity == SeverityError })
+}
+
+// Errors returns the findings of `rep` with SeverityError.
+func (rep *ValidationReport) Errors() []Finding {
+	return rep.filter(SeverityError)
+}
+
+// Warnings returns the findings of `rep` with SeverityWarning.
+func (rep *ValidationReport) Warnings() []Finding {
+	return rep.filter(SeverityWarning)
+}
+
+// filter returns the findings of `rep` with severity `s`.
+func (rep *ValidationReport) filter(s Severity) []Finding {
+	var findings []Finding
+	for _, f := range rep.Findings {
+		if f.Severity == s {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// ValidationProfile selects the rule set of validation.
+type ValidationProfile int
+
+const (
+	// ProfileDefault checks what makes a font unreadable and warns about what makes it render
+	// badly, as desktop and PDF font readers tolerate much else.
+	ProfileDefault ValidationProfile = iota
+	// ProfileOTS also checks rules of the OpenType Sanitizer (ots-sanitize), which Chrome and
+	// Firefox run on web fonts and which rejects fonts other readers accept: tables on 4-byte
+	// boundaries without overlaps, the versions and reserved fields of head, hhea and maxp,
+	// and the language, binary search parameters and idRangeOffsets of cmap format 4
+	// subtables. Name records that it drops, unsorted or of odd length in UTF-16, and table
+	// records not sorted by tag, which it sorts, are warned about.
+	ProfileOTS
+)
+
+// ValidationOptions selects the optional checks of validation.
+type ValidationOptions struct {
+	// CheckGlyphs decodes the header of every glyph in the glyf table, which takes time in
+	// proportion to the size of the table.
+	CheckGlyphs bool
+
+	// EmbeddingOnly validates the font as embedded in a document that maps characters to
+	// glyphs itself rather than as a standalone font, so that the cmap table is not required.
+	// See SubsetOptions.DropCmap.
+	EmbeddingOnly bool
+
+	// Profile selects the rule set, ProfileDefault if zero.
+	Profile ValidationProfile
+
+	// IgnoreChecksumFor lists the tags of tables, e.g. "DSIG", whose checksum mismatches are
+	// reported as warnings rather than errors. Fonts of major vendors ship with such tables,
+	// such as a DSIG left stale by subsetting upstream, and readers do not check them. All
+	// other checks still run.
+	IgnoreChecksumFor []string
+}
+
+// LenientChecksumOptions returns validation options that only warn about a wrong checksum of
+// DSIG, which is the one most often stale in fonts that are fine otherwise.
+func LenientChecksumOptions() ValidationOptions {
+	return ValidationOptions{IgnoreChecksumFor: []string{"DSIG"}}
+}
+
+// ignoresChecksum reports whether `opts` lists `tag` in IgnoreChecksumFor, with or without
+// the trailing spaces of tags shorter than four letters.
+func (opts ValidationOptions) ignoresChecksum(tag Tag) bool {
+	return slices.ContainsFunc(opts.IgnoreChecksumFor, func(s string) bool {
+		return strings.TrimRight(s, " ") == tag.String()
+	})
+}
+
+// embeddingTables are the tables required to render glyphs by index, as in a font embedded
+// in a PDF document. A standalone font also requires cmap to find the glyphs of characters.
+var embeddingTables = []Tag{tagHead, tagHhea, tagHmtx, tagMaxp, tagLoca, tagGlyf}
+
+// cffEmbeddingTables are the embeddingTables of a font with CFF outlines, which are in the CFF
+// or CFF2 table, checked for by hasCFF, rather than loca and glyf.
+var cffEmbeddingTables = []Tag{tagHead, tagHhea, tagHmtx, tagMaxp}
+
+// errorf adds an error about `table` to `rep`.
+func (rep *ValidationReport) errorf(table Tag, format string, a ...any) {
+	rep.Findings = append(rep.Findings, Finding{Severity: SeverityError, Table: table, Message: fmt.Sprintf(format, a...)})
+}
+
+// warnf adds a warning about `table` to `rep`.
+func (rep *ValidationReport) warnf(table Tag, format string, a ...any) {
+	rep.Findings = append(rep.Findings, Finding{Severity: SeverityWarning, Table: table, Message: fmt.Sprintf(format, a...)})
+}
+
 // validate font data model `f` in `r`. Checks if required tables are present and whether
-// table checksums are correct.
-func (f *font) validate(r *byteReader) error {
+// table checksums are correct, and reports questionable values that do not make the font
+// invalid. An error is returned if the report has errors.
+func (f *font) validate(r *byteReader, opts ValidationOptions) (*ValidationReport, error) {
+	rep := &ValidationReport{}
+	if err := f.validateStructure(r, rep, opts); err != nil {
+		return rep, err
+	}
+	for _, s := range f.incompatibilities {
+		rep.warnf(Tag{}, "%s", s)
+	}
+	required := embeddingTables
+	if !f.trec.HasTag(tagGlyf) && f.hasCFF() {
+		required = cffEmbeddingTables
+	}
+	if !opts.EmbeddingOnly {
+		required = append(slices.Clip(required), tagCmap)
+	}
+	for _, tag := range required {
+		if !f.trec.HasTag(tag) {
+			rep.errorf(tag, "required table missing")
+		}
+	}
+	f.checkVerticalMetrics(rep)
+	f.checkCaretSlope(rep)
+	f.checkMaxpVersion(rep)
+	f.checkGasp(rep)
+	f.checkBitmapTables(rep)
+	f.checkPostNumGlyphs(rep)
+	f.checkCmap(rep, opts.Profile)
+	if opts.CheckGlyphs {
+		f.checkGlyphs(rep)
+	}
+	if opts.Profile == ProfileOTS {
+		f.checkOTS(rep)
+	}
+	if errs := rep.Errors(); len(errs) > 0 {
+		return rep, fmt.Errorf("%s (%d errors)", errs[0], len(errs))
+	}
+	return rep, nil
+}
+
+// validateStructure checks the checksums of `f` in `r`, adding what it
+// finds to `rep`, as warnings for those that `opts` ignores. An error is returned if the
+// structure cannot be read.
+func (f *font) validateStructure(r *byteReader, rep *ValidationReport, opts ValidationOptions) error {
 	if f.trec == nil {
 		// slog.Debug("Table records missing")
 		return errRequiredField
@@ -29,6 +215,7 @@ func (f *font) validate(r *byteReader) error {
 
 	// Validate the font.
 	// slog.Debug("Validating entire font")
+	var size int64
 	{
 		err := r.SeekTo(0)
 		if err != nil {
@@ -42,27 +229,21 @@ func (f *font) validate(r *byteReader) error {
 		}
 
 		data := buf.Bytes()
+		size = int64(len(data))
 
-		headRec, ok := f.trec.trMap["head"]
+		headRec, ok := f.trec.trMap[tagHead]
 		if !ok {
 			// slog.Debug("head not set")
 			return errRequiredField
 		}
-		hoff := headRec.offset
-
-		// set checksumAdjustment data to 0 in the head table.
-		data[hoff+8] = 0
-		data[hoff+9] = 0
-		data[hoff+10] = 0
-		data[hoff+11] = 0
-
-		bw := newByteWriter(&bytes.Buffer{})
-		bw.buffer.Write(data)
+		hoff := int64(headRec.offset)
+		if hoff+headAdjustmentOffset+4 > int64(len(data)) {
+			return errors.New("head outside file")
+		}
 
-		checksum := bw.checksum()
-		adjustment := 0xB1B0AFBA - checksum
+		adjustment := fileChecksumAdjustment(data, hoff)
 		if f.head.checksumAdjustment != adjustment {
-			return errors.New("file checksum mismatch")
+			rep.errorf(tagHead, "file checksum mismatch: checksumAdjustment %08X, want %08X", f.head.checksumAdjustment, adjustment)
 		}
 	}
 
@@ -72,12 +253,14 @@ func (f *font) validate(r *byteReader) error {
 		// slog.Debug(fmt.Sprintf("Validating %s", tr.tableTag.String()))
 		// slog.Debug(fmt.Sprintf("%+v", tr))
 
-		bw := newByteWriter(&bytes.Buffer{})
-
 		if tr.offset < 0 || tr.length < 0 {
 			// slog.Debug("Range check error")
 			return errRangeCheck
 		}
+		if end := int64(tr.offset) + int64(tr.length); end > size {
+			rep.errorf(tr.tableTag, "ends at %d past the end of the file %d", end, size)
+			continue
+		}
 
 		// slog.Debug(fmt.Sprintf("Seeking to %d, to read %d bytes", tr.offset, tr.length))
 		err := r.SeekTo(int64(tr.offset))
@@ -93,30 +276,321 @@ func (f *font) validate(r *byteReader) error {
 		}
 		// slog.Debug(fmt.Sprintf("Read (%d)", len(b)))
 		// TODO(gunnsth): Validate head.
-		if tr.tableTag.String() == "head" {
-			// Set the checksumAdjustment to 0 so that head checksum is valid.
-			if len(b) < 12 {
-				return errors.New("head too short")
+		if tr.tableTag == tagHead && len(b) < headAdjustmentOffset+4 {
+			return errors.New("head too short")
+		}
+
+		checksum := tableChecksum(tr.tableTag, b)
+		if tr.checksum != checksum {
+			report := rep.errorf
+			if opts.ignoresChecksum(tr.tableTag) {
+				report = rep.warnf
 			}
-			b[8], b[9], b[10], b[11] = 0, 0, 0, 0
+			report(tr.tableTag, "checksum incorrect: %08X, table record says %08X", checksum, tr.checksum)
 		}
+	}
 
-		_, err = bw.buffer.Write(b)
-		if err != nil {
-			return err
+	return nil
+}
+
+// caretAngleTolerance is the difference in degrees between the caret angle and the italic
+// angle above which checkCaretSlope warns.
+const caretAngleTolerance = 1
+
+// checkCaretSlope reports an italic font whose caret slope in hhea does not follow the italic
+// angle in post, so that editors draw the caret across the slanted glyphs.
+func (f *font) checkCaretSlope(rep *ValidationReport) {
+	if f.hhea == nil || f.post == nil || f.post.italicAngle == 0 {
+		return
+	}
+	italic, caret := f.post.italicAngle.Float64(), f.hhea.caretAngle()
+	if math.Abs(caret-italic) > caretAngleTolerance {
+		rep.warnf(tagHhea, "caret slope %d/%d is at %.1f degrees, not at the italic angle %.1f of post",
+			f.hhea.caretSlopeRise, f.hhea.caretSlopeRun, caret, italic)
+	}
+}
+
+// checkMaxpVersion reports a maxp version that does not fit the outlines: 0.5 with glyf, whose
+// rasterizers need the maxima of 1.0, and 1.0 with CFF outlines only, which readers tolerate.
+func (f *font) checkMaxpVersion(rep *ValidationReport) {
+	if f.maxp == nil {
+		return
+	}
+	glyf := f.trec.HasTag(tagGlyf)
+	switch {
+	case f.maxp.version == maxpVersion05 && glyf:
+		rep.errorf(tagMaxp, "version 0.5 with TrueType outlines, not 1.0")
+	case f.maxp.version == maxpVersion10 && !glyf && f.hasCFF():
+		rep.warnf(tagMaxp, "version 1.0 with CFF outlines, not 0.5")
+	}
+}
+
+// checkGasp reports gasp ranges out of order, which rasterizers search assuming they are
+// sorted, and ranges that leave sizes or flags without a defined behavior.
+func (f *font) checkGasp(rep *ValidationReport) {
+	t := f.gasp
+	if t == nil {
+		return
+	}
+	if t.version > 1 {
+		rep.warnf(tagGasp, "version %d, not 0 or 1", t.version)
+	}
+	if len(t.ranges) == 0 {
+		rep.warnf(tagGasp, "no ranges")
+		return
+	}
+	for i, rng := range t.ranges {
+		if i > 0 && rng.MaxPPEM <= t.ranges[i-1].MaxPPEM {
+			rep.errorf(tagGasp, "range %d up to %d ppem is not above range %d up to %d ppem",
+				i, rng.MaxPPEM, i-1, t.ranges[i-1].MaxPPEM)
 		}
+		switch {
+		case rng.Behavior&^(GaspGridfit|GaspDoGray|GaspSymmetricGridfit|GaspSymmetricSmoothing) != 0:
+			rep.warnf(tagGasp, "range %d has reserved behavior flags 0x%04X", i, uint16(rng.Behavior))
+		case t.version == 0 && rng.Behavior&(GaspSymmetricGridfit|GaspSymmetricSmoothing) != 0:
+			rep.warnf(tagGasp, "range %d has symmetric behavior flags in version 0", i)
+		}
+	}
+	if last := t.ranges[len(t.ranges)-1].MaxPPEM; last != 0xFFFF {
+		rep.warnf(tagGasp, "last range ends at %d ppem, not 0xFFFF", last)
+	}
+}
 
-		checksum := bw.checksum()
-		if tr.checksum != checksum {
-			// slog.Debug(fmt.Sprintf("Invalid checksum (%d != %d)", checksum, tr.checksum))
-			return errors.New("checksum incorrect")
+// checkBitmapTables reports a bitmap table without the one it goes with, see bitmapRequires,
+// such as bitmap data without their locations, which rasterizers cannot use.
+func (f *font) checkBitmapTables(rep *ValidationReport) {
+	for _, tag := range bitmapTags {
+		if needs := bitmapRequires[tag]; f.trec.HasTag(tag) && !f.trec.HasTag(needs) {
+			rep.errorf(tag, "without %s", needs)
 		}
+	}
+}
 
-		if int(tr.length) != bw.bufferedLen() {
-			// slog.Debug("Length mismatch")
-			return errRangeCheck
+// checkPostNumGlyphs reports a post table with glyph names for a different number of glyphs
+// than maxp.numGlyphs, which parsing tolerates outside strict mode.
+func (f *font) checkPostNumGlyphs(rep *ValidationReport) {
+	if f.post == nil || f.maxp == nil {
+		return
+	}
+	var numGlyphs int
+	switch uint32(f.post.version) {
+	case 0x00010000:
+		numGlyphs = len(macGlyphNames)
+	case 0x00020000, 0x00025000:
+		numGlyphs = int(f.post.numGlyphs)
+	default:
+		return
+	}
+	if numGlyphs != int(f.maxp.numGlyphs) {
+		rep.errorf(tagPost, "names for %d glyphs, maxp.numGlyphs is %d", numGlyphs, f.maxp.numGlyphs)
+	}
+}
+
+// checkCmap checks the structure of the format 4 and 12 cmap subtables: segments and groups
+// in ascending order without overlaps, the final 0xFFFF segment of format 4, and glyph indices
+// below maxp.numGlyphs. The binary search parameters of format 4 are only warned about, as
+// most readers ignore them, unless `profile` is ProfileOTS, which also checks the language
+// and idRangeOffsets of format 4.
+func (f *font) checkCmap(rep *ValidationReport, profile ValidationProfile) {
+	if f.cmap == nil {
+		return
+	}
+	for _, key := range f.cmap.subtableKeys {
+		subt := f.cmap.subtables[key]
+		switch t := subt.ctx.(type) {
+		case cmapSubtableFormat4:
+			segments := int(t.segCountX2 / 2)
+			if t.segCountX2%2 != 0 || segments == 0 {
+				rep.errorf(tagCmap, "subtable %s: segCountX2 %d is odd or 0", key, t.segCountX2)
+				continue
+			}
+			searchRange, entrySelector, rangeShift := binarySearchParams(segments, 2)
+			if t.searchRange != searchRange || t.entrySelector != entrySelector || t.rangeShift != rangeShift {
+				report := rep.warnf
+				if profile == ProfileOTS {
+					report = rep.errorf
+				}
+				report(tagCmap, "subtable %s: searchRange/entrySelector/rangeShift %d/%d/%d, want %d/%d/%d for %d segments",
+					key, t.searchRange, t.entrySelector, t.rangeShift, searchRange, entrySelector, rangeShift, segments)
+			}
+			for i := 0; i < segments; i++ {
+				if t.startCode[i] > t.endCode[i] {
+					rep.errorf(tagCmap, "subtable %s: segment %d starts at %d after its end %d", key, i, t.startCode[i], t.endCode[i])
+				}
+				if i > 0 && t.startCode[i] <= t.endCode[i-1] {
+					rep.errorf(tagCmap, "subtable %s: segment %d starting at %d is not after the end %d of segment %d",
+						key, i, t.startCode[i], t.endCode[i-1], i-1)
+				}
+			}
+			if t.endCode[segments-1] != 0xFFFF {
+				rep.errorf(tagCmap, "subtable %s: last segment ends at %d, not 0xFFFF", key, t.endCode[segments-1])
+			}
+			if profile == ProfileOTS {
+				if t.language != 0 && subt.platformID != int(PlatformMacintosh) {
+					rep.errorf(tagCmap, "subtable %s: language %d, not 0", key, t.language)
+				}
+				for i, offset := range t.idRangeOffset {
+					if offset%2 != 0 {
+						rep.errorf(tagCmap, "subtable %s: idRangeOffset %d of segment %d is odd", key, offset, i)
+					}
+				}
+			}
+		case cmapSubtableFormat12:
+			for i, g := range t.groups {
+				if g.startCharCode > g.endCharCode {
+					rep.errorf(tagCmap, "subtable %s: group %d starts at %d after its end %d", key, i, g.startCharCode, g.endCharCode)
+				}
+				if i > 0 && g.startCharCode <= t.groups[i-1].endCharCode {
+					rep.errorf(tagCmap, "subtable %s: group %d starting at %d is not after the end %d of group %d",
+						key, i, g.startCharCode, t.groups[i-1].endCharCode, i-1)
+				}
+			}
+		default:
+			continue
+		}
+		if f.maxp == nil {
+			continue
+		}
+		past := 0
+		for _, gid := range subt.charcodeToGID {
+			if int(gid) >= int(f.maxp.numGlyphs) {
+				past++
+			}
+		}
+		if past > 0 {
+			rep.errorf(tagCmap, "subtable %s: %d codes map to glyphs past numGlyphs %d", key, past, f.maxp.numGlyphs)
 		}
 	}
+}
 
-	return nil
+// Tolerances as fractions of unitsPerEm by which the hhea and OS/2 typo metrics may differ
+// before checkVerticalMetrics reports them. The line height (ascender - descender + lineGap)
+// is what renderers use for line spacing, so it is held closer than the single fields, which
+// are often shifted between ascender and lineGap.
+const (
+	typoFieldTolerance  = 0.25
+	typoHeightTolerance = 0.1
+)
+
+// checkVerticalMetrics compares the vertical metrics of the hhea and OS/2 tables with each
+// other and with the font bounding box. Renderers pick different sets of them, so
+// disagreements show as clipped or differently spaced text depending on the platform.
+func (f *font) checkVerticalMetrics(rep *ValidationReport) {
+	if f.os2 == nil {
+		return
+	}
+	os2 := f.os2
+	if os2.sTypoAscender == 0 && os2.sTypoDescender == 0 && os2.sTypoLineGap == 0 {
+		rep.warnf(tagOS2, "sTypoAscender, sTypoDescender and sTypoLineGap are all 0")
+	}
+	if f.head != nil {
+		if int(os2.usWinAscent) < int(f.head.yMax) {
+			rep.warnf(tagOS2, "usWinAscent %d is less than head.yMax %d, glyph tops are clipped on Windows",
+				os2.usWinAscent, f.head.yMax)
+		}
+		if int(os2.usWinDescent) < -int(f.head.yMin) {
+			rep.warnf(tagOS2, "usWinDescent %d is less than -head.yMin %d, glyph bottoms are clipped on Windows",
+				os2.usWinDescent, -int(f.head.yMin))
+		}
+	}
+	if f.hhea == nil {
+		return
+	}
+	hhea := f.hhea
+	if int(os2.usWinAscent) < int(hhea.ascender) {
+		rep.warnf(tagOS2, "usWinAscent %d is less than hhea.ascender %d", os2.usWinAscent, hhea.ascender)
+	}
+	if int(os2.usWinDescent) < -int(hhea.descender) {
+		rep.warnf(tagOS2, "usWinDescent %d is less than -hhea.descender %d", os2.usWinDescent, -int(hhea.descender))
+	}
+	if f.head == nil || f.head.unitsPerEm == 0 {
+		return
+	}
+	upem := float64(f.head.unitsPerEm)
+	exceeds := func(typo, hhea int, tolerance float64) bool {
+		return math.Abs(float64(typo-hhea)) > tolerance*upem
+	}
+	if exceeds(int(os2.sTypoAscender), int(hhea.ascender), typoFieldTolerance) {
+		rep.warnf(tagOS2, "sTypoAscender %d differs from hhea.ascender %d by more than %.0f%% of unitsPerEm %d",
+			os2.sTypoAscender, hhea.ascender, typoFieldTolerance*100, f.head.unitsPerEm)
+	}
+	if exceeds(int(os2.sTypoDescender), int(hhea.descender), typoFieldTolerance) {
+		rep.warnf(tagOS2, "sTypoDescender %d differs from hhea.descender %d by more than %.0f%% of unitsPerEm %d",
+			os2.sTypoDescender, hhea.descender, typoFieldTolerance*100, f.head.unitsPerEm)
+	}
+	typoHeight := int(os2.sTypoAscender) - int(os2.sTypoDescender) + int(os2.sTypoLineGap)
+	hheaHeight := int(hhea.ascender) - int(hhea.descender) + int(hhea.lineGap)
+	if exceeds(typoHeight, hheaHeight, typoHeightTolerance) {
+		rep.warnf(tagOS2, "line height %d from sTypoAscender %d, sTypoDescender %d and sTypoLineGap %d "+
+			"differs from %d of hhea (%d, %d, %d) by more than %.0f%% of unitsPerEm %d",
+			typoHeight, os2.sTypoAscender, os2.sTypoDescender, os2.sTypoLineGap,
+			hheaHeight, hhea.ascender, hhea.descender, hhea.lineGap, typoHeightTolerance*100, f.head.unitsPerEm)
+	}
+}
+
+// checkGlyphs decodes the header of each glyph description and reports structural defects:
+// an invalid numberOfContours, endPtsOfContours not strictly increasing, instructions running
+// past the glyph data, and composite glyphs with unreadable components or components
+// referring to glyphs past numGlyphs.
+func (f *font) checkGlyphs(rep *ValidationReport) {
+	if f.glyf == nil {
+		return
+	}
+	numGlyphs := len(f.glyf.descs)
+	for i, desc := range f.glyf.descs {
+		raw := desc.raw
+		if desc.isEmpty() {
+			continue // Empty glyph, e.g. space.
+		}
+		if len(raw) < 10 {
+			rep.errorf(tagGlyf, "glyph %d: %d bytes are too short for the 10 byte header", i, len(raw))
+			continue
+		}
+		numberOfContours := int16(binary.BigEndian.Uint16(raw))
+		switch {
+		case numberOfContours == 0 && len(raw) <= 12:
+			// No outline, only a header and maybe an instructionLength, which some fonts
+			// give glyphs such as space instead of leaving them empty.
+		case numberOfContours < -1:
+			rep.errorf(tagGlyf, "glyph %d: numberOfContours %d is less than -1", i, numberOfContours)
+		case numberOfContours == -1:
+			gd := glyphDescription{raw: raw}
+			if err := gd.parse(); err != nil {
+				rep.errorf(tagGlyf, "glyph %d: composite: %v", i, err)
+				continue
+			}
+			for j, comp := range gd.composite.components {
+				if int(comp.glyphIndex) >= numGlyphs {
+					rep.errorf(tagGlyf, "glyph %d: component %d refers to glyph %d, numGlyphs is %d",
+						i, j, comp.glyphIndex, numGlyphs)
+				}
+			}
+		default:
+			checkSimpleGlyph(rep, i, raw, int(numberOfContours))
+		}
+	}
+}
+
+// checkSimpleGlyph checks the contour end points and the instruction length of simple glyph
+// `gid` with data `raw`.
+func checkSimpleGlyph(rep *ValidationReport, gid int, raw []byte, numberOfContours int) {
+	instrAt := 10 + 2*numberOfContours
+	if instrAt+2 > len(raw) {
+		rep.errorf(tagGlyf, "glyph %d: %d contours do not fit in %d bytes", gid, numberOfContours, len(raw))
+		return
+	}
+	prev := -1
+	for c := 0; c < numberOfContours; c++ {
+		end := int(binary.BigEndian.Uint16(raw[10+2*c:]))
+		if end <= prev {
+			rep.errorf(tagGlyf, "glyph %d: endPtsOfContours[%d] %d does not follow %d", gid, c, end, prev)
+			return
+		}
+		prev = end
+	}
+	instructionLength := int(binary.BigEndian.Uint16(raw[instrAt:]))
+	if instrAt+2+instructionLength > len(raw) {
+		rep.errorf(tagGlyf, "glyph %d: instructionLength %d runs past the glyph length %d",
+			gid, instructionLength, len(raw))
+	}
 }
@@ -0,0 +1,77 @@
+/*
+ * This file is subject to the terms and conditions defined in
+ * file 'LICENSE.md', which is part of this source code package.
+ */
+
+package ttf
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// checkOTS adds the findings of the rules of ProfileOTS that are not part of the checks of
+// ProfileDefault, except those of checkCmap, to `rep`.
+func (f *font) checkOTS(rep *ValidationReport) {
+	f.checkOTSDirectory(rep)
+	if t := f.head; t != nil {
+		if t.majorVersion != 1 || t.minorVersion != 0 {
+			rep.errorf(tagHead, "version %d.%d, not 1.0", t.majorVersion, t.minorVersion)
+		}
+		if t.unitsPerEm < 16 || t.unitsPerEm > 16384 {
+			rep.errorf(tagHead, "unitsPerEm %d outside 16 to 16384", t.unitsPerEm)
+		}
+		if t.glyphDataFormat != 0 {
+			rep.errorf(tagHead, "glyphDataFormat %d, not 0", t.glyphDataFormat)
+		}
+	}
+	if t := f.hhea; t != nil {
+		if t.majorVersion != 1 {
+			rep.errorf(tagHhea, "majorVersion %d, not 1", t.majorVersion)
+		}
+		if t.metricDataFormat != 0 {
+			rep.errorf(tagHhea, "metricDataFormat %d, not 0", t.metricDataFormat)
+		}
+		if t.reserved != [4]int16{} {
+			rep.errorf(tagHhea, "reserved fields %v, not 0", t.reserved)
+		}
+	}
+	if t := f.maxp; t != nil {
+		if t.version != maxpVersion05 && t.version != maxpVersion10 {
+			rep.errorf(tagMaxp, "version %08X, not 0.5 or 1.0", uint32(t.version))
+		}
+	}
+	if t := f.name; t != nil {
+		for i, rec := range t.nameRecords {
+			if i > 0 && compareNameRecords(t.nameRecords[i-1], rec) > 0 {
+				rep.warnf(tagName, "record %d not sorted by platform, encoding, language and name ID, dropped by OTS", i)
+			}
+			utf16 := rec.platformID == uint16(PlatformUnicode) || rec.platformID == uint16(PlatformWindows)
+			if utf16 && rec.length%2 != 0 {
+				rep.warnf(tagName, "record %d (name ID %d) of odd length %d in UTF-16, dropped by OTS", i, rec.nameID, rec.length)
+			}
+		}
+	}
+}
+
+// checkOTSDirectory checks that the tables of the table directory of `f` start on 4-byte
+// boundaries and do not overlap, and warns about records not sorted by tag.
+func (f *font) checkOTSDirectory(rep *ValidationReport) {
+	for i, tr := range f.trec.list {
+		if tr.offset%4 != 0 {
+			rep.errorf(tr.tableTag, "offset %d not a multiple of 4", tr.offset)
+		}
+		if i > 0 && bytes.Compare(f.trec.list[i-1].tableTag[:], tr.tableTag[:]) >= 0 {
+			rep.warnf(tr.tableTag, "table record not sorted by tag after %s", f.trec.list[i-1].tableTag)
+		}
+	}
+	byOffset := slices.Clone(f.trec.list)
+	slices.SortStableFunc(byOffset, func(a, b *tableRecord) int { return cmp.Compare(a.offset, b.offset) })
+	for i := 1; i < len(byOffset); i++ {
+		prev, tr := byOffset[i-1], byOffset[i]
+		if end := int64(prev.offset) + int64(prev.length); end > int64(tr.offset) {
+			rep.errorf(tr.tableTag, "at offset %d overlaps %s ending at %d", tr.offset, prev.tableTag, end)
+		}
+	}
+}
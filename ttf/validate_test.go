@@ -0,0 +1,709 @@
+package ttf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestCheckVerticalMetrics(t *testing.T) {
+	rep, err := ValidateBytesReport(goregular.TTF, ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Go Regular has glyphs reaching past its Windows metrics.
+	if len(rep.Warnings()) != 2 || !strings.Contains(rep.Warnings()[0].String(), "usWinAscent 1935 is less than head.yMax 2291") {
+		t.Fatalf("Go Regular: got warnings %v", rep.Warnings())
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *Font)
+		want   []string
+	}{
+		{"consistent", func(f *Font) {}, nil},
+		{"win ascent below hhea", func(f *Font) {
+			f.os2.usWinAscent = uint16(f.hhea.ascender) - 1
+			f.head.yMax = 1900
+		}, []string{"usWinAscent 1934 is less than hhea.ascender 1935"}},
+		{"win descent below bbox", func(f *Font) {
+			f.head.yMin = -500
+			f.hhea.descender = -400
+			f.os2.usWinDescent = 400
+			f.os2.sTypoDescender = -400
+		}, []string{"usWinDescent 400 is less than -head.yMin 500"}},
+		{"zero typo metrics", func(f *Font) {
+			f.os2.sTypoAscender, f.os2.sTypoDescender, f.os2.sTypoLineGap = 0, 0, 0
+		}, []string{
+			"sTypoAscender, sTypoDescender and sTypoLineGap are all 0",
+			"sTypoAscender 0 differs from hhea.ascender 1935 by more than 25% of unitsPerEm 2048",
+			"line height 0 from sTypoAscender 0, sTypoDescender 0 and sTypoLineGap 0 differs from 2367 of hhea (1935, -432, 0)",
+		}},
+		{"typo metrics shifted to lineGap", func(f *Font) {
+			f.os2.sTypoAscender -= 400
+			f.os2.sTypoLineGap += 400
+		}, nil},
+		{"typo line gap too large", func(f *Font) {
+			f.os2.sTypoLineGap = 300
+		}, []string{"line height 2667 from sTypoAscender 1935, sTypoDescender -432 and sTypoLineGap 300 differs from 2367"}},
+		{"typo ascender far off", func(f *Font) {
+			f.os2.sTypoAscender += 600
+			f.os2.sTypoDescender += 600
+		}, []string{"sTypoAscender 2535 differs from hhea.ascender 1935 by more than 25% of unitsPerEm 2048",
+			"sTypoDescender 168 differs from hhea.descender -432"}},
+		{"no OS/2", func(f *Font) { f.os2 = nil }, nil},
+	}
+	for _, tt := range tests {
+		f := loadGoRegular(t)
+		f.os2.sTypoAscender, f.os2.sTypoDescender, f.os2.sTypoLineGap =
+			int16(f.hhea.ascender), int16(f.hhea.descender), int16(f.hhea.lineGap)
+		f.os2.usWinAscent, f.os2.usWinDescent = uint16(f.hhea.ascender), uint16(-f.hhea.descender)
+		f.head.yMin, f.head.yMax = int16(f.hhea.descender), int16(f.hhea.ascender)
+		tt.modify(f)
+		rep := &ValidationReport{}
+		f.checkVerticalMetrics(rep)
+		if len(rep.Warnings()) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %d warnings", tt.name, rep.Warnings(), len(tt.want))
+		}
+		for i, w := range rep.Warnings() {
+			if !strings.HasPrefix(w.String(), "OS/2: "+tt.want[i]) {
+				t.Fatalf("%s: warning %d is %q, want %q", tt.name, i, w, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestCheckCaretSlope(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(f *Font)
+		want   string
+	}{
+		{"Go Italic", func(f *Font) {}, ""},
+		{"vertical caret", func(f *Font) {
+			f.hhea.caretSlopeRise, f.hhea.caretSlopeRun = 1, 0
+		}, "hhea: caret slope 1/0 is at 0.0 degrees, not at the italic angle -11.0 of post"},
+		{"leaning left", func(f *Font) {
+			f.hhea.caretSlopeRun = -398
+		}, "hhea: caret slope 2048/-398 is at 11.0 degrees, not at the italic angle -11.0 of post"},
+		{"upright", func(f *Font) {
+			f.post.italicAngle = 0
+		}, ""},
+	}
+	for _, tt := range tests {
+		f, err := Parse(bytes.NewReader(goitalic.TTF))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.modify(f)
+		rep := &ValidationReport{}
+		f.checkCaretSlope(rep)
+		switch {
+		case tt.want == "" && len(rep.Findings) > 0:
+			t.Fatalf("%s: got %v", tt.name, rep.Findings)
+		case tt.want != "" && (len(rep.Warnings()) != 1 || rep.Warnings()[0].String() != tt.want):
+			t.Fatalf("%s: got %v, want %q", tt.name, rep.Findings, tt.want)
+		}
+	}
+}
+
+func TestCheckGasp(t *testing.T) {
+	tests := []struct {
+		name string
+		gasp *gaspTable
+		want []string
+	}{
+		{"none", nil, nil},
+		{"three ranges", &gaspTable{version: 1, ranges: []GaspRange{{8, GaspDoGray}, {16, GaspGridfit}, {0xFFFF, 15}}}, nil},
+		{"out of order", &gaspTable{version: 0, ranges: []GaspRange{{16, GaspGridfit}, {8, GaspDoGray}, {0xFFFF, 3}}},
+			[]string{"error gasp: range 1 up to 8 ppem is not above range 0 up to 16 ppem"}},
+		{"open end", &gaspTable{version: 0, ranges: []GaspRange{{8, GaspDoGray}, {100, 3}}},
+			[]string{"warning gasp: last range ends at 100 ppem, not 0xFFFF"}},
+		{"flags", &gaspTable{version: 0, ranges: []GaspRange{{8, 0x12}, {0xFFFF, GaspSymmetricGridfit}}}, []string{
+			"warning gasp: range 0 has reserved behavior flags 0x0012",
+			"warning gasp: range 1 has symmetric behavior flags in version 0",
+		}},
+		{"empty", &gaspTable{version: 2}, []string{"warning gasp: version 2, not 0 or 1", "warning gasp: no ranges"}},
+	}
+	for _, tt := range tests {
+		f := loadGoRegular(t)
+		f.gasp = tt.gasp
+		rep := &ValidationReport{}
+		f.checkGasp(rep)
+		var got []string
+		for _, f := range rep.Findings {
+			got = append(got, fmt.Sprintf("%s %s", f.Severity, f))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// fixChecksums updates the table checksums and head.checksumAdjustment of font file `data`
+// after an edit.
+func fixChecksums(t *testing.T, data []byte) {
+	t.Helper()
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adj := data[f.trec.trMap[tagHead].offset+8:]
+	binary.BigEndian.PutUint32(adj, 0)
+	for i, tr := range f.trec.list {
+		sum := calcChecksum(data[tr.offset : tr.offset+offset32(tr.length)])
+		binary.BigEndian.PutUint32(data[12+16*i+4:], sum)
+	}
+	binary.BigEndian.PutUint32(adj, 0xB1B0AFBA-calcChecksum(data))
+}
+
+func TestParse_NumTables(t *testing.T) {
+	withNumTables := func(n int) []byte {
+		data := bytes.Clone(goregular.TTF)
+		binary.BigEndian.PutUint16(data[4:], uint16(n))
+		return data
+	}
+	n := int(binary.BigEndian.Uint16(goregular.TTF[4:]))
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"truncated directory", withNumTables(n)[:12+16*5+8], "offset table declares 14 tables, only 5 records present"},
+		{"numTables too large", withNumTables(n + 1), "offset table declares 15 tables, directory ending at 252 overlaps the first table"},
+		{"numTables too small", withNumTables(n - 2), "offset table declares 12 tables, but 2 more records follow"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(bytes.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: got error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	zeroTag := bytes.Clone(goregular.TTF)
+	clear(zeroTag[12+16*3 : 12+16*3+4])
+	if _, err := Parse(bytes.NewReader(zeroTag)); err == nil || !strings.Contains(err.Error(), "record 3 at offset 76: zero tag") {
+		t.Fatalf("zero tag: got error %v", err)
+	}
+
+	// A wrong searchRange is only a warning.
+	data := bytes.Clone(goregular.TTF)
+	binary.BigEndian.PutUint16(data[6:], 64)
+	fixChecksums(t, data)
+	rep, err := ValidateBytesReport(data, ValidationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "searchRange/entrySelector/rangeShift 64/3/96, want 128/3/96 for 14 tables"
+	if !slices.ContainsFunc(rep.Warnings(), func(f Finding) bool { return strings.Contains(f.Message, want) }) {
+		t.Fatalf("got warnings %v, want %q", rep.Warnings(), want)
+	}
+	for n, want := range map[int][3]uint16{0: {}, 1: {16, 0, 0}, 13: {128, 3, 80}, 16: {256, 4, 0}, 17: {256, 4, 16}} {
+		if sr, es, rs := binarySearchParams(n, 16); [3]uint16{sr, es, rs} != want {
+			t.Fatalf("binarySearchParams(%d) = %d %d %d, want %v", n, sr, es, rs, want)
+		}
+	}
+}
+
+// setGlyphData replaces the data of glyph `gid` of `f` with `raw` and updates loca.
+func setGlyphData(f *Font, gid GlyphIndex, raw []byte) {
+	f.markDirty(tagGlyf, tagLoca)
+	f.glyf.descs[gid] = &glyphDescription{raw: raw}
+	var off int
+	for i, desc := range f.glyf.descs {
+		off += len(desc.raw)
+		if f.head.indexToLocFormat == 0 {
+			f.loca.offsetsShort[i+1] = offset16(off / 2)
+		} else {
+			f.loca.offsetsLong[i+1] = offset32(off)
+		}
+	}
+}
+
+func TestCheckGlyphs(t *testing.T) {
+	rep, err := ValidateBytesReport(goregular.TTF, ValidationOptions{CheckGlyphs: true})
+	if err != nil || len(rep.Errors()) != 0 {
+		t.Fatalf("Go Regular: %v %v", err, rep.Errors())
+	}
+
+	f := loadGoRegular(t)
+	cmap := f.cmapOf(PlatformWindows, EncodingWindowsUnicodeBMP)
+	raw := func(r rune) []byte { return f.glyf.descs[cmap[r]].raw }
+	if n := int16(binary.BigEndian.Uint16(raw('o'))); n != 2 {
+		t.Fatalf("'o' has %d contours", n)
+	}
+	numGlyphs := len(f.glyf.descs)
+	// Go Regular has no composite glyphs, so one is made: header, then a component with word
+	// arguments and no more components.
+	composite := func(component GlyphIndex) []byte {
+		b := binary.BigEndian.AppendUint16(nil, 0xFFFF)
+		b = append(b, raw('A')[2:10]...)
+		b = binary.BigEndian.AppendUint16(b, uint16(arg1And2AreWords|argsAreXYValues))
+		b = binary.BigEndian.AppendUint16(b, uint16(component))
+		return append(b, 0, 0, 0, 0)
+	}
+
+	tests := []struct {
+		name   string
+		r      rune
+		modify func(raw []byte) []byte
+		want   string
+	}{
+		{"short header", 'o', func(raw []byte) []byte { return raw[:6] }, "6 bytes are too short for the 10 byte header"},
+		{"bad contour count", 'o', func(raw []byte) []byte {
+			binary.BigEndian.PutUint16(raw, 0xFFFE)
+			return raw
+		}, "numberOfContours -2 is less than -1"},
+		{"contours past end", 'o', func(raw []byte) []byte {
+			binary.BigEndian.PutUint16(raw, 5000)
+			return raw
+		}, "5000 contours do not fit in"},
+		{"end points not increasing", 'o', func(raw []byte) []byte {
+			copy(raw[12:14], raw[10:12])
+			return raw
+		}, "endPtsOfContours[1]"},
+		{"instructions past end", 'o', func(raw []byte) []byte {
+			binary.BigEndian.PutUint16(raw[14:], 0xFFFF)
+			return raw
+		}, "instructionLength 65535 runs past the glyph length"},
+		{"component past numGlyphs", 'Á', func([]byte) []byte {
+			return composite(GlyphIndex(numGlyphs))
+		}, fmt.Sprintf("component 0 refers to glyph %d, numGlyphs is %d", numGlyphs, numGlyphs)},
+		{"truncated composite", 'Á', func([]byte) []byte { return composite(cmap['A'])[:16] }, "composite:"},
+	}
+	setGlyphData(f, cmap['Á'], composite(cmap['A']))
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true}); err != nil {
+		t.Fatalf("valid composite: %v %v", err, rep.Errors())
+	}
+
+	for _, tt := range tests {
+		f := loadGoRegular(t)
+		gid := cmap[tt.r]
+		setGlyphData(f, gid, tt.modify(bytes.Clone(f.glyf.descs[gid].raw)))
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateBytes(buf.Bytes()); err != nil {
+			t.Fatalf("%s: glyph checks are opt-in, got %v", tt.name, err)
+		}
+		rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{CheckGlyphs: true})
+		want := fmt.Sprintf("glyf: glyph %d: %s", gid, tt.want)
+		if err == nil || len(rep.Errors()) != 1 || !strings.HasPrefix(rep.Errors()[0].String(), want) {
+			t.Fatalf("%s: got %v %v, want %q", tt.name, err, rep.Errors(), want)
+		}
+	}
+}
+
+func TestParse_PostNumGlyphs(t *testing.T) {
+	f := loadGoRegular(t)
+	numGlyphs := int(f.maxp.numGlyphs)
+	// The writer gives post as many names as it has, so off-by-a-few fonts are easily made.
+	withNames := func(n int) []byte {
+		f := loadGoRegular(t)
+		f.post.glyphNames = slices.Grow(f.post.glyphNames, max(0, n-numGlyphs))[:n]
+		f.markDirty(tagPost)
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, n := range []int{numGlyphs - 3, numGlyphs + 2} {
+		data := withNames(n)
+		f, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%d names: %v", n, err)
+		}
+		want := fmt.Sprintf("post has names for %d glyphs, maxp.numGlyphs is %d", n, numGlyphs)
+		if w := f.Warnings(); len(w) != 1 || w[0] != want {
+			t.Fatalf("warnings %q, want %q", w, want)
+		}
+		if len(f.post.glyphNames) != min(n, numGlyphs) {
+			t.Fatalf("%d glyph names, want %d", len(f.post.glyphNames), min(n, numGlyphs))
+		}
+
+		if _, err := ParseStrict(bytes.NewReader(data)); !errors.Is(err, errRangeCheck) || !strings.Contains(err.Error(), want) {
+			t.Fatalf("strict: got %v", err)
+		}
+		rep, err := ValidateBytesReport(data, ValidationOptions{})
+		if err == nil || len(rep.Errors()) != 1 || !strings.Contains(rep.Errors()[0].String(), "post: names for") {
+			t.Fatalf("validation: got %v %v", err, rep)
+		}
+	}
+	if f, err := ParseStrict(bytes.NewReader(withNames(numGlyphs))); err != nil || len(f.Warnings()) != 0 {
+		t.Fatalf("consistent post, strict: %v", err)
+	}
+}
+
+func TestCheckCmap(t *testing.T) {
+	f := loadGoRegular(t)
+	rep := &ValidationReport{}
+	f.checkCmap(rep, ProfileDefault)
+	if len(rep.Errors())+len(rep.Warnings()) != 0 {
+		t.Fatalf("Go Regular: %v", rep)
+	}
+
+	st := f.cmap.subtables["4,3,1"].ctx.(cmapSubtableFormat4)
+	st.endCode = slices.Clone(st.endCode)
+	st.endCode[1], st.endCode[2] = st.endCode[2], st.endCode[1]
+	st.endCode[len(st.endCode)-1] = 0xFFFE
+	st.searchRange++
+	f.cmap.subtables["4,3,1"].ctx = st
+	f.cmap.subtables["4,3,1"].charcodeToGID['A'] = GlyphIndex(f.maxp.numGlyphs)
+	rep = &ValidationReport{}
+	f.checkCmap(rep, ProfileDefault)
+	var got []string
+	for _, e := range rep.Errors() {
+		got = append(got, e.String())
+	}
+	for _, want := range []string{"segment 2 starting", "last segment ends at 65534", "1 codes map to glyphs past numGlyphs"} {
+		if !slices.ContainsFunc(got, func(s string) bool { return strings.Contains(s, want) }) {
+			t.Fatalf("no error %q in %q", want, got)
+		}
+	}
+	if len(rep.Warnings()) != 1 || !strings.Contains(rep.Warnings()[0].Message, "searchRange") {
+		t.Fatalf("warnings %v", rep.Warnings())
+	}
+}
+
+func TestValidationReport_Severity(t *testing.T) {
+	// A wrong searchRange in the offset table and Windows metrics below the glyph box only
+	// give warnings: the font is valid.
+	data := bytes.Clone(goregular.TTF)
+	binary.BigEndian.PutUint16(data[6:], 0)
+	fixChecksums(t, data)
+	rep, err := ValidateBytesReport(data, ValidationOptions{})
+	if err != nil || rep.HasErrors() || len(rep.Errors()) != 0 {
+		t.Fatalf("got %v %v", err, rep.Errors())
+	}
+	if err := ValidateBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	warnings := rep.Warnings()
+	for _, want := range []string{"offset table searchRange", "usWinAscent"} {
+		if !slices.ContainsFunc(warnings, func(f Finding) bool { return strings.Contains(f.Message, want) }) {
+			t.Fatalf("got warnings %v, want %q", warnings, want)
+		}
+	}
+	if len(warnings) != len(rep.Findings) {
+		t.Fatalf("%d warnings of %d findings", len(warnings), len(rep.Findings))
+	}
+	for _, f := range warnings {
+		if f.Severity != SeverityWarning {
+			t.Fatalf("%v: severity %v", f, f.Severity)
+		}
+	}
+
+	// A checksum mismatch is an error, reported with the warnings rather than instead of them.
+	f := loadGoRegular(t)
+	post := f.trec.trMap[tagPost]
+	data = bytes.Clone(goregular.TTF)
+	data[post.offset]++
+	rep, err = ValidateBytesReport(data, ValidationOptions{})
+	if err == nil || ValidateBytes(data) == nil || !rep.HasErrors() {
+		t.Fatalf("corrupt post: %v %v", err, rep.Findings)
+	}
+	errs := rep.Errors()
+	if len(errs) != 2 || errs[0].Severity != SeverityError || !strings.HasPrefix(errs[0].String(), "head: file checksum mismatch") ||
+		!strings.HasPrefix(errs[1].String(), "post: checksum incorrect") {
+		t.Fatalf("corrupt post: errors %v", errs)
+	}
+	if len(rep.Warnings()) == 0 {
+		t.Fatal("corrupt post: warnings dropped")
+	}
+	if SeverityError.String() != "error" || SeverityWarning.String() != "warning" {
+		t.Fatal("Severity.String")
+	}
+}
+
+// TestHeadChecksum pins the rule that the table record checksum of head, like the file
+// checksum, counts checksumAdjustment as 0, as fontTools and OTS calculate it. The Go fonts
+// were built that way, their values are pinned here.
+func TestHeadChecksum(t *testing.T) {
+	pinned := []struct {
+		name       string
+		data       []byte
+		checksum   uint32
+		adjustment uint32
+	}{
+		{"Go Regular", goregular.TTF, 0x18F252D4, 0x4E91196D},
+		{"Go Bold", gobold.TTF, 0x190552F9, 0x8B725407},
+	}
+	for _, p := range pinned {
+		f, err := Parse(bytes.NewReader(p.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		head := f.trec.trMap[tagHead]
+		if head.checksum != p.checksum || f.head.checksumAdjustment != p.adjustment {
+			t.Fatalf("%s: checksum %08X, checksumAdjustment %08X: fixture changed", p.name, head.checksum, f.head.checksumAdjustment)
+		}
+		data := p.data[head.offset : uint32(head.offset)+head.length]
+		if got := tableChecksum(tagHead, data); got != p.checksum {
+			t.Fatalf("%s: head checksum %08X, want %08X", p.name, got, p.checksum)
+		}
+		if got := fileChecksumAdjustment(bytes.Clone(p.data), int64(head.offset)); got != p.adjustment {
+			t.Fatalf("%s: checksumAdjustment %08X, want %08X", p.name, got, p.adjustment)
+		}
+		if err := ValidateBytes(p.data); err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+
+		// Write follows the rule, and validation rejects the other one.
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		written := buf.Bytes()
+		if err := ValidateBytes(written); err != nil {
+			t.Fatalf("%s: written: %v", p.name, err)
+		}
+		g, err := Parse(bytes.NewReader(written))
+		if err != nil {
+			t.Fatal(err)
+		}
+		head = g.trec.trMap[tagHead]
+		data = written[head.offset : uint32(head.offset)+head.length]
+		if want := calcChecksum(data) - g.head.checksumAdjustment; head.checksum != want {
+			t.Fatalf("%s: written head checksum %08X, want %08X", p.name, head.checksum, want)
+		}
+		i := slices.Index(g.trec.list, head)
+		binary.BigEndian.PutUint32(written[12+16*i+4:], calcChecksum(data))
+		rep, err := ValidateBytesReport(written, ValidationOptions{})
+		if err == nil || !slices.ContainsFunc(rep.Errors(), func(f Finding) bool { return strings.HasPrefix(f.String(), "head: checksum incorrect") }) {
+			t.Fatalf("%s: checksum with checksumAdjustment: %v %v", p.name, err, rep.Errors())
+		}
+	}
+}
+
+func TestValidate_OTSProfile(t *testing.T) {
+	f, err := Parse(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// patch returns Go Regular with the bytes of table `tag` from `at` on replaced by `b`.
+	patch := func(tag Tag, at int, b ...byte) []byte {
+		data := bytes.Clone(goregular.TTF)
+		copy(data[int(f.trec.trMap[tag].offset)+at:], b)
+		fixChecksums(t, data)
+		return data
+	}
+	// The format 4 subtable of Go Regular, relative to the start of cmap.
+	format4 := func() int {
+		cmap := goregular.TTF[f.trec.trMap[tagCmap].offset:]
+		for i := range int(binary.BigEndian.Uint16(cmap[2:])) {
+			at := int(binary.BigEndian.Uint32(cmap[4+8*i+4:]))
+			if binary.BigEndian.Uint16(cmap[at:]) == 4 {
+				return at
+			}
+		}
+		t.Fatal("no format 4 cmap subtable")
+		return 0
+	}()
+	// The table last in the file moved 2 bytes on.
+	misaligned := func() []byte {
+		last := slices.MaxFunc(f.trec.list, func(a, b *tableRecord) int { return int(a.offset) - int(b.offset) })
+		data := slices.Insert(bytes.Clone(goregular.TTF), int(last.offset), 0, 0)
+		binary.BigEndian.PutUint32(data[12+16*slices.Index(f.trec.list, last)+8:], uint32(last.offset)+2)
+		fixChecksums(t, data)
+		return data
+	}()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"misaligned table", misaligned, "not a multiple of 4"},
+		{"unitsPerEm", patch(tagHead, 18, 0, 8), "head: unitsPerEm 8 outside 16 to 16384"},
+		{"hhea reserved", patch(tagHhea, 26, 0, 1), "hhea: reserved fields [0 1 0 0], not 0"},
+		{"metricDataFormat", patch(tagHhea, 32, 0, 1), "hhea: metricDataFormat 1, not 0"},
+		{"maxp version", patch(tagMaxp, 0, 0, 2, 0, 0), "maxp: version 00020000, not 0.5 or 1.0"},
+		{"cmap language", patch(tagCmap, format4+4, 0, 3), "language 3, not 0"},
+		{"cmap searchRange", patch(tagCmap, format4+8, 0, 2), "searchRange/entrySelector/rangeShift 2/"},
+	}
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{{"Go Regular", goregular.TTF}, {"Go Bold", gobold.TTF}} {
+		if _, err := ValidateBytesReport(tt.data, ValidationOptions{Profile: ProfileOTS}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+	}
+	for _, tt := range tests {
+		if _, err := ValidateBytesReport(tt.data, ValidationOptions{}); err != nil {
+			t.Fatalf("%s: default profile: %v", tt.name, err)
+		}
+		rep, err := ValidateBytesReport(tt.data, ValidationOptions{Profile: ProfileOTS})
+		if err == nil || !slices.ContainsFunc(rep.Errors(), func(f Finding) bool { return strings.Contains(f.String(), tt.want) }) {
+			t.Fatalf("%s: got errors %v, want %q", tt.name, rep.Errors(), tt.want)
+		}
+	}
+
+	// Write sorts the table directory, whatever the order of the records.
+	slices.Reverse(f.trec.list)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := ValidateBytesReport(buf.Bytes(), ValidationOptions{Profile: ProfileOTS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(rep.Warnings(), func(f Finding) bool { return strings.Contains(f.Message, "not sorted") }) {
+		t.Fatalf("written: got warnings %v", rep.Warnings())
+	}
+}
+
+// cffFixture returns Go Regular converted to a font with CFF outlines: sfntVersion 'OTTO', a
+// placeholder CFF table instead of loca and glyf, and maxp version 0.5.
+func cffFixture(t *testing.T) []byte {
+	t.Helper()
+	f := loadGoRegular(t)
+	f.ot.sfntVersion = 0x4F54544F
+	f.loca, f.glyf = nil, nil
+	f.rawTables = append(f.rawTables, rawTable{tag: tagCFF, data: []byte{1, 0, 4, 4}})
+	f.markDirty(tagMaxp)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMaxpVersions(t *testing.T) {
+	data := cffFixture(t)
+	maxp := tableData(t, data)[tagMaxp]
+	if len(maxp) != 6 || binary.BigEndian.Uint32(maxp) != uint32(maxpVersion05) {
+		t.Fatalf("CFF maxp is % X, want version 0.5 with numGlyphs only", maxp)
+	}
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.maxp.version != maxpVersion05 || f.maxp.numGlyphs != loadGoRegular(t).maxp.numGlyphs {
+		t.Fatalf("CFF maxp parsed as %+v", f.maxp)
+	}
+	f.markDirty(tagMaxp)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := tableData(t, buf.Bytes())[tagMaxp]; !bytes.Equal(got, maxp) {
+		t.Fatalf("rewritten CFF maxp is % X, want % X", got, maxp)
+	}
+
+	f = loadGoRegular(t)
+	f.markDirty(tagMaxp)
+	buf.Reset()
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tableData(t, buf.Bytes())[tagMaxp], tableData(t, goregular.TTF)[tagMaxp]; !bytes.Equal(got, want) {
+		t.Fatalf("rewritten TrueType maxp is % X, want % X", got, want)
+	}
+
+	withVersion := func(data []byte, v Fixed) []byte {
+		data = bytes.Clone(data)
+		f, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary.BigEndian.PutUint32(data[f.trec.trMap[tagMaxp].offset:], uint32(v))
+		fixChecksums(t, data)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"TrueType 1.0", goregular.TTF, ""},
+		{"TrueType 0.5", withVersion(goregular.TTF, maxpVersion05), "error maxp: version 0.5 with TrueType outlines, not 1.0"},
+		{"CFF 0.5", data, ""},
+		{"CFF 1.0", withVersion(data, maxpVersion10), "warning maxp: version 1.0 with CFF outlines, not 0.5"},
+	}
+	for _, tt := range tests {
+		rep, err := ValidateBytesReport(tt.data, ValidationOptions{})
+		if rep == nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, f := range rep.Findings {
+			if f.Table == tagMaxp || f.Table == tagLoca || f.Table == tagGlyf {
+				got = append(got, fmt.Sprintf("%s %s", f.Severity, f))
+			}
+		}
+		if tt.want == "" && len(got) > 0 || tt.want != "" && !slices.Contains(got, tt.want) {
+			t.Fatalf("%s: got findings %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_IgnoreChecksums(t *testing.T) {
+	f := loadGoRegular(t)
+	f.rawTables = append(f.rawTables, rawTable{tag: MustTag("DSIG"), data: []byte{0, 0, 0, 1, 0, 0, 0, 0}})
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+	g, err := Parse(bytes.NewReader(good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	adj := good[g.trec.trMap[tagHead].offset+8:]
+
+	// A stale DSIG checksum with checksumAdjustment matching the file.
+	badDSIG := bytes.Clone(good)
+	i := slices.IndexFunc(g.trec.list, func(tr *tableRecord) bool { return tr.tableTag == MustTag("DSIG") })
+	binary.BigEndian.PutUint32(badDSIG[12+16*i+4:], 0xDEADBEEF)
+	binary.BigEndian.PutUint32(badDSIG[g.trec.trMap[tagHead].offset+8:], 0)
+	binary.BigEndian.PutUint32(badDSIG[g.trec.trMap[tagHead].offset+8:], 0xB1B0AFBA-calcChecksum(badDSIG))
+	// A wrong checksumAdjustment.
+	badAdj := bytes.Clone(good)
+	binary.BigEndian.PutUint32(badAdj[g.trec.trMap[tagHead].offset+8:], binary.BigEndian.Uint32(adj)+1)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		opts     ValidationOptions
+		errors   int
+		warnings []string
+	}{
+		{"stale DSIG", badDSIG, ValidationOptions{}, 1, nil},
+		{"stale DSIG, preset", badDSIG, LenientChecksumOptions(), 0, []string{"DSIG: checksum incorrect"}},
+		{"stale DSIG, listed with space", badDSIG, ValidationOptions{IgnoreChecksumFor: []string{"GPOS", "DSIG "}}, 0, []string{"DSIG: checksum incorrect"}},
+		{"stale DSIG, other listed", badDSIG, ValidationOptions{IgnoreChecksumFor: []string{"GPOS"}}, 1, nil},
+		{"wrong adjustment", badAdj, LenientChecksumOptions(), 1, nil},
+	}
+	for _, tt := range tests {
+		rep, err := ValidateBytesReport(tt.data, tt.opts)
+		if rep == nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(rep.Errors()) != tt.errors || (err != nil) != (tt.errors > 0) {
+			t.Fatalf("%s: got errors %v (%v), want %d", tt.name, rep.Errors(), err, tt.errors)
+		}
+		for _, want := range tt.warnings {
+			if !slices.ContainsFunc(rep.Warnings(), func(w Finding) bool { return strings.HasPrefix(w.String(), want) }) {
+				t.Fatalf("%s: got warnings %v, want %q", tt.name, rep.Warnings(), want)
+			}
+		}
+	}
+}